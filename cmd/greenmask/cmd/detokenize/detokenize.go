@@ -0,0 +1,196 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package detokenize
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/eminano/greenmask/internal/domains"
+	"github.com/eminano/greenmask/internal/utils/logger"
+	"github.com/eminano/greenmask/internal/utils/tokenvault"
+)
+
+const (
+	JsonFormatName = "json"
+	TextFormatName = "text"
+)
+
+const (
+	keyEnvVariable      = "GREENMASK_TOKENIZE_KEY"
+	vaultDsnEnvVariable = "GREENMASK_TOKENIZE_VAULT_DSN"
+)
+
+var (
+	Cmd = &cobra.Command{
+		Use:   "detokenize [flags] [token...]",
+		Short: "look up the original values of the tokens generated by Tokenize transformer",
+		Long: "Look up the original values of the tokens generated by Tokenize transformer. The tokens are read " +
+			"from arguments or from stdin line by line. The vault key must be provided via " + keyEnvVariable +
+			" environment variable. Each lookup is written to the log for audit",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := logger.SetLogLevel(Config.Log.Level, Config.Log.Format); err != nil {
+				log.Fatal().Err(err).Msg("")
+			}
+
+			if err := run(args); err != nil {
+				log.Fatal().Err(err).Msg("")
+			}
+		},
+	}
+	Config     = domains.NewConfig()
+	vaultType  string
+	vaultDsn   string
+	vaultTable string
+	format     string
+)
+
+type lookupResult struct {
+	Token string  `json:"token"`
+	Value *string `json:"value"`
+}
+
+func run(tokens []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keyHex := os.Getenv(keyEnvVariable)
+	if keyHex == "" {
+		return fmt.Errorf("vault key is required: use %s environment variable", keyEnvVariable)
+	}
+	key, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return fmt.Errorf("unable to decode vault key: %w", err)
+	}
+	if vaultDsn == "" {
+		vaultDsn = os.Getenv(vaultDsnEnvVariable)
+	}
+	if vaultDsn == "" {
+		return fmt.Errorf("vault dsn is required: use --vault-dsn flag or %s environment variable", vaultDsnEnvVariable)
+	}
+	if format != JsonFormatName && format != TextFormatName {
+		return fmt.Errorf(`unknown format "%s"`, format)
+	}
+	if len(tokens) == 0 {
+		if tokens, err = readTokens(); err != nil {
+			return err
+		}
+	}
+
+	vault, err := tokenvault.OpenExisting(ctx, vaultType, vaultDsn, vaultTable, key)
+	if err != nil {
+		return fmt.Errorf("unable to open token vault: %w", err)
+	}
+	defer vault.Close()
+
+	requestedBy := getUserName()
+	results := make([]*lookupResult, 0, len(tokens))
+	for _, token := range tokens {
+		res := &lookupResult{Token: token}
+		value, err := vault.Detokenize(ctx, token)
+		if err != nil && !errors.Is(err, tokenvault.ErrTokenNotFound) {
+			return fmt.Errorf("unable to detokenize \"%s\": %w", token, err)
+		}
+		if err == nil {
+			v := string(value)
+			res.Value = &v
+		}
+		log.Info().
+			Str("Token", token).
+			Str("RequestedBy", requestedBy).
+			Str("VaultType", vaultType).
+			Bool("Found", res.Value != nil).
+			Msg("token lookup")
+		results = append(results, res)
+	}
+
+	if format == JsonFormatName {
+		return json.NewEncoder(os.Stdout).Encode(results)
+	}
+	printText(results)
+	return nil
+}
+
+func readTokens() ([]string, error) {
+	var tokens []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		token := strings.TrimSpace(scanner.Text())
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read tokens from stdin: %w", err)
+	}
+	return tokens, nil
+}
+
+func printText(results []*lookupResult) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"token", "value"})
+	table.SetAutoWrapText(false)
+	for _, res := range results {
+		value := "<not found>"
+		if res.Value != nil {
+			value = *res.Value
+		}
+		table.Append([]string{res.Token, value})
+	}
+	table.Render()
+}
+
+func getUserName() string {
+	u, err := user.Current()
+	if err != nil {
+		return "unknown"
+	}
+	return u.Username
+}
+
+func init() {
+	Cmd.Flags().StringVar(&vaultType,
+		"vault",
+		tokenvault.SqliteVaultType,
+		fmt.Sprintf("token vault type [%s|%s]", tokenvault.SqliteVaultType, tokenvault.PostgresVaultType),
+	)
+	Cmd.Flags().StringVar(&vaultDsn,
+		"vault-dsn",
+		"",
+		"token vault file path for sqlite vault or connection string for postgres vault",
+	)
+	Cmd.Flags().StringVar(&vaultTable,
+		"vault-table",
+		tokenvault.DefaultTableName,
+		"token vault table name",
+	)
+	Cmd.Flags().StringVarP(&format,
+		"format",
+		"f",
+		TextFormatName,
+		"output format [text|json]",
+	)
+}
@@ -46,6 +46,7 @@ var (
 			if err != nil {
 				log.Fatal().Err(err).Msg("fatal")
 			}
+			dumpsSt := st
 			st = st.SubStorage(strconv.FormatInt(time.Now().UnixMilli(), 10), true)
 
 			if Config.Common.TempDirectory == "" {
@@ -53,6 +54,7 @@ var (
 			}
 
 			dump := cmdInternals.NewDump(Config, st, utils.DefaultTransformerRegistry)
+			dump.SetDumpsStorage(dumpsSt)
 
 			if err := dump.Run(ctx); err != nil {
 				log.Fatal().Err(err).Msg("cannot make a backup")
@@ -72,10 +72,15 @@ type jsonResponse struct {
 func run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	err := custom.BootstrapCustomTransformers(ctx, utils.DefaultTransformerRegistry, Config.CustomTransformers)
+	customResources, err := custom.BootstrapCustomTransformers(ctx, utils.DefaultTransformerRegistry, Config.CustomTransformers)
 	if err != nil {
 		return fmt.Errorf("error registering custom transformer: %w", err)
 	}
+	defer func() {
+		if err := customResources.Close(ctx); err != nil {
+			log.Warn().Err(err).Msg("error closing custom transformers")
+		}
+	}()
 
 	// TODO: Consider about listing format. The transformer can have one and more columns as an input
 	// 		and
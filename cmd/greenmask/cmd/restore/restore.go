@@ -27,6 +27,7 @@ import (
 	"github.com/eminano/greenmask/internal/storages"
 
 	cmdInternals "github.com/eminano/greenmask/internal/db/postgres/cmd"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
 	pgDomains "github.com/eminano/greenmask/internal/domains"
 	"github.com/eminano/greenmask/internal/storages/builder"
 	"github.com/eminano/greenmask/internal/utils/logger"
@@ -63,7 +64,7 @@ var (
 
 			restore := cmdInternals.NewRestore(
 				Config.Common.PgBinPath, st, &Config.Restore, Config.Restore.Scripts,
-				Config.Common.TempDirectory,
+				Config.Common.TempDirectory, utils.DefaultTransformerRegistry, Config.CustomTransformers,
 			)
 
 			log.Info().
@@ -123,7 +124,6 @@ func getDumpId(ctx context.Context, st storages.Storager, dumpId string) (string
 
 // TODO: Options currently are not implemented:
 //  	* exit-on-error
-// 		* single-transaction
 //		* disable-triggers
 //		* enable-row-security
 //		* no-data-for-failed-tables
@@ -153,7 +153,7 @@ func init() {
 	Cmd.Flags().StringSliceVarP(&Config.Restore.PgRestoreOptions.Table, "table", "t", []string{}, "restore named relation (table, view, etc.)")
 	Cmd.Flags().StringSliceVarP(&Config.Restore.PgRestoreOptions.Trigger, "trigger", "T", []string{}, "restore named trigger")
 	Cmd.Flags().BoolP("no-privileges", "X", false, "skip restoration of access privileges (grant/revoke)")
-	Cmd.Flags().BoolP("single-transaction", "1", false, "restore as a single transaction")
+	Cmd.Flags().BoolP("single-transaction", "1", false, "restore each section (pre-data, data, post-data) in its own single transaction")
 	Cmd.Flags().BoolP("disable-triggers", "", false, "disable triggers during data section restore")
 	Cmd.Flags().BoolP("enable-row-security", "", false, "enable row security")
 	Cmd.Flags().BoolP("if-exists", "", false, "use IF EXISTS when dropping objects")
@@ -188,6 +188,24 @@ func init() {
 		"overriding-system-value", "", false,
 		"use OVERRIDING SYSTEM VALUE clause for INSERTs",
 	)
+	Cmd.Flags().BoolP(
+		"truncate", "", false,
+		"clean the restoring tables in the reverse dependency order before the data section restore",
+	)
+	Cmd.Flags().StringP(
+		"truncate-method", "", "truncate",
+		"the method used for cleaning the tables: truncate or delete",
+	)
+	Cmd.Flags().BoolP(
+		"defer-constraints", "", false,
+		"drop indexes and foreign keys of the restoring tables before the data section and recreate them in parallel"+
+			" after the data is restored",
+	)
+
+	Cmd.Flags().StringP(
+		"sqlite-file", "", "",
+		"restore the tables into the SQLite file instead of the database (the file must not exist)",
+	)
 
 	// Connection options:
 	Cmd.Flags().StringP("host", "h", "/var/run/postgres", "database server host or socket directory")
@@ -203,6 +221,7 @@ func init() {
 		"no-security-labels", "no-subscriptions", "no-table-access-method", "no-tablespaces", "section",
 		"strict-names", "use-set-session-authorization", "inserts", "on-conflict-do-nothing", "restore-in-order",
 		"pgzip", "batch-size", "overriding-system-value", "superuser", "use-session-replication-role-replica",
+		"defer-constraints", "truncate", "truncate-method", "sqlite-file",
 
 		"host", "port", "username",
 	} {
@@ -26,6 +26,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/eminano/greenmask/cmd/greenmask/cmd/delete"
+	"github.com/eminano/greenmask/cmd/greenmask/cmd/detokenize"
 	"github.com/eminano/greenmask/cmd/greenmask/cmd/dump"
 	"github.com/eminano/greenmask/cmd/greenmask/cmd/list_dumps"
 	"github.com/eminano/greenmask/cmd/greenmask/cmd/list_transformers"
@@ -99,6 +100,7 @@ func init() {
 	RootCmd.AddCommand(list_transformers.Cmd)
 	RootCmd.AddCommand(validate.Cmd)
 	RootCmd.AddCommand(show_transformer.Cmd)
+	RootCmd.AddCommand(detokenize.Cmd)
 
 	if err := viper.BindPFlag("log.format", RootCmd.PersistentFlags().Lookup("log-format")); err != nil {
 		log.Fatal().Err(err).Msg("")
@@ -33,8 +33,9 @@ const (
 )
 
 var (
-	Config = pgDomains.NewConfig()
-	format string
+	Config  = pgDomains.NewConfig()
+	format  string
+	profile bool
 )
 
 var (
@@ -91,7 +92,7 @@ var (
 				}
 			}
 
-			if err := cmdInternals.ShowDump(ctx, st, dumpId, format); err != nil {
+			if err := cmdInternals.ShowDump(ctx, st, dumpId, format, profile); err != nil {
 				log.Fatal().Err(err).Msg("")
 			}
 		},
@@ -100,4 +101,5 @@ var (
 
 func init() {
 	Cmd.Flags().StringVarP(&format, "format", "f", "text", "output format [text|yaml|json]")
+	Cmd.Flags().BoolVar(&profile, "profile", false, "show the transformers profile of the dump ranked by the transformation time")
 }
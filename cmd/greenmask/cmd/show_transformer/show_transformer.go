@@ -61,10 +61,15 @@ const anyTypesValue = "any"
 func run(name string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	err := custom.BootstrapCustomTransformers(ctx, utils.DefaultTransformerRegistry, Config.CustomTransformers)
+	customResources, err := custom.BootstrapCustomTransformers(ctx, utils.DefaultTransformerRegistry, Config.CustomTransformers)
 	if err != nil {
 		return fmt.Errorf("error registering custom transformer: %w", err)
 	}
+	defer func() {
+		if err := customResources.Close(ctx); err != nil {
+			log.Warn().Err(err).Msg("error closing custom transformers")
+		}
+	}()
 
 	switch format {
 	case JsonFormatName:
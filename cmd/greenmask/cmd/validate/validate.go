@@ -169,4 +169,13 @@ func init() {
 		log.Fatal().Err(err).Msg("fatal")
 	}
 
+	profileFlagName := "profile"
+	Cmd.Flags().Bool(
+		profileFlagName, false, "Perform test dump for --rows-limit rows and print the transformers ranked by the transformation time",
+	)
+	flag = Cmd.Flags().Lookup(profileFlagName)
+	if err := viper.BindPFlag("validate.profile", flag); err != nil {
+		log.Fatal().Err(err).Msg("fatal")
+	}
+
 }
@@ -34,12 +34,14 @@ import (
 	"github.com/eminano/greenmask/internal/db/postgres/entries"
 	"github.com/eminano/greenmask/internal/db/postgres/pgdump"
 	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/internal/db/postgres/subset"
 	"github.com/eminano/greenmask/internal/db/postgres/toc"
 	_ "github.com/eminano/greenmask/internal/db/postgres/transformers"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/custom"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/eminano/greenmask/internal/domains"
 	"github.com/eminano/greenmask/internal/storages"
+	utils2 "github.com/eminano/greenmask/internal/utils"
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
 
@@ -83,6 +85,10 @@ type Dump struct {
 	// validate shows that dump worker must be in validation mode
 	validate          bool
 	validateRowsLimit uint64
+	// dumpsSt - the storage of all the dumps. The latest dump metadata is used for the dump tasks scheduling
+	dumpsSt storages.Storager
+	// previousDurations - the table data dump durations of the latest dump by the table "schema.name"
+	previousDurations map[string]time.Duration
 }
 
 func NewDump(cfg *domains.Config, st storages.Storager, registry *utils.TransformerRegistry) *Dump {
@@ -99,6 +105,38 @@ func NewDump(cfg *domains.Config, st storages.Storager, registry *utils.Transfor
 	}
 }
 
+// SetDumpsStorage - sets the storage of all the dumps. The table dump durations of the latest dump are used for
+// ordering the dump tasks
+func (d *Dump) SetDumpsStorage(st storages.Storager) {
+	d.dumpsSt = st
+}
+
+// readPreviousDurations - reads the table dump durations of the latest dump. The dump is not failed if the
+// durations cannot be read, the tables are ordered by their scores instead
+func (d *Dump) readPreviousDurations(ctx context.Context) {
+	if d.dumpsSt == nil {
+		return
+	}
+	dumpId, err := getLatestDumpId(ctx, d.dumpsSt)
+	if err != nil {
+		log.Warn().Err(err).Msg("cannot get previous dump id: the previous dump durations are not used")
+		return
+	}
+	if dumpId == "" {
+		return
+	}
+	md, err := getDumpMetadata(ctx, d.dumpsSt, dumpId)
+	if err != nil {
+		log.Warn().Err(err).Msg("cannot get previous metadata: the previous dump durations are not used")
+		return
+	}
+	d.previousDurations = getTablesDumpDurations(md)
+	log.Debug().
+		Str("PreviousDumpId", dumpId).
+		Int("TablesCount", len(d.previousDurations)).
+		Msg("previous dump durations are used for scheduling")
+}
+
 func (d *Dump) prune() {
 	d.schemaToc = nil
 	d.context = nil
@@ -282,6 +320,7 @@ func (d *Dump) taskProducer(ctx context.Context, tasks chan<- dumpers.DumpTask)
 		if d.validate {
 			dataObjects = d.context.DataSectionObjectsToValidate
 		}
+		dataObjects = scheduleDumpObjects(dataObjects, d.previousDurations)
 
 		for _, dumpObj := range dataObjects {
 			dumpObj.SetDumpId(d.dumpIdSequence)
@@ -327,6 +366,7 @@ func (d *Dump) createTocEntries() error {
 			d.dumpedObjectSizes[entry.DumpId] = storageDto.ObjectSizeStat{
 				Original:   v.OriginalSize,
 				Compressed: v.CompressedSize,
+				Duration:   v.DumpDuration,
 			}
 			if v.RelKind != 'p' {
 				// Do not create TOC entry for partitioned tables because they are not dumped. Only their partitions are
@@ -389,6 +429,38 @@ func (d *Dump) setDumpDependenciesGraph(tables []*entries.Table) {
 	}
 }
 
+// getTablesReferences - collects the references between the dumped tables. The references that use expressions or
+// polymorphic conditions are skipped because they cannot be matched by the column values in restoration
+func getTablesReferences(g *subset.Graph) []*storageDto.Reference {
+	var res []*storageDto.Reference
+	for _, e := range g.Edges() {
+		if len(e.From().PolymorphicExprs()) > 0 || len(e.To().Keys()) == 0 {
+			continue
+		}
+		if len(e.From().Keys()) != len(e.To().Keys()) {
+			continue
+		}
+		hasExpression := slices.ContainsFunc(e.From().Keys(), func(k *subset.Key) bool {
+			return k.Expression != ""
+		})
+		if hasExpression {
+			continue
+		}
+		ref := &storageDto.Reference{
+			TableOid:           e.From().Table().Oid,
+			ReferencedTableOid: e.To().Table().Oid,
+		}
+		for _, k := range e.From().Keys() {
+			ref.Columns = append(ref.Columns, k.Name)
+		}
+		for _, k := range e.To().Keys() {
+			ref.ReferencedColumns = append(ref.ReferencedColumns, k.Name)
+		}
+		res = append(res, ref)
+	}
+	return res
+}
+
 func (d *Dump) dataDump(ctx context.Context) error {
 	tasks := make(chan dumpers.DumpTask, d.pgDumpOptions.Jobs)
 
@@ -445,6 +517,7 @@ func (d *Dump) writeMetaData(ctx context.Context, startedAt, completedAt time.Ti
 	metadata, err := storageDto.NewMetadata(
 		d.resultToc, d.tocFileSize, startedAt, completedAt, d.config.Dump.Transformation, d.dumpedObjectSizes,
 		d.context.DatabaseSchema, d.dumpDependenciesGraph, d.sortedTablesDumpIds, cycles, d.tableOidToDumpId,
+		getTablesReferences(d.context.Graph), getTablesProfile(d.context.DataSectionObjects),
 	)
 	if err != nil {
 		return fmt.Errorf("unable build metadata: %w", err)
@@ -464,10 +537,17 @@ func (d *Dump) writeMetaData(ctx context.Context, startedAt, completedAt time.Ti
 func (d *Dump) Run(ctx context.Context) (err error) {
 	defer d.prune()
 	startedAt := time.Now()
+	ctx = utils2.WithTmpDir(ctx, d.config.Common.TempDirectory)
 
-	if err := custom.BootstrapCustomTransformers(ctx, d.registry, d.config.CustomTransformers); err != nil {
+	customResources, err := custom.BootstrapCustomTransformers(ctx, d.registry, d.config.CustomTransformers)
+	if err != nil {
 		return fmt.Errorf("error bootstraping custom transformers: %w", err)
 	}
+	defer func() {
+		if err := customResources.Close(ctx); err != nil {
+			log.Warn().Err(err).Msg("error closing custom transformers")
+		}
+	}()
 
 	dsn, err := d.pgDumpOptions.GetPgDSN()
 	if err != nil {
@@ -506,6 +586,8 @@ func (d *Dump) Run(ctx context.Context) (err error) {
 		return fmt.Errorf("schema only stage dumping error: %w", err)
 	}
 
+	d.readPreviousDurations(ctx)
+
 	if err = d.dataDump(ctx); err != nil {
 		return fmt.Errorf("data stage dumping error: %w", err)
 	}
@@ -0,0 +1,142 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/eminano/greenmask/internal/db/postgres/entries"
+	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/internal/storages"
+)
+
+// scheduleDumpObjects - returns the dump objects ordered by the estimated cost, the largest first, so the biggest
+// tables do not start at the end of the dump. The cost of the table is its score. If the table was dumped previously,
+// the previous dump duration is converted to the score units using the ratio of the scores to the durations of all
+// the previously dumped tables. The objects without a cost, such as sequences and large objects, keep their order
+// after the tables
+func scheduleDumpObjects(objects []entries.Entry, previousDurations map[string]time.Duration) []entries.Entry {
+	var totalScores, totalDurations float64
+	for _, obj := range objects {
+		t, ok := obj.(*entries.Table)
+		if !ok {
+			continue
+		}
+		if d, ok := previousDurations[tableKey(t.Schema, t.Name)]; ok && d > 0 {
+			totalScores += float64(getTableScores(t))
+			totalDurations += float64(d)
+		}
+	}
+
+	costs := make(map[entries.Entry]float64, len(objects))
+	for _, obj := range objects {
+		t, ok := obj.(*entries.Table)
+		if !ok {
+			continue
+		}
+		costs[obj] = float64(getTableScores(t))
+		if d, ok := previousDurations[tableKey(t.Schema, t.Name)]; ok && d > 0 && totalDurations > 0 {
+			costs[obj] = float64(d) * totalScores / totalDurations
+		}
+	}
+
+	res := slices.Clone(objects)
+	slices.SortStableFunc(res, func(a, b entries.Entry) int {
+		if costs[a] > costs[b] {
+			return -1
+		} else if costs[a] < costs[b] {
+			return 1
+		}
+		return 0
+	})
+	return res
+}
+
+// getTableScores - returns the table scores. The scores are not set if the tables are subset, so the relation size
+// is used instead
+func getTableScores(t *entries.Table) int64 {
+	if t.Scores > 0 {
+		return t.Scores
+	}
+	return t.Size
+}
+
+func tableKey(schema, name string) string {
+	return fmt.Sprintf("%s.%s", schema, name)
+}
+
+// getTablesDumpDurations - returns the table data dump durations of the dump by the table "schema.name"
+func getTablesDumpDurations(md *storageDto.Metadata) map[string]time.Duration {
+	res := make(map[string]time.Duration)
+	for _, e := range md.Entries {
+		if e.ObjectType == toc.TableDataDesc && e.Duration > 0 {
+			// The names of the TOC entries are quoted
+			res[tableKey(removeEscapeQuotes(e.Schema), removeEscapeQuotes(e.Name))] = e.Duration
+		}
+	}
+	return res
+}
+
+// getLatestDumpId - returns the id of the latest completed dump in the storage or empty string if there are no dumps
+func getLatestDumpId(ctx context.Context, st storages.Storager) (string, error) {
+	var backupNames []string
+
+	_, dirs, err := st.ListDir(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot walk through directory: %w", err)
+	}
+	for _, dir := range dirs {
+		exists, err := dir.Exists(ctx, MetadataJsonFileName)
+		if err != nil {
+			return "", fmt.Errorf("cannot check file existence: %w", err)
+		}
+		if exists {
+			backupNames = append(backupNames, dir.Dirname())
+		}
+	}
+
+	slices.SortFunc(
+		backupNames, func(a, b string) int {
+			if a > b {
+				return -1
+			}
+			return 1
+		},
+	)
+	if len(backupNames) > 0 {
+		return backupNames[0], nil
+	}
+	return "", nil
+}
+
+// getDumpMetadata - reads the metadata of the dump from the storage
+func getDumpMetadata(ctx context.Context, st storages.Storager, dumpId string) (*storageDto.Metadata, error) {
+	f, err := st.SubStorage(dumpId, true).GetObject(ctx, MetadataJsonFileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open metadata file: %w", err)
+	}
+	defer f.Close()
+
+	md := &storageDto.Metadata{}
+	if err = json.NewDecoder(f).Decode(md); err != nil {
+		return nil, fmt.Errorf("cannot decode metadata file: %w", err)
+	}
+	return md, nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/entries"
+	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func newTestDumpTable(name string, scores, size int64) *entries.Table {
+	return &entries.Table{
+		Table:  &toolkit.Table{Schema: "public", Name: name, Size: size},
+		Scores: scores,
+	}
+}
+
+func TestScheduleDumpObjects(t *testing.T) {
+	small := newTestDumpTable("small", 10, 0)
+	medium := newTestDumpTable("medium", 20, 0)
+	large := newTestDumpTable("large", 30, 0)
+	sizeOnly := newTestDumpTable("size_only", 0, 25)
+	sameAsSmall := newTestDumpTable("same_as_small", 10, 0)
+	seq1 := &entries.Sequence{Schema: "public", Name: "seq1"}
+	seq2 := &entries.Sequence{Schema: "public", Name: "seq2"}
+	blobs := &entries.Blobs{}
+
+	tests := []struct {
+		name              string
+		objects           []entries.Entry
+		previousDurations map[string]time.Duration
+		expected          []entries.Entry
+	}{
+		{
+			name:     "by scores",
+			objects:  []entries.Entry{small, large, medium},
+			expected: []entries.Entry{large, medium, small},
+		},
+		{
+			name:     "size is used without scores",
+			objects:  []entries.Entry{small, sizeOnly, large},
+			expected: []entries.Entry{large, sizeOnly, small},
+		},
+		{
+			name:     "objects without cost keep the order after the tables",
+			objects:  []entries.Entry{seq1, small, blobs, large, seq2},
+			expected: []entries.Entry{large, small, seq1, blobs, seq2},
+		},
+		{
+			name:     "equal costs keep the order",
+			objects:  []entries.Entry{sameAsSmall, large, small},
+			expected: []entries.Entry{large, sameAsSmall, small},
+		},
+		{
+			// The scores to durations ratio is 40 / 4s, so small costs 30 and large costs 10
+			name:    "previous durations",
+			objects: []entries.Entry{small, medium, large},
+			previousDurations: map[string]time.Duration{
+				"public.small": 3 * time.Second,
+				"public.large": time.Second,
+			},
+			expected: []entries.Entry{small, medium, large},
+		},
+		{
+			name:              "zero durations are ignored",
+			objects:           []entries.Entry{small, medium, large},
+			previousDurations: map[string]time.Duration{"public.small": 0},
+			expected:          []entries.Entry{large, medium, small},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]entries.Entry{}, tt.objects...)
+			require.Equal(t, tt.expected, scheduleDumpObjects(tt.objects, tt.previousDurations))
+			require.Equal(t, objects, tt.objects)
+		})
+	}
+}
+
+func TestGetTablesDumpDurations(t *testing.T) {
+	md := &storageDto.Metadata{
+		Entries: []*storageDto.Entry{
+			{ObjectType: toc.TableDataDesc, Schema: `"public"`, Name: `"Users"`, Duration: time.Second},
+			{ObjectType: toc.TableDataDesc, Schema: "public", Name: "orders"},
+			{ObjectType: toc.SequenceSetDesc, Schema: "public", Name: "users_id_seq", Duration: time.Second},
+		},
+	}
+	require.Equal(t, map[string]time.Duration{"public.Users": time.Second}, getTablesDumpDurations(md))
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/eminano/greenmask/internal/db/postgres/entries"
+	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
+)
+
+// getTablesProfile - returns the profiles of the dumped tables that have transformers
+func getTablesProfile(objects []entries.Entry) []*storageDto.TableProfile {
+	var res []*storageDto.TableProfile
+	for _, obj := range objects {
+		t, ok := obj.(*entries.Table)
+		if !ok || len(t.TransformersContext) == 0 {
+			continue
+		}
+		tp := &storageDto.TableProfile{
+			Schema:   t.Schema,
+			Name:     t.Name,
+			Rows:     t.DumpedRows,
+			Duration: t.DumpDuration,
+		}
+		for _, p := range t.GetTransformersProfile() {
+			tp.Transformers = append(tp.Transformers, &storageDto.TransformerProfile{
+				Name:       p.Name,
+				Calls:      p.Calls,
+				Rows:       p.Rows,
+				Duration:   p.Duration,
+				PushedDown: p.PushedDown,
+			})
+		}
+		res = append(res, tp)
+	}
+	return res
+}
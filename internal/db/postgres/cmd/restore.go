@@ -40,8 +40,11 @@ import (
 	"github.com/eminano/greenmask/internal/db/postgres/restorers"
 	"github.com/eminano/greenmask/internal/db/postgres/storage"
 	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/custom"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/eminano/greenmask/internal/domains"
 	"github.com/eminano/greenmask/internal/storages"
+	utils2 "github.com/eminano/greenmask/internal/utils"
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
 
@@ -91,29 +94,51 @@ type Restore struct {
 	preDataClenUpToc  string
 	postDataClenUpToc string
 	restoredDumpIds   map[int32]bool
+	// filterPlans - rows filter settings of the restoring tables by table dumpId
+	filterPlans map[int32]*tableFilterPlan
+	// filterKeys - shared storage of the restored rows keys that is used for the filters cascading
+	filterKeys *restorers.FilterKeys
+	// transformers - rows transformers of the restoring tables by table dumpId
+	transformers map[int32]*restorers.TableTransformer
+	// customResources - the resources of the custom transformers that are closed after the restoration
+	customResources    *custom.Resources
+	registry           *utils.TransformerRegistry
+	customTransformers []*custom.TransformerDefinition
+	// deferredIndexes and deferredForeignKeys - the dropped objects that must be recreated after the data restoration
+	deferredIndexes     []*deferredObject
+	deferredForeignKeys []*deferredObject
 }
 
 func NewRestore(
 	binPath string, st storages.Storager, cfg *domains.Restore, s map[string][]pgrestore.Script, tmpDir string,
+	registry *utils.TransformerRegistry, customTransformers []*custom.TransformerDefinition,
 ) *Restore {
 
 	return &Restore{
-		binPath:         binPath,
-		st:              st,
-		pgRestore:       pgrestore.NewPgRestore(binPath),
-		restoreOpt:      &cfg.PgRestoreOptions,
-		scripts:         s,
-		tmpDir:          path.Join(tmpDir, fmt.Sprintf("%d", time.Now().UnixNano())),
-		cfg:             cfg,
-		metadata:        &storage.Metadata{},
-		restoredDumpIds: make(map[int32]bool),
-		mx:              &sync.RWMutex{},
+		binPath:            binPath,
+		st:                 st,
+		pgRestore:          pgrestore.NewPgRestore(binPath),
+		restoreOpt:         &cfg.PgRestoreOptions,
+		scripts:            s,
+		tmpDir:             path.Join(tmpDir, fmt.Sprintf("%d", time.Now().UnixNano())),
+		cfg:                cfg,
+		metadata:           &storage.Metadata{},
+		restoredDumpIds:    make(map[int32]bool),
+		mx:                 &sync.RWMutex{},
+		registry:           registry,
+		customTransformers: customTransformers,
 	}
 }
 
 func (r *Restore) Run(ctx context.Context) error {
 
 	defer r.prune()
+	ctx = utils2.WithTmpDir(ctx, r.tmpDir)
+	defer func() {
+		if err := r.customResources.Close(ctx); err != nil {
+			log.Warn().Err(err).Msg("error closing custom transformers")
+		}
+	}()
 
 	if err := r.readMetadata(ctx); err != nil {
 		return fmt.Errorf("cannot read metadata: %w", err)
@@ -127,6 +152,13 @@ func (r *Restore) Run(ctx context.Context) error {
 		return fmt.Errorf("pre-flight stage restoration error: %w", err)
 	}
 
+	if r.restoreOpt.SqliteFile != "" {
+		if err := r.sqliteRestore(ctx); err != nil {
+			return fmt.Errorf("sqlite restoration error: %w", err)
+		}
+		return nil
+	}
+
 	if err := r.preDataRestore(ctx); err != nil {
 		return fmt.Errorf("pre-data stage restoration error: %w", err)
 	}
@@ -224,6 +256,10 @@ func (r *Restore) prepare() error {
 			return fmt.Errorf("restore list parsing error: %w", err)
 		}
 	}
+	if r.restoreOpt.Truncate && r.restoreOpt.TruncateMethod != "" &&
+		r.restoreOpt.TruncateMethod != truncateMethodTruncate && r.restoreOpt.TruncateMethod != truncateMethodDelete {
+		return fmt.Errorf("unknown truncate method \"%s\"", r.restoreOpt.TruncateMethod)
+	}
 	dsn, err := r.restoreOpt.GetPgDSN()
 	if err != nil {
 		return fmt.Errorf("cennot generate DSN: %w", err)
@@ -282,6 +318,12 @@ func (r *Restore) preFlightRestore(ctx context.Context) error {
 		}
 	}
 
+	// The transformers are validated before the schema restoration so the misconfiguration is found as early as
+	// possible
+	if err = r.buildTransformationPlans(ctx, getDataSectionTocEntries(r.tocObj.Entries)); err != nil {
+		return fmt.Errorf("cannot build restore transformation: %w", err)
+	}
+
 	return nil
 }
 
@@ -439,21 +481,37 @@ func (r *Restore) dataRestore(ctx context.Context) error {
 		return err
 	}
 
-	tasks := make(chan restorers.RestoreTask, r.restoreOpt.Jobs)
-	eg, gtx := errgroup.WithContext(ctx)
-
-	for j := 0; j < r.restoreOpt.Jobs; j++ {
-		eg.Go(func(id int) func() error {
-			return func() error {
-				return r.restoreWorker(gtx, tasks, id+1)
-			}
-		}(j))
+	if err = r.buildFilterPlans(getDataSectionTocEntries(r.tocObj.Entries)); err != nil {
+		return fmt.Errorf("cannot build restore filters: %w", err)
 	}
 
-	eg.Go(r.taskPusher(gtx, tasks))
+	// In the single transaction mode the whole data section is restored in the transaction of the main connection,
+	// so the refresh is atomic for the database users
+	var singleTx pgx.Tx
+	var dbConn deferredConn = conn
+	if r.restoreOpt.SingleTransaction {
+		if r.restoreOpt.Jobs > 1 {
+			log.Warn().Msg("data section is restored in a single transaction: jobs parameter is ignored")
+		}
+		singleTx, err = conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot start transaction: %w", err)
+		}
+		dbConn = singleTx
+	}
 
-	if err := eg.Wait(); err != nil {
-		return fmt.Errorf("at least one worker exited with error: %w", err)
+	if err = r.restoreDataSection(ctx, dbConn, singleTx); err != nil {
+		if singleTx != nil {
+			if rbErr := singleTx.Rollback(ctx); rbErr != nil {
+				log.Warn().Err(rbErr).Msg("cannot rollback transaction")
+			}
+		}
+		return err
+	}
+	if singleTx != nil {
+		if err = singleTx.Commit(ctx); err != nil {
+			return fmt.Errorf("cannot commit transaction: %w", err)
+		}
 	}
 
 	// Execute Data After scripts
@@ -464,6 +522,67 @@ func (r *Restore) dataRestore(ctx context.Context) error {
 	return nil
 }
 
+// restoreDataSection - cleans the tables if required and restores the data section entries. If the single transaction
+// is provided all the tasks are executed in it sequentially
+func (r *Restore) restoreDataSection(ctx context.Context, conn deferredConn, singleTx pgx.Tx) error {
+	entries := getDataSectionTocEntries(r.tocObj.Entries)
+
+	if r.restoreOpt.Truncate {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot start transaction: %w", err)
+		}
+		if err = r.truncateTables(ctx, tx, entries); err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Warn().Err(rbErr).Msg("cannot rollback transaction")
+			}
+			return fmt.Errorf("cannot clean tables: %w", err)
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return fmt.Errorf("cannot commit transaction: %w", err)
+		}
+	}
+
+	if r.restoreOpt.DeferConstraints {
+		if err := r.captureAndDropDeferredConstraints(ctx, conn, entries); err != nil {
+			return fmt.Errorf("cannot drop deferred indexes and constraints: %w", err)
+		}
+	}
+
+	tasks := make(chan restorers.RestoreTask, r.restoreOpt.Jobs)
+	eg, gtx := errgroup.WithContext(ctx)
+
+	if singleTx != nil {
+		eg.Go(func() error {
+			return r.processTasks(gtx, tasks, 1, singleTx)
+		})
+	} else {
+		for j := 0; j < r.restoreOpt.Jobs; j++ {
+			eg.Go(func(id int) func() error {
+				return func() error {
+					return r.restoreWorker(gtx, tasks, id+1)
+				}
+			}(j))
+		}
+	}
+
+	eg.Go(r.taskPusher(gtx, tasks))
+
+	restoreErr := eg.Wait()
+	// The dropped objects are recreated even if the data restoration failed, so the schema stays complete
+	if err := r.recreateDeferredConstraints(ctx, singleTx); err != nil {
+		if restoreErr != nil {
+			log.Error().Err(err).Msg("cannot recreate deferred indexes and constraints")
+		} else {
+			return fmt.Errorf("cannot recreate deferred indexes and constraints: %w", err)
+		}
+	}
+	if restoreErr != nil {
+		return fmt.Errorf("at least one worker exited with error: %w", restoreErr)
+	}
+	return nil
+}
+
 func (r *Restore) isNeedRestore(e *toc.Entry) bool {
 
 	if *e.Desc == toc.TableDataDesc || *e.Desc == toc.SequenceSetDesc {
@@ -600,64 +719,81 @@ func (r *Restore) taskPusher(ctx context.Context, tasks chan restorers.RestoreTa
 	return func() error {
 		defer close(tasks)
 		tocEntries := getDataSectionTocEntries(r.tocObj.Entries)
-		if r.restoreOpt.RestoreInOrder {
+		// The foreign keys are dropped in the deferred constraints mode, so the order does not matter
+		restoreInOrder := r.restoreOpt.RestoreInOrder && !r.restoreOpt.DeferConstraints
+		if restoreInOrder {
 			tocEntries = r.sortTocEntriesInTopoOrder(tocEntries)
 		}
+		tocEntries = r.sortEntriesByFilterDependencies(tocEntries)
+
+		var pending []*toc.Entry
 		for _, entry := range tocEntries {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
+			if entry.Section == toc.SectionData && r.isNeedRestore(entry) {
+				pending = append(pending, entry)
 			}
+		}
+		if r.restoreOpt.Jobs > 1 {
+			pending = sortEntriesBySize(pending, r.metadata)
+		}
 
-			if entry.Section == toc.SectionData {
-
-				if !r.isNeedRestore(entry) {
-					continue
-				}
-
-				if r.restoreOpt.RestoreInOrder && r.restoreOpt.Jobs > 1 {
-					deps := r.metadata.DependenciesGraph[entry.DumpId]
-					if err := r.waitDependenciesAreRestore(ctx, deps); err != nil {
-						return fmt.Errorf("cannot wait for dependencies are restored: %w", err)
-					}
-				}
-
-				var task restorers.RestoreTask
-				switch *entry.Desc {
-				case toc.TableDataDesc:
-					if r.restoreOpt.Inserts || r.restoreOpt.OnConflictDoNothing {
-						t, err := r.getTableDefinitionFromMeta(entry.DumpId)
-						if err != nil {
-							return fmt.Errorf("cannot get table definition from meta: %w", err)
-						}
-						task = restorers.NewTableRestorerInsertFormat(
-							entry, t, r.st, r.restoreOpt.ToDataSectionSettings(), r.cfg.ErrorExclusions,
-						)
-					} else {
-						task = restorers.NewTableRestorer(entry, r.st, r.restoreOpt.ToDataSectionSettings())
-					}
-
-				case toc.SequenceSetDesc:
-					task = restorers.NewSequenceRestorer(entry)
-				case toc.BlobsDesc:
-					task = restorers.NewBlobsRestorer(entry, r.st, r.restoreOpt.Pgzip)
-				}
+		for len(pending) > 0 {
+			idx, err := r.waitNextEntry(ctx, pending, restoreInOrder)
+			if err != nil {
+				return err
+			}
+			entry := pending[idx]
+			pending = slices.Delete(pending, idx, idx+1)
 
-				if task != nil {
-					select {
-					case <-ctx.Done():
-						return ctx.Err()
-					case tasks <- task:
-					}
+			task, err := r.newRestoreTask(entry)
+			if err != nil {
+				return err
+			}
+			if task != nil {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case tasks <- task:
 				}
 			}
-
 		}
 		return nil
 	}
 }
 
+func (r *Restore) newRestoreTask(entry *toc.Entry) (restorers.RestoreTask, error) {
+	switch *entry.Desc {
+	case toc.TableDataDesc:
+		filter, err := r.getTableFilter(entry.DumpId)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create table filter: %w", err)
+		}
+		if r.restoreOpt.Inserts || r.restoreOpt.OnConflictDoNothing {
+			t, err := r.getTableDefinitionFromMeta(entry.DumpId)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get table definition from meta: %w", err)
+			}
+			tr := restorers.NewTableRestorerInsertFormat(
+				entry, t, r.st, r.restoreOpt.ToDataSectionSettings(), r.cfg.ErrorExclusions,
+			)
+			tr.SetFilter(filter)
+			tr.SetTransformer(r.getTableTransformer(entry.DumpId))
+			return tr, nil
+		}
+		tr := restorers.NewTableRestorer(entry, r.st, r.restoreOpt.ToDataSectionSettings())
+		tr.SetFilter(filter)
+		tr.SetTransformer(r.getTableTransformer(entry.DumpId))
+		return tr, nil
+	case toc.SequenceSetDesc:
+		return restorers.NewSequenceRestorer(entry), nil
+	case toc.BlobsDesc:
+		br := restorers.NewBlobsRestorer(entry, r.st, r.restoreOpt.Pgzip)
+		// The large objects of the refreshed database might already exist
+		br.SetReload(r.restoreOpt.Truncate)
+		return br, nil
+	}
+	return nil, nil
+}
+
 func (r *Restore) getTableDefinitionFromMeta(dumpId int32) (*toolkit.Table, error) {
 	tableOid, ok := r.metadata.DumpIdsToTableOid[dumpId]
 	if !ok {
@@ -683,7 +819,14 @@ func (r *Restore) restoreWorker(ctx context.Context, tasks <-chan restorers.Rest
 			log.Warn().Err(err).Msgf("cannot close worker connection to DB")
 		}
 	}()
+	return r.processTasks(ctx, tasks, id, conn)
+}
 
+// processTasks - executes the restoration tasks received from the channel using the provided connection or
+// transaction
+func (r *Restore) processTasks(
+	ctx context.Context, tasks <-chan restorers.RestoreTask, id int, conn restorers.Beginner,
+) error {
 	for {
 		var task restorers.RestoreTask
 		select {
@@ -704,7 +847,7 @@ func (r *Restore) restoreWorker(ctx context.Context, tasks <-chan restorers.Rest
 			Msg("restoring")
 
 		// Open new transaction for each task
-		if err = task.Execute(ctx, conn); err != nil {
+		if err := task.Execute(ctx, conn); err != nil {
 			return fmt.Errorf("unable to perform restoration task (worker %d restoring %s): %w", id, task.DebugInfo(), err)
 		}
 		r.putDumpId(task)
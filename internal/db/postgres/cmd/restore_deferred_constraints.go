@@ -0,0 +1,421 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/eminano/greenmask/internal/db/postgres/restorers"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+)
+
+const (
+	deferredIndexKind      = "index"
+	deferredConstraintKind = "constraint"
+	deferredForeignKeyKind = "foreign key"
+)
+
+// deferredIndexesQuery - returns the indexes of the restoring tables. If the index backs the constraint (primary key,
+// unique or exclusion) the constraint definition is returned. The partitions indexes attached to the partitioned
+// table index cannot be dropped separately, so they are skipped. The last column shows whether the index can be the
+// arbiter of ON CONFLICT DO NOTHING
+const deferredIndexesQuery = `
+SELECT n.nspname,
+       t.relname,
+       ic.relname,
+       pg_get_indexdef(i.indexrelid),
+       coalesce(con.conname, ''),
+       coalesce(pg_get_constraintdef(con.oid), ''),
+       i.indisunique OR coalesce(con.contype = 'x', false)
+FROM pg_catalog.pg_index i
+         JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+         JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+         JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+         LEFT JOIN pg_catalog.pg_constraint con ON con.conindid = i.indexrelid
+    AND con.conrelid = i.indrelid
+    AND con.contype IN ('p', 'u', 'x')
+WHERE i.indrelid IN (SELECT to_regclass(tn) FROM unnest($1::TEXT[]) AS tn)
+  AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_inherits inh WHERE inh.inhrelid = i.indexrelid)
+ORDER BY n.nspname, t.relname, ic.relname
+`
+
+// deferredForeignKeysQuery - returns the foreign keys of the restoring tables and the foreign keys that reference
+// them. The foreign keys inherited from the partitioned table are skipped because they are dropped with the parent
+const deferredForeignKeysQuery = `
+SELECT n.nspname,
+       t.relname,
+       con.conname,
+       pg_get_constraintdef(con.oid)
+FROM pg_catalog.pg_constraint con
+         JOIN pg_catalog.pg_class t ON t.oid = con.conrelid
+         JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE con.contype = 'f'
+  AND con.conparentid = 0
+  AND (
+    con.conrelid IN (SELECT to_regclass(tn) FROM unnest($1::TEXT[]) AS tn)
+        OR con.confrelid IN (SELECT to_regclass(tn) FROM unnest($1::TEXT[]) AS tn)
+    )
+ORDER BY n.nspname, t.relname, con.conname
+`
+
+// deferredConn - the connection or the transaction used for capturing and dropping the deferred objects
+type deferredConn interface {
+	restorers.Beginner
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// deferredObject - index or constraint that is dropped before the data restoration and recreated after
+type deferredObject struct {
+	kind       string
+	schema     string
+	table      string
+	name       string
+	definition string
+	// conflictArbiter - the unique index or the exclusion constraint that detects the conflicts of INSERT ON CONFLICT
+	conflictArbiter bool
+}
+
+func (o *deferredObject) tableName() string {
+	return pgx.Identifier{o.schema, o.table}.Sanitize()
+}
+
+func (o *deferredObject) dropStmt() string {
+	if o.kind == deferredIndexKind {
+		return fmt.Sprintf("DROP INDEX %s", pgx.Identifier{o.schema, o.name}.Sanitize())
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", o.tableName(), pgx.Identifier{o.name}.Sanitize())
+}
+
+// createStmts - returns the statements for the object recreation. The foreign key is created as NOT VALID and then
+// validated, so the violation does not prevent the constraint creation and can be fixed later
+func (o *deferredObject) createStmts() []string {
+	switch o.kind {
+	case deferredIndexKind:
+		return []string{o.definition}
+	case deferredForeignKeyKind:
+		addStmt := fmt.Sprintf(
+			"ALTER TABLE %s ADD CONSTRAINT %s %s", o.tableName(), pgx.Identifier{o.name}.Sanitize(), o.definition,
+		)
+		// The constraint was not valid originally so it must not be validated
+		if strings.HasSuffix(o.definition, " NOT VALID") {
+			return []string{addStmt}
+		}
+		return []string{
+			addStmt + " NOT VALID",
+			fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", o.tableName(), pgx.Identifier{o.name}.Sanitize()),
+		}
+	default:
+		return []string{
+			fmt.Sprintf(
+				"ALTER TABLE %s ADD CONSTRAINT %s %s",
+				o.tableName(), pgx.Identifier{o.name}.Sanitize(), o.definition,
+			),
+		}
+	}
+}
+
+// deferredViolation - the error that occurred during the deferred object recreation
+type deferredViolation struct {
+	object *deferredObject
+	err    error
+}
+
+// captureAndDropDeferredConstraints - captures the indexes and the foreign keys of the restoring tables and drops them
+// in a single transaction
+func (r *Restore) captureAndDropDeferredConstraints(ctx context.Context, conn deferredConn, entries []*toc.Entry) error {
+	var tableNames []string
+	for _, entry := range entries {
+		if *entry.Desc != toc.TableDataDesc || !r.isNeedRestore(entry) {
+			continue
+		}
+		tableNames = append(tableNames, fmt.Sprintf("%s.%s", *entry.Namespace, *entry.Tag))
+	}
+	if len(tableNames) == 0 {
+		return nil
+	}
+
+	indexes, err := getDeferredIndexes(ctx, conn, tableNames)
+	if err != nil {
+		return fmt.Errorf("cannot get indexes: %w", err)
+	}
+	if r.restoreOpt.OnConflictDoNothing {
+		// The conflicts are not detected without the unique indexes, so they are kept
+		indexes = excludeConflictArbiters(indexes)
+	}
+	foreignKeys, err := getDeferredForeignKeys(ctx, conn, tableNames)
+	if err != nil {
+		return fmt.Errorf("cannot get foreign keys: %w", err)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	// The foreign keys are dropped first because they depend on the referenced tables unique indexes
+	for _, o := range slices.Concat(foreignKeys, indexes) {
+		log.Debug().
+			Str("Kind", o.kind).
+			Str("SchemaName", o.schema).
+			Str("TableName", o.table).
+			Str("Name", o.name).
+			Str("Definition", o.definition).
+			Msg("dropping deferred object")
+		if _, err = tx.Exec(ctx, o.dropStmt()); err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Warn().Err(rbErr).Msg("cannot rollback transaction")
+			}
+			return fmt.Errorf("cannot drop %s %s on table %s: %w", o.kind, o.name, o.tableName(), err)
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+
+	r.deferredIndexes = indexes
+	r.deferredForeignKeys = foreignKeys
+	log.Info().
+		Int("Indexes", len(indexes)).
+		Int("ForeignKeys", len(foreignKeys)).
+		Msg("indexes and foreign keys are dropped and will be recreated after the data restoration")
+	return nil
+}
+
+// recreateDeferredConstraints - recreates the dropped indexes and constraints in parallel and then the foreign keys.
+// The violations are reported and do not stop the recreation of the other objects. If the single transaction is
+// provided, the objects are recreated sequentially within it because the dropped objects are locked by it
+func (r *Restore) recreateDeferredConstraints(ctx context.Context, singleTx pgx.Tx) error {
+	if len(r.deferredIndexes) == 0 && len(r.deferredForeignKeys) == 0 {
+		return nil
+	}
+
+	recreate := r.recreateDeferredObjects
+	if singleTx != nil {
+		recreate = func(ctx context.Context, objects []*deferredObject) ([]*deferredViolation, error) {
+			return recreateDeferredObjectsInTx(ctx, singleTx, objects)
+		}
+	}
+
+	violations, err := recreate(ctx, r.deferredIndexes)
+	if err != nil {
+		return fmt.Errorf("cannot recreate indexes: %w", err)
+	}
+	fkViolations, err := recreate(ctx, r.deferredForeignKeys)
+	if err != nil {
+		return fmt.Errorf("cannot recreate foreign keys: %w", err)
+	}
+	violations = append(violations, fkViolations...)
+
+	for _, v := range violations {
+		log.Error().
+			Err(v.err).
+			Str("Kind", v.object.kind).
+			Str("SchemaName", v.object.schema).
+			Str("TableName", v.object.table).
+			Str("Name", v.object.name).
+			Strs("Statements", v.object.createStmts()).
+			Msg("deferred object violation")
+	}
+	if len(violations) > 0 {
+		if r.restoreOpt.ExitOnError {
+			return fmt.Errorf("%d indexes or constraints cannot be recreated", len(violations))
+		}
+		log.Warn().
+			Int("Violations", len(violations)).
+			Msg("some indexes or constraints cannot be recreated: check the violations above")
+		return nil
+	}
+	log.Info().Msg("indexes and foreign keys are recreated")
+	return nil
+}
+
+func (r *Restore) recreateDeferredObjects(ctx context.Context, objects []*deferredObject) ([]*deferredViolation, error) {
+	var violations []*deferredViolation
+	mx := &sync.Mutex{}
+	queue := make(chan *deferredObject)
+	eg, gtx := errgroup.WithContext(ctx)
+
+	for j := 0; j < max(r.restoreOpt.Jobs, 1); j++ {
+		eg.Go(func() error {
+			conn, err := pgx.Connect(gtx, r.dsn)
+			if err != nil {
+				return fmt.Errorf("cannot establish connection to db: %w", err)
+			}
+			defer conn.Close(gtx)
+			for o := range queue {
+				err = recreateDeferredObject(gtx, conn, o)
+				if err == nil {
+					continue
+				}
+				var pgErr *pgconn.PgError
+				if !errors.As(err, &pgErr) {
+					return fmt.Errorf("cannot recreate %s %s on table %s: %w", o.kind, o.name, o.tableName(), err)
+				}
+				mx.Lock()
+				violations = append(violations, &deferredViolation{object: o, err: err})
+				mx.Unlock()
+			}
+			return nil
+		})
+	}
+
+	eg.Go(func() error {
+		defer close(queue)
+		for _, o := range objects {
+			select {
+			case <-gtx.Done():
+				return gtx.Err()
+			case queue <- o:
+			}
+		}
+		return nil
+	})
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
+
+// recreateDeferredObjectsInTx - recreates the objects sequentially in the transaction. Each object is recreated in a
+// savepoint, so the violation does not abort the whole transaction
+func recreateDeferredObjectsInTx(ctx context.Context, tx pgx.Tx, objects []*deferredObject) ([]*deferredViolation, error) {
+	var violations []*deferredViolation
+	for _, o := range objects {
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create savepoint: %w", err)
+		}
+		if err = recreateDeferredObject(ctx, sp, o); err != nil {
+			if rbErr := sp.Rollback(ctx); rbErr != nil {
+				return nil, fmt.Errorf("cannot rollback to savepoint: %w", rbErr)
+			}
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) {
+				return nil, fmt.Errorf("cannot recreate %s %s on table %s: %w", o.kind, o.name, o.tableName(), err)
+			}
+			violations = append(violations, &deferredViolation{object: o, err: err})
+			continue
+		}
+		if err = sp.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("cannot release savepoint: %w", err)
+		}
+	}
+	return violations, nil
+}
+
+func recreateDeferredObject(ctx context.Context, conn execer, o *deferredObject) error {
+	log.Debug().
+		Str("Kind", o.kind).
+		Str("SchemaName", o.schema).
+		Str("TableName", o.table).
+		Str("Name", o.name).
+		Msg("recreating deferred object")
+	for _, stmt := range o.createStmts() {
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getDeferredIndexes(ctx context.Context, conn deferredConn, tableNames []string) ([]*deferredObject, error) {
+	rows, err := conn.Query(ctx, deferredIndexesQuery, tableNames)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*deferredObject
+	for rows.Next() {
+		var schema, table, indexName, indexDef, conName, conDef string
+		var conflictArbiter bool
+		if err = rows.Scan(&schema, &table, &indexName, &indexDef, &conName, &conDef, &conflictArbiter); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		o := &deferredObject{
+			kind:            deferredIndexKind,
+			schema:          schema,
+			table:           table,
+			name:            indexName,
+			definition:      indexDef,
+			conflictArbiter: conflictArbiter,
+		}
+		if conName != "" {
+			o.kind = deferredConstraintKind
+			o.name = conName
+			o.definition = conDef
+		}
+		res = append(res, o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return res, nil
+}
+
+// excludeConflictArbiters - returns the indexes and constraints that cannot be the arbiters of ON CONFLICT
+func excludeConflictArbiters(objects []*deferredObject) []*deferredObject {
+	res := make([]*deferredObject, 0, len(objects))
+	for _, o := range objects {
+		if o.conflictArbiter {
+			log.Debug().
+				Str("Kind", o.kind).
+				Str("SchemaName", o.schema).
+				Str("TableName", o.table).
+				Str("Name", o.name).
+				Msg("keeping conflict arbiter index")
+			continue
+		}
+		res = append(res, o)
+	}
+	return res
+}
+
+func getDeferredForeignKeys(ctx context.Context, conn deferredConn, tableNames []string) ([]*deferredObject, error) {
+	rows, err := conn.Query(ctx, deferredForeignKeysQuery, tableNames)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*deferredObject
+	for rows.Next() {
+		o := &deferredObject{
+			kind: deferredForeignKeyKind,
+		}
+		if err = rows.Scan(&o.schema, &o.table, &o.name, &o.definition); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		res = append(res, o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeferredObject_statements(t *testing.T) {
+	tests := []struct {
+		name         string
+		object       *deferredObject
+		expectedDrop string
+		expected     []string
+	}{
+		{
+			name: "index",
+			object: &deferredObject{
+				kind:       deferredIndexKind,
+				schema:     "public",
+				table:      "users",
+				name:       "users_email_idx",
+				definition: "CREATE INDEX users_email_idx ON public.users USING btree (email)",
+			},
+			expectedDrop: `DROP INDEX "public"."users_email_idx"`,
+			expected:     []string{"CREATE INDEX users_email_idx ON public.users USING btree (email)"},
+		},
+		{
+			name: "primary key",
+			object: &deferredObject{
+				kind:       deferredConstraintKind,
+				schema:     "public",
+				table:      "users",
+				name:       "users_pkey",
+				definition: "PRIMARY KEY (id)",
+			},
+			expectedDrop: `ALTER TABLE "public"."users" DROP CONSTRAINT "users_pkey"`,
+			expected:     []string{`ALTER TABLE "public"."users" ADD CONSTRAINT "users_pkey" PRIMARY KEY (id)`},
+		},
+		{
+			name: "foreign key",
+			object: &deferredObject{
+				kind:       deferredForeignKeyKind,
+				schema:     "public",
+				table:      "orders",
+				name:       "orders_user_id_fkey",
+				definition: "FOREIGN KEY (user_id) REFERENCES public.users(id)",
+			},
+			expectedDrop: `ALTER TABLE "public"."orders" DROP CONSTRAINT "orders_user_id_fkey"`,
+			expected: []string{
+				`ALTER TABLE "public"."orders" ADD CONSTRAINT "orders_user_id_fkey" ` +
+					`FOREIGN KEY (user_id) REFERENCES public.users(id) NOT VALID`,
+				`ALTER TABLE "public"."orders" VALIDATE CONSTRAINT "orders_user_id_fkey"`,
+			},
+		},
+		{
+			name: "not valid foreign key",
+			object: &deferredObject{
+				kind:       deferredForeignKeyKind,
+				schema:     "public",
+				table:      "orders",
+				name:       "orders_user_id_fkey",
+				definition: "FOREIGN KEY (user_id) REFERENCES public.users(id) NOT VALID",
+			},
+			expectedDrop: `ALTER TABLE "public"."orders" DROP CONSTRAINT "orders_user_id_fkey"`,
+			expected: []string{
+				`ALTER TABLE "public"."orders" ADD CONSTRAINT "orders_user_id_fkey" ` +
+					`FOREIGN KEY (user_id) REFERENCES public.users(id) NOT VALID`,
+			},
+		},
+		{
+			name: "quoted names",
+			object: &deferredObject{
+				kind:       deferredConstraintKind,
+				schema:     "My Schema",
+				table:      "Users",
+				name:       `uq "email"`,
+				definition: "UNIQUE (email)",
+			},
+			expectedDrop: `ALTER TABLE "My Schema"."Users" DROP CONSTRAINT "uq ""email"""`,
+			expected:     []string{`ALTER TABLE "My Schema"."Users" ADD CONSTRAINT "uq ""email""" UNIQUE (email)`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expectedDrop, tt.object.dropStmt())
+			require.Equal(t, tt.expected, tt.object.createStmts())
+		})
+	}
+}
+
+func TestExcludeConflictArbiters(t *testing.T) {
+	pk := &deferredObject{kind: deferredConstraintKind, name: "users_pkey", conflictArbiter: true}
+	uniqueIdx := &deferredObject{kind: deferredIndexKind, name: "users_email_idx", conflictArbiter: true}
+	idx := &deferredObject{kind: deferredIndexKind, name: "users_name_idx"}
+	require.Equal(t, []*deferredObject{idx}, excludeConflictArbiters([]*deferredObject{pk, uniqueIdx, idx}))
+}
+
+func TestRecreateDeferredObjectsInTx(t *testing.T) {
+	fk := &deferredObject{
+		kind:       deferredForeignKeyKind,
+		schema:     "public",
+		table:      "orders",
+		name:       "orders_user_id_fkey",
+		definition: "FOREIGN KEY (user_id) REFERENCES public.users(id)",
+	}
+	idx := &deferredObject{
+		kind:       deferredIndexKind,
+		schema:     "public",
+		table:      "orders",
+		name:       "orders_created_at_idx",
+		definition: "CREATE INDEX orders_created_at_idx ON public.orders USING btree (created_at)",
+	}
+
+	t.Run("violation is reported", func(t *testing.T) {
+		violation := &pgconn.PgError{Code: "23503", Message: "insert or update on table violates foreign key"}
+		tx := &testDeferredTx{
+			fail: map[string]error{fk.createStmts()[1]: violation},
+		}
+		violations, err := recreateDeferredObjectsInTx(context.Background(), tx, []*deferredObject{fk, idx})
+		require.NoError(t, err)
+		require.Len(t, violations, 1)
+		require.Same(t, fk, violations[0].object)
+		require.ErrorIs(t, violations[0].err, violation)
+		// The NOT VALID constraint is created before the validation fails
+		require.Equal(t, slices.Concat(fk.createStmts(), idx.createStmts()), tx.executed)
+		require.Equal(t, 1, tx.rollbacks)
+		require.Equal(t, 1, tx.commits)
+	})
+
+	t.Run("connection error", func(t *testing.T) {
+		tx := &testDeferredTx{
+			fail: map[string]error{idx.definition: errors.New("connection reset")},
+		}
+		_, err := recreateDeferredObjectsInTx(context.Background(), tx, []*deferredObject{idx, fk})
+		require.ErrorContains(t, err, "connection reset")
+		require.Equal(t, []string{idx.definition}, tx.executed)
+	})
+}
+
+// testDeferredTx - the transaction that records the executed statements and fails the statements from fail. The
+// savepoints are the same transaction
+type testDeferredTx struct {
+	pgx.Tx
+	fail      map[string]error
+	executed  []string
+	commits   int
+	rollbacks int
+}
+
+func (tx *testDeferredTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return tx, nil
+}
+
+func (tx *testDeferredTx) Commit(ctx context.Context) error {
+	tx.commits++
+	return nil
+}
+
+func (tx *testDeferredTx) Rollback(ctx context.Context) error {
+	tx.rollbacks++
+	return nil
+}
+
+func (tx *testDeferredTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	tx.executed = append(tx.executed, sql)
+	if err, ok := tx.fail[sql]; ok {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.CommandTag{}, nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/eminano/greenmask/internal/db/postgres/restorers"
+	"github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/internal/domains"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// tableFilterPlan - the rows filter settings of the restoring table built from the restore filters config and the
+// references stored in the dump metadata
+type tableFilterPlan struct {
+	table *toolkit.Table
+	// when - the filter condition. Empty condition means that the table is filtered only by the references
+	when string
+	// references - the references to the filtered tables which keys must be checked
+	references []*storage.Reference
+	// collect - the column sets which values must be collected for the referencing tables
+	collect [][]string
+	// dependsOn - the dump ids of the referenced tables that must be restored before the table
+	dependsOn []int32
+	cascade   bool
+}
+
+func (p *tableFilterPlan) addCollect(columns []string) {
+	if slices.ContainsFunc(p.collect, func(c []string) bool {
+		return slices.Equal(c, columns)
+	}) {
+		return
+	}
+	p.collect = append(p.collect, columns)
+}
+
+// buildFilterPlans - builds the filter plans for the restoring tables. The filter is assigned to the tables that
+// match the filter config. If cascade is enabled, the referencing tables are filtered by the restored keys of the
+// filtered tables recursively
+func (r *Restore) buildFilterPlans(entries []*toc.Entry) error {
+	if len(r.cfg.Filters) == 0 {
+		return nil
+	}
+	r.filterKeys = restorers.NewFilterKeys()
+	r.filterPlans = make(map[int32]*tableFilterPlan)
+
+	dumpIds, tables, err := r.getRestoringTables(entries)
+	if err != nil {
+		return err
+	}
+
+	var queue []int32
+	for _, f := range r.cfg.Filters {
+		matched := findFilteredTables(f, dumpIds, tables)
+		if len(matched) == 0 {
+			log.Warn().
+				Str("SchemaName", f.Schema).
+				Str("TableName", f.Name).
+				Msg("filtered table is not found in the restoring tables")
+			continue
+		}
+		for _, dumpId := range matched {
+			if _, ok := r.filterPlans[dumpId]; ok {
+				return fmt.Errorf("filter for table %s.%s is defined more than once", f.Schema, f.Name)
+			}
+			r.filterPlans[dumpId] = &tableFilterPlan{
+				table:   tables[dumpId],
+				when:    f.When,
+				cascade: f.Cascade,
+			}
+			if f.Cascade {
+				queue = append(queue, dumpId)
+			}
+		}
+	}
+
+	if len(queue) > 0 && r.metadata.References == nil {
+		log.Warn().
+			Msg("dump does not contain tables references: re-dump the data using the latest version of greenmask if you want to cascade the filters")
+		return nil
+	}
+
+	// Cascade the filters to the referencing tables
+	for len(queue) > 0 {
+		producerId := queue[0]
+		queue = queue[1:]
+		producer := tables[producerId]
+		for _, ref := range r.metadata.References {
+			if ref.ReferencedTableOid != producer.Oid &&
+				(producer.RootPtOid == 0 || ref.ReferencedTableOid != producer.RootPtOid) {
+				continue
+			}
+			for _, consumerId := range findTablesByOid(ref.TableOid, dumpIds, tables) {
+				consumer := tables[consumerId]
+				if consumerId == producerId || r.filterDependsOn(producerId, consumerId) {
+					log.Warn().
+						Str("SchemaName", consumer.Schema).
+						Str("TableName", consumer.Name).
+						Str("ReferencedSchemaName", producer.Schema).
+						Str("ReferencedTableName", producer.Name).
+						Msg("cannot cascade filter within the tables cycle: reference is skipped")
+					continue
+				}
+
+				if err := checkFilterReferenceTypes(ref, consumer, producer); err != nil {
+					return err
+				}
+
+				plan, ok := r.filterPlans[consumerId]
+				if !ok {
+					plan = &tableFilterPlan{
+						table: consumer,
+					}
+					r.filterPlans[consumerId] = plan
+				}
+				if !plan.cascade {
+					plan.cascade = true
+					queue = append(queue, consumerId)
+				}
+				if !slices.Contains(plan.references, ref) {
+					plan.references = append(plan.references, ref)
+				}
+				if !slices.Contains(plan.dependsOn, producerId) {
+					plan.dependsOn = append(plan.dependsOn, producerId)
+				}
+				r.filterPlans[producerId].addCollect(ref.ReferencedColumns)
+			}
+		}
+	}
+	return nil
+}
+
+// checkFilterReferenceTypes - checks that the values of the referencing columns can be compared with the restored
+// keys of the referenced columns. Otherwise, the referencing rows would be skipped silently
+func checkFilterReferenceTypes(ref *storage.Reference, table, referenced *toolkit.Table) error {
+	for i, name := range ref.Columns {
+		column := findColumnByName(table, name)
+		referencedColumn := findColumnByName(referenced, ref.ReferencedColumns[i])
+		if column == nil || referencedColumn == nil {
+			// The missing column is reported by the table filter
+			continue
+		}
+		if !restorers.FilterKeyColumnsComparable(column, referencedColumn) {
+			return fmt.Errorf(
+				"cannot cascade filter from %s.%s to %s.%s: column \"%s\" of type %s cannot be compared with "+
+					"referenced column \"%s\" of type %s",
+				referenced.Schema, referenced.Name, table.Schema, table.Name,
+				column.Name, column.TypeName, referencedColumn.Name, referencedColumn.TypeName,
+			)
+		}
+	}
+	return nil
+}
+
+func findColumnByName(t *toolkit.Table, name string) *toolkit.Column {
+	idx := slices.IndexFunc(t.Columns, func(c *toolkit.Column) bool {
+		return c.Name == name
+	})
+	if idx == -1 {
+		return nil
+	}
+	return t.Columns[idx]
+}
+
+// getRestoringTables - returns the dump ids of the restoring tables in the entries order and their definitions from
+// the metadata
+func (r *Restore) getRestoringTables(entries []*toc.Entry) ([]int32, map[int32]*toolkit.Table, error) {
+	tables := make(map[int32]*toolkit.Table)
+	var dumpIds []int32
+	for _, entry := range entries {
+		if *entry.Desc != toc.TableDataDesc || !r.isNeedRestore(entry) {
+			continue
+		}
+		t, err := r.getTableDefinitionFromMeta(entry.DumpId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot get table definition from meta: %w", err)
+		}
+		tables[entry.DumpId] = t
+		dumpIds = append(dumpIds, entry.DumpId)
+	}
+	return dumpIds, tables, nil
+}
+
+// filterDependsOn - checks that the table with dumpId depends on the table with dependencyId via filter plans
+func (r *Restore) filterDependsOn(dumpId, dependencyId int32) bool {
+	visited := make(map[int32]bool)
+	stack := []int32{dumpId}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == dependencyId {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		if plan, ok := r.filterPlans[id]; ok {
+			stack = append(stack, plan.dependsOn...)
+		}
+	}
+	return false
+}
+
+// sortEntriesByFilterDependencies - moves the filtered tables entries after the tables they depend on. The order of
+// the rest entries is kept
+func (r *Restore) sortEntriesByFilterDependencies(entries []*toc.Entry) []*toc.Entry {
+	if len(r.filterPlans) == 0 {
+		return entries
+	}
+	res := make([]*toc.Entry, 0, len(entries))
+	added := make(map[int32]bool)
+	pending := slices.Clone(entries)
+	for len(pending) > 0 {
+		var next []*toc.Entry
+		for _, entry := range pending {
+			if plan, ok := r.filterPlans[entry.DumpId]; ok {
+				ready := !slices.ContainsFunc(plan.dependsOn, func(id int32) bool {
+					return !added[id]
+				})
+				if !ready {
+					next = append(next, entry)
+					continue
+				}
+			}
+			added[entry.DumpId] = true
+			res = append(res, entry)
+		}
+		if len(next) == len(pending) {
+			// Must not happen because the dependencies cycles are excluded in the plan. Keep the rest as is
+			res = append(res, next...)
+			break
+		}
+		pending = next
+	}
+	return res
+}
+
+// getTableFilter - creates the rows filter for the table if the filter plan exists
+func (r *Restore) getTableFilter(dumpId int32) (*restorers.TableFilter, error) {
+	plan, ok := r.filterPlans[dumpId]
+	if !ok {
+		return nil, nil
+	}
+	return restorers.NewTableFilter(plan.table, plan.when, plan.references, plan.collect, r.filterKeys)
+}
+
+// getFilterDependencies - returns the dump ids of the tables that must be restored before filtering the table
+func (r *Restore) getFilterDependencies(dumpId int32) []int32 {
+	plan, ok := r.filterPlans[dumpId]
+	if !ok {
+		return nil
+	}
+	return plan.dependsOn
+}
+
+func findFilteredTables(f *domains.RestoreFilter, dumpIds []int32, tables map[int32]*toolkit.Table) []int32 {
+	var res []int32
+	for _, dumpId := range dumpIds {
+		t := tables[dumpId]
+		if (t.Schema == f.Schema && t.Name == f.Name) ||
+			(t.RootPtOid != 0 && t.RootPtSchema == f.Schema && t.RootPtName == f.Name) {
+			res = append(res, dumpId)
+		}
+	}
+	return res
+}
+
+// findTablesByOid - finds the restoring tables by oid. If the oid belongs to the partitioned table, then all the
+// partitions are returned
+func findTablesByOid(oid toolkit.Oid, dumpIds []int32, tables map[int32]*toolkit.Table) []int32 {
+	var res []int32
+	for _, dumpId := range dumpIds {
+		t := tables[dumpId]
+		if t.Oid == oid || t.RootPtOid == oid {
+			res = append(res, dumpId)
+		}
+	}
+	return res
+}
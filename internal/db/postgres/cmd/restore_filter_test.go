@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/internal/domains"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// newTestTableEntry - creates the table data entry of the table with the dump id equal to the table oid
+func newTestTableEntry(t *toolkit.Table) *toc.Entry {
+	desc := toc.TableDataDesc
+	return &toc.Entry{
+		DumpId:    int32(t.Oid),
+		Desc:      &desc,
+		Namespace: &t.Schema,
+		Tag:       &t.Name,
+	}
+}
+
+func newTestTable(oid toolkit.Oid, name string, columns ...*toolkit.Column) *toolkit.Table {
+	return &toolkit.Table{
+		Schema:  "public",
+		Name:    name,
+		Oid:     oid,
+		Columns: columns,
+	}
+}
+
+func newTestRestore(filters []*domains.RestoreFilter, references []*storage.Reference, tables ...*toolkit.Table) (
+	*Restore, []*toc.Entry,
+) {
+	r := NewRestore("", nil, &domains.Restore{Filters: filters}, nil, "", nil, nil)
+	r.metadata.DumpIdsToTableOid = make(map[int32]toolkit.Oid)
+	r.metadata.References = references
+	var entries []*toc.Entry
+	for _, t := range tables {
+		r.metadata.DatabaseSchema = append(r.metadata.DatabaseSchema, t)
+		r.metadata.DumpIdsToTableOid[int32(t.Oid)] = t.Oid
+		entries = append(entries, newTestTableEntry(t))
+	}
+	return r, entries
+}
+
+func int4Column(name string) *toolkit.Column {
+	return &toolkit.Column{Name: name, TypeName: "int4", TypeOid: pgtype.Int4OID, Length: -1, TypeLength: 4}
+}
+
+func TestRestore_buildFilterPlans(t *testing.T) {
+	users := newTestTable(1, "users", int4Column("id"))
+	orders := newTestTable(2, "orders", int4Column("id"), int4Column("user_id"))
+	items := newTestTable(3, "items", int4Column("id"), int4Column("order_id"))
+	products := newTestTable(4, "products", int4Column("id"))
+	ordersRef := &storage.Reference{
+		TableOid: orders.Oid, Columns: []string{"user_id"},
+		ReferencedTableOid: users.Oid, ReferencedColumns: []string{"id"},
+	}
+	itemsRef := &storage.Reference{
+		TableOid: items.Oid, Columns: []string{"order_id"},
+		ReferencedTableOid: orders.Oid, ReferencedColumns: []string{"id"},
+	}
+
+	t.Run("cascade chain", func(t *testing.T) {
+		r, entries := newTestRestore(
+			[]*domains.RestoreFilter{{Schema: "public", Name: "users", When: "record.id > 1", Cascade: true}},
+			[]*storage.Reference{ordersRef, itemsRef},
+			users, orders, items, products,
+		)
+		require.NoError(t, r.buildFilterPlans(entries))
+		require.Len(t, r.filterPlans, 3)
+
+		require.Equal(t, &tableFilterPlan{
+			table: users, when: "record.id > 1", collect: [][]string{{"id"}}, cascade: true,
+		}, r.filterPlans[1])
+		require.Equal(t, &tableFilterPlan{
+			table: orders, references: []*storage.Reference{ordersRef}, collect: [][]string{{"id"}},
+			dependsOn: []int32{1}, cascade: true,
+		}, r.filterPlans[2])
+		require.Equal(t, &tableFilterPlan{
+			table: items, references: []*storage.Reference{itemsRef}, dependsOn: []int32{2}, cascade: true,
+		}, r.filterPlans[3])
+		require.NotContains(t, r.filterPlans, int32(4))
+
+		require.True(t, r.filterDependsOn(3, 1))
+		require.True(t, r.filterDependsOn(2, 1))
+		require.False(t, r.filterDependsOn(1, 3))
+		require.False(t, r.filterDependsOn(4, 1))
+	})
+
+	t.Run("without cascade", func(t *testing.T) {
+		r, entries := newTestRestore(
+			[]*domains.RestoreFilter{{Schema: "public", Name: "users", When: "record.id > 1"}},
+			[]*storage.Reference{ordersRef, itemsRef},
+			users, orders, items,
+		)
+		require.NoError(t, r.buildFilterPlans(entries))
+		require.Len(t, r.filterPlans, 1)
+		require.Empty(t, r.filterPlans[1].collect)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		a := newTestTable(5, "a", int4Column("id"), int4Column("b_id"))
+		b := newTestTable(6, "b", int4Column("id"), int4Column("a_id"))
+		selfRef := &storage.Reference{
+			TableOid: a.Oid, Columns: []string{"b_id"}, ReferencedTableOid: a.Oid, ReferencedColumns: []string{"id"},
+		}
+		aRef := &storage.Reference{
+			TableOid: b.Oid, Columns: []string{"a_id"}, ReferencedTableOid: a.Oid, ReferencedColumns: []string{"id"},
+		}
+		bRef := &storage.Reference{
+			TableOid: a.Oid, Columns: []string{"b_id"}, ReferencedTableOid: b.Oid, ReferencedColumns: []string{"id"},
+		}
+		r, entries := newTestRestore(
+			[]*domains.RestoreFilter{{Schema: "public", Name: "a", When: "record.id > 1", Cascade: true}},
+			[]*storage.Reference{selfRef, aRef, bRef},
+			a, b,
+		)
+		require.NoError(t, r.buildFilterPlans(entries))
+		// The self reference and the reference back to the filtered table are skipped
+		require.Empty(t, r.filterPlans[5].references)
+		require.Empty(t, r.filterPlans[5].dependsOn)
+		require.Equal(t, []*storage.Reference{aRef}, r.filterPlans[6].references)
+		require.Equal(t, []int32{5}, r.filterPlans[6].dependsOn)
+	})
+
+	t.Run("incomparable column types", func(t *testing.T) {
+		comments := newTestTable(7, "comments", int4Column("id"),
+			&toolkit.Column{Name: "user_id", TypeName: "text", TypeOid: pgtype.TextOID},
+		)
+		ref := &storage.Reference{
+			TableOid: comments.Oid, Columns: []string{"user_id"},
+			ReferencedTableOid: users.Oid, ReferencedColumns: []string{"id"},
+		}
+		r, entries := newTestRestore(
+			[]*domains.RestoreFilter{{Schema: "public", Name: "users", When: "record.id > 1", Cascade: true}},
+			[]*storage.Reference{ref},
+			users, comments,
+		)
+		require.ErrorContains(t, r.buildFilterPlans(entries), `column "user_id" of type text cannot be compared`)
+	})
+
+	t.Run("duplicated filter", func(t *testing.T) {
+		r, entries := newTestRestore(
+			[]*domains.RestoreFilter{
+				{Schema: "public", Name: "users", When: "record.id > 1"},
+				{Schema: "public", Name: "users", When: "record.id > 2"},
+			},
+			nil,
+			users,
+		)
+		require.ErrorContains(t, r.buildFilterPlans(entries), "defined more than once")
+	})
+}
+
+func TestRestore_sortEntriesByFilterDependencies(t *testing.T) {
+	users := newTestTable(1, "users", int4Column("id"))
+	orders := newTestTable(2, "orders", int4Column("id"), int4Column("user_id"))
+	items := newTestTable(3, "items", int4Column("id"), int4Column("order_id"))
+	products := newTestTable(4, "products", int4Column("id"))
+	r, _ := newTestRestore(nil, nil)
+	r.filterPlans = map[int32]*tableFilterPlan{
+		1: {table: users},
+		2: {table: orders, dependsOn: []int32{1}},
+		3: {table: items, dependsOn: []int32{2}},
+	}
+	entries := []*toc.Entry{
+		newTestTableEntry(items), newTestTableEntry(orders), newTestTableEntry(products), newTestTableEntry(users),
+	}
+
+	res := r.sortEntriesByFilterDependencies(entries)
+	var dumpIds []int32
+	for _, e := range res {
+		dumpIds = append(dumpIds, e.DumpId)
+	}
+	require.Equal(t, []int32{4, 1, 2, 3}, dumpIds)
+
+	// Without the filter plans the order is kept
+	r.filterPlans = nil
+	require.Equal(t, entries, r.sortEntriesByFilterDependencies(entries))
+}
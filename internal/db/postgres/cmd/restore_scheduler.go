@@ -0,0 +1,78 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+)
+
+// sortEntriesBySize - sorts the entries by the original size of the dumped data, the largest first. The order of the
+// entries of the same size, such as sequences, is kept
+func sortEntriesBySize(entries []*toc.Entry, md *storageDto.Metadata) []*toc.Entry {
+	sizes := make(map[int32]int64, len(md.Entries))
+	for _, e := range md.Entries {
+		sizes[e.DumpId] = e.OriginalSize
+	}
+	res := slices.Clone(entries)
+	slices.SortStableFunc(res, func(a, b *toc.Entry) int {
+		if sizes[a.DumpId] > sizes[b.DumpId] {
+			return -1
+		} else if sizes[a.DumpId] < sizes[b.DumpId] {
+			return 1
+		}
+		return 0
+	})
+	return res
+}
+
+// getEntryDependencies - returns the dump ids that must be restored before the entry
+func (r *Restore) getEntryDependencies(entry *toc.Entry, restoreInOrder bool) []int32 {
+	var deps []int32
+	if restoreInOrder && r.restoreOpt.Jobs > 1 {
+		deps = append(deps, r.metadata.DependenciesGraph[entry.DumpId]...)
+	}
+	// The filter uses the keys of the referenced tables, so they must be restored before
+	deps = append(deps, r.getFilterDependencies(entry.DumpId)...)
+	return deps
+}
+
+// waitNextEntry - returns the index of the first pending entry whose dependencies are restored. If there is only one
+// job, the entries are restored in the provided order, so it waits for the dependencies of the first entry
+func (r *Restore) waitNextEntry(ctx context.Context, pending []*toc.Entry, restoreInOrder bool) (int, error) {
+	if r.restoreOpt.Jobs <= 1 {
+		if err := r.waitDependenciesAreRestore(ctx, r.getEntryDependencies(pending[0], restoreInOrder)); err != nil {
+			return 0, fmt.Errorf("cannot wait for dependencies are restored: %w", err)
+		}
+		return 0, nil
+	}
+	for {
+		for idx, entry := range pending {
+			if r.dependenciesAreRestored(r.getEntryDependencies(entry, restoreInOrder)) {
+				return idx, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("cannot wait for dependencies are restored: %w", ctx.Err())
+		case <-time.After(dependenciesCheckInterval):
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+)
+
+func newTestEntries(dumpIds ...int32) []*toc.Entry {
+	res := make([]*toc.Entry, 0, len(dumpIds))
+	for _, dumpId := range dumpIds {
+		res = append(res, &toc.Entry{DumpId: dumpId})
+	}
+	return res
+}
+
+func getEntriesDumpIds(entries []*toc.Entry) []int32 {
+	res := make([]int32, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e.DumpId)
+	}
+	return res
+}
+
+func TestSortEntriesBySize(t *testing.T) {
+	tests := []struct {
+		name     string
+		dumpIds  []int32
+		sizes    map[int32]int64
+		expected []int32
+	}{
+		{
+			name:     "largest first",
+			dumpIds:  []int32{1, 2, 3},
+			sizes:    map[int32]int64{1: 10, 2: 30, 3: 20},
+			expected: []int32{2, 3, 1},
+		},
+		{
+			name:     "equal sizes keep the order",
+			dumpIds:  []int32{4, 1, 2, 3},
+			sizes:    map[int32]int64{1: 10, 2: 30, 3: 10, 4: 10},
+			expected: []int32{2, 4, 1, 3},
+		},
+		{
+			name:     "entries missing from the metadata go last",
+			dumpIds:  []int32{5, 1, 6, 2},
+			sizes:    map[int32]int64{1: 10, 2: 30},
+			expected: []int32{2, 1, 5, 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := &storageDto.Metadata{}
+			for dumpId, size := range tt.sizes {
+				md.Entries = append(md.Entries, &storageDto.Entry{DumpId: dumpId, OriginalSize: size})
+			}
+			entries := newTestEntries(tt.dumpIds...)
+			require.Equal(t, tt.expected, getEntriesDumpIds(sortEntriesBySize(entries, md)))
+			require.Equal(t, tt.dumpIds, getEntriesDumpIds(entries))
+		})
+	}
+}
+
+// newTestSchedulerRestore - creates the restore where the graph contains the tables dependencies and the filtered
+// table 4 depends on the table 40
+func newTestSchedulerRestore(jobs int, graph map[int32][]int32, restored ...int32) *Restore {
+	r, _ := newTestRestore(nil, nil)
+	r.restoreOpt.Jobs = jobs
+	r.metadata.DependenciesGraph = graph
+	r.filterPlans = map[int32]*tableFilterPlan{4: {dependsOn: []int32{40}}}
+	for _, dumpId := range restored {
+		r.restoredDumpIds[dumpId] = true
+	}
+	return r
+}
+
+func TestRestore_waitNextEntry(t *testing.T) {
+	graph := map[int32][]int32{1: {10}, 2: {20}}
+	tests := []struct {
+		name           string
+		jobs           int
+		restoreInOrder bool
+		restored       []int32
+		pending        []int32
+		expected       int
+	}{
+		{
+			name:           "first entry is ready",
+			jobs:           2,
+			restoreInOrder: true,
+			pending:        []int32{3, 1},
+			expected:       0,
+		},
+		{
+			name:           "blocked entry is skipped",
+			jobs:           2,
+			restoreInOrder: true,
+			pending:        []int32{1, 2, 3},
+			expected:       2,
+		},
+		{
+			name:           "dependencies are restored",
+			jobs:           2,
+			restoreInOrder: true,
+			restored:       []int32{20},
+			pending:        []int32{1, 2, 3},
+			expected:       1,
+		},
+		{
+			name:     "dependencies are ignored without restore in order",
+			jobs:     2,
+			pending:  []int32{1, 2},
+			expected: 0,
+		},
+		{
+			name:     "filter dependencies are checked without restore in order",
+			jobs:     2,
+			pending:  []int32{4, 1},
+			expected: 1,
+		},
+		{
+			// The entries are restored in the dependencies order by the single job
+			name:           "single job ignores the graph",
+			jobs:           1,
+			restoreInOrder: true,
+			pending:        []int32{1, 3},
+			expected:       0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestSchedulerRestore(tt.jobs, graph, tt.restored...)
+			idx, err := r.waitNextEntry(context.Background(), newTestEntries(tt.pending...), tt.restoreInOrder)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, idx)
+		})
+	}
+}
+
+func TestRestore_waitNextEntry_blocked_entry_is_not_starved(t *testing.T) {
+	ctx := context.Background()
+	// The largest entry 1 waits for 10, the smaller ones are ready
+	r := newTestSchedulerRestore(2, map[int32][]int32{1: {10}})
+	pending := newTestEntries(1, 2, 3)
+
+	idx, err := r.waitNextEntry(ctx, pending, true)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), pending[idx].DumpId)
+	pending = append(pending[:idx], pending[idx+1:]...)
+
+	// Once the dependency is restored the blocked entry is taken before the smaller ones
+	r.restoredDumpIds[10] = true
+	idx, err = r.waitNextEntry(ctx, pending, true)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), pending[idx].DumpId)
+}
+
+func TestRestore_waitNextEntry_waits_for_dependencies(t *testing.T) {
+	for _, jobs := range []int{1, 2} {
+		r := newTestSchedulerRestore(jobs, nil)
+		go func() {
+			time.Sleep(3 * dependenciesCheckInterval)
+			r.mx.Lock()
+			r.restoredDumpIds[40] = true
+			r.mx.Unlock()
+		}()
+		idx, err := r.waitNextEntry(context.Background(), newTestEntries(4), false)
+		require.NoError(t, err)
+		require.Equal(t, 0, idx)
+		require.True(t, r.dependenciesAreRestored([]int32{40}))
+
+		r = newTestSchedulerRestore(jobs, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 3*dependenciesCheckInterval)
+		_, err = r.waitNextEntry(ctx, newTestEntries(4), false)
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+}
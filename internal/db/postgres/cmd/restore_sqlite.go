@@ -0,0 +1,284 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog/log"
+	_ "modernc.org/sqlite"
+
+	"github.com/eminano/greenmask/internal/db/postgres/restorers"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const (
+	sqliteDriverName = "sqlite"
+	sqliteTableDesc  = "TABLE"
+	sqliteColumnDesc = "COLUMN"
+)
+
+// sqliteSkippedObject - the dump object that was not restored or was restored with losses into the SQLite database
+type sqliteSkippedObject struct {
+	desc   string
+	schema string
+	name   string
+	reason string
+}
+
+// sqliteRestore - restores the dumped tables into the SQLite file instead of the PostgreSQL database. The tables are
+// created from the table definitions stored in the dump metadata and the data is loaded from the COPY data files.
+// The objects that cannot be restored into SQLite are skipped and printed in the report
+func (r *Restore) sqliteRestore(ctx context.Context) error {
+	fileName := r.restoreOpt.SqliteFile
+	if _, err := os.Stat(fileName); err == nil {
+		return fmt.Errorf("sqlite file \"%s\" already exists", fileName)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot check sqlite file existence: %w", err)
+	}
+
+	db, err := sql.Open(sqliteDriverName, fileName)
+	if err != nil {
+		return fmt.Errorf("cannot open sqlite file: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Warn().Err(err).Msg("cannot close sqlite file")
+		}
+	}()
+	// The restoration is sequential and a single connection keeps the transactions from locking each other
+	db.SetMaxOpenConns(1)
+
+	entries := getDataSectionTocEntries(r.tocObj.Entries)
+	if err = r.buildFilterPlans(entries); err != nil {
+		return fmt.Errorf("cannot build restore filters: %w", err)
+	}
+	entries = r.sortEntriesByFilterDependencies(entries)
+
+	dumpIds, tables, err := r.getRestoringTables(entries)
+	if err != nil {
+		return err
+	}
+	sqliteTables, tableNames, skipped := r.buildSqliteTables(dumpIds, tables)
+	skipped = append(skipped, r.getSqliteSkippedEntries(tables)...)
+
+	// The partitions are restored into the same table therefore it is created once
+	created := make(map[string]bool, len(sqliteTables))
+	for _, dumpId := range dumpIds {
+		t := sqliteTables[tableNames[dumpId]]
+		if created[t.Name] {
+			continue
+		}
+		if _, err = db.ExecContext(ctx, t.CreateStatement()); err != nil {
+			return fmt.Errorf("cannot create sqlite table %s: %w", t.Name, err)
+		}
+		created[t.Name] = true
+		log.Debug().
+			Str("TableName", t.Name).
+			Msg("sqlite table is created")
+	}
+
+	if !r.restoreOpt.SchemaOnly {
+		if err = r.sqliteRestoreData(ctx, db, entries, sqliteTables, tableNames); err != nil {
+			return err
+		}
+	}
+
+	printSqliteSkippedObjects(skipped)
+	log.Info().
+		Str("FileName", fileName).
+		Int("Tables", len(dumpIds)).
+		Int("SkippedObjects", len(skipped)).
+		Msg("dump is restored into the sqlite file")
+	return nil
+}
+
+// buildSqliteTables - maps the restoring tables definitions to the SQLite tables. The partitions are merged into the
+// partitioned table. The foreign keys are built from the references stored in the dump metadata
+func (r *Restore) buildSqliteTables(
+	dumpIds []int32, tables map[int32]*toolkit.Table,
+) (map[string]*restorers.SqliteTable, map[int32]string, []*sqliteSkippedObject) {
+	var skipped []*sqliteSkippedObject
+	sqliteTables := make(map[string]*restorers.SqliteTable)
+	tableNames := make(map[int32]string, len(dumpIds))
+	tableNamesByOid := make(map[toolkit.Oid]string)
+	for _, dumpId := range dumpIds {
+		t := tables[dumpId]
+		schema, name, oid := t.Schema, t.Name, t.Oid
+		if t.RootPtOid != 0 {
+			schema, name, oid = t.RootPtSchema, t.RootPtName, t.RootPtOid
+		}
+		sqliteName := restorers.GetSqliteTableName(schema, name)
+		tableNames[dumpId] = sqliteName
+		tableNamesByOid[t.Oid] = sqliteName
+		tableNamesByOid[oid] = sqliteName
+		if _, ok := sqliteTables[sqliteName]; ok {
+			continue
+		}
+		st := restorers.NewSqliteTable(sqliteName, t)
+		sqliteTables[sqliteName] = st
+
+		for _, c := range t.Columns {
+			if c.IsGenerated {
+				skipped = append(skipped, &sqliteSkippedObject{
+					desc:   sqliteColumnDesc,
+					schema: schema,
+					name:   fmt.Sprintf("%s.%s", name, c.Name),
+					reason: "generated column is not dumped",
+				})
+			}
+		}
+		for _, c := range st.Columns {
+			if c.Lossy {
+				skipped = append(skipped, &sqliteSkippedObject{
+					desc:   sqliteColumnDesc,
+					schema: schema,
+					name:   fmt.Sprintf("%s.%s", name, c.Name),
+					reason: fmt.Sprintf("type %s is stored as %s", c.PgType, c.Type),
+				})
+			}
+		}
+	}
+
+	for _, ref := range r.metadata.References {
+		tableName, ok := tableNamesByOid[ref.TableOid]
+		if !ok {
+			continue
+		}
+		referencedTableName, ok := tableNamesByOid[ref.ReferencedTableOid]
+		if !ok {
+			continue
+		}
+		st := sqliteTables[tableName]
+		fk := &restorers.SqliteForeignKey{
+			Columns:           ref.Columns,
+			ReferencedTable:   referencedTableName,
+			ReferencedColumns: ref.ReferencedColumns,
+		}
+		if slices.ContainsFunc(st.ForeignKeys, func(f *restorers.SqliteForeignKey) bool {
+			return slices.Equal(f.Columns, fk.Columns) && f.ReferencedTable == fk.ReferencedTable &&
+				slices.Equal(f.ReferencedColumns, fk.ReferencedColumns)
+		}) {
+			continue
+		}
+		st.ForeignKeys = append(st.ForeignKeys, fk)
+	}
+	return sqliteTables, tableNames, skipped
+}
+
+// sqliteRestoreData - loads the data of the restoring tables into the SQLite tables one by one
+func (r *Restore) sqliteRestoreData(
+	ctx context.Context, db *sql.DB, entries []*toc.Entry, sqliteTables map[string]*restorers.SqliteTable,
+	tableNames map[int32]string,
+) error {
+	for _, entry := range entries {
+		name, ok := tableNames[entry.DumpId]
+		if !ok {
+			continue
+		}
+		task := restorers.NewSqliteTableRestorer(entry, sqliteTables[name], r.st, r.restoreOpt.ToDataSectionSettings())
+		filter, err := r.getTableFilter(entry.DumpId)
+		if err != nil {
+			return fmt.Errorf("cannot create filter for %s: %w", task.DebugInfo(), err)
+		}
+		if filter != nil {
+			task.SetFilter(filter)
+		}
+		if tt := r.getTableTransformer(entry.DumpId); tt != nil {
+			task.SetTransformer(tt)
+		}
+
+		log.Debug().
+			Str("objectName", task.DebugInfo()).
+			Msg("restoring")
+		if err = task.Execute(ctx, db); err != nil {
+			return fmt.Errorf("unable to perform restoration task (restoring %s): %w", task.DebugInfo(), err)
+		}
+		log.Debug().
+			Str("objectName", task.DebugInfo()).
+			Int64("Rows", task.Rows).
+			Msgf("restoration complete")
+	}
+	return nil
+}
+
+// getSqliteSkippedEntries - returns the toc entries that cannot be restored into SQLite. The table definitions are
+// not reported if the table is restored
+func (r *Restore) getSqliteSkippedEntries(tables map[int32]*toolkit.Table) []*sqliteSkippedObject {
+	restoredOids := make(map[toolkit.Oid]bool, len(tables))
+	for _, t := range tables {
+		restoredOids[t.Oid] = true
+		if t.RootPtOid != 0 {
+			restoredOids[t.RootPtOid] = true
+		}
+	}
+	var res []*sqliteSkippedObject
+	for _, entry := range r.tocObj.Entries {
+		if entry.Desc == nil {
+			continue
+		}
+		desc := *entry.Desc
+		if desc == toc.TableDataDesc {
+			continue
+		}
+		if desc == sqliteTableDesc && restoredOids[toolkit.Oid(entry.CatalogId.Oid)] {
+			continue
+		}
+		var schema, name string
+		if entry.Namespace != nil {
+			schema = *entry.Namespace
+		}
+		if entry.Tag != nil {
+			name = *entry.Tag
+		}
+		reason := "object type is not supported by sqlite restoration"
+		switch desc {
+		case sqliteTableDesc:
+			reason = "table data is not restored"
+		case toc.SequenceSetDesc:
+			reason = "sequences are not supported by sqlite"
+		case toc.BlobsDesc, toc.LargeObjectsDesc:
+			reason = "large objects are not supported by sqlite"
+		}
+		res = append(res, &sqliteSkippedObject{
+			desc:   desc,
+			schema: schema,
+			name:   name,
+			reason: reason,
+		})
+	}
+	return res
+}
+
+func printSqliteSkippedObjects(skipped []*sqliteSkippedObject) {
+	if len(skipped) == 0 {
+		return
+	}
+	data := make([][]string, 0, len(skipped))
+	for _, o := range skipped {
+		data = append(data, []string{o.desc, o.schema, o.name, o.reason})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"type", "schema", "name", "reason"})
+	table.AppendBulk(data)
+	table.Render()
+}
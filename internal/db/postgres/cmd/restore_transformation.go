@@ -0,0 +1,338 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+
+	runtimeContext "github.com/eminano/greenmask/internal/db/postgres/context"
+	"github.com/eminano/greenmask/internal/db/postgres/restorers"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/custom"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/internal/domains"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const (
+	restoreColumnParameterName = "column"
+)
+
+// tableTransformationPlan - the transformation settings of the restoring table built from the restore transformation
+// config and the references stored in the dump metadata
+type tableTransformationPlan struct {
+	table               *toolkit.Table
+	when                string
+	columnsTypeOverride map[string]string
+	transformers        []*domains.TransformerConfig
+}
+
+// hasTransformer - checks that the transformer with the same name is already applied to the column
+func (p *tableTransformationPlan) hasTransformer(name, column string) bool {
+	return slices.ContainsFunc(p.transformers, func(tc *domains.TransformerConfig) bool {
+		return tc.Name == name && string(tc.Params[restoreColumnParameterName]) == column
+	})
+}
+
+// buildTransformationPlans - matches the restore transformation config with the restoring tables and initializes the
+// transformers for them. The transformers that have apply_for_references are applied to the referencing columns
+// using the references stored in the dump metadata
+func (r *Restore) buildTransformationPlans(ctx context.Context, entries []*toc.Entry) error {
+	if len(r.cfg.Transformation) == 0 || r.restoreOpt.SchemaOnly {
+		return nil
+	}
+
+	customResources, err := custom.BootstrapCustomTransformers(ctx, r.registry, r.customTransformers)
+	if err != nil {
+		return fmt.Errorf("error bootstraping custom transformers: %w", err)
+	}
+	r.customResources = customResources
+
+	dumpIds, tables, err := r.getRestoringTables(entries)
+	if err != nil {
+		return err
+	}
+
+	var warnings toolkit.ValidationWarnings
+	plans := make(map[int32]*tableTransformationPlan)
+	for _, cfg := range r.cfg.Transformation {
+		if cfg.Query != "" || len(cfg.SubsetConds) > 0 {
+			log.Warn().
+				Str("SchemaName", cfg.Schema).
+				Str("TableName", cfg.Name).
+				Msg("query and subset_conds are not supported in restoration and will be ignored")
+		}
+		matched, ok := findTransformedTables(cfg, dumpIds, tables)
+		if !ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetMsg("the table is partitioned use apply_for_inherited").
+				AddMeta("SchemaName", cfg.Schema).
+				AddMeta("TableName", cfg.Name).
+				SetSeverity(toolkit.ErrorValidationSeverity),
+			)
+			continue
+		}
+		if len(matched) == 0 {
+			log.Warn().
+				Str("SchemaName", cfg.Schema).
+				Str("TableName", cfg.Name).
+				Msg("transformed table is not found in the restoring tables")
+			continue
+		}
+		for _, dumpId := range matched {
+			if _, ok := plans[dumpId]; ok {
+				return fmt.Errorf("transformation for table %s.%s is defined more than once", cfg.Schema, cfg.Name)
+			}
+			plans[dumpId] = &tableTransformationPlan{
+				table:               tables[dumpId],
+				when:                cfg.When,
+				columnsTypeOverride: cfg.ColumnsTypeOverride,
+				transformers:        slices.Clone(cfg.Transformers),
+			}
+		}
+	}
+
+	refWarns := r.setTransformersForReferences(plans, dumpIds, tables)
+	warnings = append(warnings, refWarns...)
+
+	r.transformers = make(map[int32]*restorers.TableTransformer, len(plans))
+	for _, dumpId := range dumpIds {
+		plan, ok := plans[dumpId]
+		if !ok {
+			continue
+		}
+		tt, initWarns, err := r.initTableTransformer(ctx, plan)
+		if err != nil {
+			return fmt.Errorf(
+				"cannot initialize transformers for table %s.%s: %w", plan.table.Schema, plan.table.Name, err,
+			)
+		}
+		warnings = append(warnings, initWarns...)
+		r.transformers[dumpId] = tt
+	}
+
+	logRestoreValidationWarnings(warnings)
+	if warnings.IsFatal() {
+		return fmt.Errorf("fatal validation error")
+	}
+	return nil
+}
+
+// setTransformersForReferences - applies the transformers that have apply_for_references to the referencing columns
+// recursively. The transformer is not applied if the same transformer is already defined for the column
+func (r *Restore) setTransformersForReferences(
+	plans map[int32]*tableTransformationPlan, dumpIds []int32, tables map[int32]*toolkit.Table,
+) toolkit.ValidationWarnings {
+	var warnings toolkit.ValidationWarnings
+	var hasApplyForReferences bool
+	for _, dumpId := range dumpIds {
+		plan, ok := plans[dumpId]
+		if !ok {
+			continue
+		}
+		for _, tc := range plan.transformers {
+			if !tc.ApplyForReferences {
+				continue
+			}
+			hasApplyForReferences = true
+			allowed, w := runtimeContext.IsTransformerAllowedToApplyForReferences(tc, r.registry)
+			if !allowed {
+				warnings = append(warnings, w...)
+				continue
+			}
+			column := string(tc.Params[restoreColumnParameterName])
+			r.applyTransformerForReferences(tc, plan.table, column, plans, dumpIds, tables, make(map[string]bool))
+		}
+	}
+	if hasApplyForReferences && r.metadata.References == nil {
+		log.Warn().
+			Msg("dump does not contain tables references: re-dump the data using the latest version of greenmask if you want to use apply_for_references")
+	}
+	return warnings
+}
+
+// applyTransformerForReferences - applies the transformer to the columns that reference the provided table column
+func (r *Restore) applyTransformerForReferences(
+	tc *domains.TransformerConfig, table *toolkit.Table, column string, plans map[int32]*tableTransformationPlan,
+	dumpIds []int32, tables map[int32]*toolkit.Table, visited map[string]bool,
+) {
+	for _, ref := range r.metadata.References {
+		if ref.ReferencedTableOid != table.Oid && (table.RootPtOid == 0 || ref.ReferencedTableOid != table.RootPtOid) {
+			continue
+		}
+		colIdx := slices.Index(ref.ReferencedColumns, column)
+		if colIdx == -1 {
+			continue
+		}
+		refColumn := ref.Columns[colIdx]
+		for _, dumpId := range findTablesByOid(ref.TableOid, dumpIds, tables) {
+			refTable := tables[dumpId]
+			key := fmt.Sprintf("%d:%s", refTable.Oid, refColumn)
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+
+			plan, ok := plans[dumpId]
+			if !ok {
+				plan = &tableTransformationPlan{
+					table: refTable,
+				}
+				plans[dumpId] = plan
+			}
+			if plan.hasTransformer(tc.Name, refColumn) {
+				log.Info().
+					Str("TransformerName", tc.Name).
+					Str("SchemaName", refTable.Schema).
+					Str("TableName", refTable.Name).
+					Str("ColumnName", refColumn).
+					Msg("skipping apply transformer for reference: found manually configured transformer")
+			} else {
+				refTc := tc.Clone()
+				refTc.ApplyForReferences = false
+				refTc.Params[restoreColumnParameterName] = toolkit.ParamsValue(refColumn)
+				plan.transformers = append(plan.transformers, refTc)
+			}
+			// The referencing column might be referenced by another table as well
+			r.applyTransformerForReferences(tc, refTable, refColumn, plans, dumpIds, tables, visited)
+		}
+	}
+}
+
+// initTableTransformer - builds the driver for the real columns of the table and initializes the transformers
+func (r *Restore) initTableTransformer(
+	ctx context.Context, plan *tableTransformationPlan,
+) (*restorers.TableTransformer, toolkit.ValidationWarnings, error) {
+	// The dump contains only real columns. Generated columns are not dumped. The columns are copied because the
+	// type might be overridden
+	t := *plan.table
+	t.Columns = nil
+	for _, c := range plan.table.Columns {
+		if c.IsGenerated {
+			continue
+		}
+		column := *c
+		if overridingType, ok := plan.columnsTypeOverride[c.Name]; ok {
+			column.OverrideType(overridingType, 0, 0)
+		}
+		t.Columns = append(t.Columns, &column)
+	}
+
+	driver, warnings, err := toolkit.NewDriver(&t, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot initialise driver: %w", err)
+	}
+	enrichRestoreWarningsWithTableName(warnings, &t)
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	meta := map[string]any{
+		"TableSchema": t.Schema,
+		"TableName":   t.Name,
+	}
+	when, whenWarns := toolkit.NewWhenCond(plan.when, driver, meta)
+	enrichRestoreWarningsWithTableName(whenWarns, &t)
+	warnings = append(warnings, whenWarns...)
+
+	var transformersContext []*utils.TransformerContext
+	for _, tc := range plan.transformers {
+		td, ok := r.registry.Get(tc.Name)
+		if !ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetMsg("transformer not found").
+				AddMeta("SchemaName", t.Schema).
+				AddMeta("TableName", t.Name).
+				AddMeta("TransformerName", tc.Name).
+				SetSeverity(toolkit.ErrorValidationSeverity),
+			)
+			continue
+		}
+		transformerCtx, initWarns, err := td.Instance(ctx, driver, tc.Params, tc.DynamicParams, tc.When)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to init transformer %s: %w", tc.Name, err)
+		}
+		// The restored records are transformed one by one, so the pool would use a single worker
+		if pt, ok := transformerCtx.Transformer.(utils.PooledTransformer); ok && pt.PoolSize() > 1 {
+			initWarns = append(initWarns, toolkit.NewValidationWarning().
+				SetMsg("pool_size is not supported by the restoration transformation").
+				AddMeta("PoolSize", pt.PoolSize()).
+				SetSeverity(toolkit.ErrorValidationSeverity),
+			)
+		}
+		enrichRestoreWarningsWithTableName(initWarns, &t)
+		for _, w := range initWarns {
+			w.AddMeta("TransformerName", tc.Name)
+		}
+		warnings = append(warnings, initWarns...)
+		transformersContext = append(transformersContext, transformerCtx)
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	return restorers.NewTableTransformer(driver, when, transformersContext), warnings, nil
+}
+
+// getTableTransformer - returns the rows transformer of the table if the transformation is configured
+func (r *Restore) getTableTransformer(dumpId int32) *restorers.TableTransformer {
+	return r.transformers[dumpId]
+}
+
+// findTransformedTables - finds the restoring tables that match the transformation config. The partitions of the
+// partitioned table are matched only if apply_for_inherited is set otherwise false is returned
+func findTransformedTables(cfg *domains.Table, dumpIds []int32, tables map[int32]*toolkit.Table) ([]int32, bool) {
+	var res []int32
+	var hasPartitions bool
+	for _, dumpId := range dumpIds {
+		t := tables[dumpId]
+		if t.Schema == cfg.Schema && t.Name == cfg.Name {
+			res = append(res, dumpId)
+			continue
+		}
+		if t.RootPtOid != 0 && t.RootPtSchema == cfg.Schema && t.RootPtName == cfg.Name {
+			hasPartitions = true
+			if cfg.ApplyForInherited {
+				res = append(res, dumpId)
+			}
+		}
+	}
+	if hasPartitions && !cfg.ApplyForInherited {
+		return nil, false
+	}
+	return res, true
+}
+
+func enrichRestoreWarningsWithTableName(warns toolkit.ValidationWarnings, t *toolkit.Table) {
+	for _, w := range warns {
+		w.AddMeta("SchemaName", t.Schema).
+			AddMeta("TableName", t.Name)
+	}
+}
+
+func logRestoreValidationWarnings(warnings toolkit.ValidationWarnings) {
+	for _, w := range warnings {
+		switch w.Severity {
+		case toolkit.ErrorValidationSeverity:
+			log.Error().Any("ValidationWarning", w).Msg("")
+		case toolkit.WarningValidationSeverity:
+			log.Warn().Any("ValidationWarning", w).Msg("")
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/internal/domains"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// getTransformationTestTables - returns the tables where accounts and notes reference users and account_logs
+// references accounts by the column that references users
+func getTransformationTestTables() ([]*toolkit.Table, []*storage.Reference) {
+	users := newTestTable(1, "users", int4Column("id"))
+	accounts := newTestTable(2, "accounts", int4Column("id"), int4Column("user_id"))
+	accountLogs := newTestTable(3, "account_logs", int4Column("id"), int4Column("account_user_id"))
+	notes := newTestTable(4, "notes", int4Column("id"), int4Column("user_id"))
+	events := newTestTable(5, "events_2024", int4Column("id"))
+	events.RootPtOid = 6
+	events.RootPtSchema = "public"
+	events.RootPtName = "events"
+	references := []*storage.Reference{
+		{
+			TableOid: accounts.Oid, Columns: []string{"user_id"},
+			ReferencedTableOid: users.Oid, ReferencedColumns: []string{"id"},
+		},
+		{
+			TableOid: accountLogs.Oid, Columns: []string{"account_user_id"},
+			ReferencedTableOid: accounts.Oid, ReferencedColumns: []string{"user_id"},
+		},
+		{
+			TableOid: notes.Oid, Columns: []string{"user_id"},
+			ReferencedTableOid: users.Oid, ReferencedColumns: []string{"id"},
+		},
+	}
+	return []*toolkit.Table{users, accounts, accountLogs, notes, events}, references
+}
+
+func newTestRandomIntConfig(column string, applyForReferences bool) *domains.TransformerConfig {
+	return &domains.TransformerConfig{
+		Name:               transformers.RandomIntTransformerName,
+		ApplyForReferences: applyForReferences,
+		Params: toolkit.StaticParameters{
+			"column": toolkit.ParamsValue(column),
+			"engine": toolkit.ParamsValue("hash"),
+		},
+	}
+}
+
+func newTestTransformationRestore(transformation []*domains.Table) (*Restore, []*toc.Entry) {
+	tables, references := getTransformationTestTables()
+	r, entries := newTestRestore(nil, references, tables...)
+	r.cfg.Transformation = transformation
+	r.registry = utils.DefaultTransformerRegistry
+	return r, entries
+}
+
+func TestRestore_setTransformersForReferences(t *testing.T) {
+	t.Run("references chain", func(t *testing.T) {
+		r, entries := newTestTransformationRestore(nil)
+		dumpIds, tables, err := r.getRestoringTables(entries)
+		require.NoError(t, err)
+		usersTc := newTestRandomIntConfig("id", true)
+		notesTc := newTestRandomIntConfig("user_id", false)
+		plans := map[int32]*tableTransformationPlan{
+			1: {table: tables[1], transformers: []*domains.TransformerConfig{usersTc}},
+			4: {table: tables[4], transformers: []*domains.TransformerConfig{notesTc}},
+		}
+
+		warnings := r.setTransformersForReferences(plans, dumpIds, tables)
+		require.Empty(t, warnings)
+		require.Len(t, plans, 4)
+		require.Equal(t, []*domains.TransformerConfig{usersTc}, plans[1].transformers)
+		require.Equal(t, "id", string(usersTc.Params["column"]))
+
+		accountsTc := newTestRandomIntConfig("user_id", false)
+		require.Equal(t, &tableTransformationPlan{
+			table: tables[2], transformers: []*domains.TransformerConfig{accountsTc},
+		}, plans[2])
+		// The column of account_logs references the transformed column of accounts
+		accountLogsTc := newTestRandomIntConfig("account_user_id", false)
+		require.Equal(t, &tableTransformationPlan{
+			table: tables[3], transformers: []*domains.TransformerConfig{accountLogsTc},
+		}, plans[3])
+		// The manually configured transformer is not duplicated
+		require.Equal(t, []*domains.TransformerConfig{notesTc}, plans[4].transformers)
+		require.NotContains(t, plans, int32(5))
+	})
+
+	t.Run("transformer is not allowed", func(t *testing.T) {
+		r, entries := newTestTransformationRestore(nil)
+		dumpIds, tables, err := r.getRestoringTables(entries)
+		require.NoError(t, err)
+		tc := &domains.TransformerConfig{
+			Name:               transformers.TemplateTransformerName,
+			ApplyForReferences: true,
+			Params:             toolkit.StaticParameters{"column": toolkit.ParamsValue("id")},
+		}
+		plans := map[int32]*tableTransformationPlan{
+			1: {table: tables[1], transformers: []*domains.TransformerConfig{tc}},
+		}
+
+		warnings := r.setTransformersForReferences(plans, dumpIds, tables)
+		require.NotEmpty(t, warnings)
+		require.Len(t, plans, 1)
+	})
+
+	t.Run("references are not stored", func(t *testing.T) {
+		r, entries := newTestTransformationRestore(nil)
+		dumpIds, tables, err := r.getRestoringTables(entries)
+		require.NoError(t, err)
+		r.metadata.References = nil
+		plans := map[int32]*tableTransformationPlan{
+			1: {table: tables[1], transformers: []*domains.TransformerConfig{newTestRandomIntConfig("id", true)}},
+		}
+
+		require.Empty(t, r.setTransformersForReferences(plans, dumpIds, tables))
+		require.Len(t, plans, 1)
+	})
+}
+
+func TestRestore_buildTransformationPlans(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("apply for references", func(t *testing.T) {
+		r, entries := newTestTransformationRestore([]*domains.Table{
+			{
+				Schema:       "public",
+				Name:         "users",
+				Transformers: []*domains.TransformerConfig{newTestRandomIntConfig("id", true)},
+			},
+			{Schema: "public", Name: "unknown"},
+		})
+
+		require.NoError(t, r.buildTransformationPlans(ctx, entries))
+		for _, dumpId := range []int32{1, 2, 3, 4} {
+			require.NotNil(t, r.getTableTransformer(dumpId), "dump id %d", dumpId)
+		}
+		require.Nil(t, r.getTableTransformer(5))
+	})
+
+	t.Run("transformation is defined more than once", func(t *testing.T) {
+		cfg := &domains.Table{
+			Schema:       "public",
+			Name:         "users",
+			Transformers: []*domains.TransformerConfig{newTestRandomIntConfig("id", false)},
+		}
+		r, entries := newTestTransformationRestore([]*domains.Table{cfg, cfg})
+
+		require.ErrorContains(t, r.buildTransformationPlans(ctx, entries), "is defined more than once")
+	})
+
+	t.Run("partitioned table", func(t *testing.T) {
+		cfg := &domains.Table{
+			Schema:       "public",
+			Name:         "events",
+			Transformers: []*domains.TransformerConfig{newTestRandomIntConfig("id", false)},
+		}
+		r, entries := newTestTransformationRestore([]*domains.Table{cfg})
+		require.ErrorContains(t, r.buildTransformationPlans(ctx, entries), "fatal validation error")
+
+		cfg.ApplyForInherited = true
+		r, entries = newTestTransformationRestore([]*domains.Table{cfg})
+		require.NoError(t, r.buildTransformationPlans(ctx, entries))
+		require.NotNil(t, r.getTableTransformer(5))
+	})
+
+	t.Run("schema only", func(t *testing.T) {
+		r, entries := newTestTransformationRestore([]*domains.Table{{Schema: "public", Name: "users"}})
+		r.restoreOpt.SchemaOnly = true
+
+		require.NoError(t, r.buildTransformationPlans(ctx, entries))
+		require.Nil(t, r.transformers)
+	})
+}
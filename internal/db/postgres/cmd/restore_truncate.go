@@ -0,0 +1,147 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+)
+
+const (
+	truncateMethodTruncate = "truncate"
+	truncateMethodDelete   = "delete"
+)
+
+// truncateTables - cleans the restoring tables before the data restoration. The tables are cleaned in the reverse
+// dependency order, so the referencing tables are cleaned before the tables they refer to. The truncate method
+// truncates all the tables in a single statement because PostgreSQL does not allow truncating the referenced table
+// separately even if the referencing table is empty
+func (r *Restore) truncateTables(ctx context.Context, tx pgx.Tx, entries []*toc.Entry) error {
+	tables := make(map[int32]string)
+	var dumpIds []int32
+	for _, entry := range entries {
+		if *entry.Desc != toc.TableDataDesc || !r.isNeedRestore(entry) {
+			continue
+		}
+		tables[entry.DumpId] = fmt.Sprintf("%s.%s", *entry.Namespace, *entry.Tag)
+		dumpIds = append(dumpIds, entry.DumpId)
+	}
+	if len(dumpIds) == 0 {
+		return nil
+	}
+
+	order, hasCycles := getReverseDependenciesOrder(dumpIds, r.metadata.DependenciesGraph)
+	if hasCycles && r.restoreOpt.TruncateMethod == truncateMethodDelete {
+		log.Warn().
+			Msg("cycle between tables is detected: deletion might fail due to foreign keys within cycle")
+	}
+	tableNames := make([]string, 0, len(order))
+	for _, dumpId := range order {
+		tableNames = append(tableNames, tables[dumpId])
+	}
+
+	if r.restoreOpt.UseSessionReplicationRoleReplica {
+		if _, err := tx.Exec(ctx, "SET LOCAL session_replication_role = 'replica'"); err != nil {
+			return fmt.Errorf("cannot set session replication role: %w", err)
+		}
+	}
+
+	switch r.restoreOpt.TruncateMethod {
+	case truncateMethodTruncate, "":
+		log.Debug().
+			Strs("Tables", tableNames).
+			Msg("truncating tables")
+		names := make([]string, 0, len(tableNames))
+		for _, name := range tableNames {
+			names = append(names, "ONLY "+name)
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf("TRUNCATE TABLE %s", strings.Join(names, ", "))); err != nil {
+			return fmt.Errorf("cannot truncate tables: %w", err)
+		}
+	case truncateMethodDelete:
+		for _, name := range tableNames {
+			log.Debug().
+				Str("Table", name).
+				Msg("deleting table rows")
+			if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM ONLY %s", name)); err != nil {
+				return fmt.Errorf("cannot delete rows from table %s: %w", name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown truncate method \"%s\"", r.restoreOpt.TruncateMethod)
+	}
+
+	if r.restoreOpt.UseSessionReplicationRoleReplica {
+		if _, err := tx.Exec(ctx, "SET LOCAL session_replication_role = 'origin'"); err != nil {
+			return fmt.Errorf("cannot reset session replication role: %w", err)
+		}
+	}
+
+	log.Info().
+		Int("Tables", len(tableNames)).
+		Str("Method", r.restoreOpt.TruncateMethod).
+		Msg("tables are cleaned before the data restoration")
+	return nil
+}
+
+// getReverseDependenciesOrder - sorts the dump ids so each table goes before the tables it depends on. The graph
+// contains the dependencies (referenced tables) of each table. The tables within the cycles are added in the
+// original order and true is returned
+func getReverseDependenciesOrder(dumpIds []int32, graph map[int32][]int32) ([]int32, bool) {
+	// The number of restoring tables that depend on the table
+	dependents := make(map[int32]int, len(dumpIds))
+	for _, dumpId := range dumpIds {
+		for _, dep := range graph[dumpId] {
+			if dep != dumpId && slices.Contains(dumpIds, dep) {
+				dependents[dep]++
+			}
+		}
+	}
+
+	res := make([]int32, 0, len(dumpIds))
+	added := make(map[int32]bool, len(dumpIds))
+	for len(res) < len(dumpIds) {
+		var progress bool
+		for _, dumpId := range dumpIds {
+			if added[dumpId] || dependents[dumpId] > 0 {
+				continue
+			}
+			added[dumpId] = true
+			res = append(res, dumpId)
+			progress = true
+			for _, dep := range graph[dumpId] {
+				if dep != dumpId && slices.Contains(dumpIds, dep) {
+					dependents[dep]--
+				}
+			}
+		}
+		if !progress {
+			for _, dumpId := range dumpIds {
+				if !added[dumpId] {
+					res = append(res, dumpId)
+				}
+			}
+			return res, true
+		}
+	}
+	return res, false
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReverseDependenciesOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		dumpIds   []int32
+		graph     map[int32][]int32
+		expected  []int32
+		hasCycles bool
+	}{
+		{
+			name:     "chain",
+			dumpIds:  []int32{1, 2, 3},
+			graph:    map[int32][]int32{2: {1}, 3: {2}},
+			expected: []int32{3, 2, 1},
+		},
+		{
+			name:     "diamond",
+			dumpIds:  []int32{1, 2, 3, 4},
+			graph:    map[int32][]int32{2: {1}, 3: {1}, 4: {2, 3}},
+			expected: []int32{4, 2, 3, 1},
+		},
+		{
+			name:     "independent tables keep the original order",
+			dumpIds:  []int32{3, 1, 2},
+			expected: []int32{3, 1, 2},
+		},
+		{
+			name:     "self reference",
+			dumpIds:  []int32{1, 2},
+			graph:    map[int32][]int32{1: {1}, 2: {1}},
+			expected: []int32{2, 1},
+		},
+		{
+			name:      "cycle",
+			dumpIds:   []int32{1, 2, 3, 4},
+			graph:     map[int32][]int32{1: {2}, 2: {1}, 3: {1}},
+			expected:  []int32{3, 4, 1, 2},
+			hasCycles: true,
+		},
+		{
+			name:     "dependency is not restored",
+			dumpIds:  []int32{2, 3},
+			graph:    map[int32][]int32{2: {1}, 3: {2}},
+			expected: []int32{3, 2},
+		},
+		{
+			name:     "tables are missing from the graph",
+			dumpIds:  []int32{1, 2, 3},
+			graph:    map[int32][]int32{3: {1}},
+			expected: []int32{2, 3, 1},
+		},
+		{
+			name:     "graph is not stored",
+			dumpIds:  []int32{1, 2},
+			expected: []int32{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, hasCycles := getReverseDependenciesOrder(tt.dumpIds, tt.graph)
+			require.Equal(t, tt.expected, order)
+			require.Equal(t, tt.hasCycles, hasCycles)
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/eminano/greenmask/internal/db/postgres/cmd/validate_utils"
 	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
 	"github.com/eminano/greenmask/internal/storages"
 )
@@ -57,7 +58,8 @@ var templateString = `;
 {{- end }}
 `
 
-func ShowDump(ctx context.Context, st storages.Storager, dumpId string, format string) error {
+// ShowDump - prints the dump metadata. If profile is set, only the profile of the transformers is printed
+func ShowDump(ctx context.Context, st storages.Storager, dumpId string, format string, profile bool) error {
 	meta := &storageDto.Metadata{}
 	r, err := st.GetObject(ctx, path.Join(dumpId, MetadataJsonFileName))
 	if err != nil {
@@ -76,6 +78,10 @@ func ShowDump(ctx context.Context, st storages.Storager, dumpId string, format s
 		e.Owner = re.ReplaceAllString(e.Owner, "$1")
 	}
 
+	if profile {
+		return printProfile(meta, format)
+	}
+
 	switch format {
 	case FormatText:
 		if err = printText(meta); err != nil {
@@ -109,6 +115,24 @@ func printYaml(meta *storageDto.Metadata) error {
 	return nil
 }
 
+func printProfile(meta *storageDto.Metadata, format string) error {
+	var err error
+	switch format {
+	case FormatText:
+		err = validate_utils.NewProfileReport(meta.Profile).PrintText(os.Stdout)
+	case FormatYaml:
+		err = yaml.NewEncoder(os.Stdout).Encode(meta.Profile)
+	case FormatJson:
+		err = json.NewEncoder(os.Stdout).Encode(meta.Profile)
+	default:
+		return fmt.Errorf("unknown output format %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("profile render error: %w", err)
+	}
+	return nil
+}
+
 func printText(meta *storageDto.Metadata) error {
 	t, err := template.New(templateName).Parse(templateString)
 	if err != nil {
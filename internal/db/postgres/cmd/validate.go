@@ -20,12 +20,12 @@ import (
 	runtimeContext "github.com/eminano/greenmask/internal/db/postgres/context"
 	"github.com/eminano/greenmask/internal/db/postgres/entries"
 	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
-	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
 	"github.com/eminano/greenmask/internal/db/postgres/toc"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/custom"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/eminano/greenmask/internal/domains"
 	"github.com/eminano/greenmask/internal/storages"
+	utils2 "github.com/eminano/greenmask/internal/utils"
 	"github.com/eminano/greenmask/internal/utils/reader"
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
@@ -73,6 +73,7 @@ func NewValidate(cfg *domains.Config, registry *utils.TransformerRegistry, st st
 }
 
 func (v *Validate) Run(ctx context.Context) (int, error) {
+	ctx = utils2.WithTmpDir(ctx, v.config.Common.TempDirectory)
 
 	defer func() {
 		if !v.config.Validate.Diff {
@@ -82,9 +83,15 @@ func (v *Validate) Run(ctx context.Context) (int, error) {
 			log.Warn().Err(err).Msg("error deleting temporary directory")
 		}
 	}()
-	if err := custom.BootstrapCustomTransformers(ctx, v.registry, v.config.CustomTransformers); err != nil {
+	customResources, err := custom.BootstrapCustomTransformers(ctx, v.registry, v.config.CustomTransformers)
+	if err != nil {
 		return nonZeroExitCode, fmt.Errorf("error bootstraping custom transformers: %w", err)
 	}
+	defer func() {
+		if err := customResources.Close(ctx); err != nil {
+			log.Warn().Err(err).Msg("error closing custom transformers")
+		}
+	}()
 
 	dsn, err := v.pgDumpOptions.GetPgDSN()
 	if err != nil {
@@ -120,6 +127,9 @@ func (v *Validate) Run(ctx context.Context) (int, error) {
 	if err != nil {
 		return nonZeroExitCode, err
 	}
+	if err = v.checkKAnonymityTables(); err != nil {
+		return nonZeroExitCode, err
+	}
 	v.config.Dump.Transformation = tablesToValidate
 
 	v.context, err = runtimeContext.NewRuntimeContext(
@@ -144,7 +154,7 @@ func (v *Validate) Run(ctx context.Context) (int, error) {
 		return nonZeroExitCode, err
 	}
 
-	if !v.config.Validate.Data {
+	if !v.config.Validate.Data && !v.config.Validate.Profile {
 		return v.exitCode, nil
 	}
 
@@ -152,8 +162,16 @@ func (v *Validate) Run(ctx context.Context) (int, error) {
 		return nonZeroExitCode, err
 	}
 
-	if err = v.print(ctx); err != nil {
-		return nonZeroExitCode, err
+	if v.config.Validate.Data {
+		if err = v.print(ctx); err != nil {
+			return nonZeroExitCode, err
+		}
+	}
+
+	if v.config.Validate.Profile {
+		if err = v.printProfile(); err != nil {
+			return nonZeroExitCode, err
+		}
 	}
 
 	return v.exitCode, nil
@@ -175,7 +193,11 @@ func (v *Validate) print(ctx context.Context) error {
 		}
 
 		t := v.context.DataSectionObjectsToValidate[idx].(*entries.Table)
-		doc, err := v.createDocument(ctx, t)
+		reports, err := v.getKAnonymityReports(t)
+		if err != nil {
+			return err
+		}
+		doc, err := v.createDocument(ctx, t, reports)
 		if err != nil {
 			return fmt.Errorf("unable to create validation document: %w", err)
 		}
@@ -183,6 +205,76 @@ func (v *Validate) print(ctx context.Context) error {
 		if err = doc.Print(os.Stdout); err != nil {
 			return fmt.Errorf("unable to print validation document: %w", err)
 		}
+
+		if err = v.printKAnonymityReports(reports); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printProfile - prints the transformers of the validated tables ranked by the time spent on the sample records
+func (v *Validate) printProfile() error {
+	report := validate_utils.NewProfileReport(getTablesProfile(v.context.DataSectionObjectsToValidate))
+	var err error
+	if v.config.Validate.Format == JsonFormat {
+		err = report.PrintJson(os.Stdout)
+	} else {
+		err = report.PrintText(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to print transformers profile: %w", err)
+	}
+	return nil
+}
+
+// checkKAnonymityTables - checks that the tables of the k-anonymity checks are defined in the transformation config
+func (v *Validate) checkKAnonymityTables() error {
+	for _, c := range v.config.Validate.KAnonymity {
+		schemaName, tableName, err := parseTableName(c.Table)
+		if err != nil {
+			return err
+		}
+		if _, err = findTableBySchemaAndName(v.config.Dump.Transformation, schemaName, tableName); err != nil {
+			return fmt.Errorf("invalid k-anonymity check: %w", err)
+		}
+	}
+	return nil
+}
+
+func (v *Validate) getKAnonymityReports(t *entries.Table) ([]*validate_utils.KAnonymityReport, error) {
+	var reports []*validate_utils.KAnonymityReport
+	for _, c := range v.config.Validate.KAnonymity {
+		schemaName, tableName, err := parseTableName(c.Table)
+		if err != nil {
+			return nil, err
+		}
+		if tableName != t.Name || (schemaName != "" && schemaName != t.Schema) {
+			continue
+		}
+		report, err := validate_utils.NewKAnonymityReport(t, c.QuasiIdentifiers, c.K)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create k-anonymity report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (v *Validate) printKAnonymityReports(reports []*validate_utils.KAnonymityReport) error {
+	for _, report := range reports {
+		if !report.Get().Passed {
+			v.exitCode = nonZeroExitCode
+		}
+		var err error
+		if v.config.Validate.Format == JsonFormat {
+			err = report.PrintJson(os.Stdout)
+		} else {
+			err = report.PrintText(os.Stdout)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to print k-anonymity report: %w", err)
+		}
 	}
 	return nil
 }
@@ -257,7 +349,9 @@ func (v *Validate) readRecords(r *bufio.Reader, t *entries.Table) (original, tra
 	return originalRow, transformedRow, nil
 }
 
-func (v *Validate) createDocument(ctx context.Context, t *entries.Table) (validate_utils.Documenter, error) {
+func (v *Validate) createDocument(
+	ctx context.Context, t *entries.Table, reports []*validate_utils.KAnonymityReport,
+) (validate_utils.Documenter, error) {
 	doc := v.getDocument(t)
 
 	closeReader, r, err := v.getReader(ctx, t)
@@ -279,6 +373,11 @@ func (v *Validate) createDocument(ctx context.Context, t *entries.Table) (valida
 		if err := doc.Append(original, transformed); err != nil {
 			return nil, fmt.Errorf("unable to append line %d to document: %w", line, err)
 		}
+		for _, report := range reports {
+			if err := report.Append(original, transformed); err != nil {
+				return nil, fmt.Errorf("unable to append line %d to k-anonymity report: %w", line, err)
+			}
+		}
 
 		line++
 	}
@@ -317,7 +416,7 @@ func (v *Validate) diffWithPreviousSchema(ctx context.Context) error {
 		return nil
 	}
 
-	dumpId, err := v.getPreviousDumpId(ctx)
+	dumpId, err := getLatestDumpId(ctx, v.mainSt)
 	if err != nil {
 		return fmt.Errorf("cannot get previous dump id: %w", err)
 	}
@@ -325,7 +424,7 @@ func (v *Validate) diffWithPreviousSchema(ctx context.Context) error {
 		return nil
 	}
 
-	md, err := v.getPreviousMetadata(ctx, dumpId)
+	md, err := getDumpMetadata(ctx, v.mainSt, dumpId)
 	if err != nil {
 		return fmt.Errorf("cannot get previous metadata: %w", err)
 	}
@@ -372,55 +471,6 @@ func (v *Validate) printSchemaDiff(diff []*toolkit.DiffNode, previousDumpId stri
 	return nil
 }
 
-func (v *Validate) getPreviousDumpId(ctx context.Context) (string, error) {
-	var backupNames []string
-
-	_, dirs, err := v.mainSt.ListDir(ctx)
-	if err != nil {
-		return "", fmt.Errorf("cannot walk through directory: %w", err)
-	}
-	for _, dir := range dirs {
-		exists, err := dir.Exists(ctx, MetadataJsonFileName)
-		if err != nil {
-			return "", fmt.Errorf("cannot check file existence: %w", err)
-		}
-		if exists {
-			backupNames = append(backupNames, dir.Dirname())
-		}
-	}
-
-	slices.SortFunc(
-		backupNames, func(a, b string) int {
-			if a > b {
-				return -1
-			}
-			return 1
-		},
-	)
-	if len(backupNames) > 0 {
-		return backupNames[0], nil
-	}
-	return "", nil
-}
-
-func (v *Validate) getPreviousMetadata(ctx context.Context, dumpId string) (*storageDto.Metadata, error) {
-
-	st := v.mainSt.SubStorage(dumpId, true)
-
-	f, err := st.GetObject(ctx, MetadataJsonFileName)
-	if err != nil {
-		return nil, fmt.Errorf("cannot open metadata file: %w", err)
-	}
-	defer f.Close()
-
-	previousMetadata := &storageDto.Metadata{}
-
-	if err = json.NewDecoder(f).Decode(&previousMetadata); err != nil {
-		return nil, fmt.Errorf("cannot decode metadata file: %w", err)
-	}
-	return previousMetadata, nil
-}
-
 func findTableBySchemaAndName(Transformations []*domains.Table, schemaName, tableName string) (*domains.Table, error) {
 	var foundTable *domains.Table
 	for _, t := range Transformations {
@@ -30,6 +30,7 @@ type JsonDocumentResult struct {
 	PrimaryKeyColumns []string
 	WithDiff          bool
 	OnlyTransformed   bool
+	PushedDown        []string
 	RecordsWithDiff   []jsonRecordWithDiff
 	RecordsPlain      []jsonRecordPlain
 }
@@ -40,6 +41,7 @@ type jsonDocumentResponseWithDiff struct {
 	PrimaryKeyColumns []string             `json:"primary_key_columns"`
 	WithDiff          bool                 `json:"with_diff"`
 	TransformedOnly   bool                 `json:"transformed_only"`
+	PushedDown        []string             `json:"pushed_down,omitempty"`
 	Records           []jsonRecordWithDiff `json:"records"`
 }
 
@@ -49,6 +51,7 @@ type jsonDocumentResponsePlain struct {
 	PrimaryKeyColumns []string          `json:"primary_key_columns"`
 	WithDiff          bool              `json:"with_diff"`
 	TransformedOnly   bool              `json:"transformed_only"`
+	PushedDown        []string          `json:"pushed_down,omitempty"`
 	Records           []jsonRecordPlain `json:"records"`
 }
 
@@ -81,6 +84,7 @@ func NewJsonDocument(table *entries.Table, withDiff bool, onlyTransformed bool)
 			PrimaryKeyColumns: pkColumnsList,
 			WithDiff:          withDiff,
 			OnlyTransformed:   onlyTransformed,
+			PushedDown:        getPushedDownTransformers(table),
 			RecordsWithDiff:   make([]jsonRecordWithDiff, 0),
 		},
 		withDiff:                  withDiff,
@@ -139,6 +143,7 @@ func (jc *JsonDocument) Print(w io.Writer) error {
 			PrimaryKeyColumns: result.PrimaryKeyColumns,
 			WithDiff:          result.WithDiff,
 			TransformedOnly:   result.OnlyTransformed,
+			PushedDown:        result.PushedDown,
 			Records:           result.RecordsWithDiff,
 		}
 		if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -163,6 +168,7 @@ func (jc *JsonDocument) Print(w io.Writer) error {
 		PrimaryKeyColumns: result.PrimaryKeyColumns,
 		WithDiff:          result.WithDiff,
 		TransformedOnly:   result.OnlyTransformed,
+		PushedDown:        result.PushedDown,
 		Records:           records,
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
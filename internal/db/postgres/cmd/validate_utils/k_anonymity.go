@@ -0,0 +1,177 @@
+package validate_utils
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/eminano/greenmask/internal/db/postgres/entries"
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// KAnonymityResult - the k-anonymity of the original and transformed records. The k is the size of the smallest
+// equivalence class - the group of records with the same values of the quasi-identifier columns
+type KAnonymityResult struct {
+	Schema                     string   `json:"schema"`
+	Name                       string   `json:"name"`
+	QuasiIdentifiers           []string `json:"quasi_identifiers"`
+	Records                    int      `json:"records"`
+	OriginalK                  int      `json:"original_k"`
+	OriginalEquivalenceClasses int      `json:"original_equivalence_classes"`
+	K                          int      `json:"k"`
+	EquivalenceClasses         int      `json:"equivalence_classes"`
+	RequiredK                  int      `json:"required_k"`
+	RecordsBelowRequiredK      int      `json:"records_below_required_k"`
+	Passed                     bool     `json:"passed"`
+}
+
+type KAnonymityReport struct {
+	table            *entries.Table
+	columns          []int
+	quasiIdentifiers []string
+	requiredK        int
+	records          int
+	original         map[string]int
+	transformed      map[string]int
+	buf              []byte
+}
+
+func NewKAnonymityReport(table *entries.Table, quasiIdentifiers []string, requiredK int) (*KAnonymityReport, error) {
+	if len(quasiIdentifiers) == 0 {
+		return nil, fmt.Errorf("quasi identifiers are not specified")
+	}
+	columns := make([]int, 0, len(quasiIdentifiers))
+	for _, name := range quasiIdentifiers {
+		idx := slices.IndexFunc(table.Columns, func(column *toolkit.Column) bool {
+			return column.Name == name
+		})
+		if idx == -1 {
+			return nil, fmt.Errorf("column \"%s\" is not found in table %s.%s", name, table.Schema, table.Name)
+		}
+		columns = append(columns, idx)
+	}
+	return &KAnonymityReport{
+		table:            table,
+		columns:          columns,
+		quasiIdentifiers: quasiIdentifiers,
+		requiredK:        requiredK,
+		original:         make(map[string]int),
+		transformed:      make(map[string]int),
+	}, nil
+}
+
+func (kr *KAnonymityReport) Append(original, transformed *pgcopy.Row) (err error) {
+	kr.buf, err = kr.appendKey(kr.buf[:0], original)
+	if err != nil {
+		return fmt.Errorf("error getting column from original record: %w", err)
+	}
+	kr.original[string(kr.buf)]++
+	kr.buf, err = kr.appendKey(kr.buf[:0], transformed)
+	if err != nil {
+		return fmt.Errorf("error getting column from transformed record: %w", err)
+	}
+	kr.transformed[string(kr.buf)]++
+	kr.records++
+	return nil
+}
+
+// appendKey - appends the quasi-identifier values of the record. NULL values form their own class
+func (kr *KAnonymityReport) appendKey(buf []byte, row *pgcopy.Row) ([]byte, error) {
+	for _, idx := range kr.columns {
+		v, err := row.GetColumn(idx)
+		if err != nil {
+			return nil, err
+		}
+		if v.IsNull {
+			buf = append(buf, 1)
+			continue
+		}
+		buf = append(buf, 0)
+		buf = binary.AppendUvarint(buf, uint64(len(v.Data)))
+		buf = append(buf, v.Data...)
+	}
+	return buf, nil
+}
+
+func (kr *KAnonymityReport) Get() *KAnonymityResult {
+	res := &KAnonymityResult{
+		Schema:                     kr.table.Schema,
+		Name:                       kr.table.Name,
+		QuasiIdentifiers:           kr.quasiIdentifiers,
+		Records:                    kr.records,
+		OriginalK:                  minEquivalenceClassSize(kr.original),
+		OriginalEquivalenceClasses: len(kr.original),
+		K:                          minEquivalenceClassSize(kr.transformed),
+		EquivalenceClasses:         len(kr.transformed),
+		RequiredK:                  kr.requiredK,
+	}
+	for _, size := range kr.transformed {
+		if size < kr.requiredK {
+			res.RecordsBelowRequiredK += size
+		}
+	}
+	res.Passed = res.RecordsBelowRequiredK == 0
+	return res
+}
+
+func (kr *KAnonymityReport) PrintJson(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(kr.Get()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (kr *KAnonymityReport) PrintText(w io.Writer) error {
+	res := kr.Get()
+	_, err := w.Write([]byte(fmt.Sprintf(
+		"\n\n\t\"%s\".\"%s\" k-anonymity (%s)\n", res.Schema, res.Name, strings.Join(res.QuasiIdentifiers, ", "),
+	)))
+	if err != nil {
+		return fmt.Errorf("error writing title: %w", err)
+	}
+
+	requiredK := "-"
+	if res.RequiredK > 0 {
+		requiredK = strconv.Itoa(res.RequiredK)
+	}
+	status := "PASSED"
+	statusColors := tablewriter.Colors{tablewriter.FgHiGreenColor}
+	if !res.Passed {
+		status = "FAILED"
+		statusColors = tablewriter.Colors{tablewriter.FgHiRedColor}
+	}
+
+	prettyWriter := tablewriter.NewWriter(w)
+	prettyWriter.SetHeader([]string{"Metric", "Original", "Transformed"})
+	prettyWriter.SetAlignment(tablewriter.ALIGN_LEFT)
+	prettyWriter.SetRowLine(true)
+	prettyWriter.Append([]string{"Records", strconv.Itoa(res.Records), strconv.Itoa(res.Records)})
+	prettyWriter.Append([]string{
+		"Equivalence classes",
+		strconv.Itoa(res.OriginalEquivalenceClasses),
+		strconv.Itoa(res.EquivalenceClasses),
+	})
+	prettyWriter.Append([]string{"K", strconv.Itoa(res.OriginalK), strconv.Itoa(res.K)})
+	prettyWriter.Append([]string{"Required K", "", requiredK})
+	prettyWriter.Append([]string{"Records below required K", "", strconv.Itoa(res.RecordsBelowRequiredK)})
+	prettyWriter.Rich([]string{"Status", "", status}, []tablewriter.Colors{{}, {}, statusColors})
+	prettyWriter.Render()
+	return nil
+}
+
+func minEquivalenceClassSize(classes map[string]int) int {
+	var res int
+	for _, size := range classes {
+		if res == 0 || size < res {
+			res = size
+		}
+	}
+	return res
+}
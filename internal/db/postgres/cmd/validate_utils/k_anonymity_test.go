@@ -0,0 +1,84 @@
+package validate_utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+)
+
+func TestKAnonymityReport_Get(t *testing.T) {
+	tab, originalRecs, transformedRecs := getTableAndRows()
+
+	tests := []struct {
+		name             string
+		quasiIdentifiers []string
+		requiredK        int
+		expected         *KAnonymityResult
+	}{
+		{
+			name:             "unchanged quasi identifier",
+			quasiIdentifiers: []string{"groupname"},
+			requiredK:        2,
+			expected: &KAnonymityResult{
+				Schema:                     "humanresources",
+				Name:                       "department",
+				QuasiIdentifiers:           []string{"groupname"},
+				Records:                    6,
+				OriginalK:                  1,
+				OriginalEquivalenceClasses: 3,
+				K:                          1,
+				EquivalenceClasses:         3,
+				RequiredK:                  2,
+				RecordsBelowRequiredK:      1,
+				Passed:                     false,
+			},
+		},
+		{
+			name:             "null forms own class",
+			quasiIdentifiers: []string{"modifieddate"},
+			expected: &KAnonymityResult{
+				Schema:                     "humanresources",
+				Name:                       "department",
+				QuasiIdentifiers:           []string{"modifieddate"},
+				Records:                    6,
+				OriginalK:                  6,
+				OriginalEquivalenceClasses: 1,
+				K:                          1,
+				EquivalenceClasses:         2,
+				Passed:                     true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := NewKAnonymityReport(tab, tt.quasiIdentifiers, tt.requiredK)
+			require.NoError(t, err)
+
+			original := pgcopy.NewRow(4)
+			transformed := pgcopy.NewRow(4)
+			for idx := range originalRecs {
+				require.NoError(t, original.Decode(originalRecs[idx]))
+				require.NoError(t, transformed.Decode(transformedRecs[idx]))
+				require.NoError(t, report.Append(original, transformed))
+			}
+			require.Equal(t, tt.expected, report.Get())
+
+			buf := bytes.NewBuffer(nil)
+			require.NoError(t, report.PrintJson(buf))
+			res := &KAnonymityResult{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), res))
+			require.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestNewKAnonymityReport_unknown_column(t *testing.T) {
+	tab, _, _ := getTableAndRows()
+	_, err := NewKAnonymityReport(tab, []string{"unknown"}, 2)
+	require.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package validate_utils
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
+)
+
+// ProfileResult - the cost of the transformer on the table and its share in the total transformation time
+type ProfileResult struct {
+	Rank           int           `json:"rank"`
+	Schema         string        `json:"schema"`
+	Name           string        `json:"name"`
+	Transformer    string        `json:"transformer"`
+	Calls          uint64        `json:"calls"`
+	Rows           uint64        `json:"rows"`
+	Duration       time.Duration `json:"duration"`
+	AvgRowDuration time.Duration `json:"avg_row_duration"`
+	Share          float64       `json:"share"`
+	PushedDown     bool          `json:"pushed_down,omitempty"`
+}
+
+// ProfileReport - ranks the transformers of all the tables by the time spent on the transformation
+type ProfileReport struct {
+	results []*ProfileResult
+}
+
+func NewProfileReport(tables []*storageDto.TableProfile) *ProfileReport {
+	var results []*ProfileResult
+	var total time.Duration
+	for _, t := range tables {
+		for _, tp := range t.Transformers {
+			res := &ProfileResult{
+				Schema:      t.Schema,
+				Name:        t.Name,
+				Transformer: tp.Name,
+				Calls:       tp.Calls,
+				Rows:        tp.Rows,
+				Duration:    tp.Duration,
+				PushedDown:  tp.PushedDown,
+			}
+			if tp.Rows > 0 {
+				res.AvgRowDuration = tp.Duration / time.Duration(tp.Rows)
+			}
+			total += tp.Duration
+			results = append(results, res)
+		}
+	}
+	slices.SortStableFunc(results, func(a, b *ProfileResult) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	for idx, res := range results {
+		res.Rank = idx + 1
+		if total > 0 {
+			res.Share = float64(res.Duration) / float64(total) * 100
+		}
+	}
+	return &ProfileReport{
+		results: results,
+	}
+}
+
+func (pr *ProfileReport) Get() []*ProfileResult {
+	return pr.results
+}
+
+func (pr *ProfileReport) PrintJson(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(pr.results); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pr *ProfileReport) PrintText(w io.Writer) error {
+	if _, err := w.Write([]byte("\n\n\tTransformers profile\n")); err != nil {
+		return fmt.Errorf("error writing title: %w", err)
+	}
+
+	prettyWriter := tablewriter.NewWriter(w)
+	prettyWriter.SetHeader([]string{"Rank", "Table", "Transformer", "Calls", "Rows", "Duration", "Avg per row", "Share"})
+	prettyWriter.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, res := range pr.results {
+		transformer := res.Transformer
+		if res.PushedDown {
+			transformer += " (pushed down)"
+		}
+		prettyWriter.Append([]string{
+			strconv.Itoa(res.Rank),
+			fmt.Sprintf("%s.%s", res.Schema, res.Name),
+			transformer,
+			strconv.FormatUint(res.Calls, 10),
+			strconv.FormatUint(res.Rows, 10),
+			res.Duration.String(),
+			res.AvgRowDuration.String(),
+			fmt.Sprintf("%.1f%%", res.Share),
+		})
+	}
+	prettyWriter.Render()
+	return nil
+}
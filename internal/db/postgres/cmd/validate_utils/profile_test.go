@@ -0,0 +1,58 @@
+package validate_utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	storageDto "github.com/eminano/greenmask/internal/db/postgres/storage"
+)
+
+func TestProfileReport_Get(t *testing.T) {
+	tables := []*storageDto.TableProfile{
+		{
+			Schema: "public",
+			Name:   "users",
+			Rows:   10,
+			Transformers: []*storageDto.TransformerProfile{
+				{Name: "SetNull", PushedDown: true},
+				{Name: "RandomEmail", Calls: 10, Rows: 10, Duration: time.Second},
+			},
+		},
+		{
+			Schema: "public",
+			Name:   "orders",
+			Rows:   100,
+			Transformers: []*storageDto.TransformerProfile{
+				{Name: "Cmd", Calls: 100, Rows: 100, Duration: 3 * time.Second},
+			},
+		},
+	}
+	expected := []*ProfileResult{
+		{
+			Rank: 1, Schema: "public", Name: "orders", Transformer: "Cmd", Calls: 100, Rows: 100,
+			Duration: 3 * time.Second, AvgRowDuration: 30 * time.Millisecond, Share: 75,
+		},
+		{
+			Rank: 2, Schema: "public", Name: "users", Transformer: "RandomEmail", Calls: 10, Rows: 10,
+			Duration: time.Second, AvgRowDuration: 100 * time.Millisecond, Share: 25,
+		},
+		{Rank: 3, Schema: "public", Name: "users", Transformer: "SetNull", PushedDown: true},
+	}
+
+	report := NewProfileReport(tables)
+	require.Equal(t, expected, report.Get())
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, report.PrintJson(buf))
+	var res []*ProfileResult
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+	require.Equal(t, expected, res)
+
+	buf.Reset()
+	require.NoError(t, report.PrintText(buf))
+	require.Contains(t, buf.String(), "SetNull (pushed down)")
+}
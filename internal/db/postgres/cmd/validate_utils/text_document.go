@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"slices"
+	"strings"
 
 	"github.com/olekukonko/tablewriter"
 
@@ -265,6 +266,12 @@ func (td *TextDocument) writeTableTitle(w io.Writer) error {
 	if err != nil {
 		return fmt.Errorf("error writing title: %w", err)
 	}
+	if pushedDown := getPushedDownTransformers(td.table); len(pushedDown) > 0 {
+		_, err = w.Write([]byte(fmt.Sprintf("\tPushed down: %s\n", strings.Join(pushedDown, ", "))))
+		if err != nil {
+			return fmt.Errorf("error writing title: %w", err)
+		}
+	}
 	return nil
 }
 
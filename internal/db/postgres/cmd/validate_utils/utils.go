@@ -1,7 +1,10 @@
 package validate_utils
 
 import (
+	"fmt"
+	"maps"
 	"slices"
+	"strings"
 
 	"github.com/eminano/greenmask/internal/db/postgres/entries"
 	"github.com/eminano/greenmask/pkg/toolkit"
@@ -22,6 +25,19 @@ func getAffectedColumns(t *entries.Table) map[string]struct{} {
 	return affectedColumns
 }
 
+// getPushedDownTransformers - returns the pushed down transformers in "Name(column, ...)" format
+func getPushedDownTransformers(t *entries.Table) []string {
+	var res []string
+	for _, pt := range t.PushedDown {
+		columns := make([]string, 0, len(pt.Expressions))
+		for _, idx := range slices.Sorted(maps.Keys(pt.Expressions)) {
+			columns = append(columns, t.Columns[idx].Name)
+		}
+		res = append(res, fmt.Sprintf("%s(%s)", pt.Name, strings.Join(columns, ", ")))
+	}
+	return res
+}
+
 func LineIsEndOfData(line []byte) bool {
 	return len(endOfFileSeq) == len(line) && line[0] == '\\' && line[1] == '.'
 }
@@ -77,6 +77,12 @@ func validateAndBuildEntriesConfig(
 		setSubsetConds(cfgMapping.entry, cfgMapping.config)
 		// set query
 		setQuery(cfgMapping.entry, cfgMapping.config)
+		// set batch size
+		batchSizeWarns := setBatchSize(cfgMapping.entry, cfgMapping.config)
+		warnings = append(warnings, batchSizeWarns...)
+		if batchSizeWarns.IsFatal() {
+			return batchSizeWarns, nil
+		}
 
 		// Set global driver for the table
 		driverWarnings, err := setGlobalDriverForTable(cfgMapping.entry, types)
@@ -129,6 +135,31 @@ func validateAndBuildEntriesConfig(
 				cfgMapping.entry.Schema, cfgMapping.entry.Name, err,
 			)
 		}
+
+		// Set the transformers performed in the COPY query
+		pushdownWarns := setPushdown(cfgMapping.entry, cfgMapping.config)
+		enrichWarningsWithTableName(pushdownWarns, cfgMapping.entry)
+		warnings = append(warnings, pushdownWarns...)
+
+		// Set the transformer instances for the parallel transformation
+		if transformersInitWarns.IsFatal() {
+			continue
+		}
+		poolWarns := validatePooledTransformers(cfgMapping.entry)
+		enrichWarningsWithTableName(poolWarns, cfgMapping.entry)
+		warnings = append(warnings, poolWarns...)
+		parallelismWarns, err := setParallelism(ctx, cfgMapping.entry, cfgMapping.config, r, types)
+		enrichWarningsWithTableName(parallelismWarns, cfgMapping.entry)
+		warnings = append(warnings, parallelismWarns...)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"cannot set parallel transformation for table %s.%s: %w",
+				cfgMapping.entry.Schema, cfgMapping.entry.Name, err,
+			)
+		}
+		if parallelismWarns.IsFatal() {
+			return parallelismWarns, nil
+		}
 	}
 
 	return warnings, nil
@@ -435,6 +466,43 @@ func setQuery(t *entries.Table, cfg *domains.Table) {
 	t.Query = cfg.Query
 }
 
+func setBatchSize(t *entries.Table, cfg *domains.Table) toolkit.ValidationWarnings {
+	if cfg.BatchSize < 0 {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("SchemaName", t.Schema).
+				AddMeta("TableName", t.Name).
+				AddMeta("BatchSize", cfg.BatchSize).
+				SetMsg("batch_size must not be negative"),
+		}
+	}
+	t.BatchSize = cfg.BatchSize
+	return nil
+}
+
+// validatePooledTransformers - the workers of the pool receive the records only when the records are transformed by
+// batches. The batch is not used if the table batch_size is not set or the transformer has dynamic parameters, so
+// the pool of several workers is rejected in this case instead of running a single worker silently
+func validatePooledTransformers(t *entries.Table) toolkit.ValidationWarnings {
+	var warnings toolkit.ValidationWarnings
+	for _, tc := range t.TransformersContext {
+		pt, ok := tc.Transformer.(transformersUtils.PooledTransformer)
+		if !ok || pt.PoolSize() <= 1 || (t.BatchSize > 1 && len(tc.DynamicParameters) == 0) {
+			continue
+		}
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("TransformerName", tc.Name).
+			AddMeta("PoolSize", pt.PoolSize()).
+			AddMeta("BatchSize", t.BatchSize).
+			SetMsg("pool_size requires the table batch_size and cannot be used with dynamic parameters: "+
+				"the records are transformed one by one by a single worker otherwise"),
+		)
+	}
+	return warnings
+}
+
 func setGlobalDriverForTable(
 	t *entries.Table, types []*toolkit.Type,
 ) (toolkit.ValidationWarnings, error) {
@@ -532,6 +600,120 @@ func initAndSetupTransformers(ctx context.Context, t *entries.Table, cfg *domain
 	return warnings, nil
 }
 
+// setPushdown - finds the first transformers that can be performed by PostgreSQL in the COPY query. The transformer
+// is pushed down only if all the previous transformers are pushed down, it has no "when" condition and dynamic
+// parameters, and its columns are not affected by the other pushed down transformers, so the expressions can be
+// computed from the original values
+func setPushdown(t *entries.Table, cfg *domains.Table) toolkit.ValidationWarnings {
+	if !cfg.Pushdown || cfg.When != "" || len(t.TransformersContext) != len(cfg.Transformers) {
+		return nil
+	}
+	var warnings toolkit.ValidationWarnings
+	pushedDownColumns := make(map[int]struct{})
+	for idx, tc := range t.TransformersContext {
+		pt, ok := tc.Transformer.(transformersUtils.PushdownTransformer)
+		if !ok || cfg.Transformers[idx].When != "" || len(tc.DynamicParameters) > 0 {
+			break
+		}
+		expressions, ok := pt.PushdownExpressions()
+		if !ok || affectsPushedDownColumns(expressions, pushedDownColumns) {
+			break
+		}
+		for columnIdx, expr := range expressions {
+			pushedDownColumns[columnIdx] = struct{}{}
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.InfoValidationSeverity).
+				AddMeta("TransformerName", cfg.Transformers[idx].Name).
+				AddMeta("ColumnName", t.Columns[columnIdx].Name).
+				AddMeta("Expression", expr).
+				SetMsg("transformer is pushed down into the COPY query"),
+			)
+		}
+		t.PushedDown = append(t.PushedDown, &entries.PushedDownTransformer{
+			Name:        cfg.Transformers[idx].Name,
+			Expressions: expressions,
+		})
+	}
+	return warnings
+}
+
+// setParallelism - creates the additional instances of the driver, the when condition and the transformers for each
+// worker of the parallel transformation. The parallel transformation is not used with batches and with the transformers
+// that require all the table records
+func setParallelism(
+	ctx context.Context, t *entries.Table, cfg *domains.Table, r *transformersUtils.TransformerRegistry,
+	types []*toolkit.Type,
+) (toolkit.ValidationWarnings, error) {
+	if cfg.Parallelism < 0 {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("Parallelism", cfg.Parallelism).
+				SetMsg("parallelism must not be negative"),
+		}, nil
+	}
+	if cfg.Parallelism <= 1 || len(t.TransformersContext) == len(t.PushedDown) {
+		return nil, nil
+	}
+	if t.BatchSize > 1 {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.WarningValidationSeverity).
+				AddMeta("Parallelism", cfg.Parallelism).
+				AddMeta("BatchSize", t.BatchSize).
+				SetMsg("parallelism is not supported with batch_size: the table records are transformed sequentially"),
+		}, nil
+	}
+	hasPostponed := slices.ContainsFunc(t.TransformersContext, func(tc *transformersUtils.TransformerContext) bool {
+		_, ok := tc.Transformer.(transformersUtils.PostponedTransformer)
+		return ok
+	})
+	if hasPostponed {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.WarningValidationSeverity).
+				AddMeta("Parallelism", cfg.Parallelism).
+				SetMsg("parallelism is not supported with the transformers that require all the table records: " +
+					"the table records are transformed sequentially"),
+		}, nil
+	}
+
+	meta := map[string]any{
+		"TableSchema": t.Schema,
+		"TableName":   t.Name,
+	}
+	// The warnings of the instances are skipped since they are the same as the warnings of the table instances
+	for i := 1; i < cfg.Parallelism; i++ {
+		driver, _, err := toolkit.NewDriver(t.Table, types)
+		if err != nil {
+			return nil, fmt.Errorf("cannot initialise driver: %w", err)
+		}
+		when, _ := toolkit.NewWhenCond(cfg.When, driver, meta)
+		w := &entries.TableWorker{
+			Driver: driver,
+			When:   when,
+		}
+		for _, tc := range cfg.Transformers {
+			transformationCtx, _, err := initTransformer(ctx, driver, tc, r)
+			if err != nil {
+				return nil, err
+			}
+			w.TransformersContext = append(w.TransformersContext, transformationCtx)
+		}
+		t.Workers = append(t.Workers, w)
+	}
+	return nil, nil
+}
+
+func affectsPushedDownColumns(expressions map[int]string, pushedDownColumns map[int]struct{}) bool {
+	for columnIdx := range expressions {
+		if _, ok := pushedDownColumns[columnIdx]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func checkApplyForReferenceMetRequirements(
 	tcm *tableConfigMapping, r *transformersUtils.TransformerRegistry,
 ) (bool, toolkit.ValidationWarnings) {
@@ -540,7 +722,7 @@ func checkApplyForReferenceMetRequirements(
 		if !tr.ApplyForReferences {
 			continue
 		}
-		allowed, w := isTransformerAllowedToApplyForReferences(tr, r)
+		allowed, w := IsTransformerAllowedToApplyForReferences(tr, r)
 		if !allowed {
 			warnings = append(warnings, w...)
 		}
@@ -548,9 +730,9 @@ func checkApplyForReferenceMetRequirements(
 	return !warnings.IsFatal(), warnings
 }
 
-// isTransformerAllowedToApplyForReferences - checks if the transformer is allowed to apply for references
+// IsTransformerAllowedToApplyForReferences - checks if the transformer is allowed to apply for references
 // and if the engine parameter is hash and required
-func isTransformerAllowedToApplyForReferences(
+func IsTransformerAllowedToApplyForReferences(
 	cfg *domains.TransformerConfig, r *transformersUtils.TransformerRegistry,
 ) (bool, toolkit.ValidationWarnings) {
 	td, ok := r.Get(cfg.Name)
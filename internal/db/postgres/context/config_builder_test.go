@@ -13,6 +13,7 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 
+	"github.com/eminano/greenmask/internal/db/postgres/entries"
 	"github.com/eminano/greenmask/internal/db/postgres/pgdump"
 	"github.com/eminano/greenmask/internal/db/postgres/subset"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers"
@@ -165,7 +166,7 @@ VALUES ('2023-03-10', 200.00);
 `
 )
 
-func Test_isTransformerAllowedToApplyForReferences(t *testing.T) {
+func Test_IsTransformerAllowedToApplyForReferences(t *testing.T) {
 	r := utils.DefaultTransformerRegistry
 
 	t.Run("RandomInt and hash engine", func(t *testing.T) {
@@ -177,7 +178,7 @@ func Test_isTransformerAllowedToApplyForReferences(t *testing.T) {
 				"engine": toolkit.ParamsValue("hash"),
 			},
 		}
-		ok, w := isTransformerAllowedToApplyForReferences(cfg, r)
+		ok, w := IsTransformerAllowedToApplyForReferences(cfg, r)
 		require.Empty(t, w)
 		require.True(t, ok)
 	})
@@ -191,7 +192,7 @@ func Test_isTransformerAllowedToApplyForReferences(t *testing.T) {
 				"engine": toolkit.ParamsValue("random"),
 			},
 		}
-		ok, w := isTransformerAllowedToApplyForReferences(cfg, r)
+		ok, w := IsTransformerAllowedToApplyForReferences(cfg, r)
 		require.NotEmpty(t, w)
 		require.False(t, ok)
 	})
@@ -204,7 +205,7 @@ func Test_isTransformerAllowedToApplyForReferences(t *testing.T) {
 				"column": toolkit.ParamsValue("id"),
 			},
 		}
-		ok, w := isTransformerAllowedToApplyForReferences(cfg, r)
+		ok, w := IsTransformerAllowedToApplyForReferences(cfg, r)
 		require.NotEmpty(t, w)
 		require.False(t, ok)
 	})
@@ -217,12 +218,60 @@ func Test_isTransformerAllowedToApplyForReferences(t *testing.T) {
 				"column": toolkit.ParamsValue("id"),
 			},
 		}
-		ok, w := isTransformerAllowedToApplyForReferences(cfg, r)
+		ok, w := IsTransformerAllowedToApplyForReferences(cfg, r)
 		require.NotEmpty(t, w)
 		require.False(t, ok)
 	})
 }
 
+// testPooledTransformer - the pooled transformer that is used only for the validation
+type testPooledTransformer struct {
+	utils.Transformer
+	poolSize int
+}
+
+func (tt *testPooledTransformer) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	return nil
+}
+
+func (tt *testPooledTransformer) PoolSize() int {
+	return tt.poolSize
+}
+
+func Test_validatePooledTransformers(t *testing.T) {
+	dynamicParameters := map[string]*toolkit.DynamicParameter{"min": nil}
+	tests := []struct {
+		name              string
+		poolSize          int
+		batchSize         int
+		dynamicParameters map[string]*toolkit.DynamicParameter
+		fatal             bool
+	}{
+		{name: "single worker without batch", poolSize: 1},
+		{name: "pool with batch", poolSize: 3, batchSize: 100},
+		{name: "pool without batch", poolSize: 3, fatal: true},
+		{name: "pool with batch of one record", poolSize: 3, batchSize: 1, fatal: true},
+		{name: "pool with dynamic parameters", poolSize: 3, batchSize: 100, dynamicParameters: dynamicParameters, fatal: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &entries.Table{
+				Table:     &toolkit.Table{Schema: "public", Name: "users"},
+				BatchSize: tt.batchSize,
+				TransformersContext: []*utils.TransformerContext{
+					{
+						Name:              "PoolUpper",
+						Transformer:       &testPooledTransformer{poolSize: tt.poolSize},
+						DynamicParameters: tt.dynamicParameters,
+					},
+				},
+			}
+			warnings := validatePooledTransformers(table)
+			assert.Equal(t, tt.fatal, warnings.IsFatal())
+		})
+	}
+}
+
 func Test_runPostgresContainer(t *testing.T) {
 	ctx := context.Background()
 	// Start the PostgreSQL container
@@ -71,6 +71,8 @@ func NewRuntimeContext(
 	if err != nil {
 		return nil, fmt.Errorf("cannot set salt: %w", err)
 	}
+	// The transformers may load their data from the source database in the dump transaction
+	ctx = utils.WithQuerier(ctx, tx)
 	// Get custom types used in Tables and register them in the type map
 	typeMap := tx.Conn().TypeMap()
 	types, err := buildTypeMap(ctx, tx, typeMap)
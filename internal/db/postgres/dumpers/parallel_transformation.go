@@ -0,0 +1,202 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dumpers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/eminano/greenmask/internal/db/postgres/entries"
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// jobsPerWorker - the number of the records that can be in progress for each worker. The reading goroutine waits
+// for the oldest record when all the jobs are in progress
+const jobsPerWorker = 4
+
+// transformationJob - the record that is transformed by one of the workers
+type transformationJob struct {
+	line uint64
+	// data - the copy of the original line, since the received buffer is reused
+	data []byte
+	row  *pgcopy.Row
+	res  []byte
+	err  error
+	done chan struct{}
+}
+
+// transformationWorker - the instance of the table transformers that is used by one goroutine
+type transformationWorker struct {
+	table        *entries.Table
+	record       *toolkit.Record
+	when         *toolkit.WhenCond
+	transformers []*utils.TransformerContext
+}
+
+func newTransformationWorker(
+	table *entries.Table, driver *toolkit.Driver, when *toolkit.WhenCond, transformers []*utils.TransformerContext,
+) *transformationWorker {
+	record := toolkit.NewRecord(driver)
+	for _, tc := range transformers {
+		for _, dp := range tc.DynamicParameters {
+			dp.SetRecord(record)
+		}
+	}
+	return &transformationWorker{
+		table:        table,
+		record:       record,
+		when:         when,
+		transformers: transformers,
+	}
+}
+
+// transform - transforms the decoded row of the job and returns the encoded result. The result refers to the
+// buffers of the job row, so it is valid until the job is reused
+func (w *transformationWorker) transform(ctx context.Context, job *transformationJob) ([]byte, error) {
+	w.record.SetRow(job.row)
+	needTransform, err := w.when.Evaluate(w.record)
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating when condition: %w", err)
+	}
+	if needTransform {
+		for _, t := range w.transformers {
+			needTransform, err = t.EvaluateWhen(w.record)
+			if err != nil {
+				return nil, fmt.Errorf("error evaluating when condition: %w", err)
+			}
+			if !needTransform {
+				continue
+			}
+			if _, err = t.Transform(ctx, w.record); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return encodeRecord(w.record)
+}
+
+// parallelTransformation - transforms the table records in the worker goroutines. The rows are decoded by the reading
+// goroutine and the results are returned in the original order
+type parallelTransformation struct {
+	workers []*transformationWorker
+	input   chan *transformationJob
+	// jobs - the ring of the jobs. The jobs in progress start from head
+	jobs  []*transformationJob
+	head  int
+	count int
+	wg    sync.WaitGroup
+	// closed - the workers were stopped
+	closed bool
+}
+
+func newParallelTransformation(table *entries.Table) *parallelTransformation {
+	workers := []*transformationWorker{
+		newTransformationWorker(table, table.Driver, table.When, table.TransformersContext),
+	}
+	for _, w := range table.Workers {
+		workers = append(workers, newTransformationWorker(table, w.Driver, w.When, w.TransformersContext))
+	}
+	jobs := make([]*transformationJob, len(workers)*jobsPerWorker)
+	for i := range jobs {
+		jobs[i] = &transformationJob{
+			row:  pgcopy.NewRow(len(table.Columns)),
+			done: make(chan struct{}, 1),
+		}
+	}
+	return &parallelTransformation{
+		workers: workers,
+		input:   make(chan *transformationJob, len(jobs)),
+		jobs:    jobs,
+	}
+}
+
+// transformers - returns the transformers of all the workers
+func (pt *parallelTransformation) transformers() []*utils.TransformerContext {
+	var res []*utils.TransformerContext
+	for _, w := range pt.workers {
+		res = append(res, w.transformers...)
+	}
+	return res
+}
+
+func (pt *parallelTransformation) start(ctx context.Context) {
+	for _, w := range pt.workers {
+		pt.wg.Add(1)
+		go func(w *transformationWorker) {
+			defer pt.wg.Done()
+			for job := range pt.input {
+				job.res, job.err = w.transform(ctx, job)
+				job.done <- struct{}{}
+			}
+		}(w)
+	}
+}
+
+// submit - decodes the line and sends it to the workers. If all the jobs are in progress, it waits for the oldest one
+// and passes its result to the write function first
+func (pt *parallelTransformation) submit(
+	ctx context.Context, line uint64, data []byte, write func(job *transformationJob) error,
+) error {
+	if pt.count == len(pt.jobs) {
+		if err := pt.next(ctx, write); err != nil {
+			return err
+		}
+	}
+	job := pt.jobs[(pt.head+pt.count)%len(pt.jobs)]
+	job.line = line
+	job.data = append(job.data[:0], data...)
+	if err := job.row.Decode(job.data); err != nil {
+		return fmt.Errorf("error decoding copy line: %w", err)
+	}
+	pt.count++
+	pt.input <- job
+	return nil
+}
+
+// next - waits for the oldest job and passes its result to the write function
+func (pt *parallelTransformation) next(ctx context.Context, write func(job *transformationJob) error) error {
+	job := pt.jobs[pt.head]
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-job.done:
+	}
+	pt.head = (pt.head + 1) % len(pt.jobs)
+	pt.count--
+	return write(job)
+}
+
+// flush - waits for all the jobs in progress and passes their results to the write function in the original order
+func (pt *parallelTransformation) flush(ctx context.Context, write func(job *transformationJob) error) error {
+	for pt.count > 0 {
+		if err := pt.next(ctx, write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stop - stops the workers. The jobs in progress are completed, but their results are not written
+func (pt *parallelTransformation) stop() {
+	if pt.closed {
+		return
+	}
+	pt.closed = true
+	close(pt.input)
+	pt.wg.Wait()
+}
@@ -22,5 +22,5 @@ type Pipeliner interface {
 	Dump(ctx context.Context, data []byte) error
 	Init(ctx context.Context) error
 	Done(ctx context.Context) error
-	CompleteDump() error
+	CompleteDump(ctx context.Context) error
 }
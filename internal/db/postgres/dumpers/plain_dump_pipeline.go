@@ -52,7 +52,7 @@ func (pdp *PlainDumpPipeline) Done(ctx context.Context) error {
 	return nil
 }
 
-func (pdp *PlainDumpPipeline) CompleteDump() (err error) {
+func (pdp *PlainDumpPipeline) CompleteDump(ctx context.Context) (err error) {
 	res := make([]byte, 0, 4)
 	res = append(res, pgcopy.DefaultCopyTerminationSeq...)
 	res = append(res, '\n', '\n')
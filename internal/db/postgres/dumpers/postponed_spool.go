@@ -0,0 +1,111 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dumpers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// postponedSpool - stores the transformed records in the temporary file until the postponed transformers are
+// prepared. Each record is stored with the flags of the postponed transformers that collected it and, optionally,
+// with the original record
+type postponedSpool struct {
+	f           *os.File
+	w           *bufio.Writer
+	r           *bufio.Reader
+	flags       []byte
+	original    []byte
+	transformed []byte
+}
+
+// newPostponedSpool - creates the spool file in the provided directory or in the default directory for temporary
+// files if it is empty
+func newPostponedSpool(dir string) (*postponedSpool, error) {
+	f, err := os.CreateTemp(dir, "greenmask-spool-*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spool file: %w", err)
+	}
+	return &postponedSpool{
+		f: f,
+		w: bufio.NewWriter(f),
+	}, nil
+}
+
+func (s *postponedSpool) Write(flags, original, transformed []byte) error {
+	for _, data := range [][]byte{flags, original, transformed} {
+		var buf [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(buf[:], uint64(len(data)))
+		if _, err := s.w.Write(buf[:n]); err != nil {
+			return fmt.Errorf("unable to write spool file: %w", err)
+		}
+		if _, err := s.w.Write(data); err != nil {
+			return fmt.Errorf("unable to write spool file: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rewind - flushes the written records and prepares the spool for reading
+func (s *postponedSpool) Rewind() error {
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("unable to flush spool file: %w", err)
+	}
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("unable to rewind spool file: %w", err)
+	}
+	s.r = bufio.NewReader(s.f)
+	return nil
+}
+
+// Next - returns the next stored record. The returned slices are valid until the next call. It returns io.EOF when
+// there are no more records
+func (s *postponedSpool) Next() (flags, original, transformed []byte, err error) {
+	if s.flags, err = s.read(s.flags); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, nil, io.EOF
+		}
+		return nil, nil, nil, fmt.Errorf("unable to read spool file: %w", err)
+	}
+	if s.original, err = s.read(s.original); err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to read spool file: %w", err)
+	}
+	if s.transformed, err = s.read(s.transformed); err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to read spool file: %w", err)
+	}
+	return s.flags, s.original, s.transformed, nil
+}
+
+func (s *postponedSpool) read(buf []byte) ([]byte, error) {
+	length, err := binary.ReadUvarint(s.r)
+	if err != nil {
+		return nil, err
+	}
+	buf = slices.Grow(buf[:0], int(length))[:length]
+	if _, err = io.ReadFull(s.r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Close - closes and removes the spool file
+func (s *postponedSpool) Close() error {
+	return errors.Join(s.f.Close(), os.Remove(s.f.Name()))
+}
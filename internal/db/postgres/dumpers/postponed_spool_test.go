@@ -0,0 +1,17 @@
+package dumpers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPostponedSpool(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := newPostponedSpool(dir)
+	require.NoError(t, err)
+	require.Equal(t, dir, filepath.Dir(spool.f.Name()))
+	require.NoError(t, spool.Close())
+	require.NoFileExists(t, spool.f.Name())
+}
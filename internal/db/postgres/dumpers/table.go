@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgproto3"
@@ -30,7 +31,10 @@ import (
 )
 
 type TableDumper struct {
-	table             *entries.Table
+	table *entries.Table
+	// dumpedTable - the table that is dumped. It performs the pushed down transformers in the COPY query unless the
+	// dump is validated, since the validation requires the original values
+	dumpedTable       *entries.Table
 	recordNum         uint64
 	validate          bool
 	validateRowsLimit uint64
@@ -38,8 +42,13 @@ type TableDumper struct {
 }
 
 func NewTableDumper(table *entries.Table, validate bool, rowsLimit uint64, usePgzip bool) *TableDumper {
+	dumpedTable := table
+	if !validate {
+		dumpedTable = table.Pushdown()
+	}
 	return &TableDumper{
 		table:             table,
+		dumpedTable:       dumpedTable,
 		validate:          validate,
 		usePgzip:          usePgzip,
 		validateRowsLimit: rowsLimit,
@@ -67,21 +76,21 @@ func (td *TableDumper) dumper(ctx context.Context, eg *errgroup.Group, w io.Writ
 	return func() error {
 		var pipeline Pipeliner
 		var err error
-		if len(td.table.TransformersContext) > 0 {
+		if len(td.dumpedTable.TransformersContext) > 0 {
 			if td.validate {
-				pipeline, err = NewValidationPipeline(ctx, eg, td.table, w)
+				pipeline, err = NewValidationPipeline(ctx, eg, td.dumpedTable, w)
 				if err != nil {
 					return fmt.Errorf("cannot initialize validation pipeline: %w", err)
 				}
 			} else {
-				pipeline, err = NewTransformationPipeline(ctx, eg, td.table, w)
+				pipeline, err = NewTransformationPipeline(ctx, eg, td.dumpedTable, w)
 				if err != nil {
 					return fmt.Errorf("cannot initialize transformation pipeline: %w", err)
 				}
 			}
 
 		} else {
-			pipeline = NewPlainDumpPipeline(td.table, w)
+			pipeline = NewPlainDumpPipeline(td.dumpedTable, w)
 		}
 		if err := pipeline.Init(ctx); err != nil {
 			return fmt.Errorf("error initializing transformation pipeline: %w", err)
@@ -100,6 +109,7 @@ func (td *TableDumper) dumper(ctx context.Context, eg *errgroup.Group, w io.Writ
 
 func (td *TableDumper) Execute(ctx context.Context, tx pgx.Tx, st storages.Storager) error {
 
+	startedAt := time.Now()
 	w, r := ioutils.NewGzipPipe(td.usePgzip)
 
 	eg, gtx := errgroup.WithContext(ctx)
@@ -115,6 +125,8 @@ func (td *TableDumper) Execute(ctx context.Context, tx pgx.Tx, st storages.Stora
 
 	td.table.OriginalSize = w.GetCount()
 	td.table.CompressedSize = r.GetCount()
+	td.table.DumpDuration = time.Since(startedAt)
+	td.table.DumpedRows = td.recordNum
 	return nil
 }
 
@@ -126,7 +138,7 @@ func (td *TableDumper) process(ctx context.Context, tx pgx.Tx, w io.WriteCloser,
 	}()
 
 	frontend := tx.Conn().PgConn().Frontend()
-	query, err := td.table.GetCopyFromStatement()
+	query, err := td.dumpedTable.GetCopyFromStatement()
 	log.Debug().
 		Str("query", query).
 		Msgf("dumping table %s.%s using pgcopy query", td.table.Schema, td.table.Name)
@@ -161,18 +173,18 @@ func (td *TableDumper) process(ctx context.Context, tx pgx.Tx, w io.WriteCloser,
 				return fmt.Errorf("dump error: %w", err)
 			}
 
+			td.recordNum++
 			if td.validate {
 				// Logic for validation limiter - exit after recordNum rows
-				td.recordNum++
 				if td.recordNum == td.validateRowsLimit {
-					return nil
+					return pipeline.CompleteDump(ctx)
 				}
 			}
 
 		case *pgproto3.CopyDone:
 		case *pgproto3.CommandComplete:
 		case *pgproto3.ReadyForQuery:
-			return pipeline.CompleteDump()
+			return pipeline.CompleteDump(ctx)
 		case *pgproto3.ErrorResponse:
 			return fmt.Errorf("error from postgres connection msg = %s code=%s", v.Message, v.Code)
 		default:
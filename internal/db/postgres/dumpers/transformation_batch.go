@@ -0,0 +1,81 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dumpers
+
+import (
+	"github.com/eminano/greenmask/internal/db/postgres/entries"
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// transformationBatch - the records that are transformed together. Each record has its own row and the copy of the
+// original line, since the COPY data buffer is reused for each line
+type transformationBatch struct {
+	lines   [][]byte
+	rows    []*pgcopy.Row
+	records []*toolkit.Record
+	size    int
+	// flags - the postponed transformers that collected the record
+	flags [][]byte
+	// postponedIdx - the index of the postponed transformer for each transformer context or -1
+	postponedIdx []int
+	// active - the indexes of the records that match the table "when" condition
+	active []int
+	// selected - the indexes of the records that are transformed by the current transformer
+	selected        []int
+	selectedRecords []*toolkit.Record
+}
+
+func newTransformationBatch(table *entries.Table, size, postponedCount int) *transformationBatch {
+	b := &transformationBatch{
+		lines:        make([][]byte, size),
+		rows:         make([]*pgcopy.Row, size),
+		records:      make([]*toolkit.Record, size),
+		flags:        make([][]byte, size),
+		postponedIdx: make([]int, len(table.TransformersContext)),
+	}
+	for i := range size {
+		b.rows[i] = pgcopy.NewRow(len(table.Columns))
+		b.records[i] = toolkit.NewRecord(table.Driver)
+		b.records[i].SetRow(b.rows[i])
+		b.flags[i] = make([]byte, (postponedCount+7)/8)
+	}
+	// The postponed transformers are collected in the order of the transformers context
+	var nextPostponed int
+	for i, tc := range table.TransformersContext {
+		b.postponedIdx[i] = -1
+		if _, ok := tc.Transformer.(utils.PostponedTransformer); ok {
+			b.postponedIdx[i] = nextPostponed
+			nextPostponed++
+		}
+	}
+	return b
+}
+
+// add - copies and decodes the line. It returns true if the batch is full
+func (b *transformationBatch) add(data []byte) (bool, error) {
+	b.lines[b.size] = append(b.lines[b.size][:0], data...)
+	if err := b.rows[b.size].Decode(b.lines[b.size]); err != nil {
+		return false, err
+	}
+	clear(b.flags[b.size])
+	b.size++
+	return b.size == len(b.records), nil
+}
+
+func (b *transformationBatch) reset() {
+	b.size = 0
+}
@@ -16,9 +16,11 @@ package dumpers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"slices"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/errgroup"
@@ -27,6 +29,7 @@ import (
 	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	utils2 "github.com/eminano/greenmask/internal/utils"
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
 
@@ -43,6 +46,20 @@ type TransformationPipeline struct {
 	Transform             transformationFunc
 	isAsync               bool
 	record                *toolkit.Record
+	// postponed - the transformers that require all the table records. If there are any, the transformed records are
+	// stored in the spool and written in CompleteDump
+	postponed []utils.PostponedTransformer
+	// postponedProfiles - the profiles of the postponed transformers contexts, Prepare and Apply are registered there
+	postponedProfiles []*utils.TransformerProfile
+	postponedCounts   []uint64
+	postponedFlags    []byte
+	spool             *postponedSpool
+	// keepOriginal - write the original record before each transformed record when the spooled records are written
+	keepOriginal bool
+	// batch - the records collected for the batch transformation if the table batch size is set
+	batch *transformationBatch
+	// parallel - the workers that transform the records in parallel if the table has the worker instances
+	parallel *parallelTransformation
 }
 
 func NewTransformationPipeline(ctx context.Context, eg *errgroup.Group, table *entries.Table, w io.Writer) (*TransformationPipeline, error) {
@@ -50,14 +67,21 @@ func NewTransformationPipeline(ctx context.Context, eg *errgroup.Group, table *e
 	var tws []*transformationWindow
 	var isAsync bool
 
-	// TODO: Fix this hint. Async execution cannot be performed with template record because it is unsafe.
+	// TODO: Fix this hint. Async execution cannot be performed with template record and script because it is unsafe:
+	//       they can read and write any column of the record, not only the affected ones.
 	//       For overcoming it - implement sequence transformer wrapper - that wraps internal (non CMD) transformers
-	hasTemplateRecordTransformer := slices.ContainsFunc(table.TransformersContext, func(transformer *utils.TransformerContext) bool {
-		_, ok := transformer.Transformer.(*transformers.TemplateRecordTransformer)
-		return ok
+	hasWholeRecordTransformer := slices.ContainsFunc(table.TransformersContext, func(transformer *utils.TransformerContext) bool {
+		switch transformer.Transformer.(type) {
+		case *transformers.TemplateRecordTransformer, *transformers.ScriptTransformer:
+			return true
+		}
+		return false
 	})
 
-	if !hasTemplateRecordTransformer && table.HasCustomTransformer() && len(table.TransformersContext) > 1 {
+	// The batched records are transformed by each transformer in turn and the parallel workers transform the whole
+	// records, so the transformation windows are not used
+	if table.BatchSize <= 1 && len(table.Workers) == 0 && !hasWholeRecordTransformer &&
+		table.HasCustomTransformer() && len(table.TransformersContext) > 1 {
 		isAsync = true
 		tw := newTransformationWindow(ctx, eg)
 		tws = append(tws, tw)
@@ -78,6 +102,15 @@ func NewTransformationPipeline(ctx context.Context, eg *errgroup.Group, table *e
 		}
 	}
 
+	var postponed []utils.PostponedTransformer
+	var postponedProfiles []*utils.TransformerProfile
+	for _, tc := range table.TransformersContext {
+		if pt, ok := tc.Transformer.(utils.PostponedTransformer); ok {
+			postponed = append(postponed, pt)
+			postponedProfiles = append(postponedProfiles, &tc.Profile)
+		}
+	}
+
 	tp := &TransformationPipeline{
 		table:                 table,
 		w:                     w,
@@ -85,6 +118,15 @@ func NewTransformationPipeline(ctx context.Context, eg *errgroup.Group, table *e
 		transformationWindows: tws,
 		isAsync:               true,
 		record:                record,
+		postponed:             postponed,
+		postponedProfiles:     postponedProfiles,
+		postponedCounts:       make([]uint64, len(postponed)),
+		postponedFlags:        make([]byte, (len(postponed)+7)/8),
+	}
+	if table.BatchSize > 1 {
+		tp.batch = newTransformationBatch(table, table.BatchSize, len(postponed))
+	} else if len(table.Workers) > 0 && len(postponed) == 0 {
+		tp.parallel = newParallelTransformation(table)
 	}
 
 	var tf transformationFunc = tp.TransformSync
@@ -100,7 +142,8 @@ func (tp *TransformationPipeline) Init(ctx context.Context) error {
 	var lastInitErr error
 	var idx int
 	var t *utils.TransformerContext
-	for idx, t = range tp.table.TransformersContext {
+	transformersContext := tp.transformersContext()
+	for idx, t = range transformersContext {
 		if err := t.Transformer.Init(ctx); err != nil {
 			lastInitErr = err
 			log.Warn().Err(err).Msg("error initializing transformer")
@@ -109,7 +152,7 @@ func (tp *TransformationPipeline) Init(ctx context.Context) error {
 
 	if lastInitErr != nil {
 		lastInitialized := idx
-		for _, t = range tp.table.TransformersContext[:lastInitialized] {
+		for _, t = range transformersContext[:lastInitialized] {
 			if err := t.Transformer.Done(ctx); err != nil {
 				log.Warn().Err(err).Msg("error terminating previously initialized transformer")
 			}
@@ -124,9 +167,28 @@ func (tp *TransformationPipeline) Init(ctx context.Context) error {
 		}
 	}
 
+	if len(tp.postponed) > 0 {
+		spool, err := newPostponedSpool(utils2.TmpDirFromCtx(ctx))
+		if err != nil {
+			return err
+		}
+		tp.spool = spool
+	}
+	if tp.parallel != nil {
+		tp.parallel.start(ctx)
+	}
+
 	return nil
 }
 
+// transformersContext - returns the transformers of the table including the instances of the parallel workers
+func (tp *TransformationPipeline) transformersContext() []*utils.TransformerContext {
+	if tp.parallel != nil {
+		return tp.parallel.transformers()
+	}
+	return tp.table.TransformersContext
+}
+
 func (tp *TransformationPipeline) TransformSync(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
 	for _, t := range tp.table.TransformersContext {
 		needTransform, err := t.EvaluateWhen(r)
@@ -136,7 +198,7 @@ func (tp *TransformationPipeline) TransformSync(ctx context.Context, r *toolkit.
 		if !needTransform {
 			continue
 		}
-		_, err = t.Transformer.Transform(ctx, r)
+		_, err = t.Transform(ctx, r)
 		if err != nil {
 			return nil, NewDumpError(tp.table.Schema, tp.table.Name, tp.line, err)
 		}
@@ -156,6 +218,16 @@ func (tp *TransformationPipeline) TransformAsync(ctx context.Context, r *toolkit
 }
 
 func (tp *TransformationPipeline) Dump(ctx context.Context, data []byte) (err error) {
+	if tp.batch != nil {
+		return tp.dumpBatch(ctx, data)
+	}
+	if tp.parallel != nil {
+		tp.line++
+		if err = tp.parallel.submit(ctx, tp.line, data[:len(data)-1], tp.writeJob); err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, err)
+		}
+		return nil
+	}
 	tp.line++
 	if err = tp.row.Decode(data[:len(data)-1]); err != nil {
 		return fmt.Errorf("error decoding copy line: %w", err)
@@ -174,16 +246,160 @@ func (tp *TransformationPipeline) Dump(ctx context.Context, data []byte) (err er
 		}
 	}
 
-	rowDriver, err := tp.record.Encode()
+	res, err := encodeRecord(tp.record)
+	if err != nil {
+		return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, err)
+	}
+
+	if tp.spool != nil {
+		tp.setPostponedFlags()
+		var original []byte
+		if tp.keepOriginal {
+			original = data[:len(data)-1]
+		}
+		if err = tp.spool.Write(tp.postponedFlags, original, res); err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, err)
+		}
+		return nil
+	}
+
+	return tp.writeLine(res)
+}
+
+// dumpBatch - collects the record and transforms the batch when it is full
+func (tp *TransformationPipeline) dumpBatch(ctx context.Context, data []byte) error {
+	tp.line++
+	full, err := tp.batch.add(data[:len(data)-1])
 	if err != nil {
-		return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, fmt.Errorf("error enocding Record to RowDriver: %w", err))
+		return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, fmt.Errorf("error decoding copy line: %w", err))
+	}
+	if !full {
+		return nil
+	}
+	return tp.flushBatch(ctx)
+}
+
+// flushBatch - transforms the collected records by each transformer in turn and writes them in the original order
+func (tp *TransformationPipeline) flushBatch(ctx context.Context) error {
+	b := tp.batch
+	defer b.reset()
+	firstLine := tp.line - uint64(b.size) + 1
+
+	b.active = b.active[:0]
+	for i, r := range b.records[:b.size] {
+		needTransform, err := tp.table.When.Evaluate(r)
+		if err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, firstLine+uint64(i), fmt.Errorf("error evaluating when condition: %w", err))
+		}
+		if needTransform {
+			b.active = append(b.active, i)
+		}
+	}
+
+	for idx, t := range tp.table.TransformersContext {
+		if err := tp.transformBatch(ctx, idx, t, firstLine); err != nil {
+			return err
+		}
+	}
+
+	for i, r := range b.records[:b.size] {
+		line := firstLine + uint64(i)
+		res, err := encodeRecord(r)
+		if err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, line, err)
+		}
+		if tp.spool != nil {
+			var original []byte
+			if tp.keepOriginal {
+				original = b.lines[i]
+			}
+			if err = tp.spool.Write(b.flags[i], original, res); err != nil {
+				return NewDumpError(tp.table.Schema, tp.table.Name, line, err)
+			}
+			continue
+		}
+		if err = tp.writeLine(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transformBatch - applies the transformer to the batch records that match its "when" condition. The records are
+// passed at once to the BatchTransformer. Other transformers are called for each record and the values they set are
+// copied, since most of the transformers reuse the result buffer
+func (tp *TransformationPipeline) transformBatch(
+	ctx context.Context, idx int, t *utils.TransformerContext, firstLine uint64,
+) error {
+	b := tp.batch
+	b.selected = b.selected[:0]
+	b.selectedRecords = b.selectedRecords[:0]
+	for _, i := range b.active {
+		needTransform, err := t.EvaluateWhen(b.records[i])
+		if err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, firstLine+uint64(i), fmt.Errorf("error evaluating when condition: %w", err))
+		}
+		if needTransform {
+			b.selected = append(b.selected, i)
+			b.selectedRecords = append(b.selectedRecords, b.records[i])
+		}
+	}
+	if len(b.selected) == 0 {
+		return nil
+	}
+
+	pIdx := b.postponedIdx[idx]
+	if _, ok := t.Transformer.(utils.BatchTransformer); ok && pIdx == -1 && len(t.DynamicParameters) == 0 {
+		if err := t.TransformBatch(ctx, b.selectedRecords); err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, firstLine+uint64(b.selected[0]), err)
+		}
+		return nil
+	}
+
+	for j, r := range b.selectedRecords {
+		i := b.selected[j]
+		for _, dp := range t.DynamicParameters {
+			dp.SetRecord(r)
+		}
+		var collected uint64
+		if pIdx != -1 {
+			collected = tp.postponed[pIdx].CollectedCount()
+		}
+		if _, err := t.Transform(ctx, r); err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, firstLine+uint64(i), err)
+		}
+		if pIdx != -1 && tp.postponed[pIdx].CollectedCount() != collected {
+			b.flags[i][pIdx/8] |= 1 << (pIdx % 8)
+		}
+		if err := utils.DetachValues(r, t.Transformer.GetAffectedColumns()); err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, firstLine+uint64(i), err)
+		}
+	}
+	return nil
+}
+
+// writeJob - writes the record transformed by the parallel worker
+func (tp *TransformationPipeline) writeJob(job *transformationJob) error {
+	if job.err != nil {
+		return NewDumpError(tp.table.Schema, tp.table.Name, job.line, job.err)
+	}
+	return tp.writeLine(job.res)
+}
+
+func encodeRecord(r *toolkit.Record) ([]byte, error) {
+	rowDriver, err := r.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("error enocding Record to RowDriver: %w", err)
 	}
 	res, err := rowDriver.Encode()
 	if err != nil {
-		return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, fmt.Errorf("error encoding RowDriver to []byte: %w", err))
+		return nil, fmt.Errorf("error encoding RowDriver to []byte: %w", err)
 	}
+	return res, nil
+}
 
-	_, err = tp.w.Write(res)
+func (tp *TransformationPipeline) writeLine(data []byte) error {
+	_, err := tp.w.Write(data)
 	if err != nil {
 		return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, fmt.Errorf("error writing dumped data: %w", err))
 	}
@@ -194,7 +410,91 @@ func (tp *TransformationPipeline) Dump(ctx context.Context, data []byte) (err er
 	return nil
 }
 
-func (tp *TransformationPipeline) CompleteDump() (err error) {
+// setPostponedFlags - marks the postponed transformers that collected the current record
+func (tp *TransformationPipeline) setPostponedFlags() {
+	clear(tp.postponedFlags)
+	for idx, pt := range tp.postponed {
+		count := pt.CollectedCount()
+		if count != tp.postponedCounts[idx] {
+			tp.postponedFlags[idx/8] |= 1 << (idx % 8)
+			tp.postponedCounts[idx] = count
+		}
+	}
+}
+
+// completePostponed - prepares the postponed transformers and writes the spooled records applying the postponed
+// transformers to the records they collected
+func (tp *TransformationPipeline) completePostponed(ctx context.Context) error {
+	for idx, pt := range tp.postponed {
+		start := time.Now()
+		if err := pt.Prepare(ctx); err != nil {
+			return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, fmt.Errorf("error preparing transformer: %w", err))
+		}
+		tp.postponedProfiles[idx].Duration += time.Since(start)
+	}
+	if err := tp.spool.Rewind(); err != nil {
+		return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, err)
+	}
+
+	tp.line = 0
+	for {
+		flags, original, data, err := tp.spool.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, err)
+		}
+		tp.line++
+		if tp.keepOriginal {
+			if err = tp.writeLine(original); err != nil {
+				return err
+			}
+		}
+
+		if slices.ContainsFunc(flags, func(f byte) bool { return f != 0 }) {
+			if err = tp.row.Decode(data); err != nil {
+				return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, fmt.Errorf("error decoding spooled line: %w", err))
+			}
+			tp.record.SetRow(tp.row)
+			for idx, pt := range tp.postponed {
+				if flags[idx/8]&(1<<(idx%8)) == 0 {
+					continue
+				}
+				start := time.Now()
+				if _, err = pt.Apply(ctx, tp.record); err != nil {
+					return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, err)
+				}
+				tp.postponedProfiles[idx].Duration += time.Since(start)
+			}
+			if data, err = encodeRecord(tp.record); err != nil {
+				return NewDumpError(tp.table.Schema, tp.table.Name, tp.line, err)
+			}
+		}
+
+		if err = tp.writeLine(data); err != nil {
+			return err
+		}
+	}
+}
+
+func (tp *TransformationPipeline) CompleteDump(ctx context.Context) (err error) {
+	if tp.batch != nil && tp.batch.size > 0 {
+		if err = tp.flushBatch(ctx); err != nil {
+			return err
+		}
+	}
+	if tp.parallel != nil {
+		if err = tp.parallel.flush(ctx, tp.writeJob); err != nil {
+			return err
+		}
+	}
+	if tp.spool != nil {
+		if err = tp.completePostponed(ctx); err != nil {
+			return err
+		}
+	}
+
 	res := make([]byte, 0, 4)
 	res = append(res, pgcopy.DefaultCopyTerminationSeq...)
 	res = append(res, '\n', '\n')
@@ -207,7 +507,11 @@ func (tp *TransformationPipeline) CompleteDump() (err error) {
 
 func (tp *TransformationPipeline) Done(ctx context.Context) error {
 	var lastErr error
-	for _, t := range tp.table.TransformersContext {
+	if tp.parallel != nil {
+		// The workers must not use the transformers after they are terminated
+		tp.parallel.stop()
+	}
+	for _, t := range tp.transformersContext() {
 		if err := t.Transformer.Done(ctx); err != nil {
 			lastErr = err
 			log.Warn().Err(err).Msg("error terminating initialized transformer")
@@ -218,6 +522,13 @@ func (tp *TransformationPipeline) Done(ctx context.Context) error {
 			w.close()
 		}
 	}
+	if tp.spool != nil {
+		if err := tp.spool.Close(); err != nil {
+			lastErr = err
+			log.Warn().Err(err).Msg("error removing spool file")
+		}
+		tp.spool = nil
+	}
 
 	if lastErr != nil {
 		return fmt.Errorf("error terminating initialized transformer: %w", lastErr)
@@ -3,12 +3,18 @@ package dumpers
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/eminano/greenmask/internal/db/postgres/entries"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/custom"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
@@ -40,7 +46,7 @@ func TestTransformationPipeline_Dump(t *testing.T) {
 	err = pipeline.Dump(ctx, data)
 	require.NoError(t, err)
 	require.NoError(t, pipeline.Done(termCtx))
-	require.NoError(t, pipeline.CompleteDump())
+	require.NoError(t, pipeline.CompleteDump(ctx))
 	require.Equal(t, tt.callsCount, 1)
 	require.Equal(t, buf.String(), "2\t2023-08-27 00:00:00.00000\n\\.\n\n")
 }
@@ -72,7 +78,7 @@ func TestTransformationPipeline_Dump_with_transformer_cond(t *testing.T) {
 	err = pipeline.Dump(ctx, data)
 	require.NoError(t, err)
 	require.NoError(t, pipeline.Done(termCtx))
-	require.NoError(t, pipeline.CompleteDump())
+	require.NoError(t, pipeline.CompleteDump(ctx))
 	require.Equal(t, tt.callsCount, 0)
 	require.Equal(t, buf.String(), "1\t2023-08-27 00:00:00.00000\n\\.\n\n")
 }
@@ -104,7 +110,286 @@ func TestTransformationPipeline_Dump_with_table_cond(t *testing.T) {
 	err = pipeline.Dump(ctx, data)
 	require.NoError(t, err)
 	require.NoError(t, pipeline.Done(termCtx))
-	require.NoError(t, pipeline.CompleteDump())
+	require.NoError(t, pipeline.CompleteDump(ctx))
 	require.Equal(t, tt.callsCount, 0)
 	require.Equal(t, buf.String(), "1\t2023-08-27 00:00:00.00000\n\\.\n\n")
 }
+
+func TestTransformationPipeline_Dump_with_postponed_transformer(t *testing.T) {
+	tests := []struct {
+		name      string
+		validate  bool
+		batchSize int
+		expected  string
+	}{
+		{
+			name: "transformation",
+			expected: "3\t2023-08-27 00:00:00.000000\n" +
+				"2\t2023-08-27 00:00:00.000000\n" +
+				"1\t2023-08-27 00:00:00.000000\n" +
+				"\\.\n\n",
+		},
+		{
+			name:      "batch",
+			batchSize: 2,
+			expected: "3\t2023-08-27 00:00:00.000000\n" +
+				"2\t2023-08-27 00:00:00.000000\n" +
+				"1\t2023-08-27 00:00:00.000000\n" +
+				"\\.\n\n",
+		},
+		{
+			name:     "validation",
+			validate: true,
+			expected: "1\t2023-08-27 00:00:00.000000\n3\t2023-08-27 00:00:00.000000\n" +
+				"2\t2023-08-27 00:00:00.000000\n2\t2023-08-27 00:00:00.000000\n" +
+				"3\t2023-08-27 00:00:00.000000\n1\t2023-08-27 00:00:00.000000\n" +
+				"\\.\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			termCtx, termCancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer termCancel()
+			table := getTable("")
+			table.BatchSize = tt.batchSize
+			ctx := context.Background()
+			eg, gtx := errgroup.WithContext(ctx)
+			driver := getDriver(table.Table)
+			table.Driver = driver
+			when, warns := toolkit.NewWhenCond("record.id != 2", driver, make(map[string]any))
+			require.Empty(t, warns)
+			pt := &testPostponedTransformer{}
+			table.TransformersContext = []*utils.TransformerContext{
+				{
+					Transformer: pt,
+					When:        when,
+				},
+			}
+
+			buf := bytes.NewBuffer(nil)
+			var pipeline Pipeliner
+			var err error
+			if tt.validate {
+				pipeline, err = NewValidationPipeline(gtx, eg, table, buf)
+			} else {
+				pipeline, err = NewTransformationPipeline(gtx, eg, table, buf)
+			}
+			require.NoError(t, err)
+			require.NoError(t, pipeline.Init(termCtx))
+			for _, data := range []string{
+				"1\t2023-08-27 00:00:00.000000",
+				"2\t2023-08-27 00:00:00.000000",
+				"3\t2023-08-27 00:00:00.000000",
+			} {
+				require.NoError(t, pipeline.Dump(ctx, []byte(data+"\n")))
+			}
+			// Nothing is written until all the records are collected
+			require.Empty(t, buf.String())
+			require.NoError(t, pipeline.CompleteDump(ctx))
+			require.NoError(t, pipeline.Done(termCtx))
+			require.Equal(t, []int64{1, 3}, pt.collected)
+			require.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestTransformationPipeline_Dump_batch(t *testing.T) {
+	termCtx, termCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer termCancel()
+	table := getTable("record.id != 2")
+	table.BatchSize = 3
+	ctx := context.Background()
+	eg, gtx := errgroup.WithContext(ctx)
+	driver := getDriver(table.Table)
+	table.Driver = driver
+	when, warns := toolkit.NewWhenCond("", driver, make(map[string]any))
+	require.Empty(t, warns)
+	bt := &testBatchTransformer{}
+	table.TransformersContext = []*utils.TransformerContext{
+		{
+			Transformer: bt,
+			When:        when,
+		},
+		{
+			Transformer: &testReusingTransformer{},
+			When:        when,
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	pipeline, err := NewTransformationPipeline(gtx, eg, table, buf)
+	require.NoError(t, err)
+	require.NoError(t, pipeline.Init(termCtx))
+	for _, data := range []string{
+		"1\t2023-08-27 00:00:00.000000",
+		"2\t2023-08-27 00:00:00.000000",
+		"3\t2023-08-27 00:00:00.000000",
+		"4\t2023-08-27 00:00:00.000000",
+		"5\t2023-08-27 00:00:00.000000",
+	} {
+		require.NoError(t, pipeline.Dump(ctx, []byte(data+"\n")))
+	}
+	require.NoError(t, pipeline.CompleteDump(ctx))
+	require.NoError(t, pipeline.Done(termCtx))
+	// The record with id 2 does not match the table condition
+	require.Equal(t, []int{2, 2}, bt.batches)
+	// The batch transformer is called once per batch
+	profiles := table.GetTransformersProfile()
+	require.Equal(t, uint64(2), profiles[0].Calls)
+	require.Equal(t, uint64(4), profiles[0].Rows)
+	require.Equal(t, uint64(4), profiles[1].Calls)
+	require.Equal(t, uint64(4), profiles[1].Rows)
+	require.Equal(t, "11\t2023-08-27 00:00:00.000000\n"+
+		"2\t2023-08-27 00:00:00.000000\n"+
+		"31\t2023-08-27 00:00:00.000000\n"+
+		"41\t2023-08-27 00:00:00.000000\n"+
+		"51\t2023-08-27 00:00:00.000000\n"+
+		"\\.\n\n", buf.String())
+}
+
+func TestTransformationPipeline_Dump_parallel(t *testing.T) {
+	termCtx, termCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer termCancel()
+	table := getTable("record.id != 2")
+	ctx := context.Background()
+	eg, gtx := errgroup.WithContext(ctx)
+	newTransformers := func(driver *toolkit.Driver) []*utils.TransformerContext {
+		when, warns := toolkit.NewWhenCond("", driver, make(map[string]any))
+		require.Empty(t, warns)
+		return []*utils.TransformerContext{
+			{
+				Transformer: &testReusingTransformer{},
+				When:        when,
+			},
+		}
+	}
+	table.TransformersContext = newTransformers(table.Driver)
+	for i := 0; i < 2; i++ {
+		driver := getDriver(table.Table)
+		when, warns := toolkit.NewWhenCond("record.id != 2", driver, make(map[string]any))
+		require.Empty(t, warns)
+		table.Workers = append(table.Workers, &entries.TableWorker{
+			Driver:              driver,
+			When:                when,
+			TransformersContext: newTransformers(driver),
+		})
+	}
+
+	buf := bytes.NewBuffer(nil)
+	pipeline, err := NewTransformationPipeline(gtx, eg, table, buf)
+	require.NoError(t, err)
+	require.NoError(t, pipeline.Init(termCtx))
+	expected := strings.Builder{}
+	for id := 1; id <= 100; id++ {
+		require.NoError(t, pipeline.Dump(ctx, []byte(fmt.Sprintf("%d\t2023-08-27 00:00:00.000000\n", id))))
+		// The record with id 2 does not match the table condition
+		transformedId := id + 1
+		if id == 2 {
+			transformedId = id
+		}
+		expected.WriteString(fmt.Sprintf("%d\t2023-08-27 00:00:00.000000\n", transformedId))
+	}
+	require.NoError(t, pipeline.CompleteDump(ctx))
+	require.NoError(t, pipeline.Done(termCtx))
+	expected.WriteString("\\.\n\n")
+	require.Equal(t, expected.String(), buf.String())
+	// The profile is summed over the workers
+	profiles := table.GetTransformersProfile()
+	require.Len(t, profiles, 1)
+	require.Equal(t, uint64(99), profiles[0].Calls)
+	require.Equal(t, uint64(99), profiles[0].Rows)
+}
+
+// testBatchTransformer - multiplies the ids by 10 and stores the sizes of the received batches
+type testBatchTransformer struct {
+	testTransformer
+	batches []int
+}
+
+func (bt *testBatchTransformer) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	bt.batches = append(bt.batches, len(records))
+	for _, r := range records {
+		var id int64
+		if _, err := r.ScanColumnValueByName("id", &id); err != nil {
+			return err
+		}
+		if err := r.SetColumnValueByName("id", id*10); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// testReusingTransformer - increments the ids writing the result into the same buffer for each record
+type testReusingTransformer struct {
+	testTransformer
+	buf []byte
+}
+
+func (rt *testReusingTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	var id int64
+	if _, err := r.ScanColumnValueByName("id", &id); err != nil {
+		return nil, err
+	}
+	rt.buf = strconv.AppendInt(rt.buf[:0], id+1, 10)
+	if err := r.SetRawColumnValueByName("id", toolkit.NewRawValue(rt.buf, false)); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (rt *testReusingTransformer) GetAffectedColumns() map[int]string {
+	return map[int]string{0: "id"}
+}
+
+// testPostponedTransformer - sets the collected ids in reverse order
+type testPostponedTransformer struct {
+	testTransformer
+	collected []int64
+	applied   int
+}
+
+func (pt *testPostponedTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	var id int64
+	if _, err := r.ScanColumnValueByName("id", &id); err != nil {
+		return nil, err
+	}
+	pt.collected = append(pt.collected, id)
+	return r, nil
+}
+
+func (pt *testPostponedTransformer) Prepare(ctx context.Context) error {
+	return nil
+}
+
+func (pt *testPostponedTransformer) Apply(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	pt.applied++
+	if err := r.SetColumnValueByName("id", pt.collected[len(pt.collected)-pt.applied]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (pt *testPostponedTransformer) CollectedCount() uint64 {
+	return uint64(len(pt.collected))
+}
+
+func TestNewTransformationPipeline_whole_record_transformer_is_not_async(t *testing.T) {
+	table := getTable("")
+	script, warns, err := transformers.ScriptTransformerDefinition.Instance(
+		context.Background(), table.Driver, map[string]toolkit.ParamsValue{
+			"script":  toolkit.ParamsValue(`function transform(record) {}`),
+			"columns": toolkit.ParamsValue(`["id"]`),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.Empty(t, warns)
+	table.TransformersContext = []*utils.TransformerContext{script, {Transformer: &custom.CmdTransformer{}}}
+	require.True(t, table.HasCustomTransformer())
+
+	eg, gtx := errgroup.WithContext(context.Background())
+	pipeline, err := NewTransformationPipeline(gtx, eg, table, bytes.NewBuffer(nil))
+	require.NoError(t, err)
+	require.Empty(t, pipeline.transformationWindows)
+}
@@ -94,7 +94,7 @@ func (tw *transformationWindow) init() {
 						return nil
 					case <-ac.ch:
 					}
-					_, err := ac.tc.Transformer.Transform(tw.ctx, tw.r)
+					_, err := ac.tc.Transform(tw.ctx, tw.r)
 					if err != nil {
 						tw.wg.Done()
 						return err
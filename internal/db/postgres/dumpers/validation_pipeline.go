@@ -19,15 +19,23 @@ func NewValidationPipeline(ctx context.Context, eg *errgroup.Group, table *entri
 	if err != nil {
 		return nil, err
 	}
+	tpp.keepOriginal = true
+	// The original record is written before the transformed one, so the records are neither batched nor transformed
+	// in parallel
+	tpp.batch = nil
+	tpp.parallel = nil
 	return &ValidationPipeline{
 		TransformationPipeline: tpp,
 	}, err
 }
 
 func (vp *ValidationPipeline) Dump(ctx context.Context, data []byte) (err error) {
-	_, err = vp.w.Write(data)
-	if err != nil {
-		return NewDumpError(vp.table.Schema, vp.table.Name, vp.line, fmt.Errorf("error writing original dumped data: %w", err))
+	// The original record of the postponed transformation is written together with the transformed one
+	if len(vp.postponed) == 0 {
+		_, err = vp.w.Write(data)
+		if err != nil {
+			return NewDumpError(vp.table.Schema, vp.table.Name, vp.line, fmt.Errorf("error writing original dumped data: %w", err))
+		}
 	}
 
 	return vp.TransformationPipeline.Dump(ctx, data)
@@ -17,8 +17,10 @@ package entries
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/eminano/greenmask/internal/db/postgres/toc"
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/custom"
@@ -44,18 +46,55 @@ type Table struct {
 	DumpId              int32
 	OriginalSize        int64
 	CompressedSize      int64
+	// DumpDuration - the time spent to dump the table data
+	DumpDuration time.Duration
+	// DumpedRows - the number of the dumped table records
+	DumpedRows uint64
 	//ExcludeData          bool
 	Driver      *toolkit.Driver
 	Scores      int64
 	SubsetConds []string
 	When        *toolkit.WhenCond
+	// BatchSize - the number of records that are transformed together. The transformers that implement
+	// utils.BatchTransformer receive all the records at once
+	BatchSize int
+	// PushedDown - the first transformers of TransformersContext that are performed by PostgreSQL in the COPY query
+	PushedDown []*PushedDownTransformer
+	// Workers - the additional transformer instances that transform the table records in parallel with the
+	// TransformersContext of the table. Each worker has its own driver, so the workers do not share the type maps
+	Workers []*TableWorker
+}
+
+// TableWorker - the separate instance of the table transformers that is used by the parallel transformation
+type TableWorker struct {
+	Driver              *toolkit.Driver
+	When                *toolkit.WhenCond
+	TransformersContext []*utils.TransformerContext
+}
+
+// PushedDownTransformer - the transformer that is performed by PostgreSQL. Expressions are the SQL expressions of the
+// affected columns by column index
+type PushedDownTransformer struct {
+	Name        string
+	Expressions map[int]string
+}
+
+// TransformerProfile - the profile of the table transformer summed over the parallel worker instances. The pushed
+// down transformers are performed by PostgreSQL, so their profile is empty
+type TransformerProfile struct {
+	Name       string
+	PushedDown bool
+	utils.TransformerProfile
 }
 
 // HasCustomTransformer - check if table has custom transformer
 func (t *Table) HasCustomTransformer() bool {
 	return slices.ContainsFunc(t.TransformersContext, func(transformer *utils.TransformerContext) bool {
-		_, ok := transformer.Transformer.(*custom.CmdTransformer)
-		return ok
+		switch transformer.Transformer.(type) {
+		case *custom.CmdTransformer, *custom.CmdTransformerPool:
+			return true
+		}
+		return false
 	})
 }
 
@@ -135,6 +174,63 @@ func (t *Table) Entry() (*toc.Entry, error) {
 	}, nil
 }
 
+// Pushdown - returns the copy of the table that performs the pushed down transformers in the COPY query and runs only
+// the remaining transformers in the pipeline
+func (t *Table) Pushdown() *Table {
+	if len(t.PushedDown) == 0 {
+		return t
+	}
+	expressions := make(map[int]string)
+	for _, pt := range t.PushedDown {
+		maps.Copy(expressions, pt.Expressions)
+	}
+	columns := make([]string, 0, len(t.Columns))
+	for idx, c := range t.Columns {
+		if c.IsGenerated {
+			continue
+		}
+		if expr, ok := expressions[idx]; ok {
+			columns = append(columns, fmt.Sprintf(`%s AS %s`, expr, utils.QuoteIdent(c.Name)))
+		} else {
+			columns = append(columns, utils.QuoteIdent(c.Name))
+		}
+	}
+	from := fmt.Sprintf(`%s.%s`, utils.QuoteIdent(t.Schema), utils.QuoteIdent(t.Name))
+	if t.Query != "" {
+		from = fmt.Sprintf(`(%s) AS %s`, t.Query, utils.QuoteIdent(t.Name))
+	}
+
+	res := *t
+	res.Query = fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), from)
+	res.TransformersContext = t.TransformersContext[len(t.PushedDown):]
+	res.Workers = make([]*TableWorker, 0, len(t.Workers))
+	for _, w := range t.Workers {
+		res.Workers = append(res.Workers, &TableWorker{
+			Driver:              w.Driver,
+			When:                w.When,
+			TransformersContext: w.TransformersContext[len(t.PushedDown):],
+		})
+	}
+	return &res
+}
+
+// GetTransformersProfile - returns the profiles of the table transformers in the order of TransformersContext
+func (t *Table) GetTransformersProfile() []*TransformerProfile {
+	res := make([]*TransformerProfile, 0, len(t.TransformersContext))
+	for idx, tc := range t.TransformersContext {
+		p := &TransformerProfile{
+			Name:               tc.Name,
+			PushedDown:         idx < len(t.PushedDown),
+			TransformerProfile: tc.Profile,
+		}
+		for _, w := range t.Workers {
+			p.Add(&w.TransformersContext[idx].Profile)
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
 // GetCopyFromStatement - get COPY FROM statement for table
 func (t *Table) GetCopyFromStatement() (string, error) {
 	// We could generate an explicit column list for the COPY statement, but it’s not necessary because, by default,
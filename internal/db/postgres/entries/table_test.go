@@ -0,0 +1,109 @@
+package entries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func TestTable_Pushdown(t *testing.T) {
+	newTable := func(query string) *Table {
+		return &Table{
+			Table: &toolkit.Table{
+				Schema: "public",
+				Name:   "users",
+				Columns: []*toolkit.Column{
+					{Name: "id"},
+					{Name: "email"},
+					{Name: "full_name", IsGenerated: true},
+					{Name: "age"},
+				},
+			},
+			Query:               query,
+			TransformersContext: []*utils.TransformerContext{{}, {}, {}},
+			PushedDown: []*PushedDownTransformer{
+				{Name: "SetNull", Expressions: map[int]string{1: "NULL"}},
+				{Name: "RandomInt", Expressions: map[int]string{3: "(1 + floor(random()::numeric * 99))::int8"}},
+			},
+		}
+	}
+
+	t.Run("table", func(t *testing.T) {
+		table := newTable("")
+		res := table.Pushdown()
+		assert.Equal(t,
+			`SELECT "id", NULL AS "email", (1 + floor(random()::numeric * 99))::int8 AS "age" FROM "public"."users"`,
+			res.Query,
+		)
+		assert.Len(t, res.TransformersContext, 1)
+		assert.Empty(t, table.Query)
+		assert.Len(t, table.TransformersContext, 3)
+	})
+
+	t.Run("subset query", func(t *testing.T) {
+		res := newTable(`SELECT * FROM "public"."users" WHERE id > 10`).Pushdown()
+		assert.Equal(t,
+			`SELECT "id", NULL AS "email", (1 + floor(random()::numeric * 99))::int8 AS "age" `+
+				`FROM (SELECT * FROM "public"."users" WHERE id > 10) AS "users"`,
+			res.Query,
+		)
+	})
+
+	t.Run("quoted identifiers", func(t *testing.T) {
+		table := newTable("")
+		table.Schema = `my"schema`
+		table.Name = `my"users`
+		table.Columns[1].Name = `e"mail`
+		table.Columns[3].Name = `"age"`
+		assert.Equal(t,
+			`SELECT "id", NULL AS "e""mail", (1 + floor(random()::numeric * 99))::int8 AS """age""" `+
+				`FROM "my""schema"."my""users"`,
+			table.Pushdown().Query,
+		)
+
+		table.Query = `SELECT * FROM "my""schema"."my""users"`
+		assert.Equal(t,
+			`SELECT "id", NULL AS "e""mail", (1 + floor(random()::numeric * 99))::int8 AS """age""" `+
+				`FROM (SELECT * FROM "my""schema"."my""users") AS "my""users"`,
+			table.Pushdown().Query,
+		)
+	})
+
+	t.Run("nothing pushed down", func(t *testing.T) {
+		table := newTable("")
+		table.PushedDown = nil
+		assert.Same(t, table, table.Pushdown())
+	})
+}
+
+func TestTable_GetTransformersProfile(t *testing.T) {
+	table := &Table{
+		Table: &toolkit.Table{Schema: "public", Name: "users"},
+		TransformersContext: []*utils.TransformerContext{
+			{Name: "SetNull"},
+			{Name: "RandomDate", Profile: utils.TransformerProfile{Calls: 10, Rows: 10, Duration: time.Second}},
+		},
+		PushedDown: []*PushedDownTransformer{{Name: "SetNull", Expressions: map[int]string{1: "NULL"}}},
+		Workers: []*TableWorker{
+			{
+				TransformersContext: []*utils.TransformerContext{
+					{Name: "SetNull"},
+					{Name: "RandomDate", Profile: utils.TransformerProfile{Calls: 5, Rows: 5, Duration: time.Second}},
+				},
+			},
+		},
+	}
+
+	res := table.GetTransformersProfile()
+	assert.Equal(t, []*TransformerProfile{
+		{Name: "SetNull", PushedDown: true},
+		{
+			Name:               "RandomDate",
+			TransformerProfile: utils.TransformerProfile{Calls: 15, Rows: 15, Duration: 2 * time.Second},
+		},
+	}, res)
+}
@@ -83,8 +83,10 @@ func NewRow(tupleSize int) *Row {
 	}
 }
 
+// Decode - sets the new raw data. The values set by SetColumn for the previous data are discarded
 func (r *Row) Decode(raw []byte) error {
 	var colStartPos, colEndPos int
+	clear(r.newValues)
 
 	// Building column position slice
 	idx := 0
@@ -346,3 +346,17 @@ func TestRow_SetColumn_Encoding(t *testing.T) {
 		})
 	}
 }
+
+func TestRow_Decode_discards_set_values(t *testing.T) {
+	row := NewRow(2)
+	require.NoError(t, row.Decode([]byte("1\tAlice")))
+	require.NoError(t, row.SetColumn(1, toolkit.NewRawValue([]byte("Bob"), false)))
+
+	require.NoError(t, row.Decode([]byte("2\tCarol")))
+	v, err := row.GetColumn(1)
+	require.NoError(t, err)
+	assert.Equal(t, "Carol", string(v.Data))
+	res, err := row.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2\tCarol"), res)
+}
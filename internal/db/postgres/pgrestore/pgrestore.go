@@ -114,6 +114,14 @@ type Options struct {
 	Pgzip                            bool  `mapstructure:"pgzip"`
 	BatchSize                        int64 `mapstructure:"batch-size"`
 	UseSessionReplicationRoleReplica bool  `mapstructure:"use-session-replication-role-replica"`
+	// DeferConstraints - drop the indexes and foreign keys of the restoring tables before the data section and
+	// recreate them after
+	DeferConstraints bool `mapstructure:"defer-constraints"`
+	// Truncate - clean the restoring tables before the data section restoration using TruncateMethod
+	Truncate       bool   `mapstructure:"truncate"`
+	TruncateMethod string `mapstructure:"truncate-method"`
+	// SqliteFile - restore the tables into the SQLite file instead of the PostgreSQL database
+	SqliteFile string `mapstructure:"sqlite-file"`
 
 	// Connection options:
 	Host       string `mapstructure:"host"`
@@ -8,6 +8,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
 
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
 	"github.com/eminano/greenmask/internal/db/postgres/pgrestore"
 	"github.com/eminano/greenmask/internal/db/postgres/toc"
 	"github.com/eminano/greenmask/internal/storages"
@@ -18,6 +19,12 @@ type restoreBase struct {
 	opt   *pgrestore.DataSectionSettings
 	entry *toc.Entry
 	st    storages.Storager
+	// filter - optional rows filter. If set the rows are decoded and only matched rows are restored
+	filter *TableFilter
+	// transformer - optional rows transformer. If set the rows are transformed after filtering
+	transformer *TableTransformer
+	// row - the decoded COPY line shared by the filter and the transformer
+	row *pgcopy.Row
 }
 
 func newRestoreBase(entry *toc.Entry, st storages.Storager, opt *pgrestore.DataSectionSettings) *restoreBase {
@@ -29,6 +36,90 @@ func newRestoreBase(entry *toc.Entry, st storages.Storager, opt *pgrestore.DataS
 
 }
 
+// SetFilter - sets the rows filter that is applied to each row before restoration
+func (rb *restoreBase) SetFilter(f *TableFilter) {
+	rb.filter = f
+}
+
+// completeFilter - publishes the filter keys when the table data has been restored successfully
+func (rb *restoreBase) completeFilter() {
+	if rb.filter != nil {
+		rb.filter.Complete()
+	}
+}
+
+// SetTransformer - sets the rows transformer that is applied to each restoring row
+func (rb *restoreBase) SetTransformer(t *TableTransformer) {
+	rb.transformer = t
+}
+
+// initTransformer - initializes the rows transformer if it is set
+func (rb *restoreBase) initTransformer(ctx context.Context) error {
+	if rb.transformer == nil {
+		return nil
+	}
+	return rb.transformer.Init(ctx)
+}
+
+// doneTransformer - terminates the rows transformer if it is set
+func (rb *restoreBase) doneTransformer(ctx context.Context) {
+	if rb.transformer == nil {
+		return
+	}
+	if err := rb.transformer.Done(ctx); err != nil {
+		log.Warn().
+			Err(err).
+			Str("objectName", rb.DebugInfo()).
+			Msg("cannot terminate transformer")
+	}
+}
+
+// processLine - applies the filter and the transformer to the COPY line. The line is decoded once and only if any
+// of them is set. It returns false if the row must be skipped. The returned line is valid until the next call
+func (rb *restoreBase) processLine(ctx context.Context, line []byte) ([]byte, bool, error) {
+	if rb.filter == nil && rb.transformer == nil {
+		return line, true, nil
+	}
+	if rb.row == nil {
+		rb.row = pgcopy.NewRow(pgcopy.UseDynamicSize)
+	}
+	if err := rb.row.Decode(line); err != nil {
+		return nil, false, fmt.Errorf("error decoding copy line: %w", err)
+	}
+	keep, err := rb.processRow(ctx, rb.row)
+	if err != nil || !keep {
+		return nil, false, err
+	}
+	if rb.transformer == nil {
+		return line, true, nil
+	}
+	res, err := rb.row.Encode()
+	if err != nil {
+		return nil, false, fmt.Errorf("error encoding transformed row: %w", err)
+	}
+	return res, true, nil
+}
+
+// processRow - applies the filter and the transformer to the decoded row. The transformed values are set into the
+// row. It returns false if the row must be skipped
+func (rb *restoreBase) processRow(ctx context.Context, row *pgcopy.Row) (bool, error) {
+	if rb.filter != nil {
+		keep, err := rb.filter.Keep(row)
+		if err != nil {
+			return false, fmt.Errorf("error filtering row: %w", err)
+		}
+		if !keep {
+			return false, nil
+		}
+	}
+	if rb.transformer != nil {
+		if err := rb.transformer.Transform(ctx, row); err != nil {
+			return false, fmt.Errorf("error transforming row: %w", err)
+		}
+	}
+	return true, nil
+}
+
 func (rb *restoreBase) DebugInfo() string {
 	return fmt.Sprintf("table %s.%s", *rb.entry.Namespace, *rb.entry.Tag)
 }
@@ -36,6 +36,9 @@ type BlobsRestorer struct {
 	St               storages.Storager
 	largeObjectsOids []uint32
 	usePgzip         bool
+	// reload - the large objects might already exist in the target database. The missing large objects are created
+	// and the existing ones are truncated before writing
+	reload bool
 }
 
 func NewBlobsRestorer(entry *toc.Entry, st storages.Storager, usePgzip bool) *BlobsRestorer {
@@ -46,7 +49,12 @@ func NewBlobsRestorer(entry *toc.Entry, st storages.Storager, usePgzip bool) *Bl
 	}
 }
 
-func (td *BlobsRestorer) Execute(ctx context.Context, conn *pgx.Conn) error {
+// SetReload - enables reloading of the existing large objects
+func (td *BlobsRestorer) SetReload(reload bool) {
+	td.reload = reload
+}
+
+func (td *BlobsRestorer) Execute(ctx context.Context, conn Beginner) error {
 	tx, err := conn.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("cannot start transaction (restoring %s): %w", td.DebugInfo(), err)
@@ -118,6 +126,11 @@ func (td *BlobsRestorer) execute(ctx context.Context, tx pgx.Tx) error {
 						Msg("error closing gzip reader")
 				}
 			}(gz)
+			if td.reload {
+				if err = td.createIfNotExists(ctx, tx, loOid); err != nil {
+					return err
+				}
+			}
 			lo, err := loApi.Open(ctx, loOid, pgx.LargeObjectModeWrite)
 			if err != nil {
 				return fmt.Errorf("unable to open large object %d: %w", loOid, err)
@@ -130,6 +143,11 @@ func (td *BlobsRestorer) execute(ctx context.Context, tx pgx.Tx) error {
 						Msg("error closing large object")
 				}
 			}()
+			if td.reload {
+				if err = lo.Truncate(0); err != nil {
+					return fmt.Errorf("unable to truncate large object %d: %w", loOid, err)
+				}
+			}
 
 			select {
 			case <-ctx.Done():
@@ -167,6 +185,23 @@ func (td *BlobsRestorer) execute(ctx context.Context, tx pgx.Tx) error {
 	return nil
 }
 
+// createIfNotExists - creates the large object with the provided oid if it does not exist in the target database
+func (td *BlobsRestorer) createIfNotExists(ctx context.Context, tx pgx.Tx, loOid uint32) error {
+	var exists bool
+	row := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_largeobject_metadata WHERE oid = $1)", loOid)
+	if err := row.Scan(&exists); err != nil {
+		return fmt.Errorf("unable to check large object %d existence: %w", loOid, err)
+	}
+	if exists {
+		return nil
+	}
+	loApi := tx.LargeObjects()
+	if _, err := loApi.Create(ctx, loOid); err != nil {
+		return fmt.Errorf("unable to create large object %d: %w", loOid, err)
+	}
+	return nil
+}
+
 func (td *BlobsRestorer) GetEntry() *toc.Entry {
 	return td.Entry
 }
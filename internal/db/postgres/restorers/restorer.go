@@ -22,8 +22,14 @@ import (
 	"github.com/eminano/greenmask/internal/db/postgres/toc"
 )
 
+// Beginner - the database connection or the transaction that starts the restoration task transaction. If the
+// transaction is provided, the task transaction becomes a savepoint within it
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 type RestoreTask interface {
-	Execute(ctx context.Context, conn *pgx.Conn) error
+	Execute(ctx context.Context, conn Beginner) error
 	DebugInfo() string
 	GetEntry() *toc.Entry
 }
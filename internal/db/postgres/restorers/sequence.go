@@ -38,7 +38,7 @@ func (td *SequenceRestorer) GetEntry() *toc.Entry {
 	return td.Entry
 }
 
-func (td *SequenceRestorer) Execute(ctx context.Context, conn *pgx.Conn) error {
+func (td *SequenceRestorer) Execute(ctx context.Context, conn Beginner) error {
 	tx, err := conn.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("cannot start transaction (restoring %s): %w", td.DebugInfo(), err)
@@ -50,7 +50,7 @@ func (td *TableRestorer) GetEntry() *toc.Entry {
 	return td.entry
 }
 
-func (td *TableRestorer) Execute(ctx context.Context, conn *pgx.Conn) error {
+func (td *TableRestorer) Execute(ctx context.Context, conn Beginner) error {
 	// TODO: Add tests
 
 	if td.entry.FileName == nil {
@@ -70,6 +70,11 @@ func (td *TableRestorer) Execute(ctx context.Context, conn *pgx.Conn) error {
 		}
 	}()
 
+	if err = td.initTransformer(ctx); err != nil {
+		return fmt.Errorf("cannot initialize transformer: %w", err)
+	}
+	defer td.doneTransformer(ctx)
+
 	// Open new transaction for each task
 	tx, err := conn.Begin(ctx)
 	if err != nil {
@@ -106,6 +111,7 @@ func (td *TableRestorer) Execute(ctx context.Context, conn *pgx.Conn) error {
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("cannot commit transaction (restoring %s): %w", td.DebugInfo(), err)
 	}
+	td.completeFilter()
 
 	return nil
 }
@@ -115,7 +121,8 @@ func (td *TableRestorer) restoreCopy(ctx context.Context, f *pgproto3.Frontend,
 		return fmt.Errorf("error initializing pgcopy: %w", err)
 	}
 
-	if td.opt.BatchSize > 0 {
+	// The filtered and transformed rows must be decoded one by one therefore they are streamed line by line
+	if td.opt.BatchSize > 0 || td.filter != nil || td.transformer != nil {
 		if err := td.streamCopyDataByBatch(ctx, f, r); err != nil {
 			return fmt.Errorf("error streaming pgcopy data: %w", err)
 		}
@@ -162,10 +169,12 @@ func (td *TableRestorer) initCopy(ctx context.Context, f *pgproto3.Frontend) err
 
 // streamCopyDataByBatch - stream pgcopy data from table dump in batches. It handles errors only on the end each batch
 // If the batch size is reached it completes the batch and starts a new one. If an error occurs during the batch it
-// stops immediately and returns the error
+// stops immediately and returns the error. If the filter is set, the rows that do not match it are skipped. If the
+// transformer is set, the rows are transformed before sending
 func (td *TableRestorer) streamCopyDataByBatch(ctx context.Context, f *pgproto3.Frontend, r io.Reader) (err error) {
 	bi := bufio.NewReader(r)
 	buf := make([]byte, defaultBufferSize)
+	var data []byte
 	var lineNum int64
 	for {
 		buf, err = reader.ReadLine(bi, buf)
@@ -178,15 +187,24 @@ func (td *TableRestorer) streamCopyDataByBatch(ctx context.Context, f *pgproto3.
 		if isTerminationSeq(buf) {
 			break
 		}
+		line, keep, err := td.processLine(ctx, buf)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
 		lineNum++
-		buf = append(buf, '\n')
+		// The line is copied because the transformed line is owned by the decoded row and the read one is reused
+		data = append(data[:0], line...)
+		data = append(data, '\n')
 
-		err = sendMessage(f, &pgproto3.CopyData{Data: buf})
+		err = sendMessage(f, &pgproto3.CopyData{Data: data})
 		if err != nil {
 			return fmt.Errorf("error sending CopyData message: %w", err)
 		}
 
-		if lineNum%td.opt.BatchSize == 0 {
+		if td.opt.BatchSize > 0 && lineNum%td.opt.BatchSize == 0 {
 			if err = td.completeBatch(ctx, f); err != nil {
 				return fmt.Errorf("error completing batch: %w", err)
 			}
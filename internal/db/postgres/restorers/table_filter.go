@@ -0,0 +1,349 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restorers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// ErrReferencedTableNotRestored - the referenced table data was not restored, so the referencing rows cannot be
+// filtered
+var ErrReferencedTableNotRestored = errors.New("referenced table was not restored")
+
+// FilterKeys - storage of the referenced columns values of the restored rows. It is shared between the restoration
+// tasks and is used for cascading the rows filtering from the referenced tables to the referencing ones
+type FilterKeys struct {
+	mx   *sync.RWMutex
+	keys map[string]map[string]struct{}
+}
+
+func NewFilterKeys() *FilterKeys {
+	return &FilterKeys{
+		mx:   &sync.RWMutex{},
+		keys: make(map[string]map[string]struct{}),
+	}
+}
+
+// Get - returns the set of the restored keys of the table. It returns false if the keys were not published, that
+// means the table data was not restored successfully
+func (fk *FilterKeys) Get(oid toolkit.Oid, columns []string) (map[string]struct{}, bool) {
+	fk.mx.RLock()
+	defer fk.mx.RUnlock()
+	res, ok := fk.keys[getFilterKeysId(oid, columns)]
+	return res, ok
+}
+
+// Add - merges the keys into the table keys set. It might be called several times for the same table, for instance
+// for each partition of the partitioned table
+func (fk *FilterKeys) Add(oid toolkit.Oid, columns []string, keys map[string]struct{}) {
+	fk.mx.Lock()
+	defer fk.mx.Unlock()
+	id := getFilterKeysId(oid, columns)
+	existing, ok := fk.keys[id]
+	if !ok {
+		fk.keys[id] = keys
+		return
+	}
+	for k := range keys {
+		existing[k] = struct{}{}
+	}
+}
+
+func getFilterKeysId(oid toolkit.Oid, columns []string) string {
+	return fmt.Sprintf("%d:%s", oid, strings.Join(columns, ","))
+}
+
+// filterReference - reference to the filtered table. The row is skipped if its key is not found in the restored keys
+// of the referenced table
+type filterReference struct {
+	columnIdxs []int
+	kinds      []filterKeyKind
+	keys       map[string]struct{}
+}
+
+// keysCollector - collects the keys of the restored rows which are referenced by another filtered table
+type keysCollector struct {
+	columns    []string
+	columnIdxs []int
+	kinds      []filterKeyKind
+	keys       map[string]struct{}
+}
+
+// filterKeyKind - the normalization of the column value in the filter key. The referencing and referenced columns
+// may have different types, so the keys are built from the normalized values instead of the raw COPY text
+type filterKeyKind int
+
+const (
+	// rawFilterKey - the value is used as is, so the columns must have the same type. The types with a precision, such
+	// as timestamp(n), are printed without the trailing zeros and have the same representation for any precision
+	rawFilterKey filterKeyKind = iota
+	// numericFilterKey - the integer and numeric values are compared by value, so 1, 1.0 and 1.00 are equal
+	numericFilterKey
+	// bpcharFilterKey - the trailing spaces of the character(n) value are not significant
+	bpcharFilterKey
+	// textFilterKey - the text and varchar values have the same representation
+	textFilterKey
+)
+
+func getFilterKeyKind(c *toolkit.Column) filterKeyKind {
+	switch uint32(c.TypeOid) {
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.NumericOID:
+		return numericFilterKey
+	case pgtype.BPCharOID:
+		return bpcharFilterKey
+	case pgtype.TextOID, pgtype.VarcharOID:
+		return textFilterKey
+	}
+	return rawFilterKey
+}
+
+// FilterKeyColumnsComparable - checks that the values of the referencing and the referenced columns have the same
+// representation in the filter keys
+func FilterKeyColumnsComparable(column, referenced *toolkit.Column) bool {
+	kind := getFilterKeyKind(column)
+	if kind != getFilterKeyKind(referenced) {
+		return false
+	}
+	return kind != rawFilterKey || column.TypeOid == referenced.TypeOid
+}
+
+// appendFilterKeyValue - appends the normalized raw COPY value to the key
+func appendFilterKeyValue(buf []byte, kind filterKeyKind, raw []byte) []byte {
+	switch kind {
+	case numericFilterKey:
+		// PostgreSQL never prints numbers in the exponential notation, so only the fraction zeros are trimmed
+		if bytes.IndexByte(raw, '.') != -1 {
+			raw = bytes.TrimRight(raw, "0")
+			raw = bytes.TrimSuffix(raw, []byte("."))
+		}
+	case bpcharFilterKey:
+		raw = bytes.TrimRight(raw, " ")
+	}
+	return append(buf, raw...)
+}
+
+// TableFilter - filters the table rows in restoration. The row is restored if it matches the when condition and all
+// the references to the filtered tables point to the restored rows
+type TableFilter struct {
+	table      *toolkit.Table
+	record     *toolkit.Record
+	when       *toolkit.WhenCond
+	references []*filterReference
+	collectors []*keysCollector
+	filterKeys *FilterKeys
+	keyBuf     []byte
+	skipped    int64
+	// referenceErr - the referenced table was not restored, so the rows of the table cannot be filtered
+	referenceErr error
+}
+
+// NewTableFilter - creates a new table rows filter.
+//
+// when - the condition that the row must match. references - references of the table to the filtered tables which
+// keys must be checked. collect - the columns sets of the table that must be collected for the referencing tables.
+// filterKeys - the shared keys storage
+func NewTableFilter(
+	table *toolkit.Table, when string, references []*storage.Reference, collect [][]string,
+	filterKeys *FilterKeys,
+) (*TableFilter, error) {
+	// The dump contains only real columns. Generated columns are not dumped
+	t := *table
+	t.Columns = getRealColumns(table.Columns)
+
+	driver, warnings, err := toolkit.NewDriver(&t, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialise driver: %w", err)
+	}
+	if warnings.IsFatal() {
+		logValidationWarnings(warnings)
+		return nil, fmt.Errorf("fatal validation error")
+	}
+
+	meta := map[string]any{
+		"TableSchema": t.Schema,
+		"TableName":   t.Name,
+	}
+	whenCond, warnings := toolkit.NewWhenCond(when, driver, meta)
+	if warnings.IsFatal() {
+		logValidationWarnings(warnings)
+		return nil, fmt.Errorf("unable to compile filter condition for table %s.%s", t.Schema, t.Name)
+	}
+
+	var refs []*filterReference
+	var referenceErr error
+	for _, r := range references {
+		idxs, err := getColumnIdxs(driver, r.Columns)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find reference columns: %w", err)
+		}
+		keys, ok := filterKeys.Get(r.ReferencedTableOid, r.ReferencedColumns)
+		if !ok {
+			referenceErr = fmt.Errorf("%w: referenced table oid %d", ErrReferencedTableNotRestored, r.ReferencedTableOid)
+		}
+		refs = append(refs, &filterReference{
+			columnIdxs: idxs,
+			kinds:      getFilterKeyKinds(driver, idxs),
+			keys:       keys,
+		})
+	}
+
+	var collectors []*keysCollector
+	for _, columns := range collect {
+		idxs, err := getColumnIdxs(driver, columns)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find referenced columns: %w", err)
+		}
+		collectors = append(collectors, &keysCollector{
+			columns:    columns,
+			columnIdxs: idxs,
+			kinds:      getFilterKeyKinds(driver, idxs),
+			keys:       make(map[string]struct{}),
+		})
+	}
+
+	return &TableFilter{
+		table:        table,
+		record:       toolkit.NewRecord(driver),
+		when:         whenCond,
+		references:   refs,
+		collectors:   collectors,
+		filterKeys:   filterKeys,
+		referenceErr: referenceErr,
+	}, nil
+}
+
+// Keep - decides whether the decoded COPY row must be restored. The keys of the kept row are collected
+func (tf *TableFilter) Keep(row *pgcopy.Row) (bool, error) {
+	if tf.referenceErr != nil {
+		return false, tf.referenceErr
+	}
+	tf.record.SetRow(row)
+
+	keep, err := tf.when.Evaluate(tf.record)
+	if err != nil {
+		return false, fmt.Errorf("error evaluating filter condition: %w", err)
+	}
+	if !keep {
+		tf.skipped++
+		return false, nil
+	}
+
+	for _, ref := range tf.references {
+		key, isNull, err := tf.getKey(row, ref.columnIdxs, ref.kinds)
+		if err != nil {
+			return false, err
+		}
+		// The reference with NULL value in any of the columns is not checked by PostgreSQL (MATCH SIMPLE)
+		if isNull {
+			continue
+		}
+		if _, ok := ref.keys[string(key)]; !ok {
+			tf.skipped++
+			return false, nil
+		}
+	}
+
+	for _, c := range tf.collectors {
+		key, isNull, err := tf.getKey(row, c.columnIdxs, c.kinds)
+		if err != nil {
+			return false, err
+		}
+		if isNull {
+			continue
+		}
+		c.keys[string(key)] = struct{}{}
+	}
+
+	return true, nil
+}
+
+// Complete - publishes the collected keys so the referencing tables can use them. It must be called only when the
+// table data has been restored successfully
+func (tf *TableFilter) Complete() {
+	for _, c := range tf.collectors {
+		tf.filterKeys.Add(tf.table.Oid, c.columns, c.keys)
+		if tf.table.RootPtOid != 0 {
+			tf.filterKeys.Add(tf.table.RootPtOid, c.columns, c.keys)
+		}
+	}
+	log.Debug().
+		Str("TableSchema", tf.table.Schema).
+		Str("TableName", tf.table.Name).
+		Int64("SkippedRows", tf.skipped).
+		Msg("table rows filtering is completed")
+}
+
+// getKey - builds the key from the normalized raw column values
+func (tf *TableFilter) getKey(row *pgcopy.Row, idxs []int, kinds []filterKeyKind) ([]byte, bool, error) {
+	tf.keyBuf = tf.keyBuf[:0]
+	for i, idx := range idxs {
+		raw, err := row.GetColumnRaw(idx)
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting column %d raw value: %w", idx, err)
+		}
+		if bytes.Equal(raw, pgcopy.DefaultNullSeq) {
+			return nil, true, nil
+		}
+		if i > 0 {
+			tf.keyBuf = append(tf.keyBuf, pgcopy.DefaultCopyDelimiter)
+		}
+		tf.keyBuf = appendFilterKeyValue(tf.keyBuf, kinds[i], raw)
+	}
+	return tf.keyBuf, false, nil
+}
+
+func getFilterKeyKinds(driver *toolkit.Driver, idxs []int) []filterKeyKind {
+	res := make([]filterKeyKind, 0, len(idxs))
+	for _, idx := range idxs {
+		res = append(res, getFilterKeyKind(driver.Table.Columns[idx]))
+	}
+	return res
+}
+
+func getColumnIdxs(driver *toolkit.Driver, columns []string) ([]int, error) {
+	res := make([]int, 0, len(columns))
+	for _, name := range columns {
+		idx := slices.IndexFunc(driver.Table.Columns, func(c *toolkit.Column) bool {
+			return c.Name == name
+		})
+		if idx == -1 {
+			return nil, fmt.Errorf(`column "%s" is not found in table %s.%s`, name, driver.Table.Schema, driver.Table.Name)
+		}
+		res = append(res, idx)
+	}
+	return res, nil
+}
+
+func logValidationWarnings(warnings toolkit.ValidationWarnings) {
+	for _, w := range warnings {
+		if w.Severity == toolkit.ErrorValidationSeverity {
+			log.Error().Any("ValidationWarning", w).Msg("")
+		} else {
+			log.Warn().Any("ValidationWarning", w).Msg("")
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package restorers
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/storage"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func getFilterTestTables() (*toolkit.Table, *toolkit.Table) {
+	users := &toolkit.Table{
+		Schema: "public",
+		Name:   "users",
+		Oid:    1,
+		Columns: []*toolkit.Column{
+			{Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID},
+			{Name: "name", TypeName: "text", TypeOid: pgtype.TextOID},
+			{Name: "name_upper", TypeName: "text", TypeOid: pgtype.TextOID, IsGenerated: true},
+		},
+	}
+	orders := &toolkit.Table{
+		Schema: "public",
+		Name:   "orders",
+		Oid:    2,
+		Columns: []*toolkit.Column{
+			{Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID},
+			{Name: "user_id", TypeName: "int4", TypeOid: pgtype.Int4OID},
+		},
+	}
+	return users, orders
+}
+
+func TestTableFilter_Keep(t *testing.T) {
+	users, orders := getFilterTestTables()
+	fk := NewFilterKeys()
+
+	usersFilter, err := NewTableFilter(users, "record.id > 1", nil, [][]string{{"id"}}, fk)
+	require.NoError(t, err)
+
+	keep, err := usersFilter.Keep(decodeTestLine(t, "1\tAlice"))
+	require.NoError(t, err)
+	require.False(t, keep)
+
+	keep, err = usersFilter.Keep(decodeTestLine(t, "2\tBob"))
+	require.NoError(t, err)
+	require.True(t, keep)
+
+	keep, err = usersFilter.Keep(decodeTestLine(t, "3\tCarol"))
+	require.NoError(t, err)
+	require.True(t, keep)
+	usersFilter.Complete()
+
+	ref := &storage.Reference{
+		TableOid:           orders.Oid,
+		Columns:            []string{"user_id"},
+		ReferencedTableOid: users.Oid,
+		ReferencedColumns:  []string{"id"},
+	}
+	ordersFilter, err := NewTableFilter(orders, "", []*storage.Reference{ref}, nil, fk)
+	require.NoError(t, err)
+
+	tests := []struct {
+		line string
+		keep bool
+	}{
+		{line: "1\t1", keep: false},
+		{line: "2\t2", keep: true},
+		{line: "3\t3", keep: true},
+		{line: "4\t\\N", keep: true},
+		{line: "5\t4", keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			keep, err := ordersFilter.Keep(decodeTestLine(t, tt.line))
+			require.NoError(t, err)
+			require.Equal(t, tt.keep, keep)
+		})
+	}
+}
+
+func TestTableFilter_Complete_not_called(t *testing.T) {
+	users, orders := getFilterTestTables()
+	fk := NewFilterKeys()
+
+	usersFilter, err := NewTableFilter(users, "", nil, [][]string{{"id"}}, fk)
+	require.NoError(t, err)
+	keep, err := usersFilter.Keep(decodeTestLine(t, "1\tAlice"))
+	require.NoError(t, err)
+	require.True(t, keep)
+
+	// The referenced table was not restored successfully therefore the referencing table restoration must fail
+	ref := &storage.Reference{
+		TableOid:           orders.Oid,
+		Columns:            []string{"user_id"},
+		ReferencedTableOid: users.Oid,
+		ReferencedColumns:  []string{"id"},
+	}
+	ordersFilter, err := NewTableFilter(orders, "", []*storage.Reference{ref}, nil, fk)
+	require.NoError(t, err)
+	_, err = ordersFilter.Keep(decodeTestLine(t, "1\t1"))
+	require.ErrorIs(t, err, ErrReferencedTableNotRestored)
+}
+
+func TestTableFilter_Keep_normalized_keys(t *testing.T) {
+	tests := []struct {
+		name           string
+		referencedType *toolkit.Column
+		columnType     *toolkit.Column
+		referenced     []string
+		lines          map[string]bool
+	}{
+		{
+			name:           "int4 to int8",
+			referencedType: &toolkit.Column{TypeName: "int4", TypeOid: pgtype.Int4OID},
+			columnType:     &toolkit.Column{TypeName: "int8", TypeOid: pgtype.Int8OID},
+			referenced:     []string{"1", "20"},
+			lines:          map[string]bool{"1\t1": true, "2\t20": true, "3\t2": false},
+		},
+		{
+			name:           "numeric scale",
+			referencedType: &toolkit.Column{TypeName: "numeric(10,2)", TypeOid: pgtype.NumericOID},
+			columnType:     &toolkit.Column{TypeName: "numeric", TypeOid: pgtype.NumericOID},
+			referenced:     []string{"1.00", "2.50", "10.00", "0.00"},
+			lines: map[string]bool{
+				"1\t1": true, "2\t2.5": true, "3\t10": true, "4\t0": true, "5\t1.01": false, "6\t100": false,
+			},
+		},
+		{
+			name:           "bpchar padding",
+			referencedType: &toolkit.Column{TypeName: "character(5)", TypeOid: pgtype.BPCharOID},
+			columnType:     &toolkit.Column{TypeName: "character(3)", TypeOid: pgtype.BPCharOID},
+			referenced:     []string{"ab   ", "abc  "},
+			lines:          map[string]bool{"1\tab ": true, "2\tabc": true, "3\ta  ": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			referencedType, columnType := *tt.referencedType, *tt.columnType
+			referencedType.Name = "code"
+			columnType.Name = "code"
+			codes := &toolkit.Table{
+				Schema: "public", Name: "codes", Oid: 1,
+				Columns: []*toolkit.Column{&referencedType},
+			}
+			items := &toolkit.Table{
+				Schema: "public", Name: "items", Oid: 2,
+				Columns: []*toolkit.Column{
+					{Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID},
+					&columnType,
+				},
+			}
+			require.True(t, FilterKeyColumnsComparable(&columnType, &referencedType))
+
+			fk := NewFilterKeys()
+			codesFilter, err := NewTableFilter(codes, "", nil, [][]string{{"code"}}, fk)
+			require.NoError(t, err)
+			for _, v := range tt.referenced {
+				keep, err := codesFilter.Keep(decodeTestLine(t, v))
+				require.NoError(t, err)
+				require.True(t, keep)
+			}
+			codesFilter.Complete()
+
+			ref := &storage.Reference{
+				TableOid:           items.Oid,
+				Columns:            []string{"code"},
+				ReferencedTableOid: codes.Oid,
+				ReferencedColumns:  []string{"code"},
+			}
+			itemsFilter, err := NewTableFilter(items, "", []*storage.Reference{ref}, nil, fk)
+			require.NoError(t, err)
+			for line, expected := range tt.lines {
+				keep, err := itemsFilter.Keep(decodeTestLine(t, line))
+				require.NoError(t, err)
+				require.Equal(t, expected, keep, line)
+			}
+		})
+	}
+}
+
+func TestFilterKeyColumnsComparable(t *testing.T) {
+	int4 := &toolkit.Column{TypeName: "int4", TypeOid: pgtype.Int4OID}
+	numeric := &toolkit.Column{TypeName: "numeric", TypeOid: pgtype.NumericOID}
+	text := &toolkit.Column{TypeName: "text", TypeOid: pgtype.TextOID}
+	varchar := &toolkit.Column{TypeName: "varchar", TypeOid: pgtype.VarcharOID}
+	bpchar := &toolkit.Column{TypeName: "bpchar", TypeOid: pgtype.BPCharOID}
+	timestamp := &toolkit.Column{TypeName: "timestamp", TypeOid: pgtype.TimestampOID}
+	timestamptz := &toolkit.Column{TypeName: "timestamptz", TypeOid: pgtype.TimestamptzOID}
+
+	require.True(t, FilterKeyColumnsComparable(int4, numeric))
+	require.True(t, FilterKeyColumnsComparable(text, text))
+	require.True(t, FilterKeyColumnsComparable(timestamp, timestamp))
+	require.True(t, FilterKeyColumnsComparable(text, varchar))
+	require.False(t, FilterKeyColumnsComparable(varchar, bpchar))
+	require.False(t, FilterKeyColumnsComparable(int4, text))
+	require.False(t, FilterKeyColumnsComparable(timestamp, timestamptz))
+}
+
+func TestNewTableFilter_compilation_error(t *testing.T) {
+	users, _ := getFilterTestTables()
+	_, err := NewTableFilter(users, "record.id >", nil, nil, NewFilterKeys())
+	require.Error(t, err)
+}
+
+// decodeTestLine - decodes the COPY line as the restorer does before the filtering and transformation
+func decodeTestLine(t *testing.T, line string) *pgcopy.Row {
+	row := pgcopy.NewRow(pgcopy.UseDynamicSize)
+	require.NoError(t, row.Decode([]byte(line)))
+	return row
+}
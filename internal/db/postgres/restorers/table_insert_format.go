@@ -23,7 +23,6 @@ import (
 	"slices"
 	"strings"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rs/zerolog/log"
 
@@ -84,7 +83,12 @@ func (td *TableRestorerInsertFormat) GetEntry() *toc.Entry {
 	return td.entry
 }
 
-func (td *TableRestorerInsertFormat) Execute(ctx context.Context, conn *pgx.Conn) error {
+func (td *TableRestorerInsertFormat) Execute(ctx context.Context, conn Beginner) error {
+	if err := td.initTransformer(ctx); err != nil {
+		return fmt.Errorf("cannot initialize transformer: %w", err)
+	}
+	defer td.doneTransformer(ctx)
+
 	r, err := td.getObject(ctx)
 	if err != nil {
 		return fmt.Errorf("cannot get storage object: %w", err)
@@ -105,10 +109,11 @@ func (td *TableRestorerInsertFormat) Execute(ctx context.Context, conn *pgx.Conn
 		log.Warn().Err(err).Msg("error streaming pgcopy data")
 		return nil
 	}
+	td.completeFilter()
 	return nil
 }
 
-func (td *TableRestorerInsertFormat) streamInsertData(ctx context.Context, conn *pgx.Conn, r io.Reader) error {
+func (td *TableRestorerInsertFormat) streamInsertData(ctx context.Context, conn Beginner, r io.Reader) error {
 	// Streaming pgcopy data from table dump
 	buf := bufio.NewReader(r)
 
@@ -134,6 +139,13 @@ func (td *TableRestorerInsertFormat) streamInsertData(ctx context.Context, conn
 		if err = row.Decode(line); err != nil {
 			return fmt.Errorf("error decoding line: %w", err)
 		}
+		keep, err := td.processRow(ctx, row)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
 
 		if err = td.insertData(ctx, conn, row); err != nil {
 			if !td.isErrorAllowed(err) {
@@ -187,7 +199,7 @@ func (td *TableRestorerInsertFormat) generateInsertStmt(onConflictDoNothing bool
 }
 
 func (td *TableRestorerInsertFormat) insertData(
-	ctx context.Context, conn *pgx.Conn, row *pgcopy.Row,
+	ctx context.Context, conn Beginner, row *pgcopy.Row,
 ) error {
 	if td.query == "" {
 		td.query = td.generateInsertStmt(td.opt.OnConflictDoNothing)
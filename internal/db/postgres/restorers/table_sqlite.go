@@ -0,0 +1,353 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restorers
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/pgrestore"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/internal/storages"
+	"github.com/eminano/greenmask/internal/utils/reader"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const (
+	SqliteIntegerType = "INTEGER"
+	SqliteRealType    = "REAL"
+	SqliteNumericType = "NUMERIC"
+	SqliteTextType    = "TEXT"
+	SqliteBlobType    = "BLOB"
+)
+
+const sqliteDefaultSchemaName = "public"
+
+// sqliteTypes - the PostgreSQL canonical type names that have the SQLite equivalent. The rest of the types are
+// stored as TEXT
+var sqliteTypes = map[string]string{
+	"int2":        SqliteIntegerType,
+	"int4":        SqliteIntegerType,
+	"int8":        SqliteIntegerType,
+	"oid":         SqliteIntegerType,
+	"bool":        SqliteIntegerType,
+	"float4":      SqliteRealType,
+	"float8":      SqliteRealType,
+	"numeric":     SqliteNumericType,
+	"bytea":       SqliteBlobType,
+	"text":        SqliteTextType,
+	"varchar":     SqliteTextType,
+	"bpchar":      SqliteTextType,
+	"char":        SqliteTextType,
+	"name":        SqliteTextType,
+	"uuid":        SqliteTextType,
+	"json":        SqliteTextType,
+	"jsonb":       SqliteTextType,
+	"xml":         SqliteTextType,
+	"date":        SqliteTextType,
+	"time":        SqliteTextType,
+	"timetz":      SqliteTextType,
+	"timestamp":   SqliteTextType,
+	"timestamptz": SqliteTextType,
+	"interval":    SqliteTextType,
+	"inet":        SqliteTextType,
+	"cidr":        SqliteTextType,
+	"macaddr":     SqliteTextType,
+	"citext":      SqliteTextType,
+}
+
+// SqliteColumn - the SQLite column definition mapped from the PostgreSQL column
+type SqliteColumn struct {
+	Name    string
+	Type    string
+	NotNull bool
+	// PgType - the original PostgreSQL type name
+	PgType string
+	// Lossy - the PostgreSQL type has no SQLite equivalent and the value is stored as the text representation
+	Lossy bool
+	// pgCanonicalType - the canonical type name that is used for the values conversion
+	pgCanonicalType string
+}
+
+// SqliteForeignKey - the foreign key of the SQLite table built from the references stored in the dump metadata
+type SqliteForeignKey struct {
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+}
+
+// SqliteTable - the SQLite table definition built from the table definition stored in the dump metadata
+type SqliteTable struct {
+	Name        string
+	Columns     []*SqliteColumn
+	PrimaryKey  []string
+	ForeignKeys []*SqliteForeignKey
+}
+
+// NewSqliteTable - maps the real columns of the PostgreSQL table to the SQLite columns. The generated columns are
+// not dumped therefore they are skipped
+func NewSqliteTable(name string, t *toolkit.Table) *SqliteTable {
+	res := &SqliteTable{
+		Name:       name,
+		PrimaryKey: t.PrimaryKey,
+	}
+	for _, c := range getRealColumns(t.Columns) {
+		typeName, lossy := GetSqliteType(c)
+		canonicalType := c.CanonicalTypeName
+		if canonicalType == "" {
+			canonicalType = c.TypeName
+		}
+		res.Columns = append(res.Columns, &SqliteColumn{
+			Name:            c.Name,
+			Type:            typeName,
+			NotNull:         c.NotNull,
+			PgType:          c.TypeName,
+			Lossy:           lossy,
+			pgCanonicalType: canonicalType,
+		})
+	}
+	return res
+}
+
+// CreateStatement - generates CREATE TABLE statement
+func (t *SqliteTable) CreateStatement() string {
+	var defs []string
+	for _, c := range t.Columns {
+		def := fmt.Sprintf("%s %s", QuoteSqliteIdent(c.Name), c.Type)
+		if c.NotNull {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+	}
+	if len(t.PrimaryKey) > 0 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", quoteSqliteIdents(t.PrimaryKey)))
+	}
+	for _, fk := range t.ForeignKeys {
+		defs = append(defs, fmt.Sprintf(
+			"FOREIGN KEY (%s) REFERENCES %s (%s)",
+			quoteSqliteIdents(fk.Columns), QuoteSqliteIdent(fk.ReferencedTable), quoteSqliteIdents(fk.ReferencedColumns),
+		))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", QuoteSqliteIdent(t.Name), strings.Join(defs, ",\n\t"))
+}
+
+// InsertStatement - generates INSERT statement with placeholders for all the columns
+func (t *SqliteTable) InsertStatement() string {
+	names := make([]string, 0, len(t.Columns))
+	placeholders := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		names = append(names, QuoteSqliteIdent(c.Name))
+		placeholders = append(placeholders, "?")
+	}
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		QuoteSqliteIdent(t.Name), strings.Join(names, ", "), strings.Join(placeholders, ", "),
+	)
+}
+
+// GetSqliteType - maps the PostgreSQL column type to the SQLite type. It returns true if the type has no SQLite
+// equivalent and is stored as TEXT
+func GetSqliteType(c *toolkit.Column) (string, bool) {
+	typeName := c.CanonicalTypeName
+	if typeName == "" {
+		typeName = c.TypeName
+	}
+	if res, ok := sqliteTypes[typeName]; ok {
+		return res, false
+	}
+	return SqliteTextType, true
+}
+
+// GetSqliteTableName - returns the SQLite table name. SQLite does not have schemas therefore the tables that are not
+// in the public schema are prefixed with the schema name
+func GetSqliteTableName(schema, name string) string {
+	if schema == sqliteDefaultSchemaName {
+		return name
+	}
+	return fmt.Sprintf("%s_%s", schema, name)
+}
+
+func QuoteSqliteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteSqliteIdents(names []string) string {
+	res := make([]string, 0, len(names))
+	for _, n := range names {
+		res = append(res, QuoteSqliteIdent(n))
+	}
+	return strings.Join(res, ", ")
+}
+
+// SqliteTableRestorer - restores the table data into the SQLite database. The COPY rows are decoded, converted into
+// the SQLite values and inserted within a single transaction
+type SqliteTableRestorer struct {
+	*restoreBase
+	Table *SqliteTable
+	// Rows - the number of inserted rows
+	Rows int64
+}
+
+func NewSqliteTableRestorer(
+	entry *toc.Entry, t *SqliteTable, st storages.Storager, opt *pgrestore.DataSectionSettings,
+) *SqliteTableRestorer {
+	return &SqliteTableRestorer{
+		restoreBase: newRestoreBase(entry, st, opt),
+		Table:       t,
+	}
+}
+
+func (td *SqliteTableRestorer) GetEntry() *toc.Entry {
+	return td.entry
+}
+
+func (td *SqliteTableRestorer) Execute(ctx context.Context, db *sql.DB) error {
+	if err := td.initTransformer(ctx); err != nil {
+		return fmt.Errorf("cannot initialize transformer: %w", err)
+	}
+	defer td.doneTransformer(ctx)
+
+	r, err := td.getObject(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get storage object: %w", err)
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Warn().
+				Err(err).
+				Str("objectName", td.DebugInfo()).
+				Msg("cannot close storage object")
+		}
+	}()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction (restoring %s): %w", td.DebugInfo(), err)
+	}
+	if err = td.streamSqliteData(ctx, tx, r); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Warn().
+				Err(rbErr).
+				Str("objectName", td.DebugInfo()).
+				Msg("cannot rollback transaction")
+		}
+		if td.opt.ExitOnError {
+			return fmt.Errorf("unable to restore table: %w", err)
+		}
+		log.Warn().
+			Err(err).
+			Str("objectName", td.DebugInfo()).
+			Msg("unable to restore table")
+		td.Rows = 0
+		return nil
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit transaction (restoring %s): %w", td.DebugInfo(), err)
+	}
+	td.completeFilter()
+	return nil
+}
+
+func (td *SqliteTableRestorer) streamSqliteData(ctx context.Context, tx *sql.Tx, r io.Reader) error {
+	stmt, err := tx.PrepareContext(ctx, td.Table.InsertStatement())
+	if err != nil {
+		return fmt.Errorf("cannot prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	bi := bufio.NewReader(r)
+	buf := make([]byte, defaultBufferSize)
+	row := pgcopy.NewRow(len(td.Table.Columns))
+	args := make([]any, len(td.Table.Columns))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		buf, err = reader.ReadLine(bi, buf)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("error readimg from table dump: %w", err)
+		}
+		if isTerminationSeq(buf) {
+			break
+		}
+		if err = row.Decode(buf); err != nil {
+			return fmt.Errorf("error decoding line: %w", err)
+		}
+		keep, err := td.processRow(ctx, row)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
+		for idx, c := range td.Table.Columns {
+			v, err := row.GetColumn(idx)
+			if err != nil {
+				return fmt.Errorf("error getting column %s: %w", c.Name, err)
+			}
+			if args[idx], err = getSqliteValue(c, v); err != nil {
+				return fmt.Errorf("error converting column %s value: %w", c.Name, err)
+			}
+		}
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("error inserting data: %w", err)
+		}
+		td.Rows++
+	}
+	return nil
+}
+
+// getSqliteValue - converts the raw COPY value into the SQLite value. The numeric values are passed as text because
+// SQLite converts them according to the column type affinity
+func getSqliteValue(c *SqliteColumn, v *toolkit.RawValue) (any, error) {
+	if v.IsNull {
+		return nil, nil
+	}
+	switch c.pgCanonicalType {
+	case "bool":
+		switch string(v.Data) {
+		case "t":
+			return int64(1), nil
+		case "f":
+			return int64(0), nil
+		}
+		return nil, fmt.Errorf("unexpected boolean value \"%s\"", string(v.Data))
+	case "bytea":
+		if len(v.Data) >= 2 && v.Data[0] == '\\' && v.Data[1] == 'x' {
+			res := make([]byte, hex.DecodedLen(len(v.Data)-2))
+			if _, err := hex.Decode(res, v.Data[2:]); err != nil {
+				return nil, fmt.Errorf("cannot decode bytea value: %w", err)
+			}
+			return res, nil
+		}
+		return []byte(string(v.Data)), nil
+	}
+	return string(v.Data), nil
+}
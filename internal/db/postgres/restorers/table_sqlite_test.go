@@ -0,0 +1,112 @@
+package restorers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"path"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgrestore"
+	"github.com/eminano/greenmask/internal/db/postgres/toc"
+	"github.com/eminano/greenmask/internal/utils/testutils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func getSqliteTestTable() *toolkit.Table {
+	return &toolkit.Table{
+		Schema: "public",
+		Name:   "users",
+		Oid:    1,
+		Columns: []*toolkit.Column{
+			{Name: "id", TypeName: "integer", CanonicalTypeName: "int4", TypeOid: pgtype.Int4OID, NotNull: true},
+			{Name: "name", TypeName: "text", CanonicalTypeName: "text", TypeOid: pgtype.TextOID},
+			{Name: "active", TypeName: "boolean", CanonicalTypeName: "bool", TypeOid: pgtype.BoolOID},
+			{Name: "avatar", TypeName: "bytea", CanonicalTypeName: "bytea", TypeOid: pgtype.ByteaOID},
+			{Name: "tags", TypeName: "text[]", CanonicalTypeName: "_text", TypeOid: pgtype.TextArrayOID},
+			{Name: "name_upper", TypeName: "text", CanonicalTypeName: "text", TypeOid: pgtype.TextOID, IsGenerated: true},
+		},
+	}
+}
+
+func TestSqliteTable_CreateStatement(t *testing.T) {
+	st := NewSqliteTable(GetSqliteTableName("public", "users"), getSqliteTestTable())
+	st.PrimaryKey = []string{"id"}
+	st.ForeignKeys = []*SqliteForeignKey{
+		{Columns: []string{"id"}, ReferencedTable: "billing_accounts", ReferencedColumns: []string{"user_id"}},
+	}
+	expected := "CREATE TABLE \"users\" (\n" +
+		"\t\"id\" INTEGER NOT NULL,\n" +
+		"\t\"name\" TEXT,\n" +
+		"\t\"active\" INTEGER,\n" +
+		"\t\"avatar\" BLOB,\n" +
+		"\t\"tags\" TEXT,\n" +
+		"\tPRIMARY KEY (\"id\"),\n" +
+		"\tFOREIGN KEY (\"id\") REFERENCES \"billing_accounts\" (\"user_id\")\n" +
+		")"
+	require.Equal(t, expected, st.CreateStatement())
+	require.Equal(
+		t, `INSERT INTO "users" ("id", "name", "active", "avatar", "tags") VALUES (?, ?, ?, ?, ?)`,
+		st.InsertStatement(),
+	)
+	require.True(t, st.Columns[4].Lossy)
+	require.Equal(t, "billing_accounts", GetSqliteTableName("billing", "accounts"))
+}
+
+func TestSqliteTableRestorer_Execute(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", path.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	st := NewSqliteTable("users", getSqliteTestTable())
+	_, err = db.ExecContext(ctx, st.CreateStatement())
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	_, err = gz.Write([]byte("1\tAlice\tt\t\\\\x6869\t{a,b}\n2\t\\N\tf\t\\N\t\\N\n\\.\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	storage := new(testutils.StorageMock)
+	storage.On("GetObject", ctx, mock.Anything).Return(&readCloserMock{Buffer: buf}, nil)
+	schemaName, tableName, fileName := "public", "users", "1.dat.gz"
+	entry := &toc.Entry{
+		Namespace: &schemaName,
+		Tag:       &tableName,
+		FileName:  &fileName,
+	}
+
+	tr := NewSqliteTableRestorer(entry, st, storage, &pgrestore.DataSectionSettings{ExitOnError: true})
+	require.NoError(t, tr.Execute(ctx, db))
+	require.Equal(t, int64(2), tr.Rows)
+
+	var (
+		id     int64
+		name   sql.NullString
+		active int64
+		avatar []byte
+		tags   sql.NullString
+	)
+	row := db.QueryRowContext(ctx, `SELECT id, name, active, avatar, tags FROM users WHERE id = 1`)
+	require.NoError(t, row.Scan(&id, &name, &active, &avatar, &tags))
+	require.Equal(t, "Alice", name.String)
+	require.Equal(t, int64(1), active)
+	require.Equal(t, []byte("hi"), avatar)
+	require.Equal(t, "{a,b}", tags.String)
+
+	row = db.QueryRowContext(ctx, `SELECT typeof(id), name IS NULL, active FROM users WHERE id = 2`)
+	var idType string
+	var nameIsNull bool
+	require.NoError(t, row.Scan(&idType, &nameIsNull, &active))
+	require.Equal(t, "integer", idType)
+	require.True(t, nameIsNull)
+	require.Equal(t, int64(0), active)
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restorers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// TableTransformer - applies the transformers to the table rows in restoration. It is used for restoring the dumps
+// that were made without transformation or for applying the additional transformations on the restoration side
+type TableTransformer struct {
+	driver              *toolkit.Driver
+	when                *toolkit.WhenCond
+	transformersContext []*utils.TransformerContext
+	record              *toolkit.Record
+	line                uint64
+}
+
+// NewTableTransformer - creates a new table transformer. The driver must be built for the real columns of the table
+// only because the generated columns are not dumped
+func NewTableTransformer(
+	driver *toolkit.Driver, when *toolkit.WhenCond, transformersContext []*utils.TransformerContext,
+) *TableTransformer {
+	record := toolkit.NewRecord(driver)
+	for _, tc := range transformersContext {
+		for _, dp := range tc.DynamicParameters {
+			dp.SetRecord(record)
+		}
+	}
+	return &TableTransformer{
+		driver:              driver,
+		when:                when,
+		transformersContext: transformersContext,
+		record:              record,
+	}
+}
+
+// Init - initializes the transformers. If any of them cannot be initialized the previously initialized are terminated
+func (tt *TableTransformer) Init(ctx context.Context) error {
+	for idx, tc := range tt.transformersContext {
+		if err := tc.Transformer.Init(ctx); err != nil {
+			for _, initialized := range tt.transformersContext[:idx] {
+				if err := initialized.Transformer.Done(ctx); err != nil {
+					log.Warn().Err(err).Msg("error terminating previously initialized transformer")
+				}
+			}
+			return fmt.Errorf("unable to initialize transformer: %w", err)
+		}
+	}
+	return nil
+}
+
+// Transform - applies the transformers to the decoded COPY row. The transformed values are set into the row
+func (tt *TableTransformer) Transform(ctx context.Context, row *pgcopy.Row) error {
+	tt.line++
+	tt.record.SetRow(row)
+
+	needTransform, err := tt.when.Evaluate(tt.record)
+	if err != nil {
+		return tt.newError(fmt.Errorf("error evaluating when condition: %w", err))
+	}
+	if !needTransform {
+		return nil
+	}
+
+	for _, tc := range tt.transformersContext {
+		needTransform, err = tc.EvaluateWhen(tt.record)
+		if err != nil {
+			return tt.newError(fmt.Errorf("error evaluating transformer when condition: %w", err))
+		}
+		if !needTransform {
+			continue
+		}
+		if _, err = tc.Transformer.Transform(ctx, tt.record); err != nil {
+			return tt.newError(err)
+		}
+	}
+	return nil
+}
+
+// Done - terminates the transformers
+func (tt *TableTransformer) Done(ctx context.Context) error {
+	var lastErr error
+	for _, tc := range tt.transformersContext {
+		if err := tc.Transformer.Done(ctx); err != nil {
+			lastErr = err
+			log.Warn().Err(err).Msg("error terminating initialized transformer")
+		}
+	}
+	if lastErr != nil {
+		return fmt.Errorf("error terminating initialized transformer: %w", lastErr)
+	}
+	return nil
+}
+
+func (tt *TableTransformer) newError(err error) error {
+	return fmt.Errorf(
+		"table %s.%s line %d: %w", tt.driver.Table.Schema, tt.driver.Table.Name, tt.line, err,
+	)
+}
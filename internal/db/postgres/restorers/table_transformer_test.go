@@ -0,0 +1,122 @@
+package restorers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func getTestTableTransformer(t *testing.T, tableWhen, transformerWhen string) *TableTransformer {
+	users, _ := getFilterTestTables()
+	users.Columns = getRealColumns(users.Columns)
+	driver, warnings, err := toolkit.NewDriver(users, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	when, warnings := toolkit.NewWhenCond(tableWhen, driver, map[string]any{})
+	require.Empty(t, warnings)
+
+	tc, warnings, err := transformers.ReplaceTransformerDefinition.Instance(
+		context.Background(), driver,
+		map[string]toolkit.ParamsValue{
+			"column": toolkit.ParamsValue("name"),
+			"value":  toolkit.ParamsValue("masked"),
+		},
+		nil, transformerWhen,
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	return NewTableTransformer(driver, when, []*utils.TransformerContext{tc})
+}
+
+func TestTableTransformer_Transform(t *testing.T) {
+	ctx := context.Background()
+	tt := getTestTableTransformer(t, "", "")
+	require.NoError(t, tt.Init(ctx))
+
+	require.Equal(t, "1\tmasked", transformTestLine(t, tt, "1\tAlice"))
+	require.Equal(t, "2\t\\N", transformTestLine(t, tt, "2\t\\N"))
+
+	require.NoError(t, tt.Done(ctx))
+}
+
+func TestTableTransformer_Transform_with_when(t *testing.T) {
+	ctx := context.Background()
+	tt := getTestTableTransformer(t, "record.id != 1", "record.id != 2")
+	require.NoError(t, tt.Init(ctx))
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{line: "1\tAlice", expected: "1\tAlice"},
+		{line: "2\tBob", expected: "2\tBob"},
+		{line: "3\tCarol", expected: "3\tmasked"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.line, func(t *testing.T) {
+			require.Equal(t, tc.expected, transformTestLine(t, tt, tc.line))
+		})
+	}
+	require.NoError(t, tt.Done(ctx))
+}
+
+// transformTestLine - decodes the COPY line, transforms the row and returns the encoded line
+func transformTestLine(t *testing.T, tt *TableTransformer, line string) string {
+	row := decodeTestLine(t, line)
+	require.NoError(t, tt.Transform(context.Background(), row))
+	res, err := row.Encode()
+	require.NoError(t, err)
+	return string(res)
+}
+
+func TestRestoreBase_processLine(t *testing.T) {
+	ctx := context.Background()
+	users, _ := getFilterTestTables()
+	filter, err := NewTableFilter(users, "record.id > 1", nil, nil, NewFilterKeys())
+	require.NoError(t, err)
+	tt := getTestTableTransformer(t, "", "")
+	require.NoError(t, tt.Init(ctx))
+
+	rb := &restoreBase{}
+	line, keep, err := rb.processLine(ctx, []byte("1\tAlice"))
+	require.NoError(t, err)
+	require.True(t, keep)
+	require.Equal(t, "1\tAlice", string(line))
+
+	rb.SetFilter(filter)
+	rb.SetTransformer(tt)
+	tests := []struct {
+		line     string
+		keep     bool
+		expected string
+	}{
+		{line: "1\tAlice", keep: false},
+		{line: "2\tBob", keep: true, expected: "2\tmasked"},
+		{line: "3\t\\N", keep: true, expected: "3\t\\N"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.line, func(t *testing.T) {
+			line, keep, err := rb.processLine(ctx, []byte(tc.line))
+			require.NoError(t, err)
+			require.Equal(t, tc.keep, keep)
+			require.Equal(t, tc.expected, string(line))
+		})
+	}
+
+	// The row decoded by the restorer is filtered and transformed in place
+	row := decodeTestLine(t, "4\tDave")
+	keep, err = rb.processRow(ctx, row)
+	require.NoError(t, err)
+	require.True(t, keep)
+	v, err := row.GetColumn(1)
+	require.NoError(t, err)
+	require.Equal(t, "masked", string(v.Data))
+	require.NoError(t, tt.Done(ctx))
+}
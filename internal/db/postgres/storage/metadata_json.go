@@ -28,6 +28,8 @@ import (
 type ObjectSizeStat struct {
 	Original   int64
 	Compressed int64
+	// Duration - the time spent to dump the object
+	Duration time.Duration
 }
 
 type Header struct {
@@ -57,6 +59,36 @@ type Entry struct {
 	CompressedSize int64   `json:"compressedSize" yaml:"compressedSize"`
 	FileName       string  `json:"fileName" yaml:"fileName"`
 	Dependencies   []int32 `json:"dependencies" yaml:"dependencies"`
+	// Duration - the time spent to dump the table data. It is used for scheduling the next dumps
+	Duration time.Duration `json:"duration,omitempty" yaml:"duration,omitempty"`
+}
+
+// Reference - the foreign key (or virtual reference) between two dumped tables. It is used in restoration for
+// cascading the rows filtering from the referenced table to the referencing one
+type Reference struct {
+	TableOid           toolkit.Oid `json:"tableOid" yaml:"tableOid"`
+	Columns            []string    `json:"columns" yaml:"columns"`
+	ReferencedTableOid toolkit.Oid `json:"referencedTableOid" yaml:"referencedTableOid"`
+	ReferencedColumns  []string    `json:"referencedColumns" yaml:"referencedColumns"`
+}
+
+// TableProfile - the cost of the table data dump and its transformers
+type TableProfile struct {
+	Schema       string                `json:"schema" yaml:"schema"`
+	Name         string                `json:"name" yaml:"name"`
+	Rows         uint64                `json:"rows" yaml:"rows"`
+	Duration     time.Duration         `json:"duration" yaml:"duration"`
+	Transformers []*TransformerProfile `json:"transformers" yaml:"transformers"`
+}
+
+// TransformerProfile - the cumulative cost of the transformer on the table. Rows is the number of the records passed
+// to the transformer and Calls differs from it for the batch transformation
+type TransformerProfile struct {
+	Name       string        `json:"name" yaml:"name"`
+	Calls      uint64        `json:"calls" yaml:"calls"`
+	Rows       uint64        `json:"rows" yaml:"rows"`
+	Duration   time.Duration `json:"duration" yaml:"duration"`
+	PushedDown bool          `json:"pushed_down,omitempty" yaml:"pushed_down,omitempty"`
 }
 
 type Metadata struct {
@@ -73,6 +105,8 @@ type Metadata struct {
 	Cycles            [][]string             `yaml:"cycles" json:"cycles"`
 	TableOidToDumpId  map[toolkit.Oid]int32  `yaml:"table_dump_id" json:"table_dump_id"`
 	DumpIdsToTableOid map[int32]toolkit.Oid  `yaml:"dump_id_table" json:"dump_id_table"`
+	References        []*Reference           `yaml:"references" json:"references"`
+	Profile           []*TableProfile        `yaml:"profile,omitempty" json:"profile,omitempty"`
 }
 
 func NewMetadata(
@@ -80,7 +114,8 @@ func NewMetadata(
 	completedAt time.Time, transformers []*domains.Table,
 	stats map[int32]ObjectSizeStat, databaseSchema []*toolkit.Table,
 	dependenciesGraph map[int32][]int32, dumpIdsOrder []int32,
-	cycles [][]string, tableOidToDumpId map[toolkit.Oid]int32,
+	cycles [][]string, tableOidToDumpId map[toolkit.Oid]int32, references []*Reference,
+	profile []*TableProfile,
 ) (*Metadata, error) {
 
 	var format string
@@ -127,10 +162,12 @@ func NewMetadata(
 		}
 
 		var objCompressedSize, objOriginalSize int64
+		var objDuration time.Duration
 		if entry.Section == toc.SectionData && *entry.Desc == toc.TableDataDesc {
 			s := stats[entry.DumpId]
 			objCompressedSize = s.Compressed
 			objOriginalSize = s.Original
+			objDuration = s.Duration
 			totalCompressedSize += s.Compressed
 			totalOriginalSize += s.Original
 		}
@@ -155,6 +192,7 @@ func NewMetadata(
 				OriginalSize:   objOriginalSize,
 				CompressedSize: objCompressedSize,
 				Section:        section,
+				Duration:       objDuration,
 			},
 		)
 	}
@@ -192,5 +230,7 @@ func NewMetadata(
 		Entries:           entriesDto,
 		TableOidToDumpId:  tableOidToDumpId,
 		DumpIdsToTableOid: dumpIdsToTableOid,
+		References:        references,
+		Profile:           profile,
 	}, nil
 }
@@ -189,6 +189,11 @@ func (g *Graph) GetTables() []*entries.Table {
 	return g.tables
 }
 
+// Edges - returns all the edges (references) between the tables in the graph including virtual references
+func (g *Graph) Edges() []*Edge {
+	return g.edges
+}
+
 func (g *Graph) GetCycles() [][]*Edge {
 	var cycles [][]*Edge
 	for _, c := range g.scc {
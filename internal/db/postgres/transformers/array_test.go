@@ -0,0 +1,114 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func getArrayDriverAndRecord(t *testing.T, line string) (*toolkit.Driver, *toolkit.Record) {
+	table := &toolkit.Table{
+		Schema: "public",
+		Name:   "test",
+		Oid:    1224,
+		Columns: []*toolkit.Column{
+			{Name: "id", TypeName: "int4", TypeOid: pgtype.Int4OID, Num: 1, Length: -1, TypeLength: 4},
+			{Name: "emails", TypeName: "_text", TypeOid: pgtype.TextArrayOID, Num: 2, Length: -1, TypeLength: -1},
+			{Name: "ids", TypeName: "_int8", TypeOid: pgtype.Int8ArrayOID, Num: 3, Length: -1, TypeLength: -1},
+		},
+	}
+	driver, warnings, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	row := pgcopy.NewRow(len(table.Columns))
+	require.NoError(t, row.Decode([]byte(line)))
+	record := toolkit.NewRecord(driver)
+	record.SetRow(row)
+	return driver, record
+}
+
+func TestArrayTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition *utils.TransformerDefinition
+		columnName string
+		params     map[string]toolkit.ParamsValue
+		line       string
+		pattern    string
+	}{
+		{
+			name:       "text array",
+			definition: HashTransformerDefinition,
+			columnName: "emails",
+			params: map[string]toolkit.ParamsValue{
+				"function": toolkit.ParamsValue("sha1"),
+			},
+			line:    `1	{"john doe@example.com",NULL,jane@example.com}	\N`,
+			pattern: `^\{[0-9A-Za-z+/=]+,NULL,[0-9A-Za-z+/=]+\}$`,
+		},
+		{
+			name:       "multidimensional int8 array with bounds",
+			definition: NoiseIntTransformerDefinition,
+			columnName: "ids",
+			params: map[string]toolkit.ParamsValue{
+				"min_ratio": toolkit.ParamsValue("0.2"),
+				"max_ratio": toolkit.ParamsValue("0.9"),
+			},
+			line:    `1	\N	[0:1][1:2]={{100,200},{NULL,400}}`,
+			pattern: `^\[0:1\]\[1:2\]=\{\{-?\d+,-?\d+\},\{NULL,-?\d+\}\}$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, record := getArrayDriverAndRecord(t, tt.line)
+			tt.params["column"] = toolkit.ParamsValue(tt.columnName)
+			transformer, warnings, err := tt.definition.Instance(context.Background(), driver, tt.params, nil, "")
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+			require.IsType(t, &utils.ArrayTransformer{}, transformer.Transformer)
+
+			r, err := transformer.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName(tt.columnName)
+			require.NoError(t, err)
+			require.False(t, res.IsNull)
+			require.Regexp(t, tt.pattern, string(res.Data))
+
+			id, err := r.GetRawColumnValueByName("id")
+			require.NoError(t, err)
+			require.Equal(t, "1", string(id.Data))
+		})
+	}
+}
+
+func TestArrayTransformer_unsupported_element_type(t *testing.T) {
+	driver, _ := getArrayDriverAndRecord(t, "1\t\\N\t\\N")
+	_, warnings, err := HashTransformerDefinition.Instance(
+		context.Background(), driver,
+		map[string]toolkit.ParamsValue{
+			"column": toolkit.ParamsValue("ids"),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+}
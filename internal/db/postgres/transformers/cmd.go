@@ -84,7 +84,7 @@ var CmdTransformerDefinition = utils.NewTransformerDefinition(
 		"driver",
 		"row driver with parameters that is used for interacting with cmd. The default is csv. "+
 			`The structure is:`+
-			`{"name": "text|csv|json", "params": { "format": "[text|bytes]"} }`,
+			`{"name": "text|csv|json|binary", "params": { "format": "[text|bytes]"} }`,
 	).SetDefaultValue([]byte(`{"name": "csv"}`)),
 
 	toolkit.MustNewParameterDefinition(
@@ -139,6 +139,7 @@ type Cmd struct {
 
 	driver *toolkit.Driver
 	eg     *errgroup.Group
+	batch  []*toolkit.Record
 }
 
 func NewCmd(
@@ -470,3 +471,34 @@ func cmdValidateSkipBehaviour(p *toolkit.ParameterDefinition, v toolkit.ParamsVa
 func init() {
 	utils.DefaultTransformerRegistry.MustRegister(CmdTransformerDefinition)
 }
+
+// TransformBatch - transforms the records that are not skipped by a single call of the base transformer, so they
+// are sent in a single message if the driver supports batches
+func (c *Cmd) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	batch := c.batch[:0]
+	for _, r := range records {
+		if c.checkSkip {
+			skip, err := c.needSkip(r)
+			if err != nil {
+				return err
+			}
+			if skip {
+				c.CmdTransformerBase.ProcessedLines++
+				continue
+			}
+		}
+		batch = append(batch, r)
+	}
+	c.batch = batch
+	if err := c.CmdTransformerBase.TransformBatch(ctx, batch); err != nil {
+		return err
+	}
+	if c.validateOutput {
+		for _, r := range batch {
+			if err := c.validate(r); err != nil {
+				return fmt.Errorf("tuple validation error: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ package custom
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -29,14 +30,66 @@ const (
 	DefaultAutoDiscoveryTimeout     = 10 * time.Second
 )
 
-func BootstrapCustomTransformers(ctx context.Context, registry *utils.TransformerRegistry, customTransformers []*TransformerDefinition) (err error) {
+// Resources - the gRPC services and the compiled WASM modules shared by the custom transformers of all the tables.
+// They must be closed when the transformation is completed, otherwise the started processes outlive greenmask and
+// the WASM runtimes stay allocated
+type Resources struct {
+	services []*grpcService
+	modules  []*wasmModule
+}
+
+// Close - closes the connections, stops the started processes and releases the WASM runtimes. It is safe to call on
+// nil
+func (r *Resources) Close(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	for _, s := range r.services {
+		if err := s.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	r.services = nil
+	for _, m := range r.modules {
+		if err := m.close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error closing wasm module: %w", err))
+		}
+	}
+	r.modules = nil
+	return errors.Join(errs...)
+}
+
+func BootstrapCustomTransformers(
+	ctx context.Context, registry *utils.TransformerRegistry, customTransformers []*TransformerDefinition,
+) (_ *Resources, err error) {
+	res := &Resources{}
+	defer func() {
+		if err != nil {
+			_ = res.Close(ctx)
+		}
+	}()
 	for _, ctd := range customTransformers {
 		var td *utils.TransformerDefinition
 		if ctd.Name == "" && !ctd.AutoDiscover {
-			return fmt.Errorf("custom transformer without auto discovery must be defined staticly in the config")
+			return nil, fmt.Errorf("custom transformer without auto discovery must be defined staticly in the config")
+		}
+		if ctd.Executable == "" && ctd.Wasm == "" && ctd.Grpc == nil {
+			return nil, fmt.Errorf(`custom transformer "executable", "wasm" or "grpc" parameter is required`)
+		}
+		if ctd.Executable != "" && ctd.Wasm != "" {
+			return nil, fmt.Errorf(`custom transformer "executable" and "wasm" parameters cannot be used together`)
+		}
+		if ctd.Wasm != "" && ctd.Grpc != nil {
+			return nil, fmt.Errorf(`custom transformer "wasm" and "grpc" parameters cannot be used together`)
+		}
+		if ctd.PoolSize < 0 || ctd.MaxWorkerRestarts < 0 {
+			return nil, fmt.Errorf(`custom transformer "pool_size" and "max_worker_restarts" cannot be negative`)
 		}
-		if ctd.Executable == "" {
-			return fmt.Errorf(`custom transformer "executable" parameter is required`)
+		if (ctd.PoolSize > 1 || ctd.MaxWorkerRestarts > 0) && (ctd.Wasm != "" || ctd.Grpc != nil) {
+			return nil, fmt.Errorf(
+				`custom transformer "pool_size" and "max_worker_restarts" are supported only by the executable transformers`,
+			)
 		}
 
 		if ctd.AutoDiscoveryTimeout == 0 {
@@ -53,7 +106,50 @@ func BootstrapCustomTransformers(ctx context.Context, registry *utils.Transforme
 			ctd.Driver = &toolkit.DefaultRowDriverParams
 		}
 
-		if ctd.AutoDiscover {
+		var module *wasmModule
+		if ctd.Wasm != "" {
+			module, err = newWasmModule(ctx, ctd)
+			if err != nil {
+				return nil, fmt.Errorf("error loading wasm transformer \"%s\": %w", ctd.Wasm, err)
+			}
+			res.modules = append(res.modules, module)
+		}
+
+		var service *grpcService
+		if ctd.Grpc != nil {
+			service, err = newGrpcService(ctx, ctd)
+			if err != nil {
+				return nil, fmt.Errorf("error connecting grpc transformer \"%s\": %w", ctd.Grpc.Address, err)
+			}
+			res.services = append(res.services, service)
+		}
+
+		if ctd.AutoDiscover && module != nil {
+			// Get custom transformer definition from the module and override received data with config ctd
+			discoveryCtx, cancel := context.WithTimeout(ctx, ctd.AutoDiscoveryTimeout)
+			ctdd, err := module.definition(discoveryCtx)
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("error getting wasm transformer definition: %w", err)
+			}
+			ctd.Name = ctdd.Name
+			ctd.Description = ctdd.Description
+			ctd.Parameters = ctdd.Parameters
+			ctd.Driver = ctdd.Driver
+			ctd.Validate = ctdd.Validate
+		} else if ctd.AutoDiscover && service != nil {
+			discoveryCtx, cancel := context.WithTimeout(ctx, ctd.AutoDiscoveryTimeout)
+			ctdd, err := service.definition(discoveryCtx)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+			ctd.Name = ctdd.Name
+			ctd.Description = ctdd.Description
+			ctd.Parameters = ctdd.Parameters
+			ctd.Driver = ctdd.Driver
+			ctd.Validate = ctdd.Validate
+		} else if ctd.AutoDiscover {
 			// Get custom transformer definition from stdout and override received data with config ctd
 			err = func() error {
 				args := make([]string, len(ctd.Args))
@@ -74,7 +170,7 @@ func BootstrapCustomTransformers(ctx context.Context, registry *utils.Transforme
 				return nil
 			}()
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
@@ -86,18 +182,24 @@ func BootstrapCustomTransformers(ctx context.Context, registry *utils.Transforme
 			}
 		}
 
+		newTransformer := ProduceNewCmdTransformerFunction(ctd)
+		if module != nil {
+			newTransformer = ProduceNewWasmTransformerFunction(ctd, module)
+		} else if service != nil {
+			newTransformer = ProduceNewGrpcTransformerFunction(ctd, service)
+		}
 		td = utils.NewTransformerDefinition(
 			&utils.TransformerProperties{
 				Name:        ctd.Name,
 				Description: ctd.Description,
 				IsCustom:    true,
 			},
-			ProduceNewCmdTransformerFunction(ctd),
+			newTransformer,
 			ctd.Parameters...,
 		)
 
 		// TODO: Probably you should change MustRegister to Register
 		registry.MustRegister(td)
 	}
-	return nil
+	return res, nil
 }
@@ -0,0 +1,231 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// workerExitWaitTimeout - the time to wait for the process exit after the failed transformation
+const workerExitWaitTimeout = 100 * time.Millisecond
+
+var errCmdWorkerExited = errors.New("custom transformer process exited")
+
+// CmdTransformerPool - runs pool_size processes of the same custom transformer. The records of the batch are
+// distributed between the processes and transformed in place, so the order of the records is kept. The process that
+// crashed or exceeded the timeout is restarted up to max_worker_restarts times and the record is retried on the new
+// process
+type CmdTransformerPool struct {
+	driver     *toolkit.Driver
+	parameters map[string]toolkit.Parameterizer
+	ctd        *TransformerDefinition
+	workers    []*CmdTransformer
+	mx         sync.Mutex
+	restarts   int
+	single     [1]*toolkit.Record
+}
+
+func NewCmdTransformerPool(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+	ctd *TransformerDefinition,
+) (*CmdTransformerPool, toolkit.ValidationWarnings, error) {
+	// The first worker validates the transformer on behalf of the pool
+	first, warnings, err := NewCustomCmdTransformer(ctx, driver, parameters, ctd)
+	if err != nil {
+		return nil, nil, err
+	}
+	workers := make([]*CmdTransformer, max(ctd.PoolSize, 1))
+	workers[0] = first
+	for i := 1; i < len(workers); i++ {
+		workers[i], err = newCmdTransformer(driver, parameters, ctd)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return &CmdTransformerPool{
+		driver:     driver,
+		parameters: parameters,
+		ctd:        ctd,
+		workers:    workers,
+	}, warnings, nil
+}
+
+// PoolSize - returns the number of the processes. Transform uses only the first one
+func (p *CmdTransformerPool) PoolSize() int {
+	return len(p.workers)
+}
+
+func (p *CmdTransformerPool) GetAffectedColumns() map[int]string {
+	return p.workers[0].GetAffectedColumns()
+}
+
+func (p *CmdTransformerPool) Init(ctx context.Context) error {
+	for i, w := range p.workers {
+		if err := w.Init(ctx); err != nil {
+			for _, started := range p.workers[:i] {
+				_ = started.Done(ctx)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *CmdTransformerPool) Done(ctx context.Context) error {
+	var res error
+	for _, w := range p.workers {
+		if err := w.Done(ctx); err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+func (p *CmdTransformerPool) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	p.single[0] = r
+	if err := p.transform(ctx, 0, p.single[:]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TransformBatch - transforms the records in place. The batch is split into chunks and each process takes the next
+// chunk as soon as it is free, so the slow records do not block the other processes. If the driver supports batches,
+// the chunk is sent to the process in a single message, otherwise the chunk contains a single record
+func (p *CmdTransformerPool) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	chunkSize := 1
+	if _, ok := p.workers[0].Api.(toolkit.BatchInteractionApi); ok {
+		chunkSize = (len(records) + len(p.workers) - 1) / len(p.workers)
+	}
+
+	if len(p.workers) == 1 {
+		for start := 0; start < len(records); start += chunkSize {
+			if err := p.transformDetached(ctx, 0, records[start:min(start+chunkSize, len(records))]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var next atomic.Int64
+	eg, gtx := errgroup.WithContext(ctx)
+	for i := range p.workers {
+		eg.Go(func() error {
+			for start := int(next.Add(1)-1) * chunkSize; start < len(records); start = int(next.Add(1)-1) * chunkSize {
+				if err := p.transformDetached(gtx, i, records[start:min(start+chunkSize, len(records))]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// transformDetached - transforms the records and copies the affected values. The interaction API reuses the buffer
+// of the decoded values, so the records would be overwritten by the next chunk otherwise
+func (p *CmdTransformerPool) transformDetached(ctx context.Context, idx int, records []*toolkit.Record) error {
+	if err := p.transform(ctx, idx, records); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := utils.DetachValues(r, p.workers[idx].affectedColumns); err != nil {
+			return fmt.Errorf("error detaching transformed values: %w", err)
+		}
+	}
+	return nil
+}
+
+// transform - transforms the records by the worker. The worker is owned by the caller, so it is replaced without
+// locking. The worker is restarted only if the process exited or exceeded the timeout. Other errors, for instance
+// the invalid output, are returned as is, since the record would fail on the new process as well
+func (p *CmdTransformerPool) transform(ctx context.Context, idx int, records []*toolkit.Record) error {
+	if !p.workers[idx].isAlive() {
+		if err := p.restart(ctx, idx, errCmdWorkerExited); err != nil {
+			return err
+		}
+	}
+	for {
+		err := p.workers[idx].TransformBatch(ctx, records)
+		if err == nil || ctx.Err() != nil || !p.isRestartable(idx, err) {
+			return err
+		}
+		if err = p.restart(ctx, idx, err); err != nil {
+			return err
+		}
+	}
+}
+
+// isRestartable - checks whether the worker failed because the process exited or exceeded the timeout. The pipe
+// error is usually received before the process state is collected, so the exit is awaited for a short time
+func (p *CmdTransformerPool) isRestartable(idx int, err error) bool {
+	if errors.Is(err, utils.ErrRowTransformationTimeout) {
+		return true
+	}
+	select {
+	case <-p.workers[idx].exited:
+		return true
+	case <-time.After(workerExitWaitTimeout):
+		return false
+	}
+}
+
+// restart - replaces the failed worker with the new process. The cause is returned if the restarts limit is reached
+func (p *CmdTransformerPool) restart(ctx context.Context, idx int, cause error) error {
+	p.mx.Lock()
+	if p.restarts >= p.ctd.MaxWorkerRestarts {
+		p.mx.Unlock()
+		return cause
+	}
+	p.restarts++
+	p.mx.Unlock()
+
+	log.Warn().
+		Err(cause).
+		Str("TableSchema", p.driver.Table.Schema).
+		Str("TableName", p.driver.Table.Name).
+		Str("TransformerName", p.ctd.Name).
+		Int("Worker", idx).
+		Msg("restarting custom transformer process")
+
+	if err := p.workers[idx].Done(ctx); err != nil {
+		log.Debug().
+			Err(err).
+			Str("TableSchema", p.driver.Table.Schema).
+			Str("TableName", p.driver.Table.Name).
+			Str("TransformerName", p.ctd.Name).
+			Msg("error terminating failed custom transformer process")
+	}
+	w, err := newCmdTransformer(p.driver, p.parameters, p.ctd)
+	if err != nil {
+		return err
+	}
+	if err = w.Init(ctx); err != nil {
+		return fmt.Errorf("error restarting custom transformer process: %w", err)
+	}
+	p.workers[idx] = w
+	return nil
+}
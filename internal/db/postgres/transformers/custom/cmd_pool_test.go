@@ -0,0 +1,220 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// testPoolTransformerScript - upper cases the rows. The first row starting with "!" crashes the process and the rows
+// starting with "?" are answered with the row that cannot be parsed by the csv driver
+const testPoolTransformerScript = `#!/usr/bin/env bash
+trap 'exit 0' 15
+read -r meta
+while IFS= read -r line; do
+  if [[ $line == '?'* ]]; then
+    printf 'inv"alid,0\n'
+    continue
+  fi
+  if [[ $line == '!'* && ! -e "%s" ]]; then
+    touch "%s"
+    exit 1
+  fi
+  printf '%%s\n' "${line^^}"
+done
+`
+
+// testCmdTransformerEnv - runs the test binary as the binary driver batch transformer that upper cases the column
+const testCmdTransformerEnv = "GREENMASK_TEST_CMD_TRANSFORMER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testCmdTransformerEnv) != "" {
+		definition := toolkit.NewTransformerDefinition("BinaryUpper", newTestBatchTransformer).
+			SetMode(&toolkit.DriverParams{Name: toolkit.BinaryModeName}).
+			AddParameter(testGrpcDefinition.Parameters[0])
+		if err := toolkit.NewCmd(definition).Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testBatchTransformer - transforms all the rows of the received message by a single call
+type testBatchTransformer struct {
+	*testGrpcTransformer
+}
+
+func newTestBatchTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (toolkit.Transformer, toolkit.ValidationWarnings, error) {
+	t, warnings, err := newTestGrpcTransformer(ctx, driver, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &testBatchTransformer{testGrpcTransformer: t.(*testGrpcTransformer)}, warnings, nil
+}
+
+func (tt *testBatchTransformer) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	for _, r := range records {
+		if err := tt.Transform(ctx, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestCmdTransformerPool(t *testing.T, driver *toolkit.DriverParams, poolSize, maxWorkerRestarts int) *CmdTransformerPool {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "crashed")
+	executable := filepath.Join(dir, "upper.sh")
+	script := fmt.Sprintf(testPoolTransformerScript, marker, marker)
+	require.NoError(t, os.WriteFile(executable, []byte(script), 0700))
+	return bootstrapTestCmdTransformerPool(t, &TransformerDefinition{
+		Name:              "PoolUpper",
+		Executable:        executable,
+		Driver:            driver,
+		PoolSize:          poolSize,
+		MaxWorkerRestarts: maxWorkerRestarts,
+		Parameters: []*toolkit.ParameterDefinition{
+			toolkit.MustNewParameterDefinition("column", "column name").
+				SetIsColumn(toolkit.NewColumnProperties().SetAffected(true).SetMaxLength(-1)).
+				SetRequired(true),
+		},
+	})
+}
+
+func bootstrapTestCmdTransformerPool(t *testing.T, ctd *TransformerDefinition) *CmdTransformerPool {
+	registry := utils.NewTransformerRegistry()
+	_, err := BootstrapCustomTransformers(context.Background(), registry, []*TransformerDefinition{ctd})
+	require.NoError(t, err)
+	td, ok := registry.Get(ctd.Name)
+	require.True(t, ok)
+
+	driver, _ := getTestDriverAndRecord(t, "hello\t1")
+	tc, warnings, err := td.Instance(context.Background(), driver, map[string]toolkit.ParamsValue{
+		"column": toolkit.ParamsValue("name"),
+	}, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	pool, ok := tc.Transformer.(*CmdTransformerPool)
+	require.True(t, ok)
+	return pool
+}
+
+func TestCmdTransformerPool_TransformBatch(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestCmdTransformerPool(t, &toolkit.DriverParams{Name: toolkit.TextModeName}, 3, 0)
+	require.Len(t, pool.workers, 3)
+	require.NoError(t, pool.Init(ctx))
+
+	records := make([]*toolkit.Record, 10)
+	for i := range records {
+		_, records[i] = getTestDriverAndRecord(t, fmt.Sprintf("row%d\t%d", i, i))
+	}
+	require.NoError(t, pool.TransformBatch(ctx, records))
+	for i, r := range records {
+		v, err := r.GetRawColumnValueByName("name")
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("ROW%d", i), string(v.Data))
+	}
+	require.NoError(t, pool.Done(ctx))
+}
+
+func TestCmdTransformerPool_restart(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestCmdTransformerPool(t, &toolkit.DriverParams{Name: toolkit.TextModeName}, 1, 1)
+	require.NoError(t, pool.Init(ctx))
+
+	_, record := getTestDriverAndRecord(t, "!hello\t1")
+	r, err := pool.Transform(ctx, record)
+	require.NoError(t, err)
+	v, err := r.GetRawColumnValueByName("name")
+	require.NoError(t, err)
+	assert.Equal(t, "!HELLO", string(v.Data))
+	assert.Equal(t, 1, pool.restarts)
+	require.NoError(t, pool.Done(ctx))
+
+	pool = newTestCmdTransformerPool(t, &toolkit.DriverParams{Name: toolkit.TextModeName}, 1, 0)
+	require.NoError(t, pool.Init(ctx))
+	_, record = getTestDriverAndRecord(t, "!hello\t1")
+	_, err = pool.Transform(ctx, record)
+	require.ErrorContains(t, err, "cannot receive transformed tuple")
+	_ = pool.Done(ctx)
+}
+
+func TestCmdTransformerPool_invalid_output_not_restarted(t *testing.T) {
+	ctx := context.Background()
+	pool := newTestCmdTransformerPool(t, &toolkit.DriverParams{
+		Name:                toolkit.CsvModeName,
+		CsvAttributesFormat: toolkit.CsvAttributesDirectNumeratingFormatName,
+	}, 1, 1)
+	require.NoError(t, pool.Init(ctx))
+
+	_, record := getTestDriverAndRecord(t, "?hello\t1")
+	_, err := pool.Transform(ctx, record)
+	require.Error(t, err)
+	assert.Equal(t, 0, pool.restarts)
+	assert.True(t, pool.workers[0].isAlive())
+
+	_, record = getTestDriverAndRecord(t, "hello\t1")
+	r, err := pool.Transform(ctx, record)
+	require.NoError(t, err)
+	v, err := r.GetRawColumnValueByName("name")
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(v.Data))
+	require.NoError(t, pool.Done(ctx))
+}
+
+func TestCmdTransformerPool_binary(t *testing.T) {
+	t.Setenv(testCmdTransformerEnv, "1")
+	ctx := context.Background()
+	pool := bootstrapTestCmdTransformerPool(t, &TransformerDefinition{
+		Executable:   os.Args[0],
+		AutoDiscover: true,
+		PoolSize:     2,
+	})
+	require.Equal(t, toolkit.BinaryModeName, pool.ctd.Driver.Name)
+	require.NoError(t, pool.Init(ctx))
+
+	_, record := getTestDriverAndRecord(t, "single\t0")
+	r, err := pool.Transform(ctx, record)
+	require.NoError(t, err)
+	v, err := r.GetRawColumnValueByName("name")
+	require.NoError(t, err)
+	assert.Equal(t, "SINGLE", string(v.Data))
+
+	records := make([]*toolkit.Record, 7)
+	for i := range records {
+		_, records[i] = getTestDriverAndRecord(t, fmt.Sprintf("row%d\t%d", i, i))
+	}
+	require.NoError(t, pool.TransformBatch(ctx, records))
+	for i, r := range records {
+		v, err := r.GetRawColumnValueByName("name")
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("ROW%d", i), string(v.Data))
+	}
+	require.NoError(t, pool.Done(ctx))
+}
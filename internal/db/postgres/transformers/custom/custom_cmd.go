@@ -45,7 +45,7 @@ func ProduceNewCmdTransformerFunction(ctd *TransformerDefinition) utils.NewTrans
 	return func(
 		ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
 	) (utils.Transformer, toolkit.ValidationWarnings, error) {
-		return NewCustomCmdTransformer(ctx, driver, parameters, ctd)
+		return NewCmdTransformerPool(ctx, driver, parameters, ctd)
 	}
 }
 
@@ -60,16 +60,36 @@ type CmdTransformer struct {
 	parameters      map[string]toolkit.Parameterizer
 	affectedColumns map[int]string
 	ctd             *TransformerDefinition
+	exited          chan struct{}
 }
 
 func NewCustomCmdTransformer(
 	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
 	ctd *TransformerDefinition,
 ) (*CmdTransformer, toolkit.ValidationWarnings, error) {
+	ct, err := newCmdTransformer(driver, parameters, ctd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings toolkit.ValidationWarnings
+	if ctd.Validate {
+		warnings, err = ct.Validate(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error validating transformer: %w", err)
+		}
+	}
+
+	return ct, warnings, nil
+}
+
+func newCmdTransformer(
+	driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer, ctd *TransformerDefinition,
+) (*CmdTransformer, error) {
 	affectedColumns := make(map[int]string)
 	affectedColumnsIdx, transferringColumnsIdx, err := toolkit.GetAffectedAndTransferringColumns(parameters, driver)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error getting affeected and transferring columns: %w", err)
+		return nil, fmt.Errorf("error getting affeected and transferring columns: %w", err)
 	}
 	for _, c := range affectedColumnsIdx {
 		affectedColumns[c.Idx] = c.Name
@@ -77,12 +97,12 @@ func NewCustomCmdTransformer(
 
 	api, err := toolkit.NewApi(ctd.Driver, transferringColumnsIdx, affectedColumnsIdx, driver)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error creating InteractionApi: %w", err)
+		return nil, fmt.Errorf("error creating InteractionApi: %w", err)
 	}
 
 	cct := utils.NewCmdTransformerBase(ctd.Name, ctd.ExpectedExitCode, ctd.RowTransformationTimeout, driver, api)
 
-	ct := &CmdTransformer{
+	return &CmdTransformer{
 		CmdTransformerBase: cct,
 		executable:         ctd.Executable,
 		args:               ctd.Args,
@@ -91,17 +111,7 @@ func NewCustomCmdTransformer(
 		affectedColumns:    affectedColumns,
 		name:               ctd.Name,
 		ctd:                ctd,
-	}
-
-	var warnings toolkit.ValidationWarnings
-	if ctd.Validate {
-		warnings, err = ct.Validate(ctx)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error validating transformer: %w", err)
-		}
-	}
-
-	return ct, warnings, nil
+	}, nil
 }
 
 func (ct *CmdTransformer) GetAffectedColumns() map[int]string {
@@ -127,7 +137,9 @@ func (ct *CmdTransformer) Init(ctx context.Context) (err error) {
 		return ct.stderrForwarder(ctx)
 	})
 
+	ct.exited = make(chan struct{})
 	ct.eg.Go(func() error {
+		defer close(ct.exited)
 		if err := ct.Cmd.Wait(); err != nil {
 			var exitErr *exec.ExitError
 			if errors.As(err, &exitErr) {
@@ -166,6 +178,16 @@ func (ct *CmdTransformer) Init(ctx context.Context) (err error) {
 	return nil
 }
 
+// isAlive - reports whether the process started by Init is still running
+func (ct *CmdTransformer) isAlive() bool {
+	select {
+	case <-ct.exited:
+		return false
+	default:
+		return true
+	}
+}
+
 func (ct *CmdTransformer) Validate(ctx context.Context) (toolkit.ValidationWarnings, error) {
 
 	args := make([]string, len(ct.args))
@@ -273,9 +295,15 @@ func (ct *CmdTransformer) Done(ctx context.Context) error {
 }
 
 func (ct *CmdTransformer) getMetadata() ([]byte, error) {
+	return getTransformerMetadata(ct.driver, ct.parameters)
+}
+
+// getTransformerMetadata - returns the table, the parameter values and the custom types that are sent to the custom
+// transformer before the transformation
+func getTransformerMetadata(driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer) ([]byte, error) {
 	staticParamValues := make(toolkit.StaticParameters)
 	dynamicParamValues := make(map[string]*toolkit.DynamicParamValue)
-	for name, p := range ct.parameters {
+	for name, p := range parameters {
 		switch v := p.(type) {
 		case *toolkit.StaticParameter:
 			rawValue, err := p.RawValue()
@@ -288,12 +316,12 @@ func (ct *CmdTransformer) getMetadata() ([]byte, error) {
 		}
 	}
 	meta := &toolkit.Meta{
-		Table: ct.driver.Table,
+		Table: driver.Table,
 		Parameters: &toolkit.Parameters{
 			Static:  staticParamValues,
 			Dynamic: dynamicParamValues,
 		},
-		Types: ct.driver.CustomTypes,
+		Types: driver.CustomTypes,
 	}
 	res, err := json.Marshal(&meta)
 	if err != nil {
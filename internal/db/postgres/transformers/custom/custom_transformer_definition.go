@@ -21,15 +21,19 @@ import (
 )
 
 const (
-	JsonModeName = "json"
-	CsvModeName  = "csv"
-	TextModeName = "text"
+	JsonModeName   = "json"
+	CsvModeName    = "csv"
+	TextModeName   = "text"
+	BinaryModeName = "binary"
 )
 
 type TransformerDefinition struct {
 	Name                     string                         `mapstructure:"name" yaml:"name" json:"name"`
 	Description              string                         `mapstructure:"description" yaml:"description" json:"description"`
 	Executable               string                         `mapstructure:"executable" yaml:"executable" json:"executable"`
+	Wasm                     string                         `mapstructure:"wasm" yaml:"wasm" json:"wasm,omitempty"`
+	Mounts                   []string                       `mapstructure:"mounts" yaml:"mounts" json:"mounts,omitempty"`
+	Grpc                     *GrpcParams                    `mapstructure:"grpc" yaml:"grpc" json:"grpc,omitempty"`
 	Args                     []string                       `mapstructure:"args" yaml:"args" json:"args"`
 	Parameters               []*toolkit.ParameterDefinition `mapstructure:"parameters" yaml:"parameters" json:"parameters"`
 	Validate                 bool                           `mapstructure:"validate" yaml:"validate" json:"validate"`
@@ -38,5 +42,16 @@ type TransformerDefinition struct {
 	AutoDiscoveryTimeout     time.Duration                  `mapstructure:"auto_discovery_timeout" yaml:"auto_discovery_timeout" json:"auto_discovery_timeout"`
 	RowTransformationTimeout time.Duration                  `mapstructure:"row_transformation_timeout" yaml:"row_transformation_timeout" json:"row_transformation_timeout"`
 	ExpectedExitCode         int                            `mapstructure:"expected_exit_code" yaml:"expected_exit_code" json:"expected_exit_code"`
+	PoolSize                 int                            `mapstructure:"pool_size" yaml:"pool_size" json:"pool_size,omitempty"`
+	MaxWorkerRestarts        int                            `mapstructure:"max_worker_restarts" yaml:"max_worker_restarts" json:"max_worker_restarts,omitempty"`
 	Driver                   *toolkit.DriverParams          `mapstructure:"driver" yaml:"driver" json:"driver"`
 }
+
+// GrpcParams - the connection parameters of the custom transformer served via gRPC. If the executable is set, the
+// process is started once and is shared by all the tables and workers
+type GrpcParams struct {
+	// Address - unix:///path/to.sock or host:port
+	Address string `mapstructure:"address" yaml:"address" json:"address"`
+	// ConnectionTimeout - the time to wait until the service is ready
+	ConnectionTimeout time.Duration `mapstructure:"connection_timeout" yaml:"connection_timeout" json:"connection_timeout"`
+}
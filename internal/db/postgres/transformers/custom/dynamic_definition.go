@@ -163,20 +163,31 @@ func GetDynamicTransformerDefinition(ctx context.Context, executable string, arg
 		return nil, fmt.Errorf("received empty transformer definition: might be transfromer but or config mistake")
 	}
 
-	res := &TransformerDefinition{}
-	if err = json.Unmarshal(stdoutData, res); err != nil {
+	res, err := parseDynamicTransformerDefinition(stdoutData)
+	if err != nil {
 		log.Debug().
 			Err(err).
 			Str("Executable", executable).
 			Str("Args", strings.Join(args, " ")).
 			RawJSON("Output", stdoutData).
 			Msg("error unmarshalling custom transformer output")
+		return nil, err
+	}
+	return res, nil
+}
+
+// parseDynamicTransformerDefinition - parses the transformer definition received from the custom transformer and
+// sets the default driver
+func parseDynamicTransformerDefinition(data []byte) (*TransformerDefinition, error) {
+	res := &TransformerDefinition{}
+	if err := json.Unmarshal(data, res); err != nil {
 		return nil, fmt.Errorf("error unmarshalling custom transformer output: %w", err)
 	}
 	if res.Driver == nil {
 		res.Driver = &toolkit.DefaultRowDriverParams
 	}
-	if res.Driver.Name != "" && res.Driver.Name != JsonModeName && res.Driver.Name != CsvModeName && res.Driver.Name != TextModeName {
+	if res.Driver.Name != "" && res.Driver.Name != JsonModeName && res.Driver.Name != CsvModeName &&
+		res.Driver.Name != TextModeName && res.Driver.Name != BinaryModeName {
 		return nil, fmt.Errorf(`error parsing transformer difinition: unknown mode name %s`, res.Driver.Name)
 	}
 	if res.Driver.Name == "" {
@@ -0,0 +1,246 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func ProduceNewGrpcTransformerFunction(ctd *TransformerDefinition, service *grpcService) utils.NewTransformerFunc {
+	return func(
+		ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+	) (utils.Transformer, toolkit.ValidationWarnings, error) {
+		return NewGrpcTransformer(ctx, driver, parameters, ctd, service)
+	}
+}
+
+// GrpcTransformer - the custom transformer served via gRPC. The records are encoded with the same interaction API
+// as for the cmd transformers and are sent over the Transform stream opened for the table
+type GrpcTransformer struct {
+	name            string
+	service         *grpcService
+	stream          grpc.ClientStream
+	cancel          context.CancelFunc
+	driver          *toolkit.Driver
+	parameters      map[string]toolkit.Parameterizer
+	affectedColumns map[int]string
+	ctd             *TransformerDefinition
+	api             toolkit.InteractionApi
+	meta            []byte
+	in              *bytes.Buffer
+	reader          *bytes.Reader
+	req             *toolkit.GrpcTransformRequest
+	res             *toolkit.GrpcTransformResponse
+	rowEnds         []int
+	single          [1]*toolkit.Record
+}
+
+func NewGrpcTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+	ctd *TransformerDefinition, service *grpcService,
+) (*GrpcTransformer, toolkit.ValidationWarnings, error) {
+	affectedColumns := make(map[int]string)
+	affectedColumnsIdx, transferringColumnsIdx, err := toolkit.GetAffectedAndTransferringColumns(parameters, driver)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting affeected and transferring columns: %w", err)
+	}
+	for _, c := range affectedColumnsIdx {
+		affectedColumns[c.Idx] = c.Name
+	}
+
+	api, err := toolkit.NewApi(ctd.Driver, transferringColumnsIdx, affectedColumnsIdx, driver)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating InteractionApi: %w", err)
+	}
+	in := &bytes.Buffer{}
+	api.SetWriter(in)
+
+	meta, err := getTransformerMetadata(driver, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gt := &GrpcTransformer{
+		name:            ctd.Name,
+		service:         service,
+		driver:          driver,
+		parameters:      parameters,
+		affectedColumns: affectedColumns,
+		ctd:             ctd,
+		api:             api,
+		meta:            meta,
+		in:              in,
+		reader:          bytes.NewReader(nil),
+		req:             &toolkit.GrpcTransformRequest{},
+		res:             &toolkit.GrpcTransformResponse{},
+	}
+
+	var warnings toolkit.ValidationWarnings
+	if ctd.Validate {
+		warnings, err = gt.Validate(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error validating transformer: %w", err)
+		}
+	}
+
+	return gt, warnings, nil
+}
+
+// Validate - sends the metadata to the Validate method and returns the validation warnings
+func (gt *GrpcTransformer) Validate(ctx context.Context) (toolkit.ValidationWarnings, error) {
+	ctx, cancel := context.WithTimeout(ctx, gt.ctd.ValidationTimeout)
+	defer cancel()
+	warnings, err := gt.service.validate(ctx, gt.meta)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrValidationTimeout
+		}
+		return nil, err
+	}
+	return warnings, nil
+}
+
+func (gt *GrpcTransformer) GetAffectedColumns() map[int]string {
+	return gt.affectedColumns
+}
+
+func (gt *GrpcTransformer) Init(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, warnings, err := gt.service.transform(ctx, gt.meta)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("error initializing grpc transformer: %w", err)
+	}
+	if warnings.IsFatal() {
+		cancel()
+		return fmt.Errorf("grpc transformer initialization failed: %s", warnings[0].Msg)
+	}
+	gt.stream = stream
+	gt.cancel = cancel
+	log.Debug().
+		Str("TableSchema", gt.driver.Table.Schema).
+		Str("TableName", gt.driver.Table.Name).
+		Str("TransformerName", gt.name).
+		Msg("grpc transformer initialized")
+	return nil
+}
+
+// Done - closes the stream and waits until the service completes it, so the transformation error that happened
+// after the last response is not lost
+func (gt *GrpcTransformer) Done(ctx context.Context) error {
+	if gt.stream == nil {
+		return nil
+	}
+	defer func() {
+		gt.cancel()
+		gt.stream = nil
+	}()
+	if err := gt.stream.CloseSend(); err != nil {
+		return fmt.Errorf("error closing grpc transform stream: %w", err)
+	}
+	if err := gt.stream.RecvMsg(gt.res); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error completing grpc transform stream: %w", err)
+	}
+	return nil
+}
+
+func (gt *GrpcTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	gt.single[0] = r
+	if err := gt.transform(ctx, gt.single[:]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TransformBatch - sends all the records in a single message and transforms them in place. The response must contain
+// the same number of rows in the same order. The timeout is the row transformation timeout for each record
+func (gt *GrpcTransformer) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return gt.transform(ctx, records)
+}
+
+func (gt *GrpcTransformer) transform(ctx context.Context, records []*toolkit.Record) error {
+	// The rows are encoded into the single buffer, so they are sliced after the last one is written
+	gt.in.Reset()
+	gt.rowEnds = gt.rowEnds[:0]
+	for _, r := range records {
+		rd, err := gt.api.GetRowDriverFromRecord(r)
+		if err != nil {
+			return fmt.Errorf("dto api error: error getting dto: %w", err)
+		}
+		if err = gt.api.Encode(ctx, rd); err != nil {
+			return fmt.Errorf("interaction api error: cannot encode tuple: %w", err)
+		}
+		gt.rowEnds = append(gt.rowEnds, gt.in.Len())
+	}
+	gt.req.Rows = gt.req.Rows[:0]
+	start := 0
+	for _, end := range gt.rowEnds {
+		gt.req.Rows = append(gt.req.Rows, gt.in.Bytes()[start:end])
+		start = end
+	}
+
+	// The stream operations do not accept the context, so the stream is cancelled when the timeout is exceeded
+	timer := time.AfterFunc(gt.ctd.RowTransformationTimeout*time.Duration(len(records)), gt.cancel)
+	err := gt.stream.SendMsg(gt.req)
+	if err == nil {
+		gt.res.Rows = nil
+		err = gt.stream.RecvMsg(gt.res)
+	}
+	if !timer.Stop() {
+		return utils.ErrRowTransformationTimeout
+	}
+	if err != nil {
+		return fmt.Errorf("grpc transformer error: %w", err)
+	}
+	if len(gt.res.Rows) != len(records) {
+		return fmt.Errorf(
+			"grpc transformer error: expected %d rows but received %d", len(records), len(gt.res.Rows),
+		)
+	}
+
+	for i, r := range records {
+		gt.reader.Reset(gt.res.Rows[i])
+		gt.api.SetReader(gt.reader)
+		rd, err := gt.api.Decode(ctx)
+		if err != nil {
+			return fmt.Errorf("interaction api error: cannot decode transformed tuple: %w", err)
+		}
+		if err = gt.api.SetRowDriverToRecord(rd, r); err != nil {
+			return fmt.Errorf("interaction api error: error setting transfomed data to record: %w", err)
+		}
+		// The decoded values reference the API buffers that are reused for the next row
+		if len(records) > 1 {
+			if err = utils.DetachValues(r, gt.affectedColumns); err != nil {
+				return fmt.Errorf("error detaching transformed values: %w", err)
+			}
+		}
+		gt.api.Clean()
+	}
+	return nil
+}
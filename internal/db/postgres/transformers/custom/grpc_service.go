@@ -0,0 +1,204 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const DefaultGrpcConnectionTimeout = 10 * time.Second
+
+// grpcServiceStopTimeout - the time to wait until the started process exits after SIGTERM before it is killed
+const grpcServiceStopTimeout = 10 * time.Second
+
+var grpcTransformStreamDesc = grpc.StreamDesc{
+	StreamName:    "Transform",
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// grpcService - the connection to the custom transformer served via gRPC. The connection is shared by the
+// transformers of all the tables, each of them opens its own Transform stream
+type grpcService struct {
+	conn *grpc.ClientConn
+	cmd  *exec.Cmd
+	// exited - is closed when the started process exits
+	exited  chan struct{}
+	closing atomic.Bool
+}
+
+// newGrpcService - starts the executable if it is set and waits until the service is ready. The process is
+// terminated when the service is closed or the context is cancelled
+func newGrpcService(ctx context.Context, ctd *TransformerDefinition) (_ *grpcService, err error) {
+	if ctd.Grpc.Address == "" {
+		return nil, fmt.Errorf(`grpc "address" parameter is required`)
+	}
+	if ctd.Grpc.ConnectionTimeout == 0 {
+		ctd.Grpc.ConnectionTimeout = DefaultGrpcConnectionTimeout
+	}
+
+	waitCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	waitCtx, timeoutCancel := context.WithTimeout(waitCtx, ctd.Grpc.ConnectionTimeout)
+	defer timeoutCancel()
+
+	gs := &grpcService{}
+	if ctd.Executable != "" {
+		log.Debug().
+			Str("Executable", ctd.Executable).
+			Str("Args", strings.Join(ctd.Args, " ")).
+			Msg("starting grpc transformer")
+		cmd := exec.CommandContext(ctx, ctd.Executable, ctd.Args...)
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(syscall.SIGTERM)
+		}
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err = cmd.Start(); err != nil {
+			return nil, fmt.Errorf("error running grpc transformer: %w", err)
+		}
+		gs.cmd = cmd
+		gs.exited = make(chan struct{})
+		go func() {
+			defer close(gs.exited)
+			err := cmd.Wait()
+			if err != nil && ctx.Err() == nil && !gs.closing.Load() {
+				log.Warn().Err(err).Str("Executable", ctd.Executable).Msg("grpc transformer exited")
+			}
+			cancel(fmt.Errorf("grpc transformer exited: %w", err))
+		}()
+		defer func() {
+			if err != nil {
+				_ = gs.stop()
+			}
+		}()
+	}
+
+	conn, err := grpc.NewClient(
+		ctd.Grpc.Address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(toolkit.GrpcCodecName)),
+		// The started process needs some time to listen the address, so the connection is retried frequently
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  50 * time.Millisecond,
+				Multiplier: 1.6,
+				Jitter:     0.2,
+				MaxDelay:   time.Second,
+			},
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating grpc client: %w", err)
+	}
+
+	conn.Connect()
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		if !conn.WaitForStateChange(waitCtx, state) {
+			_ = conn.Close()
+			if cause := context.Cause(waitCtx); cause != nil && !errors.Is(cause, context.DeadlineExceeded) {
+				return nil, cause
+			}
+			return nil, fmt.Errorf("grpc transformer is not ready at %s: %w", ctd.Grpc.Address, waitCtx.Err())
+		}
+	}
+	gs.conn = conn
+	return gs, nil
+}
+
+// close - closes the connection and stops the started process
+func (gs *grpcService) close() error {
+	var errs []error
+	if err := gs.conn.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("error closing grpc connection: %w", err))
+	}
+	if err := gs.stop(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// stop - sends SIGTERM to the started process and waits until it exits. The process is killed if it does not exit
+// during grpcServiceStopTimeout
+func (gs *grpcService) stop() error {
+	if gs.cmd == nil {
+		return nil
+	}
+	gs.closing.Store(true)
+	if err := gs.cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("error terminating grpc transformer: %w", err)
+	}
+	timer := time.NewTimer(grpcServiceStopTimeout)
+	defer timer.Stop()
+	select {
+	case <-gs.exited:
+	case <-timer.C:
+		log.Warn().Str("Executable", gs.cmd.Path).Msg("grpc transformer did not exit after SIGTERM: killing it")
+		if err := gs.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("error killing grpc transformer: %w", err)
+		}
+		<-gs.exited
+	}
+	return nil
+}
+
+func (gs *grpcService) definition(ctx context.Context) (*TransformerDefinition, error) {
+	res := &toolkit.GrpcDefinitionResponse{}
+	if err := gs.conn.Invoke(ctx, toolkit.GrpcGetDefinitionMethod, &toolkit.GrpcDefinitionRequest{}, res); err != nil {
+		return nil, fmt.Errorf("error getting grpc transformer definition: %w", err)
+	}
+	return parseDynamicTransformerDefinition(res.Definition)
+}
+
+func (gs *grpcService) validate(ctx context.Context, meta []byte) (toolkit.ValidationWarnings, error) {
+	res := &toolkit.GrpcValidateResponse{}
+	if err := gs.conn.Invoke(ctx, toolkit.GrpcValidateMethod, &toolkit.GrpcValidateRequest{Meta: meta}, res); err != nil {
+		return nil, fmt.Errorf("error validating grpc transformer: %w", err)
+	}
+	return res.Warnings, nil
+}
+
+// transform - opens the Transform stream and sends the metadata. The initialization warnings are returned with
+// the stream
+func (gs *grpcService) transform(ctx context.Context, meta []byte) (grpc.ClientStream, toolkit.ValidationWarnings, error) {
+	stream, err := gs.conn.NewStream(ctx, &grpcTransformStreamDesc, toolkit.GrpcTransformMethod)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening grpc transform stream: %w", err)
+	}
+	if err = stream.SendMsg(&toolkit.GrpcTransformRequest{Meta: meta}); err != nil {
+		return nil, nil, fmt.Errorf("error sending metadata: %w", err)
+	}
+	res := &toolkit.GrpcTransformResponse{}
+	if err = stream.RecvMsg(res); err != nil {
+		return nil, nil, fmt.Errorf("error receiving initialization result: %w", err)
+	}
+	return stream, res.Warnings, nil
+}
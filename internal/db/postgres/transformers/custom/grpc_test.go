@@ -0,0 +1,229 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+var testGrpcDefinition = toolkit.NewTransformerDefinition("GrpcUpper", newTestGrpcTransformer).
+	SetDescription("Upper case the text").
+	SetMode(&toolkit.DriverParams{Name: toolkit.TextModeName}).
+	AddParameter(
+		toolkit.MustNewParameterDefinition("column", "column name").
+			SetIsColumn(toolkit.NewColumnProperties().SetAffected(true).SetMaxLength(-1)).
+			SetRequired(true),
+	)
+
+type testGrpcTransformer struct {
+	column string
+}
+
+func newTestGrpcTransformer(
+	_ context.Context, _ *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (toolkit.Transformer, toolkit.ValidationWarnings, error) {
+	var column string
+	if err := parameters["column"].Scan(&column); err != nil {
+		return nil, nil, fmt.Errorf("error scanning column name: %w", err)
+	}
+	return &testGrpcTransformer{column: column}, nil, nil
+}
+
+func (tt *testGrpcTransformer) Validate(_ context.Context) (toolkit.ValidationWarnings, error) {
+	return nil, nil
+}
+
+func (tt *testGrpcTransformer) Transform(_ context.Context, r *toolkit.Record) error {
+	v, err := r.GetRawColumnValueByName(tt.column)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(v.Data, []byte("!")) {
+		return errors.New("row starts with !")
+	}
+	return r.SetRawColumnValueByName(tt.column, toolkit.NewRawValue(bytes.ToUpper(v.Data), v.IsNull))
+}
+
+func TestGrpcTransformer_Transform(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	address := "unix://" + filepath.Join(t.TempDir(), "upper.sock")
+	served := make(chan error, 1)
+	go func() {
+		served <- toolkit.NewGrpcServer(testGrpcDefinition).Serve(ctx, address)
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-served)
+	}()
+
+	registry := utils.NewTransformerRegistry()
+	res, err := BootstrapCustomTransformers(ctx, registry, []*TransformerDefinition{
+		{AutoDiscover: true, Validate: true, Grpc: &GrpcParams{Address: address}},
+	})
+	require.NoError(t, err)
+	defer res.Close(ctx) // nolint: errcheck
+	td, ok := registry.Get("GrpcUpper")
+	require.True(t, ok)
+	assert.Equal(t, "Upper case the text", td.Properties.Description)
+
+	driver, record := getTestDriverAndRecord(t, "hello\t1")
+	tc, warnings, err := td.Instance(ctx, driver, map[string]toolkit.ParamsValue{
+		"column": toolkit.ParamsValue("name"),
+	}, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	require.NoError(t, tc.Transformer.Init(ctx))
+	r, err := tc.Transformer.Transform(ctx, record)
+	require.NoError(t, err)
+	v, err := r.GetRawColumnValueByName("name")
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(v.Data))
+
+	bt, ok := tc.Transformer.(utils.BatchTransformer)
+	require.True(t, ok)
+	records := make([]*toolkit.Record, 5)
+	for i := range records {
+		_, records[i] = getTestDriverAndRecord(t, fmt.Sprintf("row%d\t%d", i, i))
+	}
+	require.NoError(t, bt.TransformBatch(ctx, records))
+	for i, r := range records {
+		v, err := r.GetRawColumnValueByName("name")
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("ROW%d", i), string(v.Data))
+	}
+	require.NoError(t, tc.Transformer.Done(ctx))
+
+	require.NoError(t, tc.Transformer.Init(ctx))
+	_, record = getTestDriverAndRecord(t, "!hello\t2")
+	_, err = tc.Transformer.Transform(ctx, record)
+	require.ErrorContains(t, err, "row starts with !")
+	require.Error(t, tc.Transformer.Done(ctx))
+}
+
+// testGrpcStream - the Transform stream that responds with the predefined rows
+type testGrpcStream struct {
+	grpc.ClientStream
+	rows [][]byte
+	sent *toolkit.GrpcTransformRequest
+}
+
+func (ts *testGrpcStream) SendMsg(m any) error {
+	ts.sent = m.(*toolkit.GrpcTransformRequest)
+	return nil
+}
+
+func (ts *testGrpcStream) RecvMsg(m any) error {
+	m.(*toolkit.GrpcTransformResponse).Rows = ts.rows
+	return nil
+}
+
+func TestGrpcTransformer_TransformBatch_rows_count(t *testing.T) {
+	ctx := context.Background()
+	driver, _ := getTestDriverAndRecord(t, "hello\t1")
+	parameters, warnings, err := toolkit.InitParameters(driver, testGrpcDefinition.Parameters,
+		map[string]toolkit.ParamsValue{"column": toolkit.ParamsValue("name")}, nil,
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	gt, warnings, err := NewGrpcTransformer(ctx, driver, parameters, &TransformerDefinition{
+		Name:                     "GrpcUpper",
+		Driver:                   &toolkit.DriverParams{Name: toolkit.TextModeName},
+		RowTransformationTimeout: time.Second,
+	}, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	stream := &testGrpcStream{rows: [][]byte{[]byte("A\n"), []byte("B\n")}}
+	gt.stream = stream
+	gt.cancel = func() {}
+
+	records := make([]*toolkit.Record, 2)
+	for i := range records {
+		_, records[i] = getTestDriverAndRecord(t, fmt.Sprintf("row%d\t%d", i, i))
+	}
+	require.NoError(t, gt.TransformBatch(ctx, records))
+	assert.Equal(t, [][]byte{[]byte("row0\n"), []byte("row1\n")}, stream.sent.Rows)
+	for i, expected := range []string{"A", "B"} {
+		v, err := records[i].GetRawColumnValueByName("name")
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(v.Data))
+	}
+
+	records = append(records, records[0])
+	require.ErrorContains(t, gt.TransformBatch(ctx, records), "expected 3 rows but received 2")
+}
+
+func TestResources_Close_grpc_process_exited(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	address := "unix://" + filepath.Join(t.TempDir(), "upper.sock")
+	served := make(chan error, 1)
+	go func() {
+		served <- toolkit.NewGrpcServer(testGrpcDefinition).Serve(ctx, address)
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-served)
+	}()
+
+	// The started process does not serve the address, it only has to be stopped on close
+	res, err := BootstrapCustomTransformers(ctx, utils.NewTransformerRegistry(), []*TransformerDefinition{
+		{Name: "Test", Executable: "sleep", Args: []string{"60"}, Grpc: &GrpcParams{Address: address}},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.services, 1)
+	gs := res.services[0]
+
+	require.NoError(t, res.Close(ctx))
+	select {
+	case <-gs.exited:
+	default:
+		t.Fatal("grpc transformer process is not exited")
+	}
+	require.NotNil(t, gs.cmd.ProcessState)
+	require.ErrorIs(t, gs.cmd.Process.Signal(syscall.Signal(0)), os.ErrProcessDone)
+	require.Equal(t, connectivity.Shutdown, gs.conn.GetState())
+	require.NoError(t, res.Close(ctx))
+}
+
+func TestBootstrapCustomTransformers_grpc_not_ready(t *testing.T) {
+	address := "unix://" + filepath.Join(t.TempDir(), "missing.sock")
+	_, err := BootstrapCustomTransformers(context.Background(), utils.NewTransformerRegistry(), []*TransformerDefinition{
+		{Name: "Test", Grpc: &GrpcParams{Address: address, ConnectionTimeout: 200 * time.Millisecond}},
+	})
+	require.ErrorContains(t, err, "grpc transformer is not ready")
+
+	_, err = BootstrapCustomTransformers(context.Background(), utils.NewTransformerRegistry(), []*TransformerDefinition{
+		{Name: "Test", Executable: "/bin/false", Grpc: &GrpcParams{Address: address}},
+	})
+	require.ErrorContains(t, err, "grpc transformer exited")
+}
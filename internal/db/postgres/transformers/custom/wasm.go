@@ -0,0 +1,231 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func ProduceNewWasmTransformerFunction(ctd *TransformerDefinition, module *wasmModule) utils.NewTransformerFunc {
+	return func(
+		ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+	) (utils.Transformer, toolkit.ValidationWarnings, error) {
+		return NewWasmTransformer(ctx, driver, parameters, ctd, module)
+	}
+}
+
+// WasmTransformer - the custom transformer implemented as the WASM module. The records are encoded with the same
+// interaction API as for the cmd transformers and are passed to the module instance memory
+type WasmTransformer struct {
+	name            string
+	module          *wasmModule
+	instance        *wasmInstance
+	driver          *toolkit.Driver
+	parameters      map[string]toolkit.Parameterizer
+	affectedColumns map[int]string
+	ctd             *TransformerDefinition
+	api             toolkit.InteractionApi
+	meta            []byte
+	in              *bytes.Buffer
+	out             []byte
+	reader          *bytes.Reader
+	single          [1]*toolkit.Record
+}
+
+func NewWasmTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+	ctd *TransformerDefinition, module *wasmModule,
+) (*WasmTransformer, toolkit.ValidationWarnings, error) {
+	affectedColumns := make(map[int]string)
+	affectedColumnsIdx, transferringColumnsIdx, err := toolkit.GetAffectedAndTransferringColumns(parameters, driver)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting affeected and transferring columns: %w", err)
+	}
+	for _, c := range affectedColumnsIdx {
+		affectedColumns[c.Idx] = c.Name
+	}
+
+	api, err := toolkit.NewApi(ctd.Driver, transferringColumnsIdx, affectedColumnsIdx, driver)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating InteractionApi: %w", err)
+	}
+	in := &bytes.Buffer{}
+	api.SetWriter(in)
+
+	meta, err := getTransformerMetadata(driver, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wt := &WasmTransformer{
+		name:            ctd.Name,
+		module:          module,
+		driver:          driver,
+		parameters:      parameters,
+		affectedColumns: affectedColumns,
+		ctd:             ctd,
+		api:             api,
+		meta:            meta,
+		in:              in,
+		reader:          bytes.NewReader(nil),
+	}
+
+	var warnings toolkit.ValidationWarnings
+	if ctd.Validate {
+		warnings, err = wt.Validate(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error validating transformer: %w", err)
+		}
+	}
+
+	return wt, warnings, nil
+}
+
+// Validate - sends the metadata to the new module instance and returns the validation warnings
+func (wt *WasmTransformer) Validate(ctx context.Context) (toolkit.ValidationWarnings, error) {
+	ctx, cancel := context.WithTimeout(ctx, wt.ctd.ValidationTimeout)
+	defer cancel()
+	wi, err := wt.module.instantiate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer wi.close(ctx) // nolint: errcheck
+	warnings, err := wi.initialize(ctx, wt.meta)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrValidationTimeout
+		}
+		return nil, err
+	}
+	return warnings, nil
+}
+
+func (wt *WasmTransformer) GetAffectedColumns() map[int]string {
+	return wt.affectedColumns
+}
+
+func (wt *WasmTransformer) Init(ctx context.Context) error {
+	wi, err := wt.module.instantiate(ctx)
+	if err != nil {
+		return err
+	}
+	warnings, err := wi.initialize(ctx, wt.meta)
+	if err != nil {
+		_ = wi.close(ctx)
+		return fmt.Errorf("error initializing wasm transformer: %w", err)
+	}
+	if warnings.IsFatal() {
+		_ = wi.close(ctx)
+		return fmt.Errorf("wasm transformer initialization failed: %s", warnings[0].Msg)
+	}
+	wt.instance = wi
+	log.Debug().
+		Str("TableSchema", wt.driver.Table.Schema).
+		Str("TableName", wt.driver.Table.Name).
+		Str("TransformerName", wt.name).
+		Msg("wasm transformer initialized")
+	return nil
+}
+
+func (wt *WasmTransformer) Done(ctx context.Context) error {
+	if wt.instance == nil {
+		return nil
+	}
+	if err := wt.instance.close(ctx); err != nil {
+		return fmt.Errorf("error closing wasm module instance: %w", err)
+	}
+	wt.instance = nil
+	return nil
+}
+
+func (wt *WasmTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	wt.single[0] = r
+	if err := wt.transform(ctx, wt.single[:]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TransformBatch - passes all the records to the module in a single greenmask_transform call and transforms them in
+// place. The module must return the same number of records in the same order. The timeout is the row transformation
+// timeout for each record
+func (wt *WasmTransformer) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return wt.transform(ctx, records)
+}
+
+func (wt *WasmTransformer) transform(ctx context.Context, records []*toolkit.Record) error {
+	ctx, cancel := context.WithTimeout(ctx, wt.ctd.RowTransformationTimeout*time.Duration(len(records)))
+	defer cancel()
+
+	wt.in.Reset()
+	for _, r := range records {
+		rd, err := wt.api.GetRowDriverFromRecord(r)
+		if err != nil {
+			return fmt.Errorf("dto api error: error getting dto: %w", err)
+		}
+		if err = wt.api.Encode(ctx, rd); err != nil {
+			return fmt.Errorf("interaction api error: cannot encode tuple: %w", err)
+		}
+	}
+
+	res, err := wt.instance.call(ctx, wt.instance.transform, wt.in.Bytes())
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return utils.ErrRowTransformationTimeout
+		}
+		return err
+	}
+	if res == nil {
+		return wt.instance.lastError(ctx)
+	}
+	// The result is copied because the module memory is reused on the next call
+	wt.out = append(wt.out[:0], res...)
+	wt.reader.Reset(wt.out)
+	wt.api.SetReader(wt.reader)
+
+	for i, r := range records {
+		rd, err := wt.api.Decode(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("wasm transformer error: expected %d rows but received %d", len(records), i)
+			}
+			return fmt.Errorf("interaction api error: cannot decode transformed tuple: %w", err)
+		}
+		if err = wt.api.SetRowDriverToRecord(rd, r); err != nil {
+			return fmt.Errorf("interaction api error: error setting transfomed data to record: %w", err)
+		}
+		// The decoded values reference the API buffers that are reused for the next row
+		if len(records) > 1 {
+			if err = utils.DetachValues(r, wt.affectedColumns); err != nil {
+				return fmt.Errorf("error detaching transformed values: %w", err)
+			}
+		}
+		wt.api.Clean()
+	}
+	return nil
+}
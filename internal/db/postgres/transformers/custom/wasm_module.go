@@ -0,0 +1,267 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// The functions exported by the WASM transformer module. The data is passed as the pointer and the length of the
+// buffer allocated by greenmask_alloc, and the results are returned as the pointer in the high 32 bits and the length
+// in the low 32 bits of the i64 value. The input buffers are owned by the module after the call and the result
+// buffers must stay valid until the next call
+const (
+	// WasmAllocFuncName - (size i32) -> (ptr i32) allocates the buffer for the input data
+	WasmAllocFuncName = "greenmask_alloc"
+	// WasmDefinitionFuncName - () -> (i64) returns the JSON transformer definition. It is required for auto discovery
+	WasmDefinitionFuncName = "greenmask_definition"
+	// WasmInitFuncName - (ptr i32, len i32) -> (i64) receives the JSON metadata and returns the JSON list of the
+	// validation warnings or 0
+	WasmInitFuncName = "greenmask_init"
+	// WasmTransformFuncName - (ptr i32, len i32) -> (i64) receives the encoded records separated by the new line
+	// and returns the transformed records in the same format or 0 in case of error
+	WasmTransformFuncName = "greenmask_transform"
+	// WasmErrorFuncName - () -> (i64) returns the error message of the last call. It is optional
+	WasmErrorFuncName = "greenmask_error"
+)
+
+var (
+	wasmDataParams   = []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}
+	wasmResultParams = []api.ValueType{api.ValueTypeI64}
+)
+
+// wasmModule - the compiled WASM transformer module. The module is compiled once and instantiated for each
+// transformer, so the transformers of different tables do not share the memory
+type wasmModule struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	config   wazero.ModuleConfig
+}
+
+func newWasmModule(ctx context.Context, ctd *TransformerDefinition) (*wasmModule, error) {
+	data, err := os.ReadFile(ctd.Wasm)
+	if err != nil {
+		return nil, fmt.Errorf("error reading wasm module: %w", err)
+	}
+
+	// The runtime terminates the running function when the context is done, so the row transformation timeout
+	// is applied as for the cmd transformers
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err = wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("error instantiating wasi: %w", err)
+	}
+	compiled, err := r.CompileModule(ctx, data)
+	if err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("error compiling wasm module: %w", err)
+	}
+	if err = validateWasmExports(compiled.ExportedFunctions(), ctd.AutoDiscover); err != nil {
+		_ = r.Close(ctx)
+		return nil, err
+	}
+
+	// The module has no access to the filesystem except the mounted directories and has no access to the network
+	// and the environment variables
+	fsConfig := wazero.NewFSConfig()
+	for _, m := range ctd.Mounts {
+		hostPath, guestPath, ok := strings.Cut(m, ":")
+		if !ok {
+			guestPath = hostPath
+		}
+		fsConfig = fsConfig.WithReadOnlyDirMount(hostPath, guestPath)
+	}
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(append([]string{filepath.Base(ctd.Wasm)}, ctd.Args...)...).
+		WithStartFunctions("_initialize").
+		WithFSConfig(fsConfig).
+		WithStderr(os.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+
+	return &wasmModule{
+		runtime:  r,
+		compiled: compiled,
+		config:   config,
+	}, nil
+}
+
+func validateWasmExports(exports map[string]api.FunctionDefinition, autoDiscover bool) error {
+	signatures := map[string][2][]api.ValueType{
+		WasmAllocFuncName:     {{api.ValueTypeI32}, {api.ValueTypeI32}},
+		WasmInitFuncName:      {wasmDataParams, wasmResultParams},
+		WasmTransformFuncName: {wasmDataParams, wasmResultParams},
+	}
+	if autoDiscover {
+		signatures[WasmDefinitionFuncName] = [2][]api.ValueType{nil, wasmResultParams}
+	}
+	for name, sig := range signatures {
+		fd, ok := exports[name]
+		if !ok {
+			return fmt.Errorf("wasm module does not export function \"%s\"", name)
+		}
+		if !equalWasmTypes(fd.ParamTypes(), sig[0]) || !equalWasmTypes(fd.ResultTypes(), sig[1]) {
+			return fmt.Errorf("wasm module function \"%s\" has unexpected signature", name)
+		}
+	}
+	return nil
+}
+
+func equalWasmTypes(a, b []api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// definition - receives the transformer definition from the module
+func (wm *wasmModule) definition(ctx context.Context) (*TransformerDefinition, error) {
+	wi, err := wm.instantiate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer wi.close(ctx)
+
+	fn := wi.mod.ExportedFunction(WasmDefinitionFuncName)
+	res, err := fn.Call(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error calling \"%s\": %w", WasmDefinitionFuncName, err)
+	}
+	data, err := wi.read(res[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("received empty transformer definition")
+	}
+	return parseDynamicTransformerDefinition(data)
+}
+
+func (wm *wasmModule) instantiate(ctx context.Context) (*wasmInstance, error) {
+	mod, err := wm.runtime.InstantiateModule(ctx, wm.compiled, wm.config)
+	if err != nil {
+		return nil, fmt.Errorf("error instantiating wasm module: %w", err)
+	}
+	return &wasmInstance{
+		mod:       mod,
+		alloc:     mod.ExportedFunction(WasmAllocFuncName),
+		init:      mod.ExportedFunction(WasmInitFuncName),
+		transform: mod.ExportedFunction(WasmTransformFuncName),
+		error:     mod.ExportedFunction(WasmErrorFuncName),
+	}, nil
+}
+
+// close - releases the runtime and the compiled module. The instances of the module are closed with the runtime
+func (wm *wasmModule) close(ctx context.Context) error {
+	return wm.runtime.Close(ctx)
+}
+
+type wasmInstance struct {
+	mod       api.Module
+	alloc     api.Function
+	init      api.Function
+	transform api.Function
+	error     api.Function
+	stack     [2]uint64
+}
+
+// call - copies the data into the module memory and calls the function. The returned slice is the view of the
+// module memory and is valid until the next call
+func (wi *wasmInstance) call(ctx context.Context, fn api.Function, data []byte) ([]byte, error) {
+	wi.stack[0] = api.EncodeU32(uint32(len(data)))
+	if err := wi.alloc.CallWithStack(ctx, wi.stack[:]); err != nil {
+		return nil, fmt.Errorf("error calling \"%s\": %w", WasmAllocFuncName, err)
+	}
+	ptr := api.DecodeU32(wi.stack[0])
+	if !wi.mod.Memory().Write(ptr, data) {
+		return nil, fmt.Errorf("allocated buffer is out of memory range")
+	}
+	wi.stack[0] = api.EncodeU32(ptr)
+	wi.stack[1] = api.EncodeU32(uint32(len(data)))
+	if err := fn.CallWithStack(ctx, wi.stack[:]); err != nil {
+		return nil, fmt.Errorf("error calling \"%s\": %w", fn.Definition().Name(), err)
+	}
+	return wi.read(wi.stack[0])
+}
+
+// read - returns the memory view of the packed pointer and length
+func (wi *wasmInstance) read(packed uint64) ([]byte, error) {
+	ptr, size := uint32(packed>>32), uint32(packed)
+	if packed == 0 {
+		return nil, nil
+	}
+	data, ok := wi.mod.Memory().Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("result buffer is out of memory range")
+	}
+	return data, nil
+}
+
+// lastError - returns the error message of the last call or the generic error if the module does not export the
+// error function
+func (wi *wasmInstance) lastError(ctx context.Context) error {
+	if wi.error == nil {
+		return errors.New("wasm transformer returned no result")
+	}
+	res, err := wi.error.Call(ctx)
+	if err != nil {
+		return fmt.Errorf("error calling \"%s\": %w", WasmErrorFuncName, err)
+	}
+	msg, err := wi.read(res[0])
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("wasm transformer error: %s", string(msg))
+}
+
+// initialize - sends the metadata to the module and returns the validation warnings
+func (wi *wasmInstance) initialize(ctx context.Context, meta []byte) (toolkit.ValidationWarnings, error) {
+	res, err := wi.call(ctx, wi.init, meta)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	var warnings toolkit.ValidationWarnings
+	if err = json.Unmarshal(res, &warnings); err != nil {
+		return nil, fmt.Errorf("error unmarshalling validation warnings: %w", err)
+	}
+	return warnings, nil
+}
+
+func (wi *wasmInstance) close(ctx context.Context) error {
+	return wi.mod.Close(ctx)
+}
@@ -0,0 +1,291 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const testWasmDefinition = `{"name":"WasmUpper","description":"Upper case the text",` +
+	`"parameters":[{"name":"column","description":"column name","required":true,"is_column":true,` +
+	`"column_properties":{"affected":true,"max_length":-1}}],"driver":{"name":"text"}}`
+
+const testWasmErrorMessage = "row starts with !"
+
+// buildTestWasmModule - assembles the module that upper cases the ASCII letters of the input in place and returns
+// the error if the input starts with "!". It is equivalent to the following WAT:
+//
+//	(module
+//	  (memory (export "memory") 2)
+//	  (data (i32.const 16) "<definition>")
+//	  (data (i32.const 2048) "<error message>")
+//	  (func (export "greenmask_alloc") (param i32) (result i32) i32.const 4096)
+//	  (func (export "greenmask_definition") (result i64) <16 << 32 | definition length>)
+//	  (func (export "greenmask_init") (param i32 i32) (result i64) i64.const 0)
+//	  (func (export "greenmask_transform") (param $ptr i32) (param $len i32) (result i64) ...)
+//	  (func (export "greenmask_error") (result i64) <2048 << 32 | error message length>))
+func buildTestWasmModule() []byte {
+	packed := func(ptr, size int) []byte {
+		return append([]byte{0x42}, sleb128(int64(ptr)<<32|int64(size))...)
+	}
+	i32Const := func(v int32) []byte {
+		return append([]byte{0x41}, sleb128(int64(v))...)
+	}
+	concat := func(parts ...[]byte) []byte {
+		var res []byte
+		for _, p := range parts {
+			res = append(res, p...)
+		}
+		return res
+	}
+	transform := concat(
+		// if len != 0 && load8_u(ptr) == '!' return 0
+		[]byte{0x20, 0x01, 0x04, 0x40, 0x20, 0x00, 0x2d, 0x00, 0x00}, i32Const('!'),
+		[]byte{0x46, 0x04, 0x40, 0x42, 0x00, 0x0f, 0x0b, 0x0b},
+		// block loop
+		[]byte{0x02, 0x40, 0x03, 0x40},
+		// br_if 1 (i >= len)
+		[]byte{0x20, 0x02, 0x20, 0x01, 0x4f, 0x0d, 0x01},
+		// addr = ptr + i; b = load8_u(addr)
+		[]byte{0x20, 0x00, 0x20, 0x02, 0x6a, 0x21, 0x03, 0x20, 0x03, 0x2d, 0x00, 0x00, 0x22, 0x04},
+		// if b >= 'a' && b <= 'z' store8(addr, b - 32)
+		i32Const('a'), []byte{0x4f, 0x20, 0x04}, i32Const('z'), []byte{0x4d, 0x71, 0x04, 0x40},
+		[]byte{0x20, 0x03, 0x20, 0x04}, i32Const(32), []byte{0x6b, 0x3a, 0x00, 0x00, 0x0b},
+		// i++; br 0
+		[]byte{0x20, 0x02}, i32Const(1), []byte{0x6a, 0x21, 0x02, 0x0c, 0x00},
+		// end loop end block
+		[]byte{0x0b, 0x0b},
+		// ptr << 32 | len
+		[]byte{0x20, 0x00, 0xad, 0x42, 0x20, 0x86, 0x20, 0x01, 0xad, 0x84},
+	)
+
+	body := func(locals []byte, code []byte) []byte {
+		b := append(locals, code...)
+		b = append(b, 0x0b)
+		return append(uleb128(uint64(len(b))), b...)
+	}
+	noLocals := []byte{0x00}
+	dataSegment := func(offset int32, data string) []byte {
+		return concat([]byte{0x00}, i32Const(offset), []byte{0x0b}, uleb128(uint64(len(data))), []byte(data))
+	}
+	exports := [][]byte{
+		wasmExport("memory", 0x02, 0),
+		wasmExport(WasmAllocFuncName, 0x00, 0),
+		wasmExport(WasmDefinitionFuncName, 0x00, 1),
+		wasmExport(WasmInitFuncName, 0x00, 2),
+		wasmExport(WasmTransformFuncName, 0x00, 3),
+		wasmExport(WasmErrorFuncName, 0x00, 4),
+	}
+
+	return concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		// types: (i32) -> i32, () -> i64, (i32 i32) -> i64
+		wasmSection(0x01, wasmVector(
+			[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},
+			[]byte{0x60, 0x00, 0x01, 0x7e},
+			[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e},
+		)),
+		wasmSection(0x03, wasmVector([]byte{0x00}, []byte{0x01}, []byte{0x02}, []byte{0x02}, []byte{0x01})),
+		wasmSection(0x05, wasmVector([]byte{0x00, 0x02})),
+		wasmSection(0x07, wasmVector(exports...)),
+		wasmSection(0x0a, wasmVector(
+			body(noLocals, i32Const(4096)),
+			body(noLocals, packed(16, len(testWasmDefinition))),
+			body(noLocals, []byte{0x42, 0x00}),
+			body([]byte{0x01, 0x03, 0x7f}, transform),
+			body(noLocals, packed(2048, len(testWasmErrorMessage))),
+		)),
+		wasmSection(0x0b, wasmVector(
+			dataSegment(16, testWasmDefinition),
+			dataSegment(2048, testWasmErrorMessage),
+		)),
+	)
+}
+
+func wasmSection(id byte, payload []byte) []byte {
+	return append(append([]byte{id}, uleb128(uint64(len(payload)))...), payload...)
+}
+
+func wasmVector(items ...[]byte) []byte {
+	res := uleb128(uint64(len(items)))
+	for _, item := range items {
+		res = append(res, item...)
+	}
+	return res
+}
+
+func wasmExport(name string, kind byte, idx uint64) []byte {
+	res := append(uleb128(uint64(len(name))), name...)
+	res = append(res, kind)
+	return append(res, uleb128(idx)...)
+}
+
+func uleb128(v uint64) []byte {
+	var res []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			res = append(res, b|0x80)
+			continue
+		}
+		return append(res, b)
+	}
+}
+
+func sleb128(v int64) []byte {
+	var res []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(res, b)
+		}
+		res = append(res, b|0x80)
+	}
+}
+
+func bootstrapTestWasmTransformer(t *testing.T, ctd *TransformerDefinition) *utils.TransformerRegistry {
+	path := filepath.Join(t.TempDir(), "upper.wasm")
+	require.NoError(t, os.WriteFile(path, buildTestWasmModule(), 0600))
+	ctd.Wasm = path
+	registry := utils.NewTransformerRegistry()
+	res, err := BootstrapCustomTransformers(context.Background(), registry, []*TransformerDefinition{ctd})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, res.Close(context.Background()))
+	})
+	return registry
+}
+
+func TestWasmTransformer_Transform(t *testing.T) {
+	registry := bootstrapTestWasmTransformer(t, &TransformerDefinition{AutoDiscover: true})
+	td, ok := registry.Get("WasmUpper")
+	require.True(t, ok)
+	assert.Equal(t, "Upper case the text", td.Properties.Description)
+
+	driver, record := getTestDriverAndRecord(t, "hello\t1")
+	tc, warnings, err := td.Instance(context.Background(), driver, map[string]toolkit.ParamsValue{
+		"column": toolkit.ParamsValue("name"),
+	}, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	ctx := context.Background()
+	require.NoError(t, tc.Transformer.Init(ctx))
+	defer tc.Transformer.Done(ctx) // nolint: errcheck
+
+	r, err := tc.Transformer.Transform(ctx, record)
+	require.NoError(t, err)
+	v, err := r.GetRawColumnValueByName("name")
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(v.Data))
+
+	_, record = getTestDriverAndRecord(t, "!hello\t2")
+	_, err = tc.Transformer.Transform(ctx, record)
+	require.ErrorContains(t, err, testWasmErrorMessage)
+}
+
+func TestWasmTransformer_TransformBatch(t *testing.T) {
+	registry := bootstrapTestWasmTransformer(t, &TransformerDefinition{AutoDiscover: true})
+	td, ok := registry.Get("WasmUpper")
+	require.True(t, ok)
+
+	ctx := context.Background()
+	driver, _ := getTestDriverAndRecord(t, "hello\t1")
+	tc, warnings, err := td.Instance(ctx, driver, map[string]toolkit.ParamsValue{
+		"column": toolkit.ParamsValue("name"),
+	}, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	require.NoError(t, tc.Transformer.Init(ctx))
+	defer tc.Transformer.Done(ctx) // nolint: errcheck
+
+	bt, ok := tc.Transformer.(utils.BatchTransformer)
+	require.True(t, ok)
+	var records []*toolkit.Record
+	for _, line := range []string{"hello\t1", "world\t2", "batch\t3"} {
+		_, record := getTestDriverAndRecord(t, line)
+		records = append(records, record)
+	}
+	require.NoError(t, bt.TransformBatch(ctx, records))
+	for i, expected := range []string{"HELLO", "WORLD", "BATCH"} {
+		v, err := records[i].GetRawColumnValueByName("name")
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(v.Data))
+	}
+}
+
+func TestResources_Close_wasm(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "upper.wasm")
+	require.NoError(t, os.WriteFile(path, buildTestWasmModule(), 0600))
+	res, err := BootstrapCustomTransformers(ctx, utils.NewTransformerRegistry(), []*TransformerDefinition{
+		{AutoDiscover: true, Wasm: path},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.modules, 1)
+	module := res.modules[0]
+
+	require.NoError(t, res.Close(ctx))
+	_, err = module.instantiate(ctx)
+	require.Error(t, err)
+	require.Empty(t, res.modules)
+}
+
+func TestBootstrapCustomTransformers_wasm_validation(t *testing.T) {
+	_, err := BootstrapCustomTransformers(context.Background(), utils.NewTransformerRegistry(), []*TransformerDefinition{
+		{Name: "Test", Executable: "/bin/cat", Wasm: "test.wasm"},
+	})
+	require.ErrorContains(t, err, "cannot be used together")
+
+	path := filepath.Join(t.TempDir(), "empty.wasm")
+	require.NoError(t, os.WriteFile(path, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}, 0600))
+	_, err = BootstrapCustomTransformers(context.Background(), utils.NewTransformerRegistry(), []*TransformerDefinition{
+		{Name: "Test", Wasm: path},
+	})
+	require.ErrorContains(t, err, `wasm module does not export function`)
+}
+
+func getTestDriverAndRecord(t *testing.T, line string) (*toolkit.Driver, *toolkit.Record) {
+	table := &toolkit.Table{
+		Schema: "public",
+		Name:   "test",
+		Oid:    1224,
+		Columns: []*toolkit.Column{
+			{Name: "name", TypeName: "text", TypeOid: 25, Num: 1, NotNull: true, Length: -1, TypeLength: -1},
+			{Name: "id", TypeName: "int4", TypeOid: 23, Num: 2, NotNull: true, Length: -1, TypeLength: 4},
+		},
+		Constraints: []toolkit.Constraint{},
+	}
+	driver, warnings, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	row := pgcopy.NewRow(2)
+	require.NoError(t, row.Decode([]byte(line)))
+	record := toolkit.NewRecord(driver)
+	record.SetRow(row)
+	return driver, record
+}
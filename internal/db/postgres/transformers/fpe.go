@@ -0,0 +1,369 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/generators/transformers"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const (
+	FpeEncryptTransformerName = "FpeEncrypt"
+	FpeDecryptTransformerName = "FpeDecrypt"
+)
+
+const fpeDefaultAlphabet = "0123456789"
+
+var FpeEncryptTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		FpeEncryptTransformerName,
+		"Encrypt the value using format-preserving encryption (NIST FF1 or FF3-1). The characters that are not in "+
+			"the alphabet are kept as is",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewFpeEncryptTransformer,
+
+	fpeParameterDefinitions()...,
+)
+
+var FpeDecryptTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		FpeDecryptTransformerName,
+		"Decrypt the value encrypted by FpeEncrypt transformer with the same parameters",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewFpeDecryptTransformer,
+
+	fpeParameterDefinitions()...,
+)
+
+func fpeParameterDefinitions() []*toolkit.ParameterDefinition {
+	return []*toolkit.ParameterDefinition{
+		toolkit.MustNewParameterDefinition(
+			"column",
+			"column name",
+		).SetIsColumn(toolkit.NewColumnProperties().
+			SetAffected(true).
+			SetAllowedColumnTypes("text", "varchar", "bpchar", "int2", "int4", "int8").
+			SetSkipOnNull(true),
+		).SetRequired(true),
+
+		toolkit.MustNewParameterDefinition(
+			"key",
+			"hex encoded AES key (16, 24 or 32 bytes). This value may be provided via environment variable"+
+				" GREENMASK_FPE_KEY",
+		).SetGetFromGlobalEnvVariable("GREENMASK_FPE_KEY").
+			SetRawValueValidator(validateFpeKeyParameter),
+
+		toolkit.MustNewParameterDefinition(
+			"algorithm",
+			fmt.Sprintf(
+				"format-preserving encryption algorithm. Possible values: %s, %s",
+				transformers.FpeFF1AlgorithmName, transformers.FpeFF31AlgorithmName,
+			),
+		).SetDefaultValue([]byte(transformers.FpeFF1AlgorithmName)).
+			SetRawValueValidator(validateFpeAlgorithmParameter),
+
+		toolkit.MustNewParameterDefinition(
+			"alphabet",
+			"characters that are encrypted in text values. The rest of the characters are kept as is. "+
+				"Integer values are always encrypted with the decimal digits",
+		).SetDefaultValue([]byte(fpeDefaultAlphabet)),
+
+		toolkit.MustNewParameterDefinition(
+			"tweak",
+			"hex encoded tweak. FF3-1 requires the tweak of 7 bytes length",
+		).SetDefaultValue([]byte("")),
+
+		toolkit.MustNewParameterDefinition(
+			"tweak_column",
+			"column name which value is used as the tweak. If the value is NULL the tweak parameter is used",
+		).SetDefaultValue([]byte("")),
+	}
+}
+
+type FpeTransformer struct {
+	t               *transformers.FpeTransformer
+	columnName      string
+	columnIdx       int
+	affectedColumns map[int]string
+	isInt           bool
+	decrypt         bool
+	algorithm       string
+	tweak           []byte
+	tweakColumnIdx  int
+}
+
+func NewFpeEncryptTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	return newFpeTransformer(driver, parameters, false)
+}
+
+func NewFpeDecryptTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	return newFpeTransformer(driver, parameters, true)
+}
+
+func newFpeTransformer(
+	driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer, decrypt bool,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, keyHex, algorithm, alphabet, tweakHex, tweakColumnName string
+
+	p := parameters["column"]
+	if err := p.Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"column\" param: %w", err)
+	}
+	idx, c, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	p = parameters["key"]
+	if err := p.Scan(&keyHex); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"key\" param: %w", err)
+	}
+	if keyHex == "" {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "key").
+				SetMsg("encryption key is required: use key parameter or GREENMASK_FPE_KEY environment variable"),
+		}, nil
+	}
+	key, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode \"key\" param: %w", err)
+	}
+
+	p = parameters["algorithm"]
+	if err = p.Scan(&algorithm); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"algorithm\" param: %w", err)
+	}
+
+	p = parameters["alphabet"]
+	if err = p.Scan(&alphabet); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"alphabet\" param: %w", err)
+	}
+
+	p = parameters["tweak"]
+	if err = p.Scan(&tweakHex); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"tweak\" param: %w", err)
+	}
+	tweak, err := hex.DecodeString(tweakHex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode \"tweak\" param: %w", err)
+	}
+
+	tweakColumnIdx := -1
+	p = parameters["tweak_column"]
+	if err = p.Scan(&tweakColumnName); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"tweak_column\" param: %w", err)
+	}
+	if tweakColumnName != "" {
+		tweakColumnIdx, _, ok = driver.GetColumnByName(tweakColumnName)
+		if !ok {
+			return nil, toolkit.ValidationWarnings{
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "tweak_column").
+					AddMeta("ParameterValue", tweakColumnName).
+					SetMsg("column is not found"),
+			}, nil
+		}
+		if tweakColumnIdx == idx {
+			return nil, toolkit.ValidationWarnings{
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "tweak_column").
+					AddMeta("ParameterValue", tweakColumnName).
+					SetMsg("tweak column cannot be the transformed column"),
+			}, nil
+		}
+	}
+
+	maxValue, isInt := getFpeIntMaxValue(c)
+	radix := len([]rune(alphabet))
+	if isInt {
+		radix = 10
+	}
+	cipher, err := transformers.NewFpeCipher(algorithm, key, radix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create cipher: %w", err)
+	}
+	if err = cipher.ValidateTweak(tweak); err != nil {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "tweak").
+				AddMeta("Error", err.Error()).
+				SetMsg("invalid tweak"),
+		}, nil
+	}
+
+	var t *transformers.FpeTransformer
+	if isInt {
+		t, err = transformers.NewFpeIntTransformer(cipher, maxValue)
+	} else {
+		t, err = transformers.NewFpeTextTransformer(cipher, []rune(alphabet))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create transformer: %w", err)
+	}
+
+	return &FpeTransformer{
+		t:               t,
+		columnName:      columnName,
+		columnIdx:       idx,
+		affectedColumns: affectedColumns,
+		isInt:           isInt,
+		decrypt:         decrypt,
+		algorithm:       algorithm,
+		tweak:           tweak,
+		tweakColumnIdx:  tweakColumnIdx,
+	}, nil, nil
+}
+
+func (ft *FpeTransformer) GetAffectedColumns() map[int]string {
+	return ft.affectedColumns
+}
+
+func (ft *FpeTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (ft *FpeTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (ft *FpeTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(ft.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan attribute value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+
+	tweak, err := ft.getTweak(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []byte
+	if ft.isInt {
+		v, err := strconv.ParseInt(string(val.Data), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse integer value: %w", err)
+		}
+		v, err = ft.t.TransformInt64(v, tweak, ft.decrypt)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform value: %w", err)
+		}
+		res = strconv.AppendInt(nil, v, 10)
+	} else {
+		res, err = ft.t.TransformText(val.Data, tweak, ft.decrypt)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform value: %w", err)
+		}
+	}
+
+	if err = r.SetRawColumnValueByIdx(ft.columnIdx, toolkit.NewRawValue(res, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+// getTweak - returns the tweak from the tweak column if it is set and not NULL. FF3-1 requires the tweak of the fixed
+// length therefore the first 7 bytes of the SHA-256 of the value are used
+func (ft *FpeTransformer) getTweak(r *toolkit.Record) ([]byte, error) {
+	if ft.tweakColumnIdx == -1 {
+		return ft.tweak, nil
+	}
+	val, err := r.GetRawColumnValueByIdx(ft.tweakColumnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan tweak column value: %w", err)
+	}
+	if val.IsNull {
+		return ft.tweak, nil
+	}
+	if ft.algorithm == transformers.FpeFF31AlgorithmName {
+		sum := sha256.Sum256(val.Data)
+		return sum[:7], nil
+	}
+	return val.Data, nil
+}
+
+// getFpeIntMaxValue - returns the max value of the integer column type and true if the column type is integer
+func getFpeIntMaxValue(c *toolkit.Column) (int64, bool) {
+	typeName, typeOid := c.GetType()
+	switch {
+	case typeOid == pgtype.Int2OID || typeName == "int2" || typeName == "smallint":
+		return math.MaxInt16, true
+	case typeOid == pgtype.Int4OID || typeName == "int4" || typeName == "integer":
+		return math.MaxInt32, true
+	case typeOid == pgtype.Int8OID || typeName == "int8" || typeName == "bigint":
+		return math.MaxInt64, true
+	}
+	return 0, false
+}
+
+func validateFpeKeyParameter(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(string(v))
+	if err != nil || (len(key) != 16 && len(key) != 24 && len(key) != 32) {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				SetMsg("key must be hex encoded 16, 24 or 32 bytes"),
+		}, nil
+	}
+	return nil, nil
+}
+
+func validateFpeAlgorithmParameter(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	switch string(v) {
+	case transformers.FpeFF1AlgorithmName, transformers.FpeFF31AlgorithmName:
+		return nil, nil
+	}
+	return toolkit.ValidationWarnings{
+		toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterValue", string(v)).
+			SetMsg("unknown format-preserving encryption algorithm"),
+	}, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(FpeEncryptTransformerDefinition)
+	utils.DefaultTransformerRegistry.MustRegister(FpeDecryptTransformerDefinition)
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const fpeTestKey = "2B7E151628AED2A6ABF7158809CF4F3C"
+
+func fpeTransform(
+	t *testing.T, def *utils.TransformerDefinition, params map[string]toolkit.ParamsValue, columnName, value string,
+) string {
+	driver, record := getDriverAndRecord(columnName, value)
+	transformer, warnings, err := def.Instance(context.Background(), driver, params, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	r, err := transformer.Transformer.Transform(context.Background(), record)
+	require.NoError(t, err)
+	res, err := r.GetRawColumnValueByName(columnName)
+	require.NoError(t, err)
+	require.False(t, res.IsNull)
+	return string(res.Data)
+}
+
+func TestFpeTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		column   string
+		params   map[string]toolkit.ParamsValue
+		original string
+		pattern  string
+	}{
+		{
+			name:   "ff1 text",
+			column: "data",
+			params: map[string]toolkit.ParamsValue{
+				"key": toolkit.ParamsValue(fpeTestKey),
+			},
+			original: "4111-1111-1111-1111",
+			pattern:  `^\d{4}-\d{4}-\d{4}-\d{4}$`,
+		},
+		{
+			name:   "ff3-1 text with alphabet",
+			column: "data",
+			params: map[string]toolkit.ParamsValue{
+				"key":       toolkit.ParamsValue(fpeTestKey),
+				"algorithm": toolkit.ParamsValue("ff3-1"),
+				"alphabet":  toolkit.ParamsValue("ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"),
+				"tweak":     toolkit.ParamsValue("D8E7920AFA330A"),
+			},
+			original: "GB82-WEST-1234-5698",
+			pattern:  `^[A-Z0-9]{4}-[A-Z0-9]{4}-[A-Z0-9]{4}-[A-Z0-9]{4}$`,
+		},
+		{
+			name:   "ff1 int4",
+			column: "id4",
+			params: map[string]toolkit.ParamsValue{
+				"key": toolkit.ParamsValue(fpeTestKey),
+			},
+			original: "123",
+			pattern:  `^\d+$`,
+		},
+		{
+			name:   "ff3-1 negative int8",
+			column: "id8",
+			params: map[string]toolkit.ParamsValue{
+				"key":       toolkit.ParamsValue(fpeTestKey),
+				"algorithm": toolkit.ParamsValue("ff3-1"),
+				"tweak":     toolkit.ParamsValue("D8E7920AFA330A"),
+			},
+			original: "-9000",
+			pattern:  `^-\d+$`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue(tt.column)
+			encrypted := fpeTransform(t, FpeEncryptTransformerDefinition, tt.params, tt.column, tt.original)
+			require.Regexp(t, tt.pattern, encrypted)
+			require.NotEqual(t, tt.original, encrypted)
+
+			decrypted := fpeTransform(t, FpeDecryptTransformerDefinition, tt.params, tt.column, encrypted)
+			require.Equal(t, tt.original, decrypted)
+		})
+	}
+}
+
+func TestFpeTransformer_Transform_tweak_column(t *testing.T) {
+	table := &toolkit.Table{
+		Schema:  "public",
+		Name:    "test",
+		Oid:     1224,
+		Columns: []*toolkit.Column{columnList[2], columnList[6]},
+	}
+	driver, warnings, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	params := map[string]toolkit.ParamsValue{
+		"column":       toolkit.ParamsValue("data"),
+		"key":          toolkit.ParamsValue(fpeTestKey),
+		"tweak_column": toolkit.ParamsValue("id4"),
+	}
+	transformer, warnings, err := FpeEncryptTransformerDefinition.Instance(
+		context.Background(), driver, params, nil, "",
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	encrypt := func(line string) string {
+		row := pgcopy.NewRow(2)
+		require.NoError(t, row.Decode([]byte(line)))
+		record := toolkit.NewRecord(driver)
+		record.SetRow(row)
+		r, err := transformer.Transformer.Transform(context.Background(), record)
+		require.NoError(t, err)
+		res, err := r.GetRawColumnValueByName("data")
+		require.NoError(t, err)
+		return string(res.Data)
+	}
+	require.Equal(t, encrypt("0123456789\t1"), encrypt("0123456789\t1"))
+	require.NotEqual(t, encrypt("0123456789\t1"), encrypt("0123456789\t2"))
+}
+
+func TestFpeTransformer_validation(t *testing.T) {
+	driver, _ := getDriverAndRecord("data", "0123456789")
+
+	_, warnings, err := FpeEncryptTransformerDefinition.Instance(
+		context.Background(), driver,
+		map[string]toolkit.ParamsValue{
+			"column": toolkit.ParamsValue("data"),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+
+	_, warnings, err = FpeEncryptTransformerDefinition.Instance(
+		context.Background(), driver,
+		map[string]toolkit.ParamsValue{
+			"column": toolkit.ParamsValue("data"),
+			"key":    toolkit.ParamsValue("0011"),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+
+	_, warnings, err = FpeEncryptTransformerDefinition.Instance(
+		context.Background(), driver,
+		map[string]toolkit.ParamsValue{
+			"column":    toolkit.ParamsValue("data"),
+			"key":       toolkit.ParamsValue(fpeTestKey),
+			"algorithm": toolkit.ParamsValue("ff3-1"),
+			"tweak":     toolkit.ParamsValue("00"),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+}
@@ -0,0 +1,247 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const GeneralizeCategoryTransformerName = "GeneralizeCategory"
+
+var GeneralizeCategoryTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		GeneralizeCategoryTransformerName,
+		"Replace the category with its ancestor from the hierarchy file",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewGeneralizeCategoryTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes("text", "varchar", "bpchar"),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"hierarchy_file",
+		"path to the YAML or JSON file with the categories tree",
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"level",
+		"number of the hierarchy levels to go up from the original category",
+	).SetDefaultValue(toolkit.ParamsValue("1")),
+
+	toolkit.MustNewParameterDefinition(
+		"unknown_value",
+		"value that replaces the categories missing in the hierarchy. The original value is kept if empty",
+	).SetDefaultValue(toolkit.ParamsValue("")),
+)
+
+type GeneralizeCategoryTransformer struct {
+	columnName      string
+	columnIdx       int
+	parents         map[string]string
+	level           int
+	unknownValue    []byte
+	affectedColumns map[int]string
+}
+
+func NewGeneralizeCategoryTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, hierarchyFile, unknownValue string
+	var level int
+	if err := parameters["column"].Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+	idx, _, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	if err := parameters["hierarchy_file"].Scan(&hierarchyFile); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "hierarchy_file" param: %w`, err)
+	}
+	if err := parameters["level"].Scan(&level); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "level" param: %w`, err)
+	}
+	if err := parameters["unknown_value"].Scan(&unknownValue); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "unknown_value" param: %w`, err)
+	}
+
+	var warnings toolkit.ValidationWarnings
+	if level < 1 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "level").
+			AddMeta("ParameterValue", level).
+			SetMsg("level must be greater than 0"))
+	}
+	parents, err := loadCategoryHierarchyFile(hierarchyFile)
+	if err != nil {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "hierarchy_file").
+			AddMeta("ParameterValue", hierarchyFile).
+			AddMeta("Error", err.Error()).
+			SetMsg("unable to load hierarchy file"))
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	return &GeneralizeCategoryTransformer{
+		columnName:      columnName,
+		columnIdx:       idx,
+		parents:         parents,
+		level:           level,
+		unknownValue:    []byte(unknownValue),
+		affectedColumns: affectedColumns,
+	}, nil, nil
+}
+
+func (gct *GeneralizeCategoryTransformer) GetAffectedColumns() map[int]string {
+	return gct.affectedColumns
+}
+
+func (gct *GeneralizeCategoryTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (gct *GeneralizeCategoryTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (gct *GeneralizeCategoryTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(gct.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+
+	res, ok := gct.generalize(string(val.Data))
+	if !ok {
+		if len(gct.unknownValue) == 0 {
+			return r, nil
+		}
+		res = string(gct.unknownValue)
+	}
+
+	if err = r.SetRawColumnValueByIdx(gct.columnIdx, toolkit.NewRawValue([]byte(res), false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+// generalize - returns the ancestor of the category. The root category is returned if the hierarchy is not deep
+// enough
+func (gct *GeneralizeCategoryTransformer) generalize(category string) (string, bool) {
+	if _, ok := gct.parents[category]; !ok {
+		return "", false
+	}
+	for i := 0; i < gct.level; i++ {
+		parent := gct.parents[category]
+		if parent == "" {
+			break
+		}
+		category = parent
+	}
+	return category, true
+}
+
+// loadCategoryHierarchyFile - loads the categories tree and returns the parent of each category. The root categories
+// have the empty parent. The tree is the nested mapping where the leaf categories may be listed in a sequence or have
+// null value:
+//
+//	Europe:
+//	  Western Europe: [Germany, France]
+//	  Southern Europe:
+//	    Italy: ~
+func loadCategoryHierarchyFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %w", err)
+	}
+	var tree yaml.Node
+	if err = yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("unable to parse file: %w", err)
+	}
+	parents := make(map[string]string)
+	if len(tree.Content) == 0 {
+		return nil, fmt.Errorf("hierarchy is empty")
+	}
+	if err = addCategoryHierarchyNode(parents, tree.Content[0], ""); err != nil {
+		return nil, err
+	}
+	return parents, nil
+}
+
+func addCategoryHierarchyNode(parents map[string]string, node *yaml.Node, parent string) error {
+	add := func(n *yaml.Node) error {
+		if n.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: category must be a scalar value", n.Line)
+		}
+		if _, ok := parents[n.Value]; ok {
+			return fmt.Errorf("line %d: category \"%s\" is defined more than once", n.Line, n.Value)
+		}
+		parents[n.Value] = parent
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			if err := add(node.Content[i]); err != nil {
+				return err
+			}
+			if err := addCategoryHierarchyNode(parents, node.Content[i+1], node.Content[i].Value); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			if err := add(n); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		// The null value of the leaf category
+		if node.Tag != "!!null" {
+			return add(node)
+		}
+	default:
+		return fmt.Errorf("line %d: unexpected hierarchy node", node.Line)
+	}
+	return nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(GeneralizeCategoryTransformerDefinition)
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const generalizeCategoryTestHierarchy = `
+Any:
+  Europe:
+    Western Europe: [Germany, France]
+    Southern Europe:
+      Italy: ~
+  Asia:
+    - Japan
+`
+
+func TestGeneralizeCategoryTransformer_Transform(t *testing.T) {
+	hierarchyFile := filepath.Join(t.TempDir(), "hierarchy.yml")
+	require.NoError(t, os.WriteFile(hierarchyFile, []byte(generalizeCategoryTestHierarchy), 0600))
+
+	tests := []struct {
+		name     string
+		params   map[string]toolkit.ParamsValue
+		original string
+		result   string
+	}{
+		{
+			name:     "parent",
+			params:   map[string]toolkit.ParamsValue{},
+			original: "Germany",
+			result:   "Western Europe",
+		},
+		{
+			name: "two levels up",
+			params: map[string]toolkit.ParamsValue{
+				"level": toolkit.ParamsValue("2"),
+			},
+			original: "Italy",
+			result:   "Europe",
+		},
+		{
+			name: "stops at root",
+			params: map[string]toolkit.ParamsValue{
+				"level": toolkit.ParamsValue("5"),
+			},
+			original: "Japan",
+			result:   "Any",
+		},
+		{
+			name:     "unknown kept",
+			params:   map[string]toolkit.ParamsValue{},
+			original: "Mars",
+			result:   "Mars",
+		},
+		{
+			name: "unknown replaced",
+			params: map[string]toolkit.ParamsValue{
+				"unknown_value": toolkit.ParamsValue("Other"),
+			},
+			original: "Mars",
+			result:   "Other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("data")
+			tt.params["hierarchy_file"] = toolkit.ParamsValue(hierarchyFile)
+			driver, record := getDriverAndRecord("data", tt.original)
+			transformer, warnings, err := GeneralizeCategoryTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			r, err := transformer.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName("data")
+			require.NoError(t, err)
+			require.False(t, res.IsNull)
+			require.Equal(t, tt.result, string(res.Data))
+		})
+	}
+}
+
+func TestGeneralizeCategoryTransformer_validation(t *testing.T) {
+	hierarchyFile := filepath.Join(t.TempDir(), "hierarchy.yml")
+	require.NoError(t, os.WriteFile(hierarchyFile, []byte("Any:\n  Europe: [Germany]\n  Asia: [Germany]\n"), 0600))
+
+	driver, _ := getDriverAndRecord("data", "Germany")
+	_, warnings, err := GeneralizeCategoryTransformerDefinition.Instance(
+		context.Background(), driver,
+		map[string]toolkit.ParamsValue{
+			"column":         toolkit.ParamsValue("data"),
+			"hierarchy_file": toolkit.ParamsValue(hierarchyFile),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/generators/transformers"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const GeneralizeDateTransformerName = "GeneralizeDate"
+
+var GeneralizeDateTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		GeneralizeDateTransformerName,
+		"Truncate the date to the year, month or another part",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewGeneralizeDateTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes("date", "timestamp", "timestamptz"),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"truncate",
+		fmt.Sprintf("truncate date till the part (%s)", strings.Join(truncateParts, ", ")),
+	).SetDefaultValue(toolkit.ParamsValue(transformers.MonthTruncateName)).
+		SetRawValueValidator(validateDateTruncationParameterValue),
+)
+
+type GeneralizeDateTransformer struct {
+	columnName      string
+	columnIdx       int
+	truncater       *transformers.DateTruncater
+	affectedColumns map[int]string
+}
+
+func NewGeneralizeDateTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, truncate string
+	if err := parameters["column"].Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+	idx, _, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	if err := parameters["truncate"].Scan(&truncate); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "truncate" param: %w`, err)
+	}
+	truncater, err := transformers.NewDateTruncater(truncate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create date truncater: %w", err)
+	}
+
+	return &GeneralizeDateTransformer{
+		columnName:      columnName,
+		columnIdx:       idx,
+		truncater:       truncater,
+		affectedColumns: affectedColumns,
+	}, nil, nil
+}
+
+func (gdt *GeneralizeDateTransformer) GetAffectedColumns() map[int]string {
+	return gdt.affectedColumns
+}
+
+func (gdt *GeneralizeDateTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (gdt *GeneralizeDateTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (gdt *GeneralizeDateTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	var val time.Time
+	isNull, err := r.ScanColumnValueByIdx(gdt.columnIdx, &val)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan attribute value: %w", err)
+	}
+	if isNull {
+		return r, nil
+	}
+
+	if err = r.SetColumnValueByIdx(gdt.columnIdx, gdt.truncater.Truncate(val)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(GeneralizeDateTransformerDefinition)
+}
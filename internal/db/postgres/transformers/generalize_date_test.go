@@ -0,0 +1,87 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func TestGeneralizeDateTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		column   string
+		truncate string
+		original string
+		result   string
+	}{
+		{
+			name:     "date month",
+			column:   "date_date",
+			original: "2023-08-27",
+			result:   "2023-08-01",
+		},
+		{
+			name:     "date year",
+			column:   "date_date",
+			truncate: "year",
+			original: "2023-08-27",
+			result:   "2023-01-01",
+		},
+		{
+			name:     "timestamp month",
+			column:   "date_ts",
+			original: "2023-08-27 12:15:00",
+			result:   "2023-08-01 00:00:00",
+		},
+		{
+			name:     "null",
+			column:   "date_date",
+			original: "\\N",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]toolkit.ParamsValue{
+				"column": toolkit.ParamsValue(tt.column),
+			}
+			if tt.truncate != "" {
+				params["truncate"] = toolkit.ParamsValue(tt.truncate)
+			}
+			driver, record := getDriverAndRecord(tt.column, tt.original)
+			transformer, warnings, err := GeneralizeDateTransformerDefinition.Instance(
+				context.Background(), driver, params, nil, "",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			r, err := transformer.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName(tt.column)
+			require.NoError(t, err)
+			if tt.original == "\\N" {
+				require.True(t, res.IsNull)
+				return
+			}
+			require.False(t, res.IsNull)
+			require.Equal(t, tt.result, string(res.Data))
+		})
+	}
+}
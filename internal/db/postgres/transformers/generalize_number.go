@@ -0,0 +1,217 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const (
+	GeneralizeNumberTransformerName = "GeneralizeNumber"
+
+	lowerGeneralizeNumberOutput = "lower"
+	rangeGeneralizeNumberOutput = "range"
+)
+
+var generalizeNumberOutputs = []string{lowerGeneralizeNumberOutput, rangeGeneralizeNumberOutput}
+
+var generalizeNumberTextTypes = []string{"text", "varchar", "bpchar"}
+
+var generalizeNumberIntTypes = []string{"int2", "int4", "int8"}
+
+// generalizeNumberNonFiniteValues - the float and numeric values that do not belong to any bucket
+var generalizeNumberNonFiniteValues = []string{"NaN", "Infinity", "-Infinity"}
+
+var GeneralizeNumberTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		GeneralizeNumberTransformerName,
+		"Replace the number with the bucket it belongs to",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewGeneralizeNumberTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes(
+			"int2", "int4", "int8", "numeric", "decimal", "float4", "float8", "text", "varchar", "bpchar",
+		),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"bucket_size",
+		"size of the bucket. The buckets start from 0",
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"output",
+		`the bucket representation: "lower" - the lower bound of the bucket, "range" - the bucket range in `+
+			`format "lower-upper" (text columns only)`,
+	).SetDefaultValue(toolkit.ParamsValue(lowerGeneralizeNumberOutput)).
+		SetRawValueValidator(generalizeNumberOutputValidator),
+)
+
+type GeneralizeNumberTransformer struct {
+	columnName      string
+	columnIdx       int
+	bucketSize      decimal.Decimal
+	output          string
+	affectedColumns map[int]string
+}
+
+func NewGeneralizeNumberTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, output string
+	if err := parameters["column"].Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+	idx, column, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	if err := parameters["output"].Scan(&output); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "output" param: %w`, err)
+	}
+
+	bucketSizeValue, err := parameters["bucket_size"].RawValue()
+	if err != nil {
+		return nil, nil, fmt.Errorf(`unable to get "bucket_size" param: %w`, err)
+	}
+
+	var warnings toolkit.ValidationWarnings
+	bucketSize, err := decimal.NewFromString(string(bucketSizeValue))
+	if err != nil || !bucketSize.IsPositive() {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "bucket_size").
+			AddMeta("ParameterValue", string(bucketSizeValue)).
+			SetMsg("bucket size must be a positive number"))
+	}
+	columnTypeName, columnTypeOid := column.GetType()
+	isTextColumn := toolkit.IsTypeAllowedWithTypeMap(
+		driver, generalizeNumberTextTypes, columnTypeName, columnTypeOid, true,
+	)
+	isIntColumn := toolkit.IsTypeAllowedWithTypeMap(
+		driver, generalizeNumberIntTypes, columnTypeName, columnTypeOid, true,
+	)
+	if isIntColumn && bucketSize.IsPositive() && !bucketSize.IsInteger() {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "bucket_size").
+			AddMeta("ParameterValue", string(bucketSizeValue)).
+			AddMeta("ColumnType", columnTypeName).
+			SetMsg("bucket size must be an integer for integer columns"))
+	}
+	if output == rangeGeneralizeNumberOutput && !isTextColumn {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "output").
+			AddMeta("ParameterValue", output).
+			AddMeta("ColumnType", columnTypeName).
+			SetMsg("range output is supported only for text columns"))
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	return &GeneralizeNumberTransformer{
+		columnName:      columnName,
+		columnIdx:       idx,
+		bucketSize:      bucketSize,
+		output:          output,
+		affectedColumns: affectedColumns,
+	}, warnings, nil
+}
+
+func (gnt *GeneralizeNumberTransformer) GetAffectedColumns() map[int]string {
+	return gnt.affectedColumns
+}
+
+func (gnt *GeneralizeNumberTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (gnt *GeneralizeNumberTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (gnt *GeneralizeNumberTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(gnt.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	// NULL, NaN and infinity do not belong to any bucket, so they are kept as is
+	if val.IsNull || slices.Contains(generalizeNumberNonFiniteValues, string(val.Data)) {
+		return r, nil
+	}
+
+	res, err := gnt.generalize(string(val.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.SetRawColumnValueByIdx(gnt.columnIdx, toolkit.NewRawValue([]byte(res), false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func (gnt *GeneralizeNumberTransformer) generalize(value string) (string, error) {
+	v, err := decimal.NewFromString(value)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse number: %w", err)
+	}
+	lower := v.Div(gnt.bucketSize).Floor().Mul(gnt.bucketSize)
+	if gnt.output == lowerGeneralizeNumberOutput {
+		return lower.String(), nil
+	}
+	upper := lower.Add(gnt.bucketSize)
+	// The integer buckets are represented with the inclusive upper bound, e.g. 20-29
+	if lower.IsInteger() && gnt.bucketSize.IsInteger() {
+		upper = upper.Sub(decimal.NewFromInt(1))
+	}
+	return fmt.Sprintf("%s-%s", lower.String(), upper.String()), nil
+}
+
+func generalizeNumberOutputValidator(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	if !slices.Contains(generalizeNumberOutputs, string(v)) {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterValue", string(v)).
+				AddMeta("AllowedValues", generalizeNumberOutputs).
+				SetMsg("wrong output value"),
+		}, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(GeneralizeNumberTransformerDefinition)
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func TestGeneralizeNumberTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		column   string
+		params   map[string]toolkit.ParamsValue
+		original string
+		result   string
+		isNull   bool
+	}{
+		{
+			name:   "int lower bound",
+			column: "id4",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("10"),
+			},
+			original: "37",
+			result:   "30",
+		},
+		{
+			name:   "negative int",
+			column: "id4",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("10"),
+			},
+			original: "-1",
+			result:   "-10",
+		},
+		{
+			name:   "numeric fraction bucket",
+			column: "val_numeric",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("0.5"),
+			},
+			original: "12.74",
+			result:   "12.5",
+		},
+		{
+			name:   "int range",
+			column: "data",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("10"),
+				"output":      toolkit.ParamsValue("range"),
+			},
+			original: "37",
+			result:   "30-39",
+		},
+		{
+			name:   "fraction range",
+			column: "data",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("2.5"),
+				"output":      toolkit.ParamsValue("range"),
+			},
+			original: "3.1",
+			result:   "2.5-5",
+		},
+		{
+			name:   "float NaN",
+			column: "col_float8",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("10"),
+			},
+			original: "NaN",
+			result:   "NaN",
+		},
+		{
+			name:   "float infinity",
+			column: "col_float4",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("0.5"),
+			},
+			original: "-Infinity",
+			result:   "-Infinity",
+		},
+		{
+			name:   "null",
+			column: "id4",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("10"),
+			},
+			original: "\\N",
+			isNull:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue(tt.column)
+			driver, record := getDriverAndRecord(tt.column, tt.original)
+			transformer, warnings, err := GeneralizeNumberTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			r, err := transformer.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName(tt.column)
+			require.NoError(t, err)
+			require.Equal(t, tt.isNull, res.IsNull)
+			if !tt.isNull {
+				require.Equal(t, tt.result, string(res.Data))
+			}
+		})
+	}
+}
+
+func TestGeneralizeNumberTransformer_validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		column string
+		params map[string]toolkit.ParamsValue
+	}{
+		{
+			name:   "zero bucket size",
+			column: "id4",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("0"),
+			},
+		},
+		{
+			name:   "fraction bucket size for int column",
+			column: "id8",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("2.5"),
+			},
+		},
+		{
+			name:   "range for numeric column",
+			column: "id4",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("10"),
+				"output":      toolkit.ParamsValue("range"),
+			},
+		},
+		{
+			name:   "unknown output",
+			column: "id4",
+			params: map[string]toolkit.ParamsValue{
+				"bucket_size": toolkit.ParamsValue("10"),
+				"output":      toolkit.ParamsValue("upper"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue(tt.column)
+			driver, _ := getDriverAndRecord(tt.column, "1")
+			_, warnings, err := GeneralizeNumberTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "",
+			)
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+		})
+	}
+}
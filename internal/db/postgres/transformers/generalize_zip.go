@@ -0,0 +1,162 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const GeneralizeZipTransformerName = "GeneralizeZip"
+
+var GeneralizeZipTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		GeneralizeZipTransformerName,
+		"Keep the prefix of the zip code and mask or remove the rest",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewGeneralizeZipTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes("text", "varchar", "bpchar"),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"keep",
+		"number of the leading characters to keep",
+	).SetDefaultValue(toolkit.ParamsValue("3")),
+
+	toolkit.MustNewParameterDefinition(
+		"mask_char",
+		"character that replaces the rest of the characters. The rest of the characters are removed if empty",
+	).SetDefaultValue(toolkit.ParamsValue("*")),
+)
+
+type GeneralizeZipTransformer struct {
+	columnName      string
+	columnIdx       int
+	keep            int
+	maskChar        []byte
+	affectedColumns map[int]string
+	buf             []byte
+}
+
+func NewGeneralizeZipTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, maskChar string
+	var keep int
+	if err := parameters["column"].Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+	idx, _, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	if err := parameters["keep"].Scan(&keep); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "keep" param: %w`, err)
+	}
+	if err := parameters["mask_char"].Scan(&maskChar); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "mask_char" param: %w`, err)
+	}
+
+	var warnings toolkit.ValidationWarnings
+	if keep < 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "keep").
+			AddMeta("ParameterValue", keep).
+			SetMsg("keep must not be negative"))
+	}
+	if utf8.RuneCountInString(maskChar) > 1 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "mask_char").
+			AddMeta("ParameterValue", maskChar).
+			SetMsg("mask char must be a single character or empty"))
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	return &GeneralizeZipTransformer{
+		columnName:      columnName,
+		columnIdx:       idx,
+		keep:            keep,
+		maskChar:        []byte(maskChar),
+		affectedColumns: affectedColumns,
+	}, nil, nil
+}
+
+func (gzt *GeneralizeZipTransformer) GetAffectedColumns() map[int]string {
+	return gzt.affectedColumns
+}
+
+func (gzt *GeneralizeZipTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (gzt *GeneralizeZipTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (gzt *GeneralizeZipTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(gzt.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+
+	gzt.buf = gzt.generalize(gzt.buf[:0], val.Data)
+	if err = r.SetRawColumnValueByIdx(gzt.columnIdx, toolkit.NewRawValue(gzt.buf, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func (gzt *GeneralizeZipTransformer) generalize(buf, data []byte) []byte {
+	var pos, count int
+	for pos < len(data) && count < gzt.keep {
+		_, size := utf8.DecodeRune(data[pos:])
+		pos += size
+		count++
+	}
+	buf = append(buf, data[:pos]...)
+	if len(gzt.maskChar) == 0 {
+		return buf
+	}
+	for n := utf8.RuneCount(data[pos:]); n > 0; n-- {
+		buf = append(buf, gzt.maskChar...)
+	}
+	return buf
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(GeneralizeZipTransformerDefinition)
+}
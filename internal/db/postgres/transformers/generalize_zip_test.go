@@ -0,0 +1,83 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func TestGeneralizeZipTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]toolkit.ParamsValue
+		original string
+		result   string
+	}{
+		{
+			name:     "default",
+			params:   map[string]toolkit.ParamsValue{},
+			original: "10115",
+			result:   "101**",
+		},
+		{
+			name: "remove rest",
+			params: map[string]toolkit.ParamsValue{
+				"keep":      toolkit.ParamsValue("2"),
+				"mask_char": toolkit.ParamsValue(""),
+			},
+			original: "SW1A 1AA",
+			result:   "SW",
+		},
+		{
+			name: "custom mask char",
+			params: map[string]toolkit.ParamsValue{
+				"keep":      toolkit.ParamsValue("1"),
+				"mask_char": toolkit.ParamsValue("0"),
+			},
+			original: "75001",
+			result:   "70000",
+		},
+		{
+			name:     "shorter than keep",
+			params:   map[string]toolkit.ParamsValue{},
+			original: "12",
+			result:   "12",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("data")
+			driver, record := getDriverAndRecord("data", tt.original)
+			transformer, warnings, err := GeneralizeZipTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			r, err := transformer.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName("data")
+			require.NoError(t, err)
+			require.False(t, res.IsNull)
+			require.Equal(t, tt.result, string(res.Data))
+		})
+	}
+}
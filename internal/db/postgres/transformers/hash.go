@@ -87,6 +87,7 @@ const (
 
 type HashTransformer struct {
 	columnName          string
+	functionName        string
 	affectedColumns     map[int]string
 	columnIdx           int
 	h                   hash.Hash
@@ -164,6 +165,7 @@ func NewHashTransformer(
 
 	return &HashTransformer{
 		columnName:          columnName,
+		functionName:        hashFunctionName,
 		affectedColumns:     affectedColumns,
 		columnIdx:           idx,
 		maxLength:           maxLength,
@@ -250,6 +252,29 @@ func validateMaxLengthParameter(p *toolkit.ParameterDefinition, v toolkit.Params
 			SetMsg(`max_length parameter cannot be less than zero`)}, nil
 }
 
+// PushdownExpressions - computes the hash in the COPY query. Only md5, sha256 and sha512 functions are built into
+// PostgreSQL. The value is hashed in the client encoding, since COPY sends it in that encoding. The transformer with
+// the salt is not pushed down, because the query text is sent to the server and might be logged there
+func (ht *HashTransformer) PushdownExpressions() (map[int]string, bool) {
+	if len(ht.salt) > 0 {
+		return nil, false
+	}
+	data := fmt.Sprintf("convert_to(%s::text, pg_client_encoding())", utils.QuoteIdent(ht.columnName))
+	var expr string
+	switch ht.functionName {
+	case md5Name:
+		expr = fmt.Sprintf("md5(%s)", data)
+	case sha256Name, sha512Name:
+		expr = fmt.Sprintf("encode(%s(%s), 'hex')", ht.functionName, data)
+	default:
+		return nil, false
+	}
+	if ht.maxLength > 0 && ht.encodedOutputLength > ht.maxLength {
+		expr = fmt.Sprintf("left(%s, %d)", expr, ht.maxLength)
+	}
+	return map[int]string{ht.columnIdx: expr}, true
+}
+
 func init() {
 	utils.DefaultTransformerRegistry.MustRegister(HashTransformerDefinition)
 }
@@ -18,8 +18,10 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
 
@@ -235,3 +237,70 @@ func TestHashTransformer_Transform_multiple_iterations(t *testing.T) {
 		})
 	}
 }
+
+func TestHashTransformer_PushdownExpressions(t *testing.T) {
+	data := `convert_to("data"::text, pg_client_encoding())`
+	tests := []struct {
+		name     string
+		params   map[string]toolkit.ParamsValue
+		ok       bool
+		expected string
+	}{
+		{
+			name: "md5",
+			params: map[string]toolkit.ParamsValue{
+				"function": toolkit.ParamsValue("md5"),
+			},
+			ok:       true,
+			expected: "md5(" + data + ")",
+		},
+		{
+			name: "sha256 with max length",
+			params: map[string]toolkit.ParamsValue{
+				"function":   toolkit.ParamsValue("sha256"),
+				"max_length": toolkit.ParamsValue("10"),
+			},
+			ok:       true,
+			expected: "left(encode(sha256(" + data + "), 'hex'), 10)",
+		},
+		{
+			name: "salt is not pushed down",
+			params: map[string]toolkit.ParamsValue{
+				"function": toolkit.ParamsValue("md5"),
+				"salt":     toolkit.ParamsValue("73616c74"),
+			},
+			ok: false,
+		},
+		{
+			name: "sha1 is not supported",
+			params: map[string]toolkit.ParamsValue{
+				"function": toolkit.ParamsValue("sha1"),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("data")
+			if _, ok := tt.params["salt"]; !ok {
+				tt.params["salt"] = toolkit.ParamsValue("")
+			}
+			driver, _ := getDriverAndRecord("data", "123")
+			transformer, warnings, err := HashTransformerDefinition.Instance(
+				context.Background(),
+				driver, tt.params,
+				nil,
+				"",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			exprs, ok := transformer.Transformer.(utils.PushdownTransformer).PushdownExpressions()
+			require.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, map[int]string{0: tt.expected}, exprs)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/generators"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const LookupTransformerName = "Lookup"
+
+const (
+	keepLookupOnMissing  = "keep"
+	nullLookupOnMissing  = "null"
+	errorLookupOnMissing = "error"
+	poolLookupOnMissing  = "pool"
+)
+
+var LookupTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		LookupTransformerName,
+		"Replace the values with the values of the dataset loaded from the CSV or JSON file or from the query on "+
+			"the source database. The dataset row is found by the key or is chosen from the dataset as a pool",
+	).AddMeta(AllowApplyForReferenced, false).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewLookupTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"columns",
+		"map of the column names to transform to the dataset column names the new values are taken from",
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"key",
+		"map of the column names to the dataset column names that are used to find the dataset row. "+
+			"If empty the dataset is used as a pool of the replacements",
+	).SetDefaultValue(toolkit.ParamsValue("{}")),
+
+	toolkit.MustNewParameterDefinition(
+		"file",
+		"path to the CSV file with the header or to the JSON file with the list of objects",
+	),
+
+	toolkit.MustNewParameterDefinition(
+		"format",
+		"format of the file [csv, json]. By default it is detected by the file extension",
+	),
+
+	toolkit.MustNewParameterDefinition(
+		"query",
+		"query on the source database that returns the dataset",
+	),
+
+	toolkit.MustNewParameterDefinition(
+		"on_missing",
+		"action when the key is not found in the dataset [keep, null, error, pool]",
+	).SetDefaultValue(toolkit.ParamsValue(keepLookupOnMissing)).
+		SetRawValueValidator(lookupOnMissingValidator),
+
+	engineParameterDefinition,
+)
+
+func lookupOnMissingValidator(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	switch string(v) {
+	case keepLookupOnMissing, nullLookupOnMissing, errorLookupOnMissing, poolLookupOnMissing:
+		return nil, nil
+	}
+	return toolkit.ValidationWarnings{
+		toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterValue", string(v)).
+			SetMsg("unknown action"),
+	}, nil
+}
+
+// lookupColumn - the table column and the dataset column mapped to it
+type lookupColumn struct {
+	columnIdx  int
+	datasetIdx int
+}
+
+type LookupTransformer struct {
+	ds              *lookupDataset
+	columns         []lookupColumn
+	keyColumns      []lookupColumn
+	index           map[string]int
+	onMissing       string
+	g               generators.Generator
+	affectedColumns map[int]string
+	buf             []byte
+}
+
+func NewLookupTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columns, key map[string]string
+	var file, format, query, onMissing, engine string
+	if err := parameters["columns"].Scan(&columns); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "columns" param: %w`, err)
+	}
+	if err := parameters["key"].Scan(&key); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "key" param: %w`, err)
+	}
+	for name, dst := range map[string]*string{
+		"file": &file, "format": &format, "query": &query, "on_missing": &onMissing, "engine": &engine,
+	} {
+		v, err := parameters[name].RawValue()
+		if err != nil {
+			return nil, nil, fmt.Errorf(`unable to get "%s" param: %w`, name, err)
+		}
+		*dst = string(v)
+	}
+
+	var warnings toolkit.ValidationWarnings
+	if len(columns) == 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "columns").
+			SetMsg("at least one column must be specified"))
+	}
+	if len(key) == 0 && onMissing != keepLookupOnMissing && onMissing != poolLookupOnMissing {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "on_missing").
+			AddMeta("ParameterValue", onMissing).
+			SetMsg("action cannot be used without key"))
+	}
+
+	var sourceKey string
+	var load func() (*lookupDataset, error)
+	switch {
+	case (file == "") == (query == ""):
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "file").
+			SetMsg(`either "file" or "query" parameter must be specified`))
+	case file != "":
+		var err error
+		if format, err = getLookupFileFormat(file, format); err != nil {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "format").
+				AddMeta("ParameterValue", format).
+				AddMeta("Error", err.Error()).
+				SetMsg("unsupported file format"))
+			break
+		}
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		sourceKey = fmt.Sprintf("file:%s:%s", format, file)
+		load = func() (*lookupDataset, error) {
+			return loadLookupFile(file, format)
+		}
+	default:
+		sourceKey = "query:" + query
+		load = func() (*lookupDataset, error) {
+			return loadLookupQuery(ctx, query)
+		}
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	ds, err := lookupDatasets.get(sourceKey, load)
+	if err != nil {
+		parameterName := "file"
+		if query != "" {
+			parameterName = "query"
+		}
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", parameterName).
+				AddMeta("Error", err.Error()).
+				SetMsg("unable to load dataset"),
+		}, nil
+	}
+
+	lookupColumns, columnsWarns := getLookupColumns(driver, ds, "columns", columns)
+	warnings = append(warnings, columnsWarns...)
+	keyColumns, keyWarns := getLookupColumns(driver, ds, "key", key)
+	warnings = append(warnings, keyWarns...)
+	if ds.rows == 0 && (len(key) == 0 || onMissing == poolLookupOnMissing) {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "columns").
+			SetMsg("dataset is empty and cannot be used as a pool"))
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	affectedColumns := make(map[int]string, len(lookupColumns))
+	for _, c := range lookupColumns {
+		affectedColumns[c.columnIdx] = driver.Table.Columns[c.columnIdx].Name
+	}
+
+	lt := &LookupTransformer{
+		ds:              ds,
+		columns:         lookupColumns,
+		keyColumns:      keyColumns,
+		onMissing:       onMissing,
+		affectedColumns: affectedColumns,
+	}
+	if len(keyColumns) > 0 {
+		datasetIdxs := make([]int, len(keyColumns))
+		for idx, c := range keyColumns {
+			datasetIdxs[idx] = c.datasetIdx
+		}
+		lt.index = ds.getIndex(datasetIdxs)
+	}
+	if len(keyColumns) == 0 || onMissing == poolLookupOnMissing {
+		if lt.g, err = getGenerateEngine(ctx, engine, 8); err != nil {
+			return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+		}
+	}
+	return lt, warnings, nil
+}
+
+// getLookupColumns - resolves the map of the table column names to the dataset column names. The result is sorted
+// by the table column index, so the composite key has the same order on each run
+func getLookupColumns(
+	driver *toolkit.Driver, ds *lookupDataset, parameterName string, columns map[string]string,
+) ([]lookupColumn, toolkit.ValidationWarnings) {
+	var warnings toolkit.ValidationWarnings
+	res := make([]lookupColumn, 0, len(columns))
+	for name, datasetName := range columns {
+		columnIdx, _, ok := driver.GetColumnByName(name)
+		if !ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", parameterName).
+				AddMeta("ColumnName", name).
+				SetMsg("column is not found"))
+			continue
+		}
+		datasetIdx, ok := ds.getColumnIdx(datasetName)
+		if !ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", parameterName).
+				AddMeta("ColumnName", name).
+				AddMeta("DatasetColumnName", datasetName).
+				AddMeta("DatasetColumns", ds.columns).
+				SetMsg("dataset column is not found"))
+			continue
+		}
+		res = append(res, lookupColumn{columnIdx: columnIdx, datasetIdx: datasetIdx})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].columnIdx < res[j].columnIdx
+	})
+	return res, warnings
+}
+
+func (lt *LookupTransformer) GetAffectedColumns() map[int]string {
+	return lt.affectedColumns
+}
+
+func (lt *LookupTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (lt *LookupTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (lt *LookupTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	if len(lt.keyColumns) == 0 {
+		// The replacement is chosen by the original values, so the same values get the same replacement with
+		// the hash engine
+		buf, _, err := lt.appendColumnValues(lt.buf[:0], r, lt.columns)
+		lt.buf = buf
+		if err != nil {
+			return nil, err
+		}
+		row, err := lt.choose(buf)
+		if err != nil {
+			return nil, err
+		}
+		return r, lt.setValues(r, row)
+	}
+
+	buf, isNull, err := lt.appendColumnValues(lt.buf[:0], r, lt.keyColumns)
+	lt.buf = buf
+	if err != nil {
+		return nil, err
+	}
+	row, ok := 0, false
+	if !isNull {
+		row, ok = lt.index[string(buf)]
+	}
+	if ok {
+		return r, lt.setValues(r, row)
+	}
+
+	switch lt.onMissing {
+	case nullLookupOnMissing:
+		for _, c := range lt.columns {
+			if err = r.SetRawColumnValueByIdx(c.columnIdx, toolkit.NewRawValue(nil, true)); err != nil {
+				return nil, fmt.Errorf("unable to set new value: %w", err)
+			}
+		}
+	case errorLookupOnMissing:
+		return nil, errors.New("key is not found in the dataset")
+	case poolLookupOnMissing:
+		if row, err = lt.choose(buf); err != nil {
+			return nil, err
+		}
+		return r, lt.setValues(r, row)
+	}
+	return r, nil
+}
+
+// appendColumnValues - appends the composite key of the record values. It also returns true if any of the values
+// is NULL
+func (lt *LookupTransformer) appendColumnValues(
+	buf []byte, r *toolkit.Record, columns []lookupColumn,
+) ([]byte, bool, error) {
+	hasNull := false
+	for _, c := range columns {
+		v, err := r.GetRawColumnValueByIdx(c.columnIdx)
+		if err != nil {
+			return buf, false, fmt.Errorf("unable to scan attribute value: %w", err)
+		}
+		if v.IsNull {
+			hasNull = true
+			buf = appendLookupKeyPart(buf, []byte(defaultNullSeq))
+			continue
+		}
+		buf = appendLookupKeyPart(buf, v.Data)
+	}
+	return buf, hasNull, nil
+}
+
+func (lt *LookupTransformer) choose(data []byte) (int, error) {
+	res, err := lt.g.Generate(data)
+	if err != nil {
+		return 0, fmt.Errorf("unable to generate value: %w", err)
+	}
+	return int(binary.LittleEndian.Uint64(res) % uint64(lt.ds.rows)), nil
+}
+
+func (lt *LookupTransformer) setValues(r *toolkit.Record, row int) error {
+	for _, c := range lt.columns {
+		v, isNull := lt.ds.cell(row, c.datasetIdx)
+		if err := r.SetRawColumnValueByIdx(c.columnIdx, toolkit.NewRawValue(v, isNull)); err != nil {
+			return fmt.Errorf("unable to set new value: %w", err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(LookupTransformerDefinition)
+}
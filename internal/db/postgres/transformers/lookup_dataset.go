@@ -0,0 +1,407 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/eminano/greenmask/internal/utils"
+)
+
+const (
+	csvLookupFormat  = "csv"
+	jsonLookupFormat = "json"
+)
+
+// lookupDatasets - the loaded datasets shared by all the Lookup transformers of the process. The same source is
+// loaded once and its indexes are built once regardless of the number of tables and workers that use it
+var lookupDatasets = &lookupDatasetCache{
+	datasets: make(map[string]*lookupDatasetEntry),
+}
+
+type lookupDatasetCache struct {
+	mx       sync.Mutex
+	datasets map[string]*lookupDatasetEntry
+}
+
+type lookupDatasetEntry struct {
+	once sync.Once
+	ds   *lookupDataset
+	err  error
+}
+
+// get - returns the dataset by the key loading it with the load func if it has not been loaded yet
+func (c *lookupDatasetCache) get(key string, load func() (*lookupDataset, error)) (*lookupDataset, error) {
+	c.mx.Lock()
+	e, ok := c.datasets[key]
+	if !ok {
+		e = &lookupDatasetEntry{}
+		c.datasets[key] = e
+	}
+	c.mx.Unlock()
+
+	e.once.Do(func() {
+		e.ds, e.err = load()
+	})
+	return e.ds, e.err
+}
+
+// lookupDataset - the read-only table of text values. The values of all the cells are stored in the single buffer
+// row by row, so the dataset has no per-value allocations
+type lookupDataset struct {
+	columns []string
+	data    []byte
+	// ends - end offset of each cell in data
+	ends []uint32
+	// nulls - indexes of the cells that contain NULL
+	nulls map[int]struct{}
+	rows  int
+
+	mx      sync.Mutex
+	indexes map[string]map[string]int
+}
+
+func newLookupDataset(columns []string) (*lookupDataset, error) {
+	seen := make(map[string]struct{}, len(columns))
+	for _, name := range columns {
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("duplicate column \"%s\"", name)
+		}
+		seen[name] = struct{}{}
+	}
+	return &lookupDataset{
+		columns: columns,
+		nulls:   make(map[int]struct{}),
+		indexes: make(map[string]map[string]int),
+	}, nil
+}
+
+// appendRow - appends the row values. The nil value is NULL
+func (ds *lookupDataset) appendRow(values [][]byte) error {
+	if len(values) != len(ds.columns) {
+		return fmt.Errorf("expected %d values got %d", len(ds.columns), len(values))
+	}
+	for _, v := range values {
+		if len(ds.data)+len(v) > math.MaxUint32 {
+			return errors.New("dataset is too large")
+		}
+		if v == nil {
+			ds.nulls[len(ds.ends)] = struct{}{}
+		}
+		ds.data = append(ds.data, v...)
+		ds.ends = append(ds.ends, uint32(len(ds.data)))
+	}
+	ds.rows++
+	return nil
+}
+
+// compact - releases the unused capacity of the buffers after loading
+func (ds *lookupDataset) compact() {
+	ds.data = bytes.Clone(ds.data)
+	ends := make([]uint32, len(ds.ends))
+	copy(ends, ds.ends)
+	ds.ends = ends
+}
+
+func (ds *lookupDataset) getColumnIdx(name string) (int, bool) {
+	for idx, c := range ds.columns {
+		if c == name {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// cell - returns the value of the cell. The returned slice must not be modified
+func (ds *lookupDataset) cell(row, col int) ([]byte, bool) {
+	idx := row*len(ds.columns) + col
+	if _, ok := ds.nulls[idx]; ok {
+		return nil, true
+	}
+	var start uint32
+	if idx > 0 {
+		start = ds.ends[idx-1]
+	}
+	return ds.data[start:ds.ends[idx]:ds.ends[idx]], false
+}
+
+// getIndex - returns the index of the key columns values to the row number. The rows with NULL in any of the key
+// columns are not indexed and the first row wins if the key is duplicated
+func (ds *lookupDataset) getIndex(keyColumns []int) map[string]int {
+	var sig strings.Builder
+	for _, c := range keyColumns {
+		sig.WriteString(fmt.Sprintf("%d,", c))
+	}
+
+	ds.mx.Lock()
+	defer ds.mx.Unlock()
+	if idx, ok := ds.indexes[sig.String()]; ok {
+		return idx
+	}
+	idx := make(map[string]int, ds.rows)
+	var buf []byte
+	for row := 0; row < ds.rows; row++ {
+		buf = buf[:0]
+		isNull := false
+		for _, c := range keyColumns {
+			var v []byte
+			v, isNull = ds.cell(row, c)
+			if isNull {
+				break
+			}
+			buf = appendLookupKeyPart(buf, v)
+		}
+		if isNull {
+			continue
+		}
+		if _, ok := idx[string(buf)]; !ok {
+			idx[string(buf)] = row
+		}
+	}
+	ds.indexes[sig.String()] = idx
+	return idx
+}
+
+// appendLookupKeyPart - appends the length-prefixed value to the composite key, so the composite keys of different
+// values never collide
+func appendLookupKeyPart(buf, v []byte) []byte {
+	buf = fmt.Appendf(buf, "%d:", len(v))
+	return append(buf, v...)
+}
+
+func getLookupFileFormat(path, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case csvLookupFormat, jsonLookupFormat:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported file format \"%s\"", format)
+}
+
+// loadLookupFile - loads the dataset from the CSV file with the header or from the JSON file with the list of objects
+func loadLookupFile(path, format string) (*lookupDataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file: %w", err)
+	}
+	defer f.Close()
+
+	var ds *lookupDataset
+	switch format {
+	case csvLookupFormat:
+		ds, err = readLookupCsv(f)
+	case jsonLookupFormat:
+		ds, err = readLookupJson(f)
+	default:
+		return nil, fmt.Errorf("unsupported file format \"%s\"", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file \"%s\": %w", path, err)
+	}
+	ds.compact()
+	return ds, nil
+}
+
+func readLookupCsv(r io.Reader) (*lookupDataset, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("header is not found")
+		}
+		return nil, fmt.Errorf("unable to read header: %w", err)
+	}
+	ds, err := newLookupDataset(append([]string(nil), header...))
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(header))
+	for {
+		record, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		for idx, v := range record {
+			values[idx] = []byte(v)
+		}
+		if err = ds.appendRow(values); err != nil {
+			return nil, fmt.Errorf("line %d: %w", ds.rows+2, err)
+		}
+	}
+	return ds, nil
+}
+
+// readLookupJson - reads the list of objects. The columns are defined by the first object, the missing attributes
+// of the next objects are NULL. The objects and lists are stored as the JSON text
+func readLookupJson(r io.Reader) (*lookupDataset, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read list: %w", err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return nil, errors.New("expected list of objects")
+	}
+
+	var ds *lookupDataset
+	var values [][]byte
+	for dec.More() {
+		obj := make(map[string]json.RawMessage)
+		keys, err := decodeLookupJsonObject(dec, obj)
+		if err != nil {
+			return nil, fmt.Errorf("object %d: %w", rowsCount(ds), err)
+		}
+		if ds == nil {
+			if ds, err = newLookupDataset(keys); err != nil {
+				return nil, err
+			}
+			values = make([][]byte, len(keys))
+		}
+		for idx, name := range ds.columns {
+			v, ok := obj[name]
+			if !ok {
+				values[idx] = nil
+				continue
+			}
+			if values[idx], err = lookupJsonValue(v); err != nil {
+				return nil, fmt.Errorf("object %d attribute \"%s\": %w", ds.rows, name, err)
+			}
+		}
+		for name := range obj {
+			if _, ok := ds.getColumnIdx(name); !ok {
+				return nil, fmt.Errorf("object %d: unknown attribute \"%s\"", ds.rows, name)
+			}
+		}
+		if err = ds.appendRow(values); err != nil {
+			return nil, fmt.Errorf("object %d: %w", ds.rows, err)
+		}
+	}
+	if _, err = dec.Token(); err != nil {
+		return nil, fmt.Errorf("unable to read list end: %w", err)
+	}
+	if ds == nil {
+		return nil, errors.New("list is empty")
+	}
+	return ds, nil
+}
+
+func rowsCount(ds *lookupDataset) int {
+	if ds == nil {
+		return 0
+	}
+	return ds.rows
+}
+
+// decodeLookupJsonObject - decodes the object and returns its keys in the original order
+func decodeLookupJsonObject(dec *json.Decoder, obj map[string]json.RawMessage) ([]string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, errors.New("expected object")
+	}
+	var keys []string
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+		var v json.RawMessage
+		if err = dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if _, ok := obj[key]; ok {
+			return nil, fmt.Errorf("duplicate attribute \"%s\"", key)
+		}
+		obj[key] = v
+		keys = append(keys, key)
+	}
+	if _, err = dec.Token(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// lookupJsonValue - returns the text representation of the JSON value. The nil result is NULL
+func lookupJsonValue(v json.RawMessage) ([]byte, error) {
+	v = bytes.TrimSpace(v)
+	switch {
+	case bytes.Equal(v, []byte("null")):
+		return nil, nil
+	case len(v) > 0 && v[0] == '"':
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+	return bytes.Clone(v), nil
+}
+
+// loadLookupQuery - loads the dataset from the query result using the source database connection from the context
+func loadLookupQuery(ctx context.Context, query string) (*lookupDataset, error) {
+	q := utils.QuerierFromCtx(ctx)
+	if q == nil {
+		return nil, errors.New("source database connection is not available")
+	}
+	// The simple protocol returns the values in the text format
+	rows, err := q.Query(ctx, query, pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform query: %w", err)
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	columns := make([]string, len(fields))
+	for idx, f := range fields {
+		columns[idx] = f.Name
+	}
+	ds, err := newLookupDataset(columns)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		if err = ds.appendRow(rows.RawValues()); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read query result: %w", err)
+	}
+	ds.compact()
+	return ds, nil
+}
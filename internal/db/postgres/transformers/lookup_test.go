@@ -0,0 +1,268 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const lookupTestCsv = `country,region,name
+DE,BY,Bavaria
+DE,BE,Berlin
+US,CA,California
+US,BY,
+`
+
+const lookupTestJson = `[
+	{"first_name": "Ann", "last_name": "Lee"},
+	{"first_name": "Bob", "last_name": null},
+	{"first_name": "Eve", "last_name": "Moss"}
+]`
+
+func writeLookupTestFile(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	return path
+}
+
+func newLookupTestTransformer(
+	t *testing.T, params map[string]toolkit.ParamsValue,
+) (*toolkit.Driver, *LookupTransformer, toolkit.ValidationWarnings) {
+	// data, id2, id4
+	table := &toolkit.Table{
+		Schema:  "public",
+		Name:    "test",
+		Oid:     1224,
+		Columns: []*toolkit.Column{columnList[2], columnList[5], columnList[6]},
+	}
+	driver, warnings, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	ctx := context.WithValue(context.Background(), "salt", []byte("12345678"))
+	transformer, warnings, err := LookupTransformerDefinition.Instance(ctx, driver, params, nil, "")
+	require.NoError(t, err)
+	if transformer == nil || transformer.Transformer == nil {
+		return driver, nil, warnings
+	}
+	return driver, transformer.Transformer.(*LookupTransformer), warnings
+}
+
+func lookupTransform(t *testing.T, driver *toolkit.Driver, lt *LookupTransformer, line string) (string, error) {
+	row := pgcopy.NewRow(3)
+	require.NoError(t, row.Decode([]byte(line)))
+	record := toolkit.NewRecord(driver)
+	record.SetRow(row)
+	r, err := lt.Transform(context.Background(), record)
+	if err != nil {
+		return "", err
+	}
+	rowDriver, err := r.Encode()
+	require.NoError(t, err)
+	res, err := rowDriver.Encode()
+	require.NoError(t, err)
+	return string(res), nil
+}
+
+func TestLookupTransformer_Transform_key(t *testing.T) {
+	path := writeLookupTestFile(t, "regions.csv", lookupTestCsv)
+	tests := []struct {
+		name      string
+		onMissing string
+		original  string
+		expected  string
+		expectErr bool
+	}{
+		{name: "found", onMissing: "keep", original: "DE\tBE\t1", expected: "Berlin\tBE\t1"},
+		{name: "composite key", onMissing: "keep", original: "DE\tBY\t1", expected: "Bavaria\tBY\t1"},
+		{name: "empty value", onMissing: "keep", original: "US\tBY\t1", expected: "\tBY\t1"},
+		{name: "keep", onMissing: "keep", original: "FR\tBY\t1", expected: "FR\tBY\t1"},
+		{name: "null key", onMissing: "keep", original: "DE\t\\N\t1", expected: "DE\t\\N\t1"},
+		{name: "null", onMissing: "null", original: "FR\tBY\t1", expected: "\\N\tBY\t1"},
+		{name: "error", onMissing: "error", original: "FR\tBY\t1", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, lt, warnings := newLookupTestTransformer(t, map[string]toolkit.ParamsValue{
+				"file":       toolkit.ParamsValue(path),
+				"key":        toolkit.ParamsValue(`{"data": "country", "id2": "region"}`),
+				"columns":    toolkit.ParamsValue(`{"data": "name"}`),
+				"on_missing": toolkit.ParamsValue(tt.onMissing),
+			})
+			require.Empty(t, warnings)
+			res, err := lookupTransform(t, driver, lt, tt.original)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestLookupTransformer_Transform_pool(t *testing.T) {
+	path := writeLookupTestFile(t, "names.json", lookupTestJson)
+	params := map[string]toolkit.ParamsValue{
+		"file":    toolkit.ParamsValue(path),
+		"columns": toolkit.ParamsValue(`{"data": "first_name", "id4": "last_name"}`),
+		"engine":  toolkit.ParamsValue("hash"),
+	}
+	driver, lt, warnings := newLookupTestTransformer(t, params)
+	require.Empty(t, warnings)
+
+	res, err := lookupTransform(t, driver, lt, "John\t1\t\\N")
+	require.NoError(t, err)
+	assert.Contains(t, []string{"Ann\t1\tLee", "Bob\t1\t\\N", "Eve\t1\tMoss"}, res)
+
+	// The same original values get the same replacement
+	again, err := lookupTransform(t, driver, lt, "John\t2\t\\N")
+	require.NoError(t, err)
+	assert.Equal(t, res[:4], again[:4])
+
+	seen := make(map[string]struct{})
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		res, err = lookupTransform(t, driver, lt, name+"\t1\t\\N")
+		require.NoError(t, err)
+		seen[res] = struct{}{}
+	}
+	assert.Greater(t, len(seen), 1)
+}
+
+func TestLookupTransformer_Transform_missing_pool(t *testing.T) {
+	path := writeLookupTestFile(t, "regions.csv", lookupTestCsv)
+	driver, lt, warnings := newLookupTestTransformer(t, map[string]toolkit.ParamsValue{
+		"file":       toolkit.ParamsValue(path),
+		"key":        toolkit.ParamsValue(`{"data": "country", "id2": "region"}`),
+		"columns":    toolkit.ParamsValue(`{"data": "name"}`),
+		"on_missing": toolkit.ParamsValue("pool"),
+		"engine":     toolkit.ParamsValue("hash"),
+	})
+	require.Empty(t, warnings)
+	res, err := lookupTransform(t, driver, lt, "FR\tBY\t1")
+	require.NoError(t, err)
+	assert.Contains(t, []string{"Bavaria\tBY\t1", "Berlin\tBY\t1", "California\tBY\t1", "\tBY\t1"}, res)
+}
+
+func TestLookupTransformer_shared_dataset(t *testing.T) {
+	path := writeLookupTestFile(t, "regions.csv", lookupTestCsv)
+	params := map[string]toolkit.ParamsValue{
+		"file":    toolkit.ParamsValue(path),
+		"key":     toolkit.ParamsValue(`{"data": "country", "id2": "region"}`),
+		"columns": toolkit.ParamsValue(`{"data": "name"}`),
+	}
+	_, lt1, warnings := newLookupTestTransformer(t, params)
+	require.Empty(t, warnings)
+	_, lt2, warnings := newLookupTestTransformer(t, params)
+	require.Empty(t, warnings)
+	assert.Same(t, lt1.ds, lt2.ds)
+	assert.Equal(t, 4, lt1.ds.rows)
+	assert.Equal(t, len(lt1.index), len(lt2.index))
+	lt1.index["test"] = 0
+	assert.Contains(t, lt2.index, "test")
+}
+
+func TestLookupTransformer_validation(t *testing.T) {
+	csvPath := writeLookupTestFile(t, "regions.csv", lookupTestCsv)
+	tests := []struct {
+		name   string
+		params map[string]toolkit.ParamsValue
+		msg    string
+	}{
+		{
+			name: "no source",
+			params: map[string]toolkit.ParamsValue{
+				"columns": toolkit.ParamsValue(`{"data": "name"}`),
+			},
+			msg: `either "file" or "query" parameter must be specified`,
+		},
+		{
+			name: "unknown format",
+			params: map[string]toolkit.ParamsValue{
+				"file":    toolkit.ParamsValue("regions.txt"),
+				"columns": toolkit.ParamsValue(`{"data": "name"}`),
+			},
+			msg: "unsupported file format",
+		},
+		{
+			name: "file not found",
+			params: map[string]toolkit.ParamsValue{
+				"file":    toolkit.ParamsValue(filepath.Join(t.TempDir(), "missing.csv")),
+				"columns": toolkit.ParamsValue(`{"data": "name"}`),
+			},
+			msg: "unable to load dataset",
+		},
+		{
+			name: "query without connection",
+			params: map[string]toolkit.ParamsValue{
+				"query":   toolkit.ParamsValue("select name from regions"),
+				"columns": toolkit.ParamsValue(`{"data": "name"}`),
+			},
+			msg: "unable to load dataset",
+		},
+		{
+			name: "unknown dataset column",
+			params: map[string]toolkit.ParamsValue{
+				"file":    toolkit.ParamsValue(csvPath),
+				"columns": toolkit.ParamsValue(`{"data": "city"}`),
+			},
+			msg: "dataset column is not found",
+		},
+		{
+			name: "null without key",
+			params: map[string]toolkit.ParamsValue{
+				"file":       toolkit.ParamsValue(csvPath),
+				"columns":    toolkit.ParamsValue(`{"data": "name"}`),
+				"on_missing": toolkit.ParamsValue("null"),
+			},
+			msg: "action cannot be used without key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, warnings := newLookupTestTransformer(t, tt.params)
+			require.True(t, warnings.IsFatal())
+			assert.Equal(t, tt.msg, warnings[0].Msg)
+		})
+	}
+}
+
+func TestReadLookupJson(t *testing.T) {
+	ds, err := readLookupJson(bytes.NewBufferString(
+		`[{"id": 1, "tags": ["a"], "ok": true}, {"id": 2.5, "ok": false}]`,
+	))
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "tags", "ok"}, ds.columns)
+	v, isNull := ds.cell(0, 1)
+	assert.False(t, isNull)
+	assert.Equal(t, `["a"]`, string(v))
+	v, _ = ds.cell(1, 0)
+	assert.Equal(t, "2.5", string(v))
+	_, isNull = ds.cell(1, 1)
+	assert.True(t, isNull)
+
+	_, err = readLookupJson(bytes.NewBufferString(`[{"id": 1}, {"name": "a"}]`))
+	require.ErrorContains(t, err, `unknown attribute "name"`)
+}
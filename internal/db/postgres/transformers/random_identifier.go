@@ -0,0 +1,177 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/generators/transformers"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const RandomIdentifierTransformerName = "RandomIdentifier"
+
+var RandomIdentifierTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		RandomIdentifierTransformerName,
+		"Generate national identifier or financial number that passes the format and checksum validation",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, true),
+
+	NewRandomIdentifierTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes("text", "varchar", "bpchar"),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"kind",
+		fmt.Sprintf("identifier kind. Possible values: %s", strings.Join(transformers.IdentifierKinds, ", ")),
+	).SetRequired(true).
+		SetRawValueValidator(validateRandomIdentifierKindParameter),
+
+	toolkit.MustNewParameterDefinition(
+		"keep_prefix",
+		"keep the prefix of the original value: SSN area number, IBAN country code, card issuer identification "+
+			"number, NINO prefix letters or CPF fiscal region digit",
+	).SetDefaultValue([]byte("false")),
+
+	keepNullParameterDefinition,
+
+	engineParameterDefinition,
+)
+
+type RandomIdentifierTransformer struct {
+	t               *transformers.RandomIdentifierTransformer
+	columnName      string
+	columnIdx       int
+	keepNull        bool
+	affectedColumns map[int]string
+}
+
+func NewRandomIdentifierTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, kind, engine string
+	var keepPrefix, keepNull bool
+
+	p := parameters["column"]
+	if err := p.Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "column" param: %w`, err)
+	}
+	idx, _, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	p = parameters["kind"]
+	if err := p.Scan(&kind); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "kind" param: %w`, err)
+	}
+
+	p = parameters["keep_prefix"]
+	if err := p.Scan(&keepPrefix); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "keep_prefix" param: %w`, err)
+	}
+
+	p = parameters["keep_null"]
+	if err := p.Scan(&keepNull); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "keep_null" param: %w`, err)
+	}
+
+	p = parameters["engine"]
+	if err := p.Scan(&engine); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "engine" param: %w`, err)
+	}
+
+	t, err := transformers.NewRandomIdentifierTransformer(kind, keepPrefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create identifier transformer: %w", err)
+	}
+	g, err := getGenerateEngine(ctx, engine, t.GetRequiredGeneratorByteLength())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+	}
+	if err = t.SetGenerator(g); err != nil {
+		return nil, nil, fmt.Errorf("unable to set generator: %w", err)
+	}
+
+	return &RandomIdentifierTransformer{
+		t:               t,
+		columnName:      columnName,
+		columnIdx:       idx,
+		keepNull:        keepNull,
+		affectedColumns: affectedColumns,
+	}, nil, nil
+}
+
+func (rit *RandomIdentifierTransformer) GetAffectedColumns() map[int]string {
+	return rit.affectedColumns
+}
+
+func (rit *RandomIdentifierTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (rit *RandomIdentifierTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (rit *RandomIdentifierTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(rit.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	if val.IsNull && rit.keepNull {
+		return r, nil
+	}
+
+	res, err := rit.t.Transform(val.Data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to transform value: %w", err)
+	}
+	if err = r.SetRawColumnValueByIdx(rit.columnIdx, toolkit.NewRawValue(res, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func validateRandomIdentifierKindParameter(
+	p *toolkit.ParameterDefinition, v toolkit.ParamsValue,
+) (toolkit.ValidationWarnings, error) {
+	if !slices.Contains(transformers.IdentifierKinds, string(v)) {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterValue", string(v)).
+				SetMsg("unknown identifier kind"),
+		}, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(RandomIdentifierTransformerDefinition)
+}
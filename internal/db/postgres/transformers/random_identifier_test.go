@@ -0,0 +1,100 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func TestRandomIdentifierTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		params   map[string]toolkit.ParamsValue
+		pattern  string
+		isNull   bool
+	}{
+		{
+			name:     "ssn",
+			original: "123-45-6789",
+			params: map[string]toolkit.ParamsValue{
+				"kind": toolkit.ParamsValue("ssn"),
+			},
+			pattern: `^\d{3}-\d{2}-\d{4}$`,
+		},
+		{
+			name:     "iban keep country",
+			original: "DE89370400440532013000",
+			params: map[string]toolkit.ParamsValue{
+				"kind":        toolkit.ParamsValue("iban"),
+				"keep_prefix": toolkit.ParamsValue("true"),
+				"engine":      toolkit.ParamsValue("hash"),
+			},
+			pattern: `^DE\d{20}$`,
+		},
+		{
+			name:     "keep null",
+			original: "\\N",
+			params: map[string]toolkit.ParamsValue{
+				"kind": toolkit.ParamsValue("card"),
+			},
+			isNull: true,
+		},
+		{
+			name:     "generate on null",
+			original: "\\N",
+			params: map[string]toolkit.ParamsValue{
+				"kind":      toolkit.ParamsValue("nino"),
+				"keep_null": toolkit.ParamsValue("false"),
+			},
+			pattern: `^[A-Z]{2}\d{6}[A-D]$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("data")
+			driver, record := getDriverAndRecord("data", tt.original)
+			transformer, warnings, err := RandomIdentifierTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+			r, err := transformer.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName("data")
+			require.NoError(t, err)
+			require.Equal(t, tt.isNull, res.IsNull)
+			if !tt.isNull {
+				require.Regexp(t, tt.pattern, string(res.Data))
+			}
+		})
+	}
+}
+
+func TestRandomIdentifierTransformer_unknownKind(t *testing.T) {
+	driver, _ := getDriverAndRecord("data", "test")
+	params := map[string]toolkit.ParamsValue{
+		"column": toolkit.ParamsValue("data"),
+		"kind":   toolkit.ParamsValue("passport"),
+	}
+	_, warnings, err := RandomIdentifierTransformerDefinition.Instance(context.Background(), driver, params, nil, "")
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+}
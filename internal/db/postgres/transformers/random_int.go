@@ -83,6 +83,8 @@ type IntegerTransformer struct {
 	columnIdx       int
 	dynamicMode     bool
 	intSize         int
+	engine          string
+	limiter         *transformers.Int64Limiter
 
 	columnParam   toolkit.Parameterizer
 	maxParam      toolkit.Parameterizer
@@ -186,6 +188,8 @@ func NewIntegerTransformer(ctx context.Context, driver *toolkit.Driver, paramete
 
 		dynamicMode: dynamicMode,
 		intSize:     intSize,
+		engine:      engine,
+		limiter:     limiter,
 
 		transform: func(bytes []byte) (int64, error) {
 			return t.Transform(nil, bytes)
@@ -252,6 +256,19 @@ func (rit *IntegerTransformer) Transform(ctx context.Context, r *toolkit.Record)
 	return r, nil
 }
 
+// PushdownExpressions - generates the value by PostgreSQL random function in the same [min, max) range. Only the
+// random engine with the static thresholds can be pushed down
+func (rit *IntegerTransformer) PushdownExpressions() (map[int]string, bool) {
+	if rit.engine != RandomEngineParameterName || rit.dynamicMode {
+		return nil, false
+	}
+	expr := fmt.Sprintf(
+		"(%d + floor(random()::numeric * %d))::int8",
+		rit.limiter.MinValue, uint64(rit.limiter.MaxValue-rit.limiter.MinValue),
+	)
+	return map[int]string{rit.columnIdx: utils.NullSafeExpression(rit.columnName, expr, rit.keepNull)}, true
+}
+
 func getIntThresholds(size int) (int64, int64, error) {
 	switch size {
 	case Int2Length:
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
@@ -313,3 +314,64 @@ func TestRandomIntTransformer_Transform_deterministic_dynamic(t *testing.T) {
 		})
 	}
 }
+
+func TestRandomIntTransformer_PushdownExpressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]toolkit.ParamsValue
+		ok       bool
+		expected string
+	}{
+		{
+			name: "random",
+			params: map[string]toolkit.ParamsValue{
+				"min":       toolkit.ParamsValue("1"),
+				"max":       toolkit.ParamsValue("100"),
+				"keep_null": toolkit.ParamsValue("false"),
+				"engine":    toolkit.ParamsValue("random"),
+			},
+			ok:       true,
+			expected: "(1 + floor(random()::numeric * 99))::int8",
+		},
+		{
+			name: "random keep null",
+			params: map[string]toolkit.ParamsValue{
+				"min":    toolkit.ParamsValue("-10"),
+				"max":    toolkit.ParamsValue("10"),
+				"engine": toolkit.ParamsValue("random"),
+			},
+			ok:       true,
+			expected: `CASE WHEN "id" IS NULL THEN NULL ELSE (-10 + floor(random()::numeric * 20))::int8 END`,
+		},
+		{
+			name: "hash engine is not supported",
+			params: map[string]toolkit.ParamsValue{
+				"min":    toolkit.ParamsValue("1"),
+				"max":    toolkit.ParamsValue("100"),
+				"engine": toolkit.ParamsValue("hash"),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["column"] = toolkit.ParamsValue("id")
+			driver, _ := getDriverAndRecord("id", "1")
+			transformer, warnings, err := integerTransformerDefinition.Instance(
+				context.Background(),
+				driver, tt.params,
+				nil,
+				"",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			exprs, ok := transformer.Transformer.(utils.PushdownTransformer).PushdownExpressions()
+			require.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, map[int]string{0: tt.expected}, exprs)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 	"text/template"
 
 	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
@@ -24,7 +25,7 @@ const RandomPersonTransformerName = "RandomPerson"
 var randomPersonTransformerDefinition = utils.NewTransformerDefinition(
 	utils.NewTransformerProperties(
 		RandomPersonTransformerName,
-		"Generate random person data (Title, FirstName, LastName, Gender)",
+		"Generate random person data (Title, FirstName, LastName, Gender, address and phone) for the locale",
 	),
 
 	NewRandomNameTransformer,
@@ -53,7 +54,19 @@ var randomPersonTransformerDefinition = utils.NewTransformerDefinition(
 	).SetSupportTemplate(true).
 		SetDefaultValue(toolkit.ParamsValue("Any")),
 
-	// TODO: Allow user to override the default names, surnames and genders with kind of dictionary
+	toolkit.MustNewParameterDefinition(
+		"locale",
+		fmt.Sprintf(
+			"locale of the built-in dataset. Possible values: %s",
+			strings.Join(transformers.GetPersonLocales(), ", "),
+		),
+	).SetDefaultValue(toolkit.ParamsValue(transformers.DefaultPersonLocale)).
+		SetRawValueValidator(validateRandomPersonLocaleParameter),
+
+	toolkit.MustNewParameterDefinition(
+		"dataset_file",
+		"path to the YAML or JSON file with the custom dataset. The values of the file extend the locale dataset",
+	),
 
 	engineParameterDefinition,
 )
@@ -84,7 +97,7 @@ type RandomNameTransformer struct {
 }
 
 func NewRandomNameTransformer(ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer) (utils.Transformer, toolkit.ValidationWarnings, error) {
-	var engine, fallbackGender string
+	var engine, fallbackGender, locale, datasetFile string
 	var dynamicMode bool
 	var columns []*randomNameColumns
 	var warns toolkit.ValidationWarnings
@@ -111,7 +124,42 @@ func NewRandomNameTransformer(ctx context.Context, driver *toolkit.Driver, param
 		engineMode = hashEngineMode
 	}
 
-	t := transformers.NewRandomPersonTransformer(gender, nil)
+	p := parameters["locale"]
+	if err := p.Scan(&locale); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "locale" param: %w`, err)
+	}
+	dataset := transformers.PersonLocaleDatasets[locale]
+
+	p = parameters["dataset_file"]
+	if err := p.Scan(&datasetFile); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "dataset_file" param: %w`, err)
+	}
+	if datasetFile != "" {
+		customDataset, err := transformers.LoadPersonDatasetFile(datasetFile)
+		if err != nil {
+			return nil, toolkit.ValidationWarnings{
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "dataset_file").
+					AddMeta("ParameterValue", datasetFile).
+					AddMeta("Error", err.Error()).
+					SetMsg("unable to load dataset"),
+			}, nil
+		}
+		if err = customDataset.Validate(); err != nil {
+			return nil, toolkit.ValidationWarnings{
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "dataset_file").
+					AddMeta("ParameterValue", datasetFile).
+					AddMeta("Error", err.Error()).
+					SetMsg("invalid dataset"),
+			}, nil
+		}
+		dataset = dataset.Extend(customDataset)
+	}
+
+	t := transformers.NewRandomPersonTransformerFromDataset(gender, dataset)
 
 	g, err := getGenerateEngine(ctx, engine, t.GetRequiredGeneratorByteLength())
 	if err != nil {
@@ -177,7 +225,7 @@ func NewRandomNameTransformer(ctx context.Context, driver *toolkit.Driver, param
 		genderParam:     genderParam,
 		affectedColumns: affectedColumns,
 		dynamicMode:     dynamicMode,
-		originalData:    make([]byte, 256),
+		originalData:    make([]byte, 0, 256),
 		engine:          engineMode,
 		buf:             bytes.NewBuffer(nil),
 		nullableMap:     make(map[int]bool, len(columns)),
@@ -211,7 +259,7 @@ func (nft *RandomNameTransformer) Transform(ctx context.Context, r *toolkit.Reco
 
 	// if we are in hash engine mode, we need to clear buffer before filling it with new data
 	if nft.engine == hashEngineMode {
-		clear(nft.originalData)
+		nft.originalData = nft.originalData[:0]
 		for _, c := range nft.columns {
 			rawVal, err := r.GetRawColumnValueByIdx(c.columnIdx)
 			if err != nil {
@@ -264,6 +312,21 @@ func randomNameTransformerValidateGender(gender string, genders []string) toolki
 	return nil
 }
 
+func validateRandomPersonLocaleParameter(
+	p *toolkit.ParameterDefinition, v toolkit.ParamsValue,
+) (toolkit.ValidationWarnings, error) {
+	if _, ok := transformers.PersonLocaleDatasets[string(v)]; !ok {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterValue", string(v)).
+				AddMeta("AllowedValues", transformers.GetPersonLocales()).
+				SetMsg("unknown locale"),
+		}, nil
+	}
+	return nil, nil
+}
+
 func validateColumnsAndSetDefault(driver *toolkit.Driver, columns []*randomNameColumns, engineMode int, attributes []string) (map[int]string, toolkit.ValidationWarnings) {
 	affectedColumns := make(map[int]string)
 	var warns toolkit.ValidationWarnings
@@ -2,6 +2,8 @@ package transformers
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
@@ -169,3 +171,85 @@ func TestRandomPersonTransformer_Transform_static_nullable(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, rawVal.IsNull)
 }
+
+func TestRandomPersonTransformer_Transform_locale(t *testing.T) {
+	datasetFile := filepath.Join(t.TempDir(), "dataset.yml")
+	require.NoError(t, os.WriteFile(datasetFile, []byte(`
+genders:
+  Male:
+    FirstName: ["Hinnerk"]
+common:
+  Country: ["Deutschland"]
+`), 0o644))
+
+	params := map[string]toolkit.ParamsValue{
+		"columns": toolkit.ParamsValue(
+			`[{"name": "data", "template": "{{ .FirstName }} {{ .LastName }}, {{ .PostalCode }} {{ .City }}, {{ .Country }}"}]`,
+		),
+		"engine":       toolkit.ParamsValue("hash"),
+		"gender":       toolkit.ParamsValue("Male"),
+		"locale":       toolkit.ParamsValue("de"),
+		"dataset_file": toolkit.ParamsValue(datasetFile),
+	}
+
+	transform := func(value string) string {
+		driver, record := getDriverAndRecord("data", value)
+		transformer, warnings, err := randomPersonTransformerDefinition.Instance(
+			context.Background(), driver, params, nil, "",
+		)
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		r, err := transformer.Transformer.Transform(context.Background(), record)
+		require.NoError(t, err)
+		rawVal, err := r.GetRawColumnValueByName("data")
+		require.NoError(t, err)
+		return string(rawVal.Data)
+	}
+
+	res := transform("John Doe")
+	require.Regexp(t, `^\S+ \S+, \d{5} [^,]+, Deutschland$`, res)
+	dataset := transformers.PersonLocaleDatasets[transformers.DePersonLocale]
+	require.True(t, testStringContainsOneOfItemFromList(res, dataset.Common["City"]))
+	// The hash engine generates the same value for the same input across the records
+	require.Equal(t, res, transform("John Doe"))
+}
+
+func TestRandomPersonTransformer_Transform_locale_validation(t *testing.T) {
+	driver, _ := getDriverAndRecord("data", "test")
+	datasetFile := filepath.Join(t.TempDir(), "dataset.yml")
+	require.NoError(t, os.WriteFile(datasetFile, []byte(`genders: {Male: {FirstName: []}}`), 0o644))
+
+	tests := []struct {
+		name   string
+		params map[string]toolkit.ParamsValue
+	}{
+		{
+			name: "unknown locale",
+			params: map[string]toolkit.ParamsValue{
+				"locale": toolkit.ParamsValue("fr"),
+			},
+		},
+		{
+			name: "missing dataset file",
+			params: map[string]toolkit.ParamsValue{
+				"dataset_file": toolkit.ParamsValue(filepath.Join(t.TempDir(), "missing.yml")),
+			},
+		},
+		{
+			name: "invalid dataset",
+			params: map[string]toolkit.ParamsValue{
+				"dataset_file": toolkit.ParamsValue(datasetFile),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["columns"] = toolkit.ParamsValue(`[{"name": "data", "template": "{{ .FirstName }}"}]`)
+			_, warnings, err := randomPersonTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "",
+			)
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+		})
+	}
+}
@@ -150,6 +150,12 @@ func (rt *ReplaceTransformer) Transform(ctx context.Context, r *toolkit.Record)
 	return r, nil
 }
 
+// PushdownExpressions - replaces the value by the literal in the COPY query
+func (rt *ReplaceTransformer) PushdownExpressions() (map[int]string, bool) {
+	expr := utils.NullSafeExpression(rt.columnName, utils.QuoteLiteral(string(rt.rawValue.Data)), rt.keepNull)
+	return map[int]string{rt.columnIdx: expr}, true
+}
+
 func init() {
 	utils.DefaultTransformerRegistry.MustRegister(ReplaceTransformerDefinition)
 }
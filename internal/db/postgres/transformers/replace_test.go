@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
 
@@ -193,3 +194,47 @@ func TestReplaceTransformer_Transform_with_validation_error(t *testing.T) {
 	assert.NotEmpty(t, warnings)
 	assert.Equal(t, warnings[0].Severity, toolkit.ErrorValidationSeverity)
 }
+
+func TestReplaceTransformer_PushdownExpressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]toolkit.ParamsValue
+		expected string
+	}{
+		{
+			name: "keep null",
+			params: map[string]toolkit.ParamsValue{
+				"column": toolkit.ParamsValue("data"),
+				"value":  toolkit.ParamsValue("it's"),
+			},
+			expected: `CASE WHEN "data" IS NULL THEN NULL ELSE 'it''s' END`,
+		},
+		{
+			name: "replace null",
+			params: map[string]toolkit.ParamsValue{
+				"column":    toolkit.ParamsValue("data"),
+				"value":     toolkit.ParamsValue(`a\b`),
+				"keep_null": toolkit.ParamsValue("false"),
+			},
+			expected: `E'a\\b'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, _ := getDriverAndRecord(string(tt.params["column"]), "test")
+			transformerCtx, warnings, err := ReplaceTransformerDefinition.Instance(
+				context.Background(),
+				driver, tt.params,
+				nil,
+				"",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+
+			exprs, ok := transformerCtx.Transformer.(utils.PushdownTransformer).PushdownExpressions()
+			require.True(t, ok)
+			assert.Equal(t, map[int]string{0: tt.expected}, exprs)
+		})
+	}
+}
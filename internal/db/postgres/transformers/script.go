@@ -0,0 +1,315 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const ScriptTransformerName = "Script"
+
+const scriptTransformFuncName = "transform"
+
+var ScriptTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		ScriptTransformerName,
+		"Modify the record using JavaScript",
+	),
+	NewScriptTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"script",
+		"JavaScript code that defines the transform(record) function called for each record",
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"columns",
+		"columns that supposed to be affected by the script. The list of columns will be checked for constraint violation",
+	).SetRequired(false).
+		SetDefaultValue(toolkit.ParamsValue("[]")),
+
+	toolkit.MustNewParameterDefinition(
+		"timeout",
+		"timeout of the transform function call for each record",
+	).SetDefaultValue([]byte("2s")),
+)
+
+// scriptPrograms - the compiled scripts shared by the transformers. The program is immutable and can be run by
+// several runtimes, so the same script is compiled once for all the tables
+var scriptPrograms = &scriptProgramCache{
+	programs: make(map[string]*goja.Program),
+}
+
+type scriptProgramCache struct {
+	mx       sync.Mutex
+	programs map[string]*goja.Program
+}
+
+func (c *scriptProgramCache) get(src string) (*goja.Program, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if p, ok := c.programs[src]; ok {
+		return p, nil
+	}
+	p, err := goja.Compile("script", src, true)
+	if err != nil {
+		return nil, err
+	}
+	c.programs[src] = p
+	return p, nil
+}
+
+type ScriptTransformer struct {
+	affectedColumns map[int]string
+	vm              *goja.Runtime
+	transform       goja.Callable
+	record          goja.Value
+	rctx            *scriptRecordContext
+	timeout         time.Duration
+}
+
+func NewScriptTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var script string
+	var columns []string
+	var timeout time.Duration
+	if err := parameters["script"].Scan(&script); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"script\" param: %w", err)
+	}
+	if err := parameters["columns"].Scan(&columns); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"columns\" param: %w", err)
+	}
+	if err := parameters["timeout"].Scan(&timeout); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"timeout\" param: %w", err)
+	}
+
+	var warnings toolkit.ValidationWarnings
+	affectedColumns := make(map[int]string)
+	for num, columnName := range columns {
+		idx, column, ok := driver.GetColumnByName(columnName)
+		if !ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				AddMeta("ElementNum", num).
+				AddMeta("ColumnName", columnName).
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				SetMsg("column not found"))
+			continue
+		}
+
+		warns := utils.ValidateSchema(driver.Table, column, nil)
+		warnings = append(warnings, warns...)
+
+		affectedColumns[idx] = columnName
+	}
+
+	program, err := scriptPrograms.get(script)
+	if err != nil {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			AddMeta("ParameterName", "script").
+			AddMeta("Error", err.Error()).
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			SetMsg("unable to compile script"))
+		return nil, warnings, nil
+	}
+
+	// Each transformer has its own runtime, so the global variables of the script keep the state between the
+	// records of the table
+	vm := goja.New()
+	for name, fn := range scriptFuncMap() {
+		if err = vm.Set(name, fn); err != nil {
+			return nil, nil, fmt.Errorf("unable to set function \"%s\": %w", name, err)
+		}
+	}
+	if err = vm.Set("state", vm.NewObject()); err != nil {
+		return nil, nil, fmt.Errorf("unable to set state: %w", err)
+	}
+	if err = vm.Set("table", map[string]string{"schema": driver.Table.Schema, "name": driver.Table.Name}); err != nil {
+		return nil, nil, fmt.Errorf("unable to set table: %w", err)
+	}
+	if _, err = vm.RunProgram(program); err != nil {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			AddMeta("ParameterName", "script").
+			AddMeta("Error", err.Error()).
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			SetMsg("unable to run script"))
+		return nil, warnings, nil
+	}
+	// The function might be declared with let or const that are not the properties of the global object
+	transformValue, err := vm.RunString(fmt.Sprintf(`typeof %[1]s === "undefined" ? undefined : %[1]s`, scriptTransformFuncName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get transform function: %w", err)
+	}
+	transform, ok := goja.AssertFunction(transformValue)
+	if !ok {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			AddMeta("ParameterName", "script").
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			SetMsg(fmt.Sprintf("script must define function \"%s\"", scriptTransformFuncName)))
+		return nil, warnings, nil
+	}
+
+	rctx := &scriptRecordContext{RecordContext: toolkit.NewRecordContext()}
+	return &ScriptTransformer{
+		affectedColumns: affectedColumns,
+		vm:              vm,
+		transform:       transform,
+		record:          vm.ToValue(rctx),
+		rctx:            rctx,
+		timeout:         timeout,
+	}, warnings, nil
+}
+
+func (st *ScriptTransformer) GetAffectedColumns() map[int]string {
+	return st.affectedColumns
+}
+
+func (st *ScriptTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (st *ScriptTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (st *ScriptTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	st.rctx.SetRecord(r)
+	defer st.rctx.Clean()
+
+	ctx, cancel := context.WithTimeout(ctx, st.timeout)
+	defer cancel()
+	// The script is interrupted when the timeout is exceeded or the dump is cancelled
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		st.vm.Interrupt(ctx.Err())
+		close(interrupted)
+	})
+	_, err := st.transform(goja.Undefined(), st.record)
+	if !stop() {
+		<-interrupted
+	}
+	// The interruption might be requested after the call is completed, so it is cleared for the next record
+	st.vm.ClearInterrupt()
+
+	if err != nil {
+		var interruptedErr *goja.InterruptedError
+		if errors.As(err, &interruptedErr) {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, utils.ErrRowTransformationTimeout
+			}
+			return nil, fmt.Errorf("error executing script: %w", ctx.Err())
+		}
+		var exception *goja.Exception
+		if errors.As(err, &exception) {
+			return nil, fmt.Errorf("error executing script: %s", exception.Error())
+		}
+		return nil, fmt.Errorf("error executing script: %w", err)
+	}
+	return r, nil
+}
+
+// scriptFuncMap - the template functions available in the script. The functions that rely on the template NULL
+// value are replaced with the ones that use the JavaScript null
+func scriptFuncMap() map[string]any {
+	funcs := make(map[string]any)
+	for name, fn := range toolkit.FuncMap() {
+		funcs[name] = fn
+	}
+	// null is the keyword in JavaScript
+	delete(funcs, "null")
+	funcs["isNull"] = func(v any) bool {
+		return v == nil
+	}
+	funcs["isNotNull"] = func(v any) bool {
+		return v != nil
+	}
+	funcs["sqlCoalesce"] = func(vv ...any) any {
+		for _, v := range vv {
+			if v != nil {
+				return v
+			}
+		}
+		return nil
+	}
+	return funcs
+}
+
+// scriptRecordContext - the RecordContext that represents NULL as the JavaScript null instead of the template NULL
+// value
+type scriptRecordContext struct {
+	*toolkit.RecordContext
+}
+
+func fromScriptValue(v any) any {
+	if v == nil {
+		return toolkit.NullValue
+	}
+	return v
+}
+
+func toScriptValue(v any, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(toolkit.NullType); ok {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (src *scriptRecordContext) GetColumnValue(name string) (any, error) {
+	return toScriptValue(src.RecordContext.GetColumnValue(name))
+}
+
+func (src *scriptRecordContext) GetRawColumnValue(name string) (any, error) {
+	return toScriptValue(src.RecordContext.GetRawColumnValue(name))
+}
+
+func (src *scriptRecordContext) SetColumnValue(name string, v any) (bool, error) {
+	return src.RecordContext.SetColumnValue(name, fromScriptValue(v))
+}
+
+func (src *scriptRecordContext) SetRawColumnValue(name string, v any) (bool, error) {
+	return src.RecordContext.SetRawColumnValue(name, fromScriptValue(v))
+}
+
+func (src *scriptRecordContext) EncodeValueByColumn(name string, v any) (any, error) {
+	return toScriptValue(src.RecordContext.EncodeValueByColumn(name, fromScriptValue(v)))
+}
+
+func (src *scriptRecordContext) DecodeValueByColumn(name string, v any) (any, error) {
+	return toScriptValue(src.RecordContext.DecodeValueByColumn(name, fromScriptValue(v)))
+}
+
+func (src *scriptRecordContext) EncodeValueByType(name string, v any) (any, error) {
+	return toScriptValue(src.RecordContext.EncodeValueByType(name, fromScriptValue(v)))
+}
+
+func (src *scriptRecordContext) DecodeValueByType(name string, v any) (any, error) {
+	return toScriptValue(src.RecordContext.DecodeValueByType(name, fromScriptValue(v)))
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(ScriptTransformerDefinition)
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func scriptTransform(t *testing.T, tc *utils.TransformerContext, columnName, original string) (string, error) {
+	_, record := getDriverAndRecord(columnName, original)
+	r, err := tc.Transformer.Transform(context.Background(), record)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := r.Encode()
+	require.NoError(t, err)
+	res, err := encoded.Encode()
+	require.NoError(t, err)
+	return string(res), nil
+}
+
+func TestScriptTransformer_Transform(t *testing.T) {
+	var script = `
+		state.count = 0;
+
+		const transform = (record) => {
+			state.count++;
+			const val = record.GetColumnValue("id4");
+			if (val === null) {
+				record.SetColumnValue("id4", -1);
+				return;
+			}
+			record.SetColumnValue("id4", val * 10 + state.count);
+		};
+	`
+
+	tests := []struct {
+		name     string
+		original string
+		expected string
+	}{
+		{
+			name:     "first record",
+			original: "1",
+			expected: "11",
+		},
+		{
+			name:     "null",
+			original: "\\N",
+			expected: "-1",
+		},
+		{
+			name:     "state is kept",
+			original: "5",
+			expected: "53",
+		},
+	}
+
+	driver, _ := getDriverAndRecord("id4", "\\N")
+	transformerCtx, warnings, err := ScriptTransformerDefinition.Instance(
+		context.Background(),
+		driver, map[string]toolkit.ParamsValue{
+			"script":  toolkit.ParamsValue(script),
+			"columns": toolkit.ParamsValue(`["id4"]`),
+		},
+		nil,
+		"",
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := scriptTransform(t, transformerCtx, "id4", tt.original)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestScriptTransformer_Transform_functions(t *testing.T) {
+	var script = `
+		function transform(record) {
+			const doc = record.GetRawColumnValue("doc");
+			if (isNull(doc)) {
+				record.SetRawColumnValue("doc", null);
+				return;
+			}
+			record.SetRawColumnValue("doc", jsonValidate(jsonSet("name", upper(jsonGet("name", doc)), doc)));
+		}
+	`
+
+	driver, _ := getDriverAndRecord("doc", "\\N")
+	transformerCtx, warnings, err := ScriptTransformerDefinition.Instance(
+		context.Background(),
+		driver, map[string]toolkit.ParamsValue{
+			"script": toolkit.ParamsValue(script),
+		},
+		nil,
+		"",
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	res, err := scriptTransform(t, transformerCtx, "doc", `{"name": "test"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name": "TEST"}`, res)
+
+	res, err = scriptTransform(t, transformerCtx, "doc", `\N`)
+	require.NoError(t, err)
+	assert.Equal(t, `\N`, res)
+
+	_, err = scriptTransform(t, transformerCtx, "doc", `{"name": "test"`)
+	require.Error(t, err)
+}
+
+func TestScriptTransformer_validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		msg    string
+	}{
+		{
+			name:   "syntax error",
+			script: `function transform(record) {`,
+			msg:    "unable to compile script",
+		},
+		{
+			name:   "runtime error",
+			script: `unknownFunction();`,
+			msg:    "unable to run script",
+		},
+		{
+			name:   "no transform function",
+			script: `const transform = 1;`,
+			msg:    `script must define function "transform"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, _ := getDriverAndRecord("id4", "\\N")
+			_, warnings, err := ScriptTransformerDefinition.Instance(
+				context.Background(),
+				driver, map[string]toolkit.ParamsValue{
+					"script": toolkit.ParamsValue(tt.script),
+				},
+				nil,
+				"",
+			)
+			require.NoError(t, err)
+			require.Len(t, warnings, 1)
+			assert.Equal(t, tt.msg, warnings[0].Msg)
+		})
+	}
+}
+
+func TestScriptTransformer_Transform_interrupted(t *testing.T) {
+	newTransformer := func(timeout string) *utils.TransformerContext {
+		driver, _ := getDriverAndRecord("id4", "1")
+		transformerCtx, warnings, err := ScriptTransformerDefinition.Instance(
+			context.Background(),
+			driver, map[string]toolkit.ParamsValue{
+				"script": toolkit.ParamsValue(`
+					function transform(record) {
+						if (record.GetColumnValue("id4") === 1) {
+							for (;;) {}
+						}
+						record.SetColumnValue("id4", 2);
+					}
+				`),
+				"columns": toolkit.ParamsValue(`["id4"]`),
+				"timeout": toolkit.ParamsValue(timeout),
+			},
+			nil,
+			"",
+		)
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		return transformerCtx
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		transformerCtx := newTransformer("50ms")
+		_, record := getDriverAndRecord("id4", "1")
+		_, err := transformerCtx.Transformer.Transform(context.Background(), record)
+		require.ErrorIs(t, err, utils.ErrRowTransformationTimeout)
+
+		// The interruption does not affect the next records
+		res, err := scriptTransform(t, transformerCtx, "id4", "5")
+		require.NoError(t, err)
+		assert.Equal(t, "2", res)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		transformerCtx := newTransformer("1m")
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		_, record := getDriverAndRecord("id4", "1")
+		_, err := transformerCtx.Transformer.Transform(ctx, record)
+		require.ErrorIs(t, err, context.Canceled)
+
+		res, err := scriptTransform(t, transformerCtx, "id4", "5")
+		require.NoError(t, err)
+		assert.Equal(t, "2", res)
+	})
+}
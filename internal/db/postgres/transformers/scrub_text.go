@@ -0,0 +1,251 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/generators"
+	"github.com/eminano/greenmask/pkg/generators/transformers"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const ScrubTextTransformerName = "ScrubText"
+
+var ScrubTextTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		ScrubTextTransformerName,
+		"Find emails, phone numbers, IBANs, card numbers, IPs and custom patterns in free text and replace them "+
+			"with redact token, fake value of the same kind or deterministic hash",
+	).AddMeta(AllowApplyForReferenced, false).
+		AddMeta(RequireHashEngineParameter, true),
+
+	NewScrubTextTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes("text", "varchar", "bpchar").
+		SetSkipOnNull(true),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"detectors",
+		`list of detectors [{"name": "built-in detector name or custom detector name", "pattern": "regular `+
+			`expression of custom detector", "strategy": "redact|fake|hash", "replacement": "redact token"}]. `+
+			`Built-in detectors: email, iban, card, ipv4, ipv6, phone. By default all built-in detectors are used`,
+	),
+
+	toolkit.MustNewParameterDefinition(
+		"strategy",
+		"default replacement strategy of the detectors [redact, fake, hash]",
+	).SetDefaultValue([]byte(transformers.ScrubRedactStrategyName)).
+		SetRawValueValidator(validateScrubTextStrategyParameter),
+
+	engineParameterDefinition,
+)
+
+type scrubTextDetectorConfig struct {
+	Name        string  `json:"name"`
+	Pattern     string  `json:"pattern"`
+	Strategy    string  `json:"strategy"`
+	Replacement *string `json:"replacement"`
+}
+
+type ScrubTextTransformer struct {
+	columnName      string
+	columnIdx       int
+	affectedColumns map[int]string
+	t               *transformers.ScrubTextTransformer
+}
+
+func NewScrubTextTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, strategy, engine string
+	var configs []*scrubTextDetectorConfig
+
+	p := parameters["column"]
+	if err := p.Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"column\" param: %w", err)
+	}
+	idx, _, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	p = parameters["strategy"]
+	if err := p.Scan(&strategy); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"strategy\" param: %w", err)
+	}
+
+	p = parameters["engine"]
+	if err := p.Scan(&engine); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"engine\" param: %w", err)
+	}
+
+	p = parameters["detectors"]
+	isEmpty, err := p.IsEmpty()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to check \"detectors\" param: %w", err)
+	}
+	if isEmpty {
+		for _, name := range transformers.ScrubBuiltInDetectorNames {
+			configs = append(configs, &scrubTextDetectorConfig{Name: name})
+		}
+	} else if err = p.Scan(&configs); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"detectors\" param: %w", err)
+	}
+
+	detectors, warnings := newScrubTextDetectors(configs, strategy)
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	t, err := transformers.NewScrubTextTransformer(detectors)
+	if err != nil {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "detectors").
+				SetMsg(err.Error()),
+		}, nil
+	}
+	g, err := getGenerateEngine(ctx, engine, t.GetRequiredGeneratorByteLength())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+	}
+	if err = t.SetGenerator(g); err != nil {
+		return nil, nil, fmt.Errorf("unable to set generator: %w", err)
+	}
+	// The hash strategy must produce the same value for the same input regardless of the engine
+	salt, err := getSaltFromCtx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get salt: %w", err)
+	}
+	hashGenerator, err := generators.GetHashBytesGen(salt, t.GetRequiredGeneratorByteLength())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get hash generator: %w", err)
+	}
+	if err = t.SetHashGenerator(hashGenerator); err != nil {
+		return nil, nil, fmt.Errorf("unable to set hash generator: %w", err)
+	}
+
+	return &ScrubTextTransformer{
+		columnName:      columnName,
+		columnIdx:       idx,
+		affectedColumns: affectedColumns,
+		t:               t,
+	}, warnings, nil
+}
+
+// newScrubTextDetectors - creates the detectors from the config. The detector without pattern is the built-in one
+func newScrubTextDetectors(
+	configs []*scrubTextDetectorConfig, defaultStrategy string,
+) ([]*transformers.ScrubDetector, toolkit.ValidationWarnings) {
+	var warnings toolkit.ValidationWarnings
+	detectors := make([]*transformers.ScrubDetector, 0, len(configs))
+	for idx, c := range configs {
+		strategy := c.Strategy
+		if strategy == "" {
+			strategy = defaultStrategy
+		}
+		var replacement []byte
+		if c.Replacement != nil {
+			replacement = []byte(*c.Replacement)
+		}
+
+		var d *transformers.ScrubDetector
+		var err error
+		switch {
+		case c.Name == "":
+			err = fmt.Errorf("detector name is required")
+		case c.Pattern == "":
+			d, err = transformers.NewScrubBuiltInDetector(c.Name, strategy, replacement)
+		default:
+			d, err = transformers.NewScrubDetector(c.Name, c.Pattern, strategy, replacement)
+		}
+		if err != nil {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "detectors").
+				AddMeta("DetectorIdx", idx).
+				SetMsg(err.Error()),
+			)
+			continue
+		}
+		detectors = append(detectors, d)
+	}
+	return detectors, warnings
+}
+
+func (sct *ScrubTextTransformer) GetAffectedColumns() map[int]string {
+	return sct.affectedColumns
+}
+
+func (sct *ScrubTextTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (sct *ScrubTextTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (sct *ScrubTextTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(sct.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+
+	res, err := sct.t.Transform(val.Data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scrub value: %w", err)
+	}
+	if err = r.SetRawColumnValueByIdx(sct.columnIdx, toolkit.NewRawValue(res, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func validateScrubTextStrategyParameter(
+	p *toolkit.ParameterDefinition, v toolkit.ParamsValue,
+) (toolkit.ValidationWarnings, error) {
+	strategies := []string{
+		transformers.ScrubRedactStrategyName, transformers.ScrubFakeStrategyName, transformers.ScrubHashStrategyName,
+	}
+	if !slices.Contains(strategies, string(v)) {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterValue", string(v)).
+				SetMsg("unknown strategy"),
+		}, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(ScrubTextTransformerDefinition)
+}
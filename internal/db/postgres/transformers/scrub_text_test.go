@@ -0,0 +1,129 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func TestScrubTextTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		params   map[string]toolkit.ParamsValue
+		expected string
+		pattern  string
+	}{
+		{
+			name:     "default detectors",
+			original: "Call +44 20 7946 0958 or write to john@corp.com",
+			params: map[string]toolkit.ParamsValue{
+				"column": toolkit.ParamsValue("data"),
+			},
+			expected: "Call [PHONE] or write to [EMAIL]",
+		},
+		{
+			name:     "custom detector and strategies",
+			original: "EMP-123456 paid with 4111 1111 1111 1111 from john@corp.com",
+			params: map[string]toolkit.ParamsValue{
+				"column": toolkit.ParamsValue("data"),
+				"detectors": toolkit.ParamsValue(`[
+					{"name": "employee", "pattern": "EMP-\\d{6}", "replacement": "<EMPLOYEE>"},
+					{"name": "card", "strategy": "hash"},
+					{"name": "email", "strategy": "fake"}
+				]`),
+				"engine": toolkit.ParamsValue("hash"),
+			},
+			pattern: `^<EMPLOYEE> paid with CARD_[0-9a-f]{16} from [a-z]{4}@example\.com$`,
+		},
+		{
+			name:     "no matches",
+			original: "nothing to scrub here",
+			params: map[string]toolkit.ParamsValue{
+				"column": toolkit.ParamsValue("data"),
+			},
+			expected: "nothing to scrub here",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, record := getDriverAndRecord("data", tt.original)
+			transformer, warnings, err := ScrubTextTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "",
+			)
+			require.NoError(t, err)
+			require.Empty(t, warnings)
+			r, err := transformer.Transformer.Transform(context.Background(), record)
+			require.NoError(t, err)
+			res, err := r.GetRawColumnValueByName("data")
+			require.NoError(t, err)
+			require.False(t, res.IsNull)
+			if tt.pattern != "" {
+				require.Regexp(t, tt.pattern, string(res.Data))
+			} else {
+				require.Equal(t, tt.expected, string(res.Data))
+			}
+		})
+	}
+}
+
+func TestScrubTextTransformer_validation(t *testing.T) {
+	driver, _ := getDriverAndRecord("data", "test")
+	tests := []struct {
+		name   string
+		params map[string]toolkit.ParamsValue
+	}{
+		{
+			name: "unknown strategy",
+			params: map[string]toolkit.ParamsValue{
+				"column":   toolkit.ParamsValue("data"),
+				"strategy": toolkit.ParamsValue("mask"),
+			},
+		},
+		{
+			name: "unknown built-in detector",
+			params: map[string]toolkit.ParamsValue{
+				"column":    toolkit.ParamsValue("data"),
+				"detectors": toolkit.ParamsValue(`[{"name": "passport"}]`),
+			},
+		},
+		{
+			name: "invalid pattern",
+			params: map[string]toolkit.ParamsValue{
+				"column":    toolkit.ParamsValue("data"),
+				"detectors": toolkit.ParamsValue(`[{"name": "custom", "pattern": "[a-"}]`),
+			},
+		},
+		{
+			name: "empty detectors",
+			params: map[string]toolkit.ParamsValue{
+				"column":    toolkit.ParamsValue("data"),
+				"detectors": toolkit.ParamsValue(`[]`),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, warnings, err := ScrubTextTransformerDefinition.Instance(context.Background(), driver, tt.params, nil, "")
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+		})
+	}
+}
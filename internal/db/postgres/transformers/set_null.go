@@ -86,6 +86,11 @@ func (sut *SetNullTransformer) Transform(ctx context.Context, r *toolkit.Record)
 	return r, nil
 }
 
+// PushdownExpressions - sets NULL in the COPY query
+func (sut *SetNullTransformer) PushdownExpressions() (map[int]string, bool) {
+	return map[int]string{sut.columnIdx: "NULL"}, true
+}
+
 func init() {
 	utils.DefaultTransformerRegistry.MustRegister(SetNullTransformerDefinition)
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
 
@@ -53,3 +54,21 @@ func TestSetNullTransformer_Transform(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, expectedValue, string(res))
 }
+
+func TestSetNullTransformer_PushdownExpressions(t *testing.T) {
+	driver, _ := getDriverAndRecord("id", "1")
+	transformerCtx, warnings, err := SetNullTransformerDefinition.Instance(
+		context.Background(),
+		driver, map[string]toolkit.ParamsValue{
+			"column": toolkit.ParamsValue("id"),
+		},
+		nil,
+		"",
+	)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	exprs, ok := transformerCtx.Transformer.(utils.PushdownTransformer).PushdownExpressions()
+	require.True(t, ok)
+	assert.Equal(t, map[int]string{0: "NULL"}, exprs)
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/pkg/generators/transformers"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const ShiftDatesTransformerName = "ShiftDates"
+
+var shiftDatesAllowedTypes = []string{"date", "timestamp", "timestamptz"}
+
+var ShiftDatesTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		ShiftDatesTransformerName,
+		"Shift the dates of the same entity by the same offset derived from the entity key",
+	).AddMeta(AllowApplyForReferenced, false).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewShiftDatesTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"key_column",
+		"column name which value identifies the entity, for instance the primary key or the foreign key of the entity",
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"columns",
+		"list of the date, timestamp or timestamptz column names to shift",
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"min_days",
+		"min offset in days",
+	).SetDefaultValue(toolkit.ParamsValue("-365")),
+
+	toolkit.MustNewParameterDefinition(
+		"max_days",
+		"max offset in days",
+	).SetDefaultValue(toolkit.ParamsValue("365")),
+
+	toolkit.MustNewParameterDefinition(
+		"exclude_zero",
+		"never produce the zero offset that keeps the original dates",
+	).SetDefaultValue(toolkit.ParamsValue("true")),
+)
+
+type ShiftDatesTransformer struct {
+	t               *transformers.DateShift
+	keyColumnIdx    int
+	columnIdxs      []int
+	affectedColumns map[int]string
+}
+
+func NewShiftDatesTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var keyColumnName string
+	var columns []string
+	var minDays, maxDays int64
+	var excludeZero bool
+	if err := parameters["key_column"].Scan(&keyColumnName); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "key_column" param: %w`, err)
+	}
+	if err := parameters["columns"].Scan(&columns); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "columns" param: %w`, err)
+	}
+	if err := parameters["min_days"].Scan(&minDays); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "min_days" param: %w`, err)
+	}
+	if err := parameters["max_days"].Scan(&maxDays); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "max_days" param: %w`, err)
+	}
+	if err := parameters["exclude_zero"].Scan(&excludeZero); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "exclude_zero" param: %w`, err)
+	}
+
+	var warnings toolkit.ValidationWarnings
+	keyColumnIdx, _, ok := driver.GetColumnByName(keyColumnName)
+	if !ok {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "key_column").
+			AddMeta("ParameterValue", keyColumnName).
+			SetMsg("column is not found"))
+	}
+	if len(columns) == 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "columns").
+			SetMsg("at least one column must be specified"))
+	}
+
+	affectedColumns := make(map[int]string, len(columns))
+	columnIdxs := make([]int, 0, len(columns))
+	for idx, name := range columns {
+		columnIdx, column, ok := driver.GetColumnByName(name)
+		if !ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "columns").
+				AddMeta(fmt.Sprintf("ParameterItemValue[%d]", idx), name).
+				SetMsg("column is not found"))
+			continue
+		}
+		columnTypeName, columnTypeOid := column.GetType()
+		if !toolkit.IsTypeAllowedWithTypeMap(driver, shiftDatesAllowedTypes, columnTypeName, columnTypeOid, true) {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "columns").
+				AddMeta(fmt.Sprintf("ParameterItemValue[%d]", idx), name).
+				AddMeta("TypeName", columnTypeName).
+				AddMeta("AllowedTypes", shiftDatesAllowedTypes).
+				SetMsg("unsupported column type"))
+			continue
+		}
+		if columnIdx == keyColumnIdx {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "columns").
+				AddMeta(fmt.Sprintf("ParameterItemValue[%d]", idx), name).
+				SetMsg("key column cannot be shifted"))
+			continue
+		}
+		affectedColumns[columnIdx] = name
+		columnIdxs = append(columnIdxs, columnIdx)
+	}
+
+	t, err := transformers.NewDateShift(minDays, maxDays, excludeZero)
+	if err != nil {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "min_days").
+			AddMeta("MinDays", minDays).
+			AddMeta("MaxDays", maxDays).
+			AddMeta("Error", err.Error()).
+			SetMsg("wrong offset range"))
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	g, err := getGenerateEngine(ctx, HashEngineParameterName, t.GetRequiredGeneratorByteLength())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+	}
+	if err = t.SetGenerator(g); err != nil {
+		return nil, nil, fmt.Errorf("unable to set generator: %w", err)
+	}
+
+	return &ShiftDatesTransformer{
+		t:               t,
+		keyColumnIdx:    keyColumnIdx,
+		columnIdxs:      columnIdxs,
+		affectedColumns: affectedColumns,
+	}, warnings, nil
+}
+
+func (sdt *ShiftDatesTransformer) GetAffectedColumns() map[int]string {
+	return sdt.affectedColumns
+}
+
+func (sdt *ShiftDatesTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (sdt *ShiftDatesTransformer) Done(ctx context.Context) error {
+	return nil
+}
+
+func (sdt *ShiftDatesTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	key, err := r.GetRawColumnValueByIdx(sdt.keyColumnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan key value: %w", err)
+	}
+	// The dates of the records without the entity are shifted by the same offset
+	keyData := key.Data
+	if key.IsNull {
+		keyData = []byte(defaultNullSeq)
+	}
+	offset, err := sdt.t.GetOffset(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get offset: %w", err)
+	}
+
+	for _, idx := range sdt.columnIdxs {
+		var val time.Time
+		isNull, err := r.ScanColumnValueByIdx(idx, &val)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan attribute value: %w", err)
+		}
+		if isNull {
+			continue
+		}
+		if err = r.SetColumnValueByIdx(idx, val.AddDate(0, 0, int(offset))); err != nil {
+			return nil, fmt.Errorf("unable to set new value: %w", err)
+		}
+	}
+	return r, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(ShiftDatesTransformerDefinition)
+}
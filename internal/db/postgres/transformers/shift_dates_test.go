@@ -0,0 +1,137 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func shiftDatesTransform(t *testing.T, columns []*toolkit.Column, params map[string]toolkit.ParamsValue, line string) *toolkit.Record {
+	table := &toolkit.Table{
+		Schema:  "public",
+		Name:    "test",
+		Oid:     1224,
+		Columns: columns,
+	}
+	driver, warnings, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	ctx := context.WithValue(context.Background(), "salt", []byte("12345678"))
+	transformer, warnings, err := ShiftDatesTransformerDefinition.Instance(ctx, driver, params, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	row := pgcopy.NewRow(len(columns))
+	require.NoError(t, row.Decode([]byte(line)))
+	record := toolkit.NewRecord(driver)
+	record.SetRow(row)
+	r, err := transformer.Transformer.Transform(ctx, record)
+	require.NoError(t, err)
+	return r
+}
+
+func TestShiftDatesTransformer_Transform(t *testing.T) {
+	// id4, date_date, date_ts
+	visits := []*toolkit.Column{columnList[6], columnList[8], columnList[9]}
+	// id4, date_ts
+	prescriptions := []*toolkit.Column{columnList[6], columnList[9]}
+
+	params := map[string]toolkit.ParamsValue{
+		"key_column": toolkit.ParamsValue("id4"),
+		"columns":    toolkit.ParamsValue(`["date_date", "date_ts"]`),
+		"min_days":   toolkit.ParamsValue("-30"),
+		"max_days":   toolkit.ParamsValue("30"),
+	}
+	visit := shiftDatesTransform(t, visits, params, "42\t2023-03-01\t2023-03-12 18:00:00")
+
+	var admitted, discharged time.Time
+	_, err := visit.ScanColumnValueByName("date_date", &admitted)
+	require.NoError(t, err)
+	_, err = visit.ScanColumnValueByName("date_ts", &discharged)
+	require.NoError(t, err)
+
+	offset := admitted.Sub(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NotZero(t, offset)
+	require.LessOrEqual(t, offset.Abs(), 30*24*time.Hour)
+	require.Equal(t, time.Date(2023, 3, 12, 18, 0, 0, 0, time.UTC).Add(offset), discharged)
+
+	// The dates of the same entity in the other table are shifted by the same offset
+	prescription := shiftDatesTransform(t, prescriptions, map[string]toolkit.ParamsValue{
+		"key_column": toolkit.ParamsValue("id4"),
+		"columns":    toolkit.ParamsValue(`["date_ts"]`),
+		"min_days":   toolkit.ParamsValue("-30"),
+		"max_days":   toolkit.ParamsValue("30"),
+	}, "42\t2023-05-10 09:00:00")
+	var prescribed time.Time
+	_, err = prescription.ScanColumnValueByName("date_ts", &prescribed)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, 5, 10, 9, 0, 0, 0, time.UTC).Add(offset), prescribed)
+
+	// NULL dates are kept
+	visit = shiftDatesTransform(t, visits, params, "42\t\\N\t2023-03-12 18:00:00")
+	v, err := visit.GetRawColumnValueByName("date_date")
+	require.NoError(t, err)
+	require.True(t, v.IsNull)
+}
+
+func TestShiftDatesTransformer_validation(t *testing.T) {
+	driver, _ := getDriverAndRecord("date_date", "2023-03-01")
+
+	tests := []struct {
+		name   string
+		params map[string]toolkit.ParamsValue
+	}{
+		{
+			name: "unknown key column",
+			params: map[string]toolkit.ParamsValue{
+				"key_column": toolkit.ParamsValue("unknown"),
+				"columns":    toolkit.ParamsValue(`["date_date"]`),
+			},
+		},
+		{
+			name: "key column shifted",
+			params: map[string]toolkit.ParamsValue{
+				"key_column": toolkit.ParamsValue("date_date"),
+				"columns":    toolkit.ParamsValue(`["date_date"]`),
+			},
+		},
+		{
+			name: "wrong range",
+			params: map[string]toolkit.ParamsValue{
+				"key_column": toolkit.ParamsValue("date_date"),
+				"columns":    toolkit.ParamsValue(`[]`),
+				"min_days":   toolkit.ParamsValue("10"),
+				"max_days":   toolkit.ParamsValue("-10"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, warnings, err := ShiftDatesTransformerDefinition.Instance(
+				context.Background(), driver, tt.params, nil, "",
+			)
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+		})
+	}
+}
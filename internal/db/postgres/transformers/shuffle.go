@@ -0,0 +1,403 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	utils2 "github.com/eminano/greenmask/internal/utils"
+	"github.com/eminano/greenmask/internal/utils/extsort"
+	"github.com/eminano/greenmask/pkg/generators"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const (
+	ShuffleTransformerName = "Shuffle"
+	shuffleSortKeyLength   = 8
+)
+
+var ShuffleTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		ShuffleTransformerName,
+		"Permute the column values across the table rows or within the groups of rows",
+	).AddMeta(AllowApplyForReferenced, false).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewShuffleTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"columns",
+		"list of the column names to shuffle",
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"group_by",
+		"list of the column names. The values are shuffled only within the rows having the same values of these columns",
+	).SetDefaultValue(toolkit.ParamsValue("[]")),
+
+	toolkit.MustNewParameterDefinition(
+		"joint",
+		"shuffle the columns together keeping the values of the same row coherent. Otherwise each column is shuffled independently",
+	).SetDefaultValue(toolkit.ParamsValue("false")),
+
+	toolkit.MustNewParameterDefinition(
+		"memory_limit",
+		"memory limit in megabytes for collected values. The values exceeding the limit are sorted in temporary files",
+	).SetDefaultValue(toolkit.ParamsValue("64")),
+
+	toolkit.MustNewParameterDefinition(
+		"tmp_dir",
+		"directory for temporary files. The common tmp_dir is used if empty",
+	).SetDefaultValue(toolkit.ParamsValue("")),
+)
+
+// shuffleGroupRange - the range of the shuffled values of the group in the values file
+type shuffleGroupRange struct {
+	offset int64
+	end    int64
+}
+
+// shuffleUnit - the columns shuffled together
+type shuffleUnit struct {
+	columns []int
+	sorter  *extsort.Sorter
+	values  *os.File
+	groups  map[string]*shuffleGroupRange
+}
+
+type ShuffleTransformer struct {
+	units           []*shuffleUnit
+	groupBy         []int
+	affectedColumns map[int]string
+	tmpDir          string
+	g               generators.Generator
+	collected       uint64
+	groupBuf        []byte
+	keyBuf          []byte
+	valueBuf        []byte
+}
+
+func NewShuffleTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columns, groupBy []string
+	if err := parameters["columns"].Scan(&columns); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "columns" param: %w`, err)
+	}
+	if err := parameters["group_by"].Scan(&groupBy); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "group_by" param: %w`, err)
+	}
+	var joint bool
+	if err := parameters["joint"].Scan(&joint); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "joint" param: %w`, err)
+	}
+	var memoryLimit int
+	if err := parameters["memory_limit"].Scan(&memoryLimit); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "memory_limit" param: %w`, err)
+	}
+	var tmpDir string
+	if err := parameters["tmp_dir"].Scan(&tmpDir); err != nil {
+		return nil, nil, fmt.Errorf(`unable to scan "tmp_dir" param: %w`, err)
+	}
+	if tmpDir == "" {
+		tmpDir = utils2.TmpDirFromCtx(ctx)
+	}
+
+	var warnings toolkit.ValidationWarnings
+	if len(columns) == 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "columns").
+			SetMsg("at least one column must be specified"))
+	}
+	if memoryLimit <= 0 {
+		warnings = append(warnings, toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterName", "memory_limit").
+			AddMeta("ParameterValue", memoryLimit).
+			SetMsg("memory limit must be greater than 0"))
+	}
+
+	affectedColumns := make(map[int]string, len(columns))
+	columnIdxs := make([]int, 0, len(columns))
+	for idx, name := range columns {
+		columnIdx, _, ok := driver.GetColumnByName(name)
+		if !ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "columns").
+				AddMeta(fmt.Sprintf("ParameterItemValue[%d]", idx), name).
+				SetMsg("column is not found"))
+			continue
+		}
+		if _, ok = affectedColumns[columnIdx]; ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "columns").
+				AddMeta(fmt.Sprintf("ParameterItemValue[%d]", idx), name).
+				SetMsg("column is specified more than once"))
+			continue
+		}
+		affectedColumns[columnIdx] = name
+		columnIdxs = append(columnIdxs, columnIdx)
+	}
+
+	groupByIdxs := make([]int, 0, len(groupBy))
+	for idx, name := range groupBy {
+		columnIdx, _, ok := driver.GetColumnByName(name)
+		if !ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "group_by").
+				AddMeta(fmt.Sprintf("ParameterItemValue[%d]", idx), name).
+				SetMsg("column is not found"))
+			continue
+		}
+		if _, ok = affectedColumns[columnIdx]; ok {
+			warnings = append(warnings, toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "group_by").
+				AddMeta(fmt.Sprintf("ParameterItemValue[%d]", idx), name).
+				SetMsg("group by column cannot be shuffled"))
+			continue
+		}
+		groupByIdxs = append(groupByIdxs, columnIdx)
+	}
+
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	var units []*shuffleUnit
+	if joint {
+		units = append(units, &shuffleUnit{columns: columnIdxs})
+	} else {
+		for _, columnIdx := range columnIdxs {
+			units = append(units, &shuffleUnit{columns: []int{columnIdx}})
+		}
+	}
+
+	g, err := getGenerateEngine(ctx, RandomEngineParameterName, shuffleSortKeyLength)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get generator: %w", err)
+	}
+
+	unitMemoryLimit := max(memoryLimit*1024*1024/len(units), 1)
+	for _, u := range units {
+		u.sorter = extsort.NewSorter(tmpDir, unitMemoryLimit)
+	}
+
+	return &ShuffleTransformer{
+		units:           units,
+		groupBy:         groupByIdxs,
+		affectedColumns: affectedColumns,
+		tmpDir:          tmpDir,
+		g:               g,
+	}, warnings, nil
+}
+
+func (st *ShuffleTransformer) GetAffectedColumns() map[int]string {
+	return st.affectedColumns
+}
+
+func (st *ShuffleTransformer) Init(ctx context.Context) error {
+	return nil
+}
+
+func (st *ShuffleTransformer) Done(ctx context.Context) error {
+	var errs []error
+	for _, u := range st.units {
+		errs = append(errs, u.sorter.Close())
+		if u.values != nil {
+			errs = append(errs, u.values.Close(), os.Remove(u.values.Name()))
+			u.values = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Transform - collects the values of the record. The values are sorted by the group and the random key, so
+// the values of each group are stored in random order
+func (st *ShuffleTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	var err error
+	st.groupBuf, err = appendShuffleValues(st.groupBuf[:0], r, st.groupBy)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range st.units {
+		sortKey, err := st.g.Generate(nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate sort key: %w", err)
+		}
+		st.keyBuf = append(append(st.keyBuf[:0], st.groupBuf...), sortKey...)
+		st.valueBuf, err = appendShuffleValues(st.valueBuf[:0], r, u.columns)
+		if err != nil {
+			return nil, err
+		}
+		if err = u.sorter.Add(st.keyBuf, st.valueBuf); err != nil {
+			return nil, fmt.Errorf("unable to collect values: %w", err)
+		}
+	}
+	st.collected++
+	return r, nil
+}
+
+func (st *ShuffleTransformer) CollectedCount() uint64 {
+	return st.collected
+}
+
+// Prepare - writes the sorted values into the values file and remembers the range of each group
+func (st *ShuffleTransformer) Prepare(ctx context.Context) error {
+	for _, u := range st.units {
+		if err := st.prepareUnit(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (st *ShuffleTransformer) prepareUnit(u *shuffleUnit) error {
+	f, err := os.CreateTemp(st.tmpDir, "greenmask-shuffle-*")
+	if err != nil {
+		return fmt.Errorf("unable to create values file: %w", err)
+	}
+	u.values = f
+	u.groups = make(map[string]*shuffleGroupRange)
+
+	w := bufio.NewWriter(f)
+	var offset int64
+	var current *shuffleGroupRange
+	err = u.sorter.Iterate(func(key, value []byte) error {
+		group := key[:len(key)-shuffleSortKeyLength]
+		if current == nil || u.groups[string(group)] != current {
+			current = &shuffleGroupRange{offset: offset, end: offset}
+			u.groups[string(group)] = current
+		}
+		var buf [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(buf[:], uint64(len(value)))
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+		if _, err := w.Write(value); err != nil {
+			return err
+		}
+		offset += int64(n + len(value))
+		current.end = offset
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write values file: %w", err)
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("unable to write values file: %w", err)
+	}
+	return u.sorter.Close()
+}
+
+// Apply - sets the next shuffled values of the record group
+func (st *ShuffleTransformer) Apply(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	var err error
+	st.groupBuf, err = appendShuffleValues(st.groupBuf[:0], r, st.groupBy)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range st.units {
+		gr, ok := u.groups[string(st.groupBuf)]
+		if !ok || gr.offset >= gr.end {
+			return nil, fmt.Errorf("shuffled values of the group are exhausted")
+		}
+		if err = st.readValue(u.values, gr); err != nil {
+			return nil, err
+		}
+		if err = setShuffleValues(r, u.columns, st.valueBuf); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (st *ShuffleTransformer) readValue(f *os.File, gr *shuffleGroupRange) error {
+	var header [binary.MaxVarintLen64]byte
+	n, err := f.ReadAt(header[:min(int64(len(header)), gr.end-gr.offset)], gr.offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unable to read values file: %w", err)
+	}
+	length, headerLen := binary.Uvarint(header[:n])
+	if headerLen <= 0 {
+		return fmt.Errorf("unable to read values file: malformed value length")
+	}
+	st.valueBuf = append(st.valueBuf[:0], make([]byte, length)...)
+	if _, err = f.ReadAt(st.valueBuf, gr.offset+int64(headerLen)); err != nil {
+		return fmt.Errorf("unable to read values file: %w", err)
+	}
+	gr.offset += int64(headerLen) + int64(length)
+	return nil
+}
+
+// appendShuffleValues - appends the raw values of the columns as the sequence of the null flag, value length and
+// value
+func appendShuffleValues(buf []byte, r *toolkit.Record, columns []int) ([]byte, error) {
+	for _, idx := range columns {
+		v, err := r.GetRawColumnValueByIdx(idx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get raw value: %w", err)
+		}
+		if v.IsNull {
+			buf = append(buf, 1)
+			continue
+		}
+		buf = append(buf, 0)
+		buf = binary.AppendUvarint(buf, uint64(len(v.Data)))
+		buf = append(buf, v.Data...)
+	}
+	return buf, nil
+}
+
+func setShuffleValues(r *toolkit.Record, columns []int, data []byte) error {
+	for _, idx := range columns {
+		if len(data) == 0 {
+			return fmt.Errorf("unable to decode shuffled value: unexpected end of data")
+		}
+		isNull := data[0] == 1
+		data = data[1:]
+		var v *toolkit.RawValue
+		if isNull {
+			v = toolkit.NewRawValue(nil, true)
+		} else {
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return fmt.Errorf("unable to decode shuffled value: malformed value length")
+			}
+			v = toolkit.NewRawValue(bytes.Clone(data[n:n+int(length)]), false)
+			data = data[n+int(length):]
+		}
+		if err := r.SetRawColumnValueByIdx(idx, v); err != nil {
+			return fmt.Errorf("unable to set new value: %w", err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(ShuffleTransformerDefinition)
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/db/postgres/pgcopy"
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	utils2 "github.com/eminano/greenmask/internal/utils"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+func shuffleTransform(t *testing.T, params map[string]toolkit.ParamsValue, lines []string) [][]string {
+	table := &toolkit.Table{
+		Schema:  "public",
+		Name:    "test",
+		Oid:     1224,
+		Columns: []*toolkit.Column{columnList[2], columnList[6], columnList[7]},
+	}
+	driver, warnings, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	ctx := context.Background()
+	transformer, warnings, err := ShuffleTransformerDefinition.Instance(ctx, driver, params, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	st, ok := transformer.Transformer.(utils.PostponedTransformer)
+	require.True(t, ok)
+	defer func() {
+		require.NoError(t, st.Done(ctx))
+	}()
+
+	newRecord := func(line string) *toolkit.Record {
+		row := pgcopy.NewRow(3)
+		require.NoError(t, row.Decode([]byte(line)))
+		record := toolkit.NewRecord(driver)
+		record.SetRow(row)
+		return record
+	}
+
+	for _, line := range lines {
+		_, err = st.Transform(ctx, newRecord(line))
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, len(lines), st.CollectedCount())
+	require.NoError(t, st.Prepare(ctx))
+
+	res := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		r, err := st.Apply(ctx, newRecord(line))
+		require.NoError(t, err)
+		values := make([]string, 0, 3)
+		for _, name := range []string{"data", "id4", "id8"} {
+			v, err := r.GetRawColumnValueByName(name)
+			require.NoError(t, err)
+			if v.IsNull {
+				values = append(values, "\\N")
+			} else {
+				values = append(values, string(v.Data))
+			}
+		}
+		res = append(res, values)
+	}
+	return res
+}
+
+func TestShuffleTransformer_Apply(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf("value-%d\t%d\t%d", i, i%2, i))
+	}
+	lines = append(lines, "\\N\t0\t\\N")
+
+	t.Run("joint within groups", func(t *testing.T) {
+		res := shuffleTransform(t, map[string]toolkit.ParamsValue{
+			"columns":  toolkit.ParamsValue(`["data", "id8"]`),
+			"group_by": toolkit.ParamsValue(`["id4"]`),
+			"joint":    toolkit.ParamsValue("true"),
+		}, lines)
+
+		var moved int
+		seen := make(map[string]struct{}, len(res))
+		for idx, values := range res {
+			// The group column is kept and the shuffled values are coherent and come from the same group
+			require.Equal(t, fmt.Sprint(idx%2), values[1])
+			if values[2] == "\\N" {
+				require.Equal(t, "\\N", values[0])
+				continue
+			}
+			require.Equal(t, "value-"+values[2], values[0])
+			var i int
+			_, err := fmt.Sscan(values[2], &i)
+			require.NoError(t, err)
+			require.Equal(t, idx%2, i%2)
+			seen[values[2]] = struct{}{}
+			if i != idx {
+				moved++
+			}
+		}
+		require.Len(t, seen, 200)
+		require.Greater(t, moved, 100)
+	})
+
+	t.Run("independent columns", func(t *testing.T) {
+		res := shuffleTransform(t, map[string]toolkit.ParamsValue{
+			"columns": toolkit.ParamsValue(`["data", "id8"]`),
+		}, lines)
+
+		var data, ids []string
+		var incoherent int
+		for _, values := range res {
+			data = append(data, values[0])
+			ids = append(ids, values[2])
+			if values[0] != "value-"+values[2] {
+				incoherent++
+			}
+		}
+		var expectedData, expectedIds []string
+		for i := 0; i < 200; i++ {
+			expectedData = append(expectedData, fmt.Sprintf("value-%d", i))
+			expectedIds = append(expectedIds, fmt.Sprint(i))
+		}
+		expectedData = append(expectedData, "\\N")
+		expectedIds = append(expectedIds, "\\N")
+		slices.Sort(data)
+		slices.Sort(ids)
+		slices.Sort(expectedData)
+		slices.Sort(expectedIds)
+		require.Equal(t, expectedData, data)
+		require.Equal(t, expectedIds, ids)
+		require.Greater(t, incoherent, 100)
+	})
+}
+
+func TestShuffleTransformer_validation(t *testing.T) {
+	driver, _ := getDriverAndRecord("data", "test")
+
+	_, warnings, err := ShuffleTransformerDefinition.Instance(
+		context.Background(), driver,
+		map[string]toolkit.ParamsValue{
+			"columns": toolkit.ParamsValue(`["unknown"]`),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+
+	_, warnings, err = ShuffleTransformerDefinition.Instance(
+		context.Background(), driver,
+		map[string]toolkit.ParamsValue{
+			"columns":  toolkit.ParamsValue(`["data"]`),
+			"group_by": toolkit.ParamsValue(`["data"]`),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+}
+
+func TestShuffleTransformer_tmp_dir(t *testing.T) {
+	driver, _ := getDriverAndRecord("data", "test")
+	ctx := utils2.WithTmpDir(context.Background(), "/var/lib/greenmask/tmp")
+
+	transformer, warnings, err := ShuffleTransformerDefinition.Instance(
+		ctx, driver, map[string]toolkit.ParamsValue{"columns": toolkit.ParamsValue(`["data"]`)}, nil, "",
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	require.Equal(t, "/var/lib/greenmask/tmp", transformer.Transformer.(*ShuffleTransformer).tmpDir)
+
+	transformer, warnings, err = ShuffleTransformerDefinition.Instance(
+		ctx, driver, map[string]toolkit.ParamsValue{
+			"columns": toolkit.ParamsValue(`["data"]`),
+			"tmp_dir": toolkit.ParamsValue("/mnt/shuffle"),
+		}, nil, "",
+	)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	require.Equal(t, "/mnt/shuffle", transformer.Transformer.(*ShuffleTransformer).tmpDir)
+}
@@ -0,0 +1,308 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/eminano/greenmask/internal/db/postgres/transformers/utils"
+	"github.com/eminano/greenmask/internal/utils/tokenvault"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const TokenizeTransformerName = "Tokenize"
+
+// tokenizeCacheSize - the max number of tokens that are cached in memory by the transformer. The cache is reset when
+// the limit is reached
+const tokenizeCacheSize = 100_000
+
+// varlenaHeaderSize - the size of the varlena header that is included into the type modifier of varchar(n) and char(n)
+const varlenaHeaderSize = 4
+
+var TokenizeTransformerDefinition = utils.NewTransformerDefinition(
+	utils.NewTransformerProperties(
+		TokenizeTransformerName,
+		"Replace the value with the random token and store the mapping in the token vault. The same value always "+
+			"receives the same token across tables and dumps. The original value can be restored using detokenize command",
+	).AddMeta(AllowApplyForReferenced, true).
+		AddMeta(RequireHashEngineParameter, false),
+
+	NewTokenizeTransformer,
+
+	toolkit.MustNewParameterDefinition(
+		"column",
+		"column name",
+	).SetIsColumn(toolkit.NewColumnProperties().
+		SetAffected(true).
+		SetAllowedColumnTypes("text", "varchar", "bpchar").
+		SetSkipOnNull(true),
+	).SetRequired(true),
+
+	toolkit.MustNewParameterDefinition(
+		"vault",
+		fmt.Sprintf(
+			"token vault type. Possible values: %s, %s",
+			tokenvault.SqliteVaultType, tokenvault.PostgresVaultType,
+		),
+	).SetDefaultValue([]byte(tokenvault.SqliteVaultType)).
+		SetRawValueValidator(validateTokenizeVaultParameter),
+
+	toolkit.MustNewParameterDefinition(
+		"vault_dsn",
+		"token vault file path for sqlite vault or connection string for postgres vault. This value may be "+
+			"provided via environment variable GREENMASK_TOKENIZE_VAULT_DSN",
+	).SetGetFromGlobalEnvVariable("GREENMASK_TOKENIZE_VAULT_DSN"),
+
+	toolkit.MustNewParameterDefinition(
+		"vault_table",
+		"token vault table name. For postgres vault it may be schema-qualified",
+	).SetDefaultValue([]byte(tokenvault.DefaultTableName)),
+
+	toolkit.MustNewParameterDefinition(
+		"key",
+		"hex encoded master key (at least 16 bytes) that is used for the per-value encryption of the stored values. This "+
+			"value may be provided via environment variable GREENMASK_TOKENIZE_KEY",
+	).SetGetFromGlobalEnvVariable("GREENMASK_TOKENIZE_KEY").
+		SetRawValueValidator(validateTokenizeKeyParameter),
+
+	toolkit.MustNewParameterDefinition(
+		"token_prefix",
+		"prefix of the generated tokens",
+	).SetDefaultValue([]byte("")),
+
+	toolkit.MustNewParameterDefinition(
+		"token_length",
+		"length of the random part of the generated tokens",
+	).SetDefaultValue([]byte("16")).
+		SetRawValueValidator(validateTokenizeTokenLengthParameter),
+)
+
+type TokenizeTransformer struct {
+	columnName      string
+	columnIdx       int
+	affectedColumns map[int]string
+	vaultType       string
+	vaultDsn        string
+	vaultTable      string
+	key             []byte
+	tokenPrefix     string
+	tokenLength     int
+	vault           *tokenvault.Vault
+	cache           map[string]string
+}
+
+func NewTokenizeTransformer(
+	ctx context.Context, driver *toolkit.Driver, parameters map[string]toolkit.Parameterizer,
+) (utils.Transformer, toolkit.ValidationWarnings, error) {
+	var columnName, vaultType, vaultDsn, vaultTable, keyHex, tokenPrefix string
+	var tokenLength int
+
+	p := parameters["column"]
+	if err := p.Scan(&columnName); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"column\" param: %w", err)
+	}
+	idx, column, ok := driver.GetColumnByName(columnName)
+	if !ok {
+		return nil, nil, fmt.Errorf("column with name %s is not found", columnName)
+	}
+	affectedColumns := make(map[int]string)
+	affectedColumns[idx] = columnName
+
+	p = parameters["vault"]
+	if err := p.Scan(&vaultType); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"vault\" param: %w", err)
+	}
+
+	p = parameters["vault_dsn"]
+	if err := p.Scan(&vaultDsn); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"vault_dsn\" param: %w", err)
+	}
+	if vaultDsn == "" {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "vault_dsn").
+				SetMsg("vault dsn is required: use vault_dsn parameter or GREENMASK_TOKENIZE_VAULT_DSN environment variable"),
+		}, nil
+	}
+
+	p = parameters["vault_table"]
+	if err := p.Scan(&vaultTable); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"vault_table\" param: %w", err)
+	}
+
+	p = parameters["key"]
+	if err := p.Scan(&keyHex); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"key\" param: %w", err)
+	}
+	if keyHex == "" {
+		return nil, toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterName", "key").
+				SetMsg("vault key is required: use key parameter or GREENMASK_TOKENIZE_KEY environment variable"),
+		}, nil
+	}
+	key, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode \"key\" param: %w", err)
+	}
+
+	p = parameters["token_prefix"]
+	if err = p.Scan(&tokenPrefix); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"token_prefix\" param: %w", err)
+	}
+
+	p = parameters["token_length"]
+	if err = p.Scan(&tokenLength); err != nil {
+		return nil, nil, fmt.Errorf("unable to scan \"token_length\" param: %w", err)
+	}
+	if maxLength := getTokenizeColumnMaxLength(column); maxLength != -1 {
+		if tokenLen := utf8.RuneCountInString(tokenPrefix) + tokenLength; tokenLen > maxLength {
+			return nil, toolkit.ValidationWarnings{
+				toolkit.NewValidationWarning().
+					SetSeverity(toolkit.ErrorValidationSeverity).
+					AddMeta("ParameterName", "token_length").
+					AddMeta("ColumnName", columnName).
+					AddMeta("ColumnMaxLength", maxLength).
+					AddMeta("TokenLength", tokenLen).
+					SetMsg("the token does not fit the column length: decrease token_length or token_prefix"),
+			}, nil
+		}
+	}
+
+	return &TokenizeTransformer{
+		columnName:      columnName,
+		columnIdx:       idx,
+		affectedColumns: affectedColumns,
+		vaultType:       vaultType,
+		vaultDsn:        vaultDsn,
+		vaultTable:      vaultTable,
+		key:             key,
+		tokenPrefix:     tokenPrefix,
+		tokenLength:     tokenLength,
+	}, nil, nil
+}
+
+// getTokenizeColumnMaxLength - returns the max number of characters of the varchar(n) and char(n) column or -1 if the
+// column length is not limited. The type modifier of these types is the length plus the varlena header size
+func getTokenizeColumnMaxLength(column *toolkit.Column) int {
+	if column.Length < varlenaHeaderSize {
+		return -1
+	}
+	return column.Length - varlenaHeaderSize
+}
+
+func (tt *TokenizeTransformer) GetAffectedColumns() map[int]string {
+	return tt.affectedColumns
+}
+
+func (tt *TokenizeTransformer) Init(ctx context.Context) error {
+	vault, err := tokenvault.Open(ctx, tt.vaultType, tt.vaultDsn, tt.vaultTable, tt.key)
+	if err != nil {
+		return fmt.Errorf("unable to open token vault: %w", err)
+	}
+	tt.vault = vault
+	tt.cache = make(map[string]string)
+	return nil
+}
+
+func (tt *TokenizeTransformer) Done(ctx context.Context) error {
+	tt.cache = nil
+	if tt.vault == nil {
+		return nil
+	}
+	if err := tt.vault.Close(); err != nil {
+		return fmt.Errorf("unable to close token vault: %w", err)
+	}
+	tt.vault = nil
+	return nil
+}
+
+func (tt *TokenizeTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(tt.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan attribute value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+
+	token, ok := tt.cache[string(val.Data)]
+	if !ok {
+		token, err = tt.vault.Tokenize(ctx, val.Data, tt.tokenPrefix, tt.tokenLength)
+		if err != nil {
+			return nil, fmt.Errorf("unable to tokenize value: %w", err)
+		}
+		if len(tt.cache) >= tokenizeCacheSize {
+			clear(tt.cache)
+		}
+		tt.cache[string(val.Data)] = token
+	}
+
+	if err = r.SetRawColumnValueByIdx(tt.columnIdx, toolkit.NewRawValue([]byte(token), false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+func validateTokenizeVaultParameter(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	switch string(v) {
+	case tokenvault.SqliteVaultType, tokenvault.PostgresVaultType:
+		return nil, nil
+	}
+	return toolkit.ValidationWarnings{
+		toolkit.NewValidationWarning().
+			SetSeverity(toolkit.ErrorValidationSeverity).
+			AddMeta("ParameterValue", string(v)).
+			SetMsg("unknown token vault type"),
+	}, nil
+}
+
+func validateTokenizeKeyParameter(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(string(v))
+	if err != nil || len(key) < tokenvault.MinKeyLength {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				SetMsg(fmt.Sprintf("key must be hex encoded and at least %d bytes length", tokenvault.MinKeyLength)),
+		}, nil
+	}
+	return nil, nil
+}
+
+func validateTokenizeTokenLengthParameter(p *toolkit.ParameterDefinition, v toolkit.ParamsValue) (toolkit.ValidationWarnings, error) {
+	length, err := strconv.Atoi(string(v))
+	if err != nil || length < tokenvault.MinTokenLength {
+		return toolkit.ValidationWarnings{
+			toolkit.NewValidationWarning().
+				SetSeverity(toolkit.ErrorValidationSeverity).
+				AddMeta("ParameterValue", string(v)).
+				SetMsg(fmt.Sprintf("token length must be an integer greater or equal to %d", tokenvault.MinTokenLength)),
+		}, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	utils.DefaultTransformerRegistry.MustRegister(TokenizeTransformerDefinition)
+}
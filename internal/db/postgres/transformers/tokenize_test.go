@@ -0,0 +1,169 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transformers
+
+import (
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/internal/utils/tokenvault"
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const tokenizeTestKey = "000102030405060708090a0b0c0d0e0f"
+
+func TestTokenizeTransformer_Transform(t *testing.T) {
+	ctx := context.Background()
+	vaultDsn := filepath.Join(t.TempDir(), "vault.db")
+	params := map[string]toolkit.ParamsValue{
+		"column":       toolkit.ParamsValue("data"),
+		"vault_dsn":    toolkit.ParamsValue(vaultDsn),
+		"key":          toolkit.ParamsValue(tokenizeTestKey),
+		"token_prefix": toolkit.ParamsValue("tok_"),
+	}
+
+	tokenize := func(value string) string {
+		driver, record := getDriverAndRecord("data", value)
+		transformer, warnings, err := TokenizeTransformerDefinition.Instance(ctx, driver, params, nil, "")
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		require.NoError(t, transformer.Transformer.Init(ctx))
+		defer func() {
+			require.NoError(t, transformer.Transformer.Done(ctx))
+		}()
+		r, err := transformer.Transformer.Transform(ctx, record)
+		require.NoError(t, err)
+		res, err := r.GetRawColumnValueByName("data")
+		require.NoError(t, err)
+		require.False(t, res.IsNull)
+		return string(res.Data)
+	}
+
+	token := tokenize("john@example.com")
+	require.Regexp(t, `^tok_[0-9A-Za-z]{16}$`, token)
+	require.Equal(t, token, tokenize("john@example.com"))
+	require.NotEqual(t, token, tokenize("jane@example.com"))
+
+	key, err := hex.DecodeString(tokenizeTestKey)
+	require.NoError(t, err)
+	vault, err := tokenvault.Open(ctx, tokenvault.SqliteVaultType, vaultDsn, "", key)
+	require.NoError(t, err)
+	defer vault.Close()
+	value, err := vault.Detokenize(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, "john@example.com", string(value))
+}
+
+func TestTokenizeTransformer_validation(t *testing.T) {
+	driver, _ := getDriverAndRecord("data", "test")
+	vaultDsn := filepath.Join(t.TempDir(), "vault.db")
+
+	tests := []struct {
+		name   string
+		params map[string]toolkit.ParamsValue
+	}{
+		{
+			name: "missing key",
+			params: map[string]toolkit.ParamsValue{
+				"column":    toolkit.ParamsValue("data"),
+				"vault_dsn": toolkit.ParamsValue(vaultDsn),
+			},
+		},
+		{
+			name: "short key",
+			params: map[string]toolkit.ParamsValue{
+				"column":    toolkit.ParamsValue("data"),
+				"vault_dsn": toolkit.ParamsValue(vaultDsn),
+				"key":       toolkit.ParamsValue("0011"),
+			},
+		},
+		{
+			name: "missing vault dsn",
+			params: map[string]toolkit.ParamsValue{
+				"column": toolkit.ParamsValue("data"),
+				"key":    toolkit.ParamsValue(tokenizeTestKey),
+			},
+		},
+		{
+			name: "unknown vault",
+			params: map[string]toolkit.ParamsValue{
+				"column":    toolkit.ParamsValue("data"),
+				"vault":     toolkit.ParamsValue("redis"),
+				"vault_dsn": toolkit.ParamsValue(vaultDsn),
+				"key":       toolkit.ParamsValue(tokenizeTestKey),
+			},
+		},
+		{
+			name: "short token",
+			params: map[string]toolkit.ParamsValue{
+				"column":       toolkit.ParamsValue("data"),
+				"vault_dsn":    toolkit.ParamsValue(vaultDsn),
+				"key":          toolkit.ParamsValue(tokenizeTestKey),
+				"token_length": toolkit.ParamsValue("4"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, warnings, err := TokenizeTransformerDefinition.Instance(context.Background(), driver, tt.params, nil, "")
+			require.NoError(t, err)
+			require.True(t, warnings.IsFatal())
+		})
+	}
+}
+
+func TestTokenizeTransformer_validation_column_length(t *testing.T) {
+	table := &toolkit.Table{
+		Schema: "public",
+		Name:   "test",
+		Oid:    1224,
+		Columns: []*toolkit.Column{
+			{
+				Name:       "data",
+				TypeName:   "varchar",
+				TypeOid:    pgtype.VarcharOID,
+				Num:        1,
+				Length:     20 + varlenaHeaderSize,
+				TypeLength: -1,
+			},
+		},
+		Constraints: []toolkit.Constraint{},
+	}
+	driver, warnings, err := toolkit.NewDriver(table, nil)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	params := map[string]toolkit.ParamsValue{
+		"column":    toolkit.ParamsValue("data"),
+		"vault_dsn": toolkit.ParamsValue(filepath.Join(t.TempDir(), "vault.db")),
+		"key":       toolkit.ParamsValue(tokenizeTestKey),
+	}
+
+	params["token_prefix"] = toolkit.ParamsValue("tok_")
+	_, warnings, err = TokenizeTransformerDefinition.Instance(context.Background(), driver, params, nil, "")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	params["token_prefix"] = toolkit.ParamsValue("token_")
+	_, warnings, err = TokenizeTransformerDefinition.Instance(context.Background(), driver, params, nil, "")
+	require.NoError(t, err)
+	require.True(t, warnings.IsFatal())
+	require.Equal(t, 22, warnings[0].Meta["TokenLength"])
+	require.Equal(t, 20, warnings[0].Meta["ColumnMaxLength"])
+}
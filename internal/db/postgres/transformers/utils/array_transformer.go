@@ -0,0 +1,184 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+// arrayElementTypeLengths - pg_type.typlen of the fixed-size built-in types. The column introspection provides the
+// length of the array type only, but some transformers depend on the size of the element type
+var arrayElementTypeLengths = map[uint32]int{
+	pgtype.BoolOID:        1,
+	pgtype.Int2OID:        2,
+	pgtype.Int4OID:        4,
+	pgtype.Int8OID:        8,
+	pgtype.Float4OID:      4,
+	pgtype.Float8OID:      8,
+	pgtype.OIDOID:         4,
+	pgtype.DateOID:        4,
+	pgtype.TimeOID:        8,
+	pgtype.TimestampOID:   8,
+	pgtype.TimestamptzOID: 8,
+	pgtype.IntervalOID:    16,
+	pgtype.UUIDOID:        16,
+}
+
+// ArrayTransformer - applies the scalar transformer to each element of the array column. The scalar transformer is
+// created with the driver where the array column has the element type, so it works with the elements as with the
+// ordinary column values. NULL elements, dimensions and bounds of the array are kept
+type ArrayTransformer struct {
+	t         Transformer
+	columnIdx int
+	record    *toolkit.Record
+	buf       []byte
+}
+
+func NewArrayTransformer(t Transformer, elementDriver *toolkit.Driver, columnIdx int) *ArrayTransformer {
+	return &ArrayTransformer{
+		t:         t,
+		columnIdx: columnIdx,
+		record:    toolkit.NewRecord(elementDriver),
+	}
+}
+
+func (at *ArrayTransformer) GetAffectedColumns() map[int]string {
+	return at.t.GetAffectedColumns()
+}
+
+func (at *ArrayTransformer) Init(ctx context.Context) error {
+	return at.t.Init(ctx)
+}
+
+func (at *ArrayTransformer) Done(ctx context.Context) error {
+	return at.t.Done(ctx)
+}
+
+func (at *ArrayTransformer) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	val, err := r.GetRawColumnValueByIdx(at.columnIdx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan attribute value: %w", err)
+	}
+	if val.IsNull {
+		return r, nil
+	}
+	arr, err := ParseTextArray(val.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	// The element record shares the row with the original record, so the scalar transformer can use the values of
+	// the other columns
+	at.record.SetRow(r.Row)
+	for _, e := range arr.Elements {
+		if e.IsNull {
+			continue
+		}
+		if err = at.record.SetRawColumnValueByIdx(at.columnIdx, e); err != nil {
+			return nil, fmt.Errorf("unable to set array element: %w", err)
+		}
+		if _, err = at.t.Transform(ctx, at.record); err != nil {
+			return nil, fmt.Errorf("unable to transform array element: %w", err)
+		}
+		res, err := at.record.GetRawColumnValueByIdx(at.columnIdx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get transformed array element: %w", err)
+		}
+		// The transformer may reuse the buffer of the result, so it is copied
+		e.IsNull = res.IsNull
+		e.Data = bytes.Clone(res.Data)
+	}
+
+	at.buf = arr.Encode(at.buf[:0])
+	if err = r.SetRawColumnValueByIdx(at.columnIdx, toolkit.NewRawValue(at.buf, false)); err != nil {
+		return nil, fmt.Errorf("unable to set new value: %w", err)
+	}
+	return r, nil
+}
+
+// getArrayElementDriver - returns the driver where the affected array column has the element type. It returns false
+// if the transformer has no single affected column, the column is not an array, the transformer accepts the array
+// type itself or does not accept the element type
+func (d *TransformerDefinition) getArrayElementDriver(
+	driver *toolkit.Driver, rawParams map[string]toolkit.ParamsValue,
+) (*toolkit.Driver, int, bool, error) {
+	if d.Properties.IsCustom {
+		return nil, 0, false, nil
+	}
+	var columnParam *toolkit.ParameterDefinition
+	for _, pd := range d.Parameters {
+		if !pd.IsColumn || pd.ColumnProperties == nil || !pd.ColumnProperties.Affected {
+			continue
+		}
+		if columnParam != nil {
+			return nil, 0, false, nil
+		}
+		columnParam = pd
+	}
+	if columnParam == nil || len(columnParam.ColumnProperties.AllowedTypes) == 0 {
+		return nil, 0, false, nil
+	}
+	idx, c, ok := driver.GetColumnByName(string(rawParams[columnParam.Name]))
+	if !ok {
+		return nil, 0, false, nil
+	}
+
+	typeName, typeOid := c.GetType()
+	t, ok := driver.SharedTypeMap.TypeForOID(uint32(typeOid))
+	if !ok {
+		return nil, 0, false, nil
+	}
+	codec, ok := t.Codec.(*pgtype.ArrayCodec)
+	if !ok {
+		return nil, 0, false, nil
+	}
+	allowedTypes := columnParam.ColumnProperties.AllowedTypes
+	if toolkit.IsTypeAllowedWithTypeMap(driver, allowedTypes, typeName, typeOid, true) ||
+		!toolkit.IsTypeAllowedWithTypeMap(driver, allowedTypes, codec.ElementType.Name, toolkit.Oid(codec.ElementType.OID), true) {
+		return nil, 0, false, nil
+	}
+
+	table := *driver.Table
+	table.Columns = slices.Clone(driver.Table.Columns)
+	elementColumn := *c
+	elementColumn.TypeName = codec.ElementType.Name
+	elementColumn.CanonicalTypeName = codec.ElementType.Name
+	elementColumn.TypeOid = toolkit.Oid(codec.ElementType.OID)
+	elementColumn.TypeLength = getArrayElementTypeLength(codec.ElementType.OID)
+	elementColumn.OverriddenTypeName = ""
+	elementColumn.OverriddenTypeOid = 0
+	elementColumn.OverriddenTypeSize = 0
+	table.Columns[idx] = &elementColumn
+
+	elementDriver, _, err := toolkit.NewDriver(&table, driver.CustomTypes)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("unable to create array element driver: %w", err)
+	}
+	return elementDriver, idx, true, nil
+}
+
+func getArrayElementTypeLength(oid uint32) int {
+	if l, ok := arrayElementTypeLengths[oid]; ok {
+		return l
+	}
+	return -1
+}
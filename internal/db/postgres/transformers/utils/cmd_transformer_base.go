@@ -54,6 +54,7 @@ type CmdTransformerBase struct {
 	receiveChan chan struct{}
 	opIsDone    chan struct{}
 	terminated  bool
+	batchRows   []toolkit.RowDriver
 }
 
 func NewCmdTransformerBase(
@@ -161,6 +162,87 @@ func (ctb *CmdTransformerBase) Transform(ctx context.Context, r *toolkit.Record)
 	return r, nil
 }
 
+// TransformBatch - transforms the records. If the interaction API supports batches, the records are sent in a single
+// message and the transformed records must be received in a single message too. The transformed values reference
+// the API buffers until the next call. The records are not changed if the transformation fails. Otherwise, the
+// records are transformed one by one and the values are copied, since the API buffers are reused for each record
+func (ctb *CmdTransformerBase) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	batchApi, ok := ctb.Api.(toolkit.BatchInteractionApi)
+	if !ok || len(records) <= 1 {
+		for _, r := range records {
+			if _, err := ctb.Transform(ctx, r); err != nil {
+				return err
+			}
+			if len(records) > 1 {
+				if err := DetachValues(r, nil); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	ctb.ProcessedLines += len(records)
+	ctx, cancel := context.WithTimeout(ctx, ctb.RowTransformationTimeout*time.Duration(len(records)))
+	defer cancel()
+
+	for _, r := range records {
+		rd, err := batchApi.GetRowDriverFromRecord(r)
+		if err != nil {
+			return fmt.Errorf("dto api error: error getting dto: %w", err)
+		}
+		if err = batchApi.AppendRow(rd); err != nil {
+			return fmt.Errorf("interaction api error: cannot encode tuple: %w", err)
+		}
+	}
+
+	var err error
+	ctb.batchRows = ctb.batchRows[:0]
+	go func() {
+		defer func() {
+			ctb.opIsDone <- struct{}{}
+		}()
+		if err = batchApi.Flush(ctx); err != nil {
+			err = fmt.Errorf("interaction api error: cannot send tuples to transformer: %w", err)
+			return
+		}
+		for range records {
+			var rd toolkit.RowDriver
+			rd, err = batchApi.Decode(ctx)
+			if err != nil {
+				err = fmt.Errorf("interaction api error: cannot receive transformed tuple from transformer: %w", err)
+				return
+			}
+			ctb.batchRows = append(ctb.batchRows, rd)
+			if batchApi.PendingRows() != len(records)-len(ctb.batchRows) {
+				err = fmt.Errorf(
+					"interaction api error: expected %d transformed tuples in the message", len(records),
+				)
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrRowTransformationTimeout
+		}
+		return ctx.Err()
+	case <-ctb.opIsDone:
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, r := range records {
+		if err = batchApi.SetRowDriverToRecord(ctb.batchRows[i], r); err != nil {
+			return fmt.Errorf("interaction api error: error setting transfomed data to record: %w", err)
+		}
+	}
+	batchApi.Clean()
+	return nil
+}
+
 func (ctb *CmdTransformerBase) BaseInitWithContext(ctx context.Context, executable string, args []string) error {
 	log.Debug().
 		Str("Executable", executable).
@@ -17,6 +17,7 @@ package utils
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
@@ -56,6 +57,14 @@ func (d *TransformerDefinition) Instance(
 	ctx context.Context, driver *toolkit.Driver, rawParams map[string]toolkit.ParamsValue, dynamicParameters map[string]*toolkit.DynamicParamValue,
 	whenCond string,
 ) (*TransformerContext, toolkit.ValidationWarnings, error) {
+	elementDriver, columnIdx, isArray, err := d.getArrayElementDriver(driver, rawParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isArray {
+		return d.instanceForArray(ctx, driver, elementDriver, columnIdx, rawParams, dynamicParameters, whenCond)
+	}
+
 	// DecodeValue parameters and get the pgcopy of parsed
 	params, parametersWarnings, err := toolkit.InitParameters(driver, d.Parameters, rawParams, dynamicParameters)
 	if err != nil {
@@ -108,6 +117,7 @@ func (d *TransformerDefinition) Instance(
 	res = append(res, condWarns...)
 
 	return &TransformerContext{
+		Name:              d.Properties.Name,
 		Transformer:       t,
 		StaticParameters:  staticParams,
 		DynamicParameters: dynamicParams,
@@ -115,13 +125,64 @@ func (d *TransformerDefinition) Instance(
 	}, res, nil
 }
 
+// instanceForArray - creates the scalar transformer for the elements of the array column and wraps it into
+// ArrayTransformer. The when condition is evaluated for the original record, so it is created with the table driver
+func (d *TransformerDefinition) instanceForArray(
+	ctx context.Context, driver, elementDriver *toolkit.Driver, columnIdx int,
+	rawParams map[string]toolkit.ParamsValue, dynamicParameters map[string]*toolkit.DynamicParamValue, whenCond string,
+) (*TransformerContext, toolkit.ValidationWarnings, error) {
+	// The element driver has no array column, so the recursion stops here
+	tc, warnings, err := d.Instance(ctx, elementDriver, rawParams, dynamicParameters, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if warnings.IsFatal() {
+		return nil, warnings, nil
+	}
+
+	meta := map[string]interface{}{
+		"TableSchema": driver.Table.Schema,
+		"TableName":   driver.Table.Name,
+		"Transformer": d.Properties.Name,
+	}
+	when, condWarns := toolkit.NewWhenCond(whenCond, driver, meta)
+	warnings = append(warnings, condWarns...)
+
+	tc.Transformer = NewArrayTransformer(tc.Transformer, elementDriver, columnIdx)
+	tc.When = when
+	return tc, warnings, nil
+}
+
 type TransformerContext struct {
+	Name              string
 	Transformer       Transformer
 	StaticParameters  map[string]*toolkit.StaticParameter
 	DynamicParameters map[string]*toolkit.DynamicParameter
 	When              *toolkit.WhenCond
+	// Profile - the cost of the transformer calls made via Transform and TransformBatch
+	Profile TransformerProfile
 }
 
 func (tc *TransformerContext) EvaluateWhen(r *toolkit.Record) (bool, error) {
 	return tc.When.Evaluate(r)
 }
+
+// Transform - calls the transformer and registers the call in the profile
+func (tc *TransformerContext) Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error) {
+	start := time.Now()
+	res, err := tc.Transformer.Transform(ctx, r)
+	tc.Profile.Observe(1, time.Since(start))
+	return res, err
+}
+
+// TransformBatch - calls the batch transformer and registers the call in the profile
+func (tc *TransformerContext) TransformBatch(ctx context.Context, records []*toolkit.Record) error {
+	bt, ok := tc.Transformer.(BatchTransformer)
+	if !ok {
+		return fmt.Errorf("transformer %s does not support the batch transformation", tc.Name)
+	}
+	start := time.Now()
+	err := bt.TransformBatch(ctx, records)
+	tc.Profile.Observe(len(records), time.Since(start))
+	return err
+}
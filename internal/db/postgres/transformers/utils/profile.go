@@ -0,0 +1,50 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"time"
+)
+
+// TransformerProfile - the cumulative cost of the transformer on the table. Calls is the number of the transformer
+// calls and Rows is the number of the transformed records, they differ for the batch transformation. The duration
+// includes the round-trip of the custom (Cmd) transformers and the Prepare and Apply calls of the postponed ones
+type TransformerProfile struct {
+	Calls    uint64
+	Rows     uint64
+	Duration time.Duration
+}
+
+// Observe - registers the call that transformed the rows in the duration
+func (p *TransformerProfile) Observe(rows int, d time.Duration) {
+	p.Calls++
+	p.Rows += uint64(rows)
+	p.Duration += d
+}
+
+// Add - adds the other profile, for instance the profile of the parallel worker instance of the transformer
+func (p *TransformerProfile) Add(other *TransformerProfile) {
+	p.Calls += other.Calls
+	p.Rows += other.Rows
+	p.Duration += other.Duration
+}
+
+// AvgRowDuration - returns the average duration of the transformation per row
+func (p *TransformerProfile) AvgRowDuration() time.Duration {
+	if p.Rows == 0 {
+		return 0
+	}
+	return p.Duration / time.Duration(p.Rows)
+}
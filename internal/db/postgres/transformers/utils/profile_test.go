@@ -0,0 +1,39 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformerProfile_Observe(t *testing.T) {
+	p := &TransformerProfile{}
+	p.Observe(1, time.Millisecond)
+	p.Observe(3, 5*time.Millisecond)
+	require.Equal(t, uint64(2), p.Calls)
+	require.Equal(t, uint64(4), p.Rows)
+	require.Equal(t, 6*time.Millisecond, p.Duration)
+	require.Equal(t, 1500*time.Microsecond, p.AvgRowDuration())
+}
+
+func TestTransformerProfile_Add(t *testing.T) {
+	p := &TransformerProfile{Calls: 1, Rows: 1, Duration: time.Second}
+	p.Add(&TransformerProfile{Calls: 2, Rows: 10, Duration: 2 * time.Second})
+	require.Equal(t, &TransformerProfile{Calls: 3, Rows: 11, Duration: 3 * time.Second}, p)
+	require.Equal(t, time.Duration(0), (&TransformerProfile{}).AvgRowDuration())
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"strings"
+)
+
+// PushdownTransformer - the transformer that can be performed by PostgreSQL in the COPY query. PushdownExpressions
+// returns the SQL expressions of the affected columns by column index. The expressions are computed from the
+// original column values. It returns false if the transformer cannot be pushed down with the provided parameters
+type PushdownTransformer interface {
+	Transformer
+	PushdownExpressions() (map[int]string, bool)
+}
+
+// QuoteLiteral - quotes the string as a SQL literal in the same way as PostgreSQL quote_literal function does
+func QuoteLiteral(s string) string {
+	res := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if strings.Contains(s, `\`) {
+		res = "E" + strings.ReplaceAll(res, `\`, `\\`)
+	}
+	return res
+}
+
+// QuoteIdent - quotes the SQL identifier
+func QuoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// NullSafeExpression - returns the expression that keeps the NULL values of the column if keepNull is set
+func NullSafeExpression(column, expr string, keepNull bool) string {
+	if !keepNull {
+		return expr
+	}
+	return "CASE WHEN " + QuoteIdent(column) + " IS NULL THEN NULL ELSE " + expr + " END"
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteLiteral(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "test", expected: `'test'`},
+		{value: "it's", expected: `'it''s'`},
+		{value: `a\b`, expected: `E'a\\b'`},
+		{value: "", expected: `''`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, QuoteLiteral(tt.value))
+		})
+	}
+}
+
+func TestNullSafeExpression(t *testing.T) {
+	assert.Equal(t, "NULL", NullSafeExpression("col", "NULL", false))
+	assert.Equal(t, `CASE WHEN "co""l" IS NULL THEN NULL ELSE 1 END`, NullSafeExpression(`co"l`, "1", true))
+}
@@ -0,0 +1,272 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/eminano/greenmask/pkg/toolkit"
+)
+
+const textArrayDelimiter = ','
+
+// TextArray - PostgreSQL array in text format. The elements of multidimensional array are stored in the flat list
+// in row-major order. The explicit bounds decoration (for instance "[0:1]=") is kept as is
+type TextArray struct {
+	Bounds   []byte
+	Dims     []int
+	Elements []*toolkit.RawValue
+}
+
+// ParseTextArray - parses one- or multi-dimensional array literal. The element values are copied, so the provided
+// data may be reused after parsing
+func ParseTextArray(data []byte) (*TextArray, error) {
+	p := &textArrayParser{data: data, leafDim: -1}
+	res := &TextArray{}
+	if p.peek() == '[' {
+		end := bytes.IndexByte(data, '=')
+		if end == -1 {
+			return nil, fmt.Errorf("invalid array bounds decoration")
+		}
+		res.Bounds = bytes.Clone(data[:end+1])
+		p.pos = end + 1
+	}
+	p.skipSpaces()
+	if err := p.parseDimension(res, 0); err != nil {
+		return nil, fmt.Errorf("unable to parse array: %w", err)
+	}
+	p.skipSpaces()
+	if p.pos != len(data) {
+		return nil, fmt.Errorf("unable to parse array: unexpected data after array end at position %d", p.pos)
+	}
+	return res, nil
+}
+
+// Encode - encodes the array into text format keeping the dimensions and bounds
+func (a *TextArray) Encode(buf []byte) []byte {
+	buf = append(buf, a.Bounds...)
+	if len(a.Dims) == 0 {
+		return append(buf, '{', '}')
+	}
+	var pos int
+	return a.encodeDimension(buf, 0, &pos)
+}
+
+func (a *TextArray) encodeDimension(buf []byte, dim int, pos *int) []byte {
+	buf = append(buf, '{')
+	for i := 0; i < a.Dims[dim]; i++ {
+		if i > 0 {
+			buf = append(buf, textArrayDelimiter)
+		}
+		if dim < len(a.Dims)-1 {
+			buf = a.encodeDimension(buf, dim+1, pos)
+			continue
+		}
+		buf = encodeTextArrayElement(buf, a.Elements[*pos])
+		*pos++
+	}
+	return append(buf, '}')
+}
+
+func encodeTextArrayElement(buf []byte, v *toolkit.RawValue) []byte {
+	if v.IsNull {
+		return append(buf, "NULL"...)
+	}
+	if !textArrayElementNeedsQuoting(v.Data) {
+		return append(buf, v.Data...)
+	}
+	buf = append(buf, '"')
+	for _, c := range v.Data {
+		if c == '"' || c == '\\' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, '"')
+}
+
+func textArrayElementNeedsQuoting(data []byte) bool {
+	if len(data) == 0 || strings.EqualFold(string(data), "NULL") {
+		return true
+	}
+	for _, c := range data {
+		switch c {
+		case '{', '}', '"', '\\', textArrayDelimiter, ' ', '\t', '\n', '\r', '\v', '\f':
+			return true
+		}
+	}
+	return false
+}
+
+type textArrayParser struct {
+	data []byte
+	pos  int
+	// leafDim - the dimension that contains the elements
+	leafDim int
+}
+
+func (p *textArrayParser) peek() byte {
+	if p.pos >= len(p.data) {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+func (p *textArrayParser) skipSpaces() {
+	for p.pos < len(p.data) && isTextArraySpace(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *textArrayParser) expect(c byte) error {
+	if p.peek() != c {
+		return fmt.Errorf("expected '%c' at position %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+// parseDimension - parses the sub array of the dimension and checks that all the sub arrays of the same dimension
+// have the same length
+func (p *textArrayParser) parseDimension(a *TextArray, dim int) error {
+	if err := p.expect('{'); err != nil {
+		return err
+	}
+	p.skipSpaces()
+	var count int
+	if p.peek() == '}' {
+		p.pos++
+		if dim > 0 {
+			return fmt.Errorf("empty sub array at position %d", p.pos)
+		}
+		return nil
+	}
+	// the dimension lengths are stored from the outer to the inner dimension
+	if len(a.Dims) <= dim {
+		a.Dims = append(a.Dims, -1)
+	}
+	for {
+		p.skipSpaces()
+		if p.peek() == '{' {
+			if p.leafDim != -1 && dim >= p.leafDim {
+				return fmt.Errorf("unexpected sub array at position %d", p.pos)
+			}
+			if err := p.parseDimension(a, dim+1); err != nil {
+				return err
+			}
+		} else {
+			if p.leafDim == -1 {
+				p.leafDim = dim
+			} else if p.leafDim != dim {
+				return fmt.Errorf("expected sub array at position %d", p.pos)
+			}
+			v, err := p.parseElement()
+			if err != nil {
+				return err
+			}
+			a.Elements = append(a.Elements, v)
+		}
+		count++
+		p.skipSpaces()
+		switch p.peek() {
+		case textArrayDelimiter:
+			p.pos++
+			continue
+		case '}':
+			p.pos++
+		default:
+			return fmt.Errorf("expected '%c' or '}' at position %d", textArrayDelimiter, p.pos)
+		}
+		break
+	}
+	if a.Dims[dim] == -1 {
+		a.Dims[dim] = count
+	} else if a.Dims[dim] != count {
+		return fmt.Errorf("multidimensional array must have sub arrays with matching dimensions")
+	}
+	return nil
+}
+
+func (p *textArrayParser) parseElement() (*toolkit.RawValue, error) {
+	if p.peek() == '"' {
+		p.pos++
+		var res []byte
+		for {
+			if p.pos >= len(p.data) {
+				return nil, fmt.Errorf("unterminated quoted element")
+			}
+			c := p.data[p.pos]
+			p.pos++
+			switch c {
+			case '"':
+				return toolkit.NewRawValue(res, false), nil
+			case '\\':
+				if p.pos >= len(p.data) {
+					return nil, fmt.Errorf("unterminated escape sequence")
+				}
+				c = p.data[p.pos]
+				p.pos++
+			}
+			res = append(res, c)
+		}
+	}
+
+	var res []byte
+	var escaped bool
+	// trailing spaces of the unquoted element are ignored but the escaped ones are kept
+	var significantLength int
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == textArrayDelimiter || c == '}' {
+			break
+		}
+		if c == '{' || c == '"' {
+			return nil, fmt.Errorf("unexpected '%c' at position %d", c, p.pos)
+		}
+		p.pos++
+		if c == '\\' {
+			if p.pos >= len(p.data) {
+				return nil, fmt.Errorf("unterminated escape sequence")
+			}
+			res = append(res, p.data[p.pos])
+			p.pos++
+			escaped = true
+			significantLength = len(res)
+			continue
+		}
+		res = append(res, c)
+		if !isTextArraySpace(c) {
+			significantLength = len(res)
+		}
+	}
+	res = res[:significantLength]
+	if len(res) == 0 {
+		return nil, fmt.Errorf("empty unquoted element at position %d", p.pos)
+	}
+	if !escaped && strings.EqualFold(string(res), "NULL") {
+		return toolkit.NewRawValue(nil, true), nil
+	}
+	return toolkit.NewRawValue(res, false), nil
+}
+
+func isTextArraySpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
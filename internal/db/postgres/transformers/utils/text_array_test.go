@@ -0,0 +1,109 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTextArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		dims     []int
+		elements []*string
+		expected string
+	}{
+		{
+			name:     "empty",
+			original: "{}",
+			expected: "{}",
+		},
+		{
+			name:     "one dimension",
+			original: "{1,2,3}",
+			dims:     []int{3},
+			elements: []*string{strPtr("1"), strPtr("2"), strPtr("3")},
+			expected: "{1,2,3}",
+		},
+		{
+			name:     "null elements",
+			original: `{a,NULL,"NULL",null}`,
+			dims:     []int{4},
+			elements: []*string{strPtr("a"), nil, strPtr("NULL"), nil},
+			expected: `{a,NULL,"NULL",NULL}`,
+		},
+		{
+			name:     "quoted elements",
+			original: `{"a b","c,d","e\"f","g\\h",""," i "}`,
+			dims:     []int{6},
+			elements: []*string{strPtr("a b"), strPtr("c,d"), strPtr(`e"f`), strPtr(`g\h`), strPtr(""), strPtr(" i ")},
+			expected: `{"a b","c,d","e\"f","g\\h",""," i "}`,
+		},
+		{
+			name:     "unquoted with spaces",
+			original: `{ a , b c }`,
+			dims:     []int{2},
+			elements: []*string{strPtr("a"), strPtr("b c")},
+			expected: `{a,"b c"}`,
+		},
+		{
+			name:     "multidimensional",
+			original: "{{1,2,3},{4,NULL,6}}",
+			dims:     []int{2, 3},
+			elements: []*string{strPtr("1"), strPtr("2"), strPtr("3"), strPtr("4"), nil, strPtr("6")},
+			expected: "{{1,2,3},{4,NULL,6}}",
+		},
+		{
+			name:     "bounds",
+			original: "[0:1][2:2]={{a},{b}}",
+			dims:     []int{2, 1},
+			elements: []*string{strPtr("a"), strPtr("b")},
+			expected: "[0:1][2:2]={{a},{b}}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arr, err := ParseTextArray([]byte(tt.original))
+			require.NoError(t, err)
+			require.Equal(t, tt.dims, arr.Dims)
+			require.Len(t, arr.Elements, len(tt.elements))
+			for idx, e := range tt.elements {
+				if e == nil {
+					require.True(t, arr.Elements[idx].IsNull)
+					continue
+				}
+				require.False(t, arr.Elements[idx].IsNull)
+				require.Equal(t, *e, string(arr.Elements[idx].Data))
+			}
+			require.Equal(t, tt.expected, string(arr.Encode(nil)))
+		})
+	}
+}
+
+func TestParseTextArray_errors(t *testing.T) {
+	for _, v := range []string{"", "{", "{1,2", "{{1,2},{3}}", "{{1},2}", "{1,{2}}", `{"a}`, "{1,,2}", "{1}x", "[1:2]{1,2}"} {
+		t.Run(v, func(t *testing.T) {
+			_, err := ParseTextArray([]byte(v))
+			require.Error(t, err)
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -15,7 +15,9 @@
 package utils
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
@@ -26,3 +28,64 @@ type Transformer interface {
 	Transform(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error)
 	GetAffectedColumns() map[int]string
 }
+
+// BatchTransformer - the transformer that transforms a slice of records at once, for instance to perform a single
+// lookup for all of them. The pipeline calls TransformBatch instead of Transform when the table batch_size is set.
+// The records are transformed in place and the values set into them must stay valid until the next TransformBatch
+// call
+type BatchTransformer interface {
+	Transformer
+	TransformBatch(ctx context.Context, records []*toolkit.Record) error
+}
+
+// PostponedTransformer - the transformer that requires all the table records before producing the result. The
+// pipeline calls Transform for each record to collect the data, stores the transformed records and, after the last
+// record, calls Prepare and then Apply for each stored record collected by Transform in the same order
+type PostponedTransformer interface {
+	Transformer
+	// Prepare - is called after the last record is collected
+	Prepare(ctx context.Context) error
+	// Apply - sets the result values of the collected record
+	Apply(ctx context.Context, r *toolkit.Record) (*toolkit.Record, error)
+	// CollectedCount - returns the number of the collected records. The pipeline uses it to find out whether the
+	// record was collected, since Transform is not called when the "when" condition is false
+	CollectedCount() uint64
+}
+
+// PooledTransformer - the BatchTransformer that distributes the batch records between several workers. Transform
+// uses a single worker, so the pool is useful only when the records are transformed by batches
+type PooledTransformer interface {
+	BatchTransformer
+	// PoolSize - returns the number of the workers
+	PoolSize() int
+}
+
+// DetachValues - replaces the values of the columns with their copies, so the record does not reference the buffers
+// that are reused by the transformer for the next record. If columns is empty, all the columns are copied
+func DetachValues(r *toolkit.Record, columns map[int]string) error {
+	if len(columns) == 0 {
+		for _, c := range r.Driver.Table.Columns {
+			if err := detachValue(r, c.Idx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for idx := range columns {
+		if err := detachValue(r, idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func detachValue(r *toolkit.Record, idx int) error {
+	v, err := r.GetRawColumnValueByIdx(idx)
+	if err != nil {
+		return fmt.Errorf("error getting column value: %w", err)
+	}
+	if err = r.SetRawColumnValueByIdx(idx, toolkit.NewRawValue(bytes.Clone(v.Data), v.IsNull)); err != nil {
+		return fmt.Errorf("error setting column value: %w", err)
+	}
+	return nil
+}
@@ -65,16 +65,26 @@ type Config struct {
 }
 
 type Validate struct {
-	Tables           []string `mapstructure:"tables" yaml:"tables" json:"tables,omitempty"`
-	Data             bool     `mapstructure:"data" yaml:"data" json:"data,omitempty"`
-	Diff             bool     `mapstructure:"diff" yaml:"diff" json:"diff,omitempty"`
-	Schema           bool     `mapstructure:"schema" yaml:"schema" json:"schema,omitempty"`
-	RowsLimit        uint64   `mapstructure:"rows_limit" yaml:"rows_limit" json:"rows_limit,omitempty"`
-	ResolvedWarnings []string `mapstructure:"resolved_warnings" yaml:"resolved_warnings" json:"resolved_warnings,omitempty"`
-	TableFormat      string   `mapstructure:"table_format" yaml:"table_format" json:"table_format,omitempty"`
-	Format           string   `mapstructure:"format" yaml:"format" json:"format,omitempty"`
-	OnlyTransformed  bool     `mapstructure:"transformed_only" yaml:"transformed_only" json:"transformed_only,omitempty"`
-	Warnings         bool     `mapstructure:"warnings" yaml:"warnings" json:"warnings,omitempty"`
+	Tables           []string           `mapstructure:"tables" yaml:"tables" json:"tables,omitempty"`
+	Data             bool               `mapstructure:"data" yaml:"data" json:"data,omitempty"`
+	Diff             bool               `mapstructure:"diff" yaml:"diff" json:"diff,omitempty"`
+	Schema           bool               `mapstructure:"schema" yaml:"schema" json:"schema,omitempty"`
+	RowsLimit        uint64             `mapstructure:"rows_limit" yaml:"rows_limit" json:"rows_limit,omitempty"`
+	ResolvedWarnings []string           `mapstructure:"resolved_warnings" yaml:"resolved_warnings" json:"resolved_warnings,omitempty"`
+	TableFormat      string             `mapstructure:"table_format" yaml:"table_format" json:"table_format,omitempty"`
+	Format           string             `mapstructure:"format" yaml:"format" json:"format,omitempty"`
+	OnlyTransformed  bool               `mapstructure:"transformed_only" yaml:"transformed_only" json:"transformed_only,omitempty"`
+	Warnings         bool               `mapstructure:"warnings" yaml:"warnings" json:"warnings,omitempty"`
+	KAnonymity       []*KAnonymityCheck `mapstructure:"k_anonymity" yaml:"k_anonymity" json:"k_anonymity,omitempty"`
+	Profile          bool               `mapstructure:"profile" yaml:"profile" json:"profile,omitempty"`
+}
+
+// KAnonymityCheck - the set of quasi-identifier columns of the table which k-anonymity is computed over the validated
+// records. The validation fails if the achieved k is less than K
+type KAnonymityCheck struct {
+	Table            string   `mapstructure:"table" yaml:"table" json:"table"`
+	QuasiIdentifiers []string `mapstructure:"quasi_identifiers" yaml:"quasi_identifiers" json:"quasi_identifiers"`
+	K                int      `mapstructure:"k" yaml:"k" json:"k,omitempty"`
 }
 
 type Common struct {
@@ -103,6 +113,17 @@ type Restore struct {
 	PgRestoreOptions pgrestore.Options               `mapstructure:"pg_restore_options" yaml:"pg_restore_options" json:"pg_restore_options"`
 	Scripts          map[string][]pgrestore.Script   `mapstructure:"scripts" yaml:"scripts" json:"scripts,omitempty"`
 	ErrorExclusions  *DataRestorationErrorExclusions `mapstructure:"insert_error_exclusions" yaml:"insert_error_exclusions" json:"insert_error_exclusions,omitempty"`
+	Filters          []*RestoreFilter                `mapstructure:"filters" yaml:"filters" json:"filters,omitempty"`
+	Transformation   []*Table                        `mapstructure:"transformation" yaml:"transformation" json:"transformation,omitempty"`
+}
+
+// RestoreFilter - rows filter of the table applied in restoration. Only the rows that match When condition are
+// restored. If Cascade is true then the rows of the referencing tables that refer to the skipped rows are skipped too
+type RestoreFilter struct {
+	Schema  string `mapstructure:"schema" yaml:"schema" json:"schema,omitempty"`
+	Name    string `mapstructure:"name" yaml:"name" json:"name,omitempty"`
+	When    string `mapstructure:"when" yaml:"when" json:"when,omitempty"`
+	Cascade bool   `mapstructure:"cascade" yaml:"cascade" json:"cascade,omitempty"`
 }
 
 type TablesDataRestorationErrorExclusions struct {
@@ -163,6 +184,9 @@ type Table struct {
 	ColumnsTypeOverride map[string]string    `mapstructure:"columns_type_override" yaml:"columns_type_override" json:"columns_type_override,omitempty"`
 	SubsetConds         []string             `mapstructure:"subset_conds" yaml:"subset_conds" json:"subset_conds,omitempty"`
 	When                string               `mapstructure:"when" yaml:"when" json:"when,omitempty"`
+	BatchSize           int                  `mapstructure:"batch_size" yaml:"batch_size" json:"batch_size,omitempty"`
+	Pushdown            bool                 `mapstructure:"pushdown" yaml:"pushdown" json:"pushdown,omitempty"`
+	Parallelism         int                  `mapstructure:"parallelism" yaml:"parallelism" json:"parallelism,omitempty"`
 }
 
 // DummyConfig - This is a dummy config to the viper workaround
@@ -170,10 +194,15 @@ type Table struct {
 // The reason why is there https://github.com/GreenmaskIO/greenmask/discussions/85
 type DummyConfig struct {
 	Dump struct {
-		Transformation []struct {
-			Transformers []struct {
-				Params map[string]interface{} `yaml:"params" json:"params"`
-			} `yaml:"transformers" json:"transformers"`
-		} `yaml:"transformation" json:"transformation"`
+		Transformation []DummyTable `yaml:"transformation" json:"transformation"`
 	} `yaml:"dump" json:"dump"`
+	Restore struct {
+		Transformation []DummyTable `yaml:"transformation" json:"transformation"`
+	} `yaml:"restore" json:"restore"`
+}
+
+type DummyTable struct {
+	Transformers []struct {
+		Params map[string]interface{} `yaml:"params" json:"params"`
+	} `yaml:"transformers" json:"transformers"`
 }
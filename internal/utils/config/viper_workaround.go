@@ -26,7 +26,8 @@ import (
 	"github.com/eminano/greenmask/pkg/toolkit"
 )
 
-// ParseTransformerParamsManually - manually parse dump.transformation[a].transformers[b].params
+// ParseTransformerParamsManually - manually parse dump.transformation[a].transformers[b].params and
+// restore.transformation[a].transformers[b].params
 // The problem described https://github.com/GreenmaskIO/greenmask/issues/76
 // We need to keep the original keys in the map without lowercasing
 // To overcome this problem we need use default yaml and json parsers avoiding vaiper or mapstructure usage.
@@ -56,11 +57,21 @@ func ParseTransformerParamsManually(cfgFilePath string, cfg *domains.Config) err
 
 // setTransformerParams - get the value from domains.TransformerConfig.MetadataParams, marshall this value and store into
 // domains.TransformerConfig.Params
-func setTransformerParams(tmpCfg *domains.DummyConfig, cfg *domains.Config) (err error) {
-	for tableIdx, tableObj := range tmpCfg.Dump.Transformation {
+func setTransformerParams(tmpCfg *domains.DummyConfig, cfg *domains.Config) error {
+	if err := setTablesTransformerParams(tmpCfg.Dump.Transformation, cfg.Dump.Transformation); err != nil {
+		return fmt.Errorf("dump transformation: %w", err)
+	}
+	if err := setTablesTransformerParams(tmpCfg.Restore.Transformation, cfg.Restore.Transformation); err != nil {
+		return fmt.Errorf("restore transformation: %w", err)
+	}
+	return nil
+}
+
+func setTablesTransformerParams(tmpTables []domains.DummyTable, tables []*domains.Table) (err error) {
+	for tableIdx, tableObj := range tmpTables {
 		for transformationIdx, transformationObj := range tableObj.Transformers {
-			transformer := cfg.Dump.Transformation[tableIdx].Transformers[transformationIdx]
-			tmpTransformer := tmpCfg.Dump.Transformation[tableIdx].Transformers[transformationIdx]
+			transformer := tables[tableIdx].Transformers[transformationIdx]
+			tmpTransformer := tmpTables[tableIdx].Transformers[transformationIdx]
 			paramsMap := make(map[string]toolkit.ParamsValue, len(transformationObj.Params))
 			for paramName, decodedValue := range tmpTransformer.Params {
 				var encodedVal toolkit.ParamsValue
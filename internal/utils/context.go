@@ -1,9 +1,22 @@
 package utils
 
-import "context"
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
 
 type saltKey struct{}
 
+type querierKey struct{}
+
+type tmpDirKey struct{}
+
+// Querier - the source database connection that is available during the transformers initialization
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
 func WithSalt(ctx context.Context, salt []byte) context.Context {
 	return context.WithValue(ctx, saltKey{}, salt)
 }
@@ -12,3 +25,24 @@ func SaltFromCtx(ctx context.Context) []byte {
 	salt, _ := ctx.Value(saltKey{}).([]byte)
 	return salt
 }
+
+func WithQuerier(ctx context.Context, q Querier) context.Context {
+	return context.WithValue(ctx, querierKey{}, q)
+}
+
+func QuerierFromCtx(ctx context.Context) Querier {
+	q, _ := ctx.Value(querierKey{}).(Querier)
+	return q
+}
+
+// WithTmpDir - sets the configured directory for the temporary files of the transformers
+func WithTmpDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, tmpDirKey{}, dir)
+}
+
+// TmpDirFromCtx - returns the configured directory for the temporary files. It returns an empty string if the
+// directory is not set, so the default directory for temporary files is used
+func TmpDirFromCtx(ctx context.Context) string {
+	dir, _ := ctx.Value(tmpDirKey{}).(string)
+	return dir
+}
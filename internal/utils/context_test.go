@@ -3,6 +3,8 @@ package utils
 import (
 	"context"
 	"testing"
+
+	"github.com/jackc/pgx/v5"
 )
 
 func TestContextSalt(t *testing.T) {
@@ -14,3 +16,21 @@ func TestContextSalt(t *testing.T) {
 		t.Errorf("expected %s, got %s", salt, got)
 	}
 }
+
+type testQuerier struct{}
+
+func (tq *testQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, nil
+}
+
+func TestContextQuerier(t *testing.T) {
+	ctx := context.Background()
+	if QuerierFromCtx(ctx) != nil {
+		t.Errorf("expected nil querier")
+	}
+	q := &testQuerier{}
+	ctx = WithQuerier(ctx, q)
+	if QuerierFromCtx(ctx) != q {
+		t.Errorf("expected querier to be set")
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extsort
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// recordOverhead - the approximate memory size of the record structure that is added to the key and value length
+const recordOverhead = 64
+
+type record struct {
+	key   []byte
+	value []byte
+}
+
+// Sorter - sorts the key-value records by key using bounded memory. The records are accumulated in memory until the
+// memory limit is reached, then they are sorted and written to the temporary file as a sorted run. The runs are
+// merged when the records are iterated. The records with equal keys are returned in the order they were added
+type Sorter struct {
+	dir         string
+	memoryLimit int
+	memorySize  int
+	records     []*record
+	runs        []*os.File
+}
+
+// NewSorter - creates the sorter. The temporary files are created in dir or in the default directory for temporary
+// files if dir is empty
+func NewSorter(dir string, memoryLimit int) *Sorter {
+	return &Sorter{
+		dir:         dir,
+		memoryLimit: memoryLimit,
+	}
+}
+
+// Add - adds the record. The key and value are copied
+func (s *Sorter) Add(key, value []byte) error {
+	s.records = append(s.records, &record{key: bytes.Clone(key), value: bytes.Clone(value)})
+	s.memorySize += len(key) + len(value) + recordOverhead
+	if s.memorySize >= s.memoryLimit {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush - writes the accumulated records to the temporary file as a sorted run
+func (s *Sorter) flush() error {
+	if len(s.records) == 0 {
+		return nil
+	}
+	s.sortRecords()
+	f, err := os.CreateTemp(s.dir, "greenmask-sort-*")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	s.runs = append(s.runs, f)
+
+	w := bufio.NewWriter(f)
+	for _, r := range s.records {
+		if err = writeRecord(w, r); err != nil {
+			return fmt.Errorf("unable to write sorted run: %w", err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("unable to write sorted run: %w", err)
+	}
+	clear(s.records)
+	s.records = s.records[:0]
+	s.memorySize = 0
+	return nil
+}
+
+func (s *Sorter) sortRecords() {
+	slices.SortStableFunc(s.records, func(a, b *record) int {
+		return bytes.Compare(a.key, b.key)
+	})
+}
+
+// Iterate - calls fn for each record in key order. The key and value are valid only until fn returns
+func (s *Sorter) Iterate(fn func(key, value []byte) error) error {
+	if len(s.runs) == 0 {
+		s.sortRecords()
+		for _, r := range s.records {
+			if err := fn(r.key, r.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+	h := make(runHeap, 0, len(s.runs))
+	for idx, f := range s.runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("unable to rewind sorted run: %w", err)
+		}
+		rr := &runReader{r: bufio.NewReader(f), idx: idx}
+		ok, err := rr.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, rr)
+		}
+	}
+	heap.Init(&h)
+	for len(h) > 0 {
+		rr := h[0]
+		if err := fn(rr.current.key, rr.current.value); err != nil {
+			return err
+		}
+		ok, err := rr.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// Close - removes the temporary files
+func (s *Sorter) Close() error {
+	var errs []error
+	for _, f := range s.runs {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := os.Remove(f.Name()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.runs = nil
+	s.records = nil
+	return errors.Join(errs...)
+}
+
+func writeRecord(w *bufio.Writer, r *record) error {
+	if err := writeBytes(w, r.key); err != nil {
+		return err
+	}
+	return writeBytes(w, r.value)
+}
+
+func writeBytes(w *bufio.Writer, data []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(data)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func readBytes(r *bufio.Reader, buf []byte) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	buf = slices.Grow(buf[:0], int(length))[:length]
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+type runReader struct {
+	r       *bufio.Reader
+	idx     int
+	current record
+}
+
+func (rr *runReader) next() (bool, error) {
+	var err error
+	rr.current.key, err = readBytes(rr.r, rr.current.key)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to read sorted run: %w", err)
+	}
+	rr.current.value, err = readBytes(rr.r, rr.current.value)
+	if err != nil {
+		return false, fmt.Errorf("unable to read sorted run: %w", err)
+	}
+	return true, nil
+}
+
+// runHeap - the heap of the run readers ordered by the current key. The runs are ordered by index for the equal keys,
+// so the merge is stable
+type runHeap []*runReader
+
+func (h runHeap) Len() int {
+	return len(h)
+}
+
+func (h runHeap) Less(i, j int) bool {
+	if c := bytes.Compare(h[i].current.key, h[j].current.key); c != 0 {
+		return c < 0
+	}
+	return h[i].idx < h[j].idx
+}
+
+func (h runHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *runHeap) Push(x any) {
+	*h = append(*h, x.(*runReader))
+}
+
+func (h *runHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
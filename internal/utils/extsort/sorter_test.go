@@ -0,0 +1,81 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extsort
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSorter_Iterate(t *testing.T) {
+	tests := []struct {
+		name        string
+		memoryLimit int
+		expectRuns  bool
+	}{
+		{
+			name:        "in memory",
+			memoryLimit: 1 << 20,
+		},
+		{
+			name:        "external",
+			memoryLimit: 1024,
+			expectRuns:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := NewSorter(dir, tt.memoryLimit)
+			r := rand.New(rand.NewPCG(1, 2))
+			const count = 1000
+			for i := 0; i < count; i++ {
+				key := binary.BigEndian.AppendUint16(nil, uint16(r.IntN(100)))
+				require.NoError(t, s.Add(key, []byte(fmt.Sprintf("%d", i))))
+			}
+			require.Equal(t, tt.expectRuns, len(s.runs) > 0)
+
+			var prevKey uint16
+			var prevValue int
+			var res int
+			err := s.Iterate(func(key, value []byte) error {
+				k := binary.BigEndian.Uint16(key)
+				var v int
+				_, err := fmt.Sscanf(string(value), "%d", &v)
+				require.NoError(t, err)
+				require.GreaterOrEqual(t, k, prevKey)
+				// The order of records with equal keys is kept
+				if k == prevKey && res > 0 {
+					require.Greater(t, v, prevValue)
+				}
+				prevKey, prevValue = k, v
+				res++
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, count, res)
+
+			require.NoError(t, s.Close())
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Empty(t, files)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenvault
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDsnParams - the concurrent dump workers use the same vault file, so the WAL mode and busy timeout are used
+const sqliteDsnParams = "_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+
+// dialect - contains the driver and queries of the vault database
+type dialect struct {
+	driverName string
+	dsn        func(dsn string) string
+	// checkDsn - checks that the vault exists without creating it
+	checkDsn         func(dsn string) error
+	createTableQuery string
+	tableExistsQuery string
+	tableName        string
+	selectTokenQuery string
+	selectValueQuery string
+	insertQuery      string
+}
+
+func newDialect(vaultType, table string) (*dialect, error) {
+	switch vaultType {
+	case SqliteVaultType:
+		tableName := table
+		table = `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+		return &dialect{
+			driverName: "sqlite",
+			dsn: func(dsn string) string {
+				if strings.Contains(dsn, "?") {
+					return dsn + "&" + sqliteDsnParams
+				}
+				return dsn + "?" + sqliteDsnParams
+			},
+			checkDsn:         checkSqliteVaultFile,
+			tableExistsQuery: `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`,
+			tableName:        tableName,
+			createTableQuery: fmt.Sprintf(
+				`CREATE TABLE IF NOT EXISTS %s (digest BLOB PRIMARY KEY, token TEXT NOT NULL UNIQUE, value BLOB NOT NULL)`,
+				table,
+			),
+			selectTokenQuery: fmt.Sprintf(`SELECT token FROM %s WHERE digest = ?`, table),
+			selectValueQuery: fmt.Sprintf(`SELECT digest, value FROM %s WHERE token = ?`, table),
+			insertQuery: fmt.Sprintf(
+				`INSERT INTO %s (digest, token, value) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`, table,
+			),
+		}, nil
+	case PostgresVaultType:
+		table = pgx.Identifier(strings.Split(table, ".")).Sanitize()
+		return &dialect{
+			driverName: "pgx",
+			dsn: func(dsn string) string {
+				return dsn
+			},
+			checkDsn: func(dsn string) error {
+				return nil
+			},
+			tableExistsQuery: `SELECT to_regclass($1) IS NOT NULL`,
+			tableName:        table,
+			createTableQuery: fmt.Sprintf(
+				`CREATE TABLE IF NOT EXISTS %s (digest BYTEA PRIMARY KEY, token TEXT NOT NULL UNIQUE, value BYTEA NOT NULL)`,
+				table,
+			),
+			selectTokenQuery: fmt.Sprintf(`SELECT token FROM %s WHERE digest = $1`, table),
+			selectValueQuery: fmt.Sprintf(`SELECT digest, value FROM %s WHERE token = $1`, table),
+			insertQuery: fmt.Sprintf(
+				`INSERT INTO %s (digest, token, value) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, table,
+			),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown vault type \"%s\"", vaultType)
+}
+
+// checkSqliteVaultFile - checks that the vault file exists, since sqlite creates the missing database file on open
+func checkSqliteVaultFile(dsn string) error {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if path == ":memory:" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cannot find vault file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenvault
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	SqliteVaultType   = "sqlite"
+	PostgresVaultType = "postgres"
+)
+
+const (
+	DefaultTableName = "greenmask_token_vault"
+	// MinKeyLength - the minimal length of the master key in bytes
+	MinKeyLength = 16
+	// MinTokenLength - the minimal length of the random part of the token
+	MinTokenLength = 8
+)
+
+const (
+	tokenAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// maxTokenGenerationAttempts - the number of attempts to generate unique token
+	maxTokenGenerationAttempts = 10
+	digestKeyInfo              = "greenmask token vault digest key"
+	encryptionKeyInfo          = "greenmask token vault encryption key"
+)
+
+var ErrTokenNotFound = errors.New("token is not found")
+
+// Vault - stores the mapping between the original values and the tokens. The vault uses per-value encryption: the
+// original values are stored encrypted with AES-GCM and are searched by their HMAC-SHA256 digest, so the same value
+// always receives the same token. The vault table or file itself is not encrypted
+type Vault struct {
+	db        *sql.DB
+	dialect   *dialect
+	digestKey []byte
+	aead      cipher.AEAD
+}
+
+// Open - opens the vault of the provided type and creates the vault table if it does not exist. The dsn is a file
+// path for sqlite vault and a connection string for postgres vault
+func Open(ctx context.Context, vaultType, dsn, table string, key []byte) (*Vault, error) {
+	return open(ctx, vaultType, dsn, table, key, true)
+}
+
+// OpenExisting - opens the existing vault. Unlike Open it does not create the vault file or table and returns an
+// error if they do not exist
+func OpenExisting(ctx context.Context, vaultType, dsn, table string, key []byte) (*Vault, error) {
+	return open(ctx, vaultType, dsn, table, key, false)
+}
+
+func open(ctx context.Context, vaultType, dsn, table string, key []byte, create bool) (*Vault, error) {
+	if len(key) < MinKeyLength {
+		return nil, fmt.Errorf("key must be at least %d bytes length got %d", MinKeyLength, len(key))
+	}
+	if table == "" {
+		table = DefaultTableName
+	}
+	d, err := newDialect(vaultType, table)
+	if err != nil {
+		return nil, err
+	}
+	digestKey, encryptionKey, err := deriveKeys(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create AES cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GCM cipher: %w", err)
+	}
+
+	if !create {
+		if err = d.checkDsn(dsn); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open(d.driverName, d.dsn(dsn))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open vault: %w", err)
+	}
+	if create {
+		if _, err = db.ExecContext(ctx, d.createTableQuery); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("cannot create vault table: %w", err)
+		}
+	} else {
+		var exists bool
+		if err = db.QueryRowContext(ctx, d.tableExistsQuery, d.tableName).Scan(&exists); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("cannot check vault table: %w", err)
+		}
+		if !exists {
+			_ = db.Close()
+			return nil, fmt.Errorf("vault table \"%s\" does not exist", table)
+		}
+	}
+	return &Vault{
+		db:        db,
+		dialect:   d,
+		digestKey: digestKey,
+		aead:      aead,
+	}, nil
+}
+
+// Digest - returns the digest of the value that is used for the token lookup
+func (v *Vault) Digest(value []byte) []byte {
+	mac := hmac.New(sha256.New, v.digestKey)
+	mac.Write(value)
+	return mac.Sum(nil)
+}
+
+// Tokenize - returns the token of the value. If the value has not been tokenized yet the new random token is
+// generated and stored. The concurrent tokenization of the same value returns the token that was stored first
+func (v *Vault) Tokenize(ctx context.Context, value []byte, prefix string, length int) (string, error) {
+	digest := v.Digest(value)
+	token, err := v.getToken(ctx, digest)
+	if err == nil {
+		return token, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("cannot get token: %w", err)
+	}
+
+	encrypted, err := v.encrypt(value, digest)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < maxTokenGenerationAttempts; i++ {
+		token, err = generateToken(prefix, length)
+		if err != nil {
+			return "", err
+		}
+		res, err := v.db.ExecContext(ctx, v.dialect.insertQuery, digest, token, encrypted)
+		if err != nil {
+			return "", fmt.Errorf("cannot store token: %w", err)
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return "", fmt.Errorf("cannot store token: %w", err)
+		}
+		if inserted == 1 {
+			return token, nil
+		}
+		// Nothing was inserted: either the value was tokenized concurrently or the token collided with
+		// existing one. In the first case the stored token is used otherwise the new token is generated
+		token, err = v.getToken(ctx, digest)
+		if err == nil {
+			return token, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("cannot get token: %w", err)
+		}
+	}
+	return "", fmt.Errorf("cannot generate unique token after %d attempts: increase token length",
+		maxTokenGenerationAttempts)
+}
+
+// Detokenize - returns the original value of the token. It returns ErrTokenNotFound if there is no such token
+func (v *Vault) Detokenize(ctx context.Context, token string) ([]byte, error) {
+	var digest, encrypted []byte
+	err := v.db.QueryRowContext(ctx, v.dialect.selectValueQuery, token).Scan(&digest, &encrypted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("cannot get value: %w", err)
+	}
+	return v.decrypt(encrypted, digest)
+}
+
+func (v *Vault) Close() error {
+	return v.db.Close()
+}
+
+func (v *Vault) getToken(ctx context.Context, digest []byte) (string, error) {
+	var token string
+	if err := v.db.QueryRowContext(ctx, v.dialect.selectTokenQuery, digest).Scan(&token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// encrypt - encrypts the value. The digest is used as additional data, so the encrypted value cannot be moved to
+// another vault row
+func (v *Vault) encrypt(value, digest []byte) ([]byte, error) {
+	nonce := make([]byte, v.aead.NonceSize(), v.aead.NonceSize()+len(value)+v.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("cannot generate nonce: %w", err)
+	}
+	return v.aead.Seal(nonce, nonce, value, digest), nil
+}
+
+func (v *Vault) decrypt(encrypted, digest []byte) ([]byte, error) {
+	if len(encrypted) < v.aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted value is too short")
+	}
+	nonce, data := encrypted[:v.aead.NonceSize()], encrypted[v.aead.NonceSize():]
+	res, err := v.aead.Open(nil, nonce, data, digest)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt value: probably wrong key is used: %w", err)
+	}
+	return res, nil
+}
+
+// deriveKeys - derives independent keys for the digest and the encryption from the master key
+func deriveKeys(key []byte) ([]byte, []byte, error) {
+	digestKey := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(digestKeyInfo)), digestKey); err != nil {
+		return nil, nil, fmt.Errorf("cannot derive digest key: %w", err)
+	}
+	encryptionKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(encryptionKeyInfo)), encryptionKey); err != nil {
+		return nil, nil, fmt.Errorf("cannot derive encryption key: %w", err)
+	}
+	return digestKey, encryptionKey, nil
+}
+
+func generateToken(prefix string, length int) (string, error) {
+	res := make([]byte, 0, len(prefix)+length)
+	res = append(res, prefix...)
+	max := big.NewInt(int64(len(tokenAlphabet)))
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("cannot generate token: %w", err)
+		}
+		res = append(res, tokenAlphabet[n.Int64()])
+	}
+	return string(res), nil
+}
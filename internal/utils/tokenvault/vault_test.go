@@ -0,0 +1,104 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenvault
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestVault(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "vault.db")
+
+	v, err := Open(ctx, SqliteVaultType, dsn, "", testKey)
+	require.NoError(t, err)
+
+	token, err := v.Tokenize(ctx, []byte("john@example.com"), "tok_", 16)
+	require.NoError(t, err)
+	require.Regexp(t, `^tok_[0-9A-Za-z]{16}$`, token)
+
+	other, err := v.Tokenize(ctx, []byte("jane@example.com"), "tok_", 16)
+	require.NoError(t, err)
+	require.NotEqual(t, token, other)
+	require.NoError(t, v.Close())
+
+	// The same value receives the same token after reopening the vault
+	v, err = Open(ctx, SqliteVaultType, dsn, "", testKey)
+	require.NoError(t, err)
+	defer v.Close()
+	again, err := v.Tokenize(ctx, []byte("john@example.com"), "tok_", 16)
+	require.NoError(t, err)
+	require.Equal(t, token, again)
+
+	value, err := v.Detokenize(ctx, other)
+	require.NoError(t, err)
+	require.Equal(t, "jane@example.com", string(value))
+
+	_, err = v.Detokenize(ctx, "tok_unknown")
+	require.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestVault_wrong_key(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "vault.db")
+
+	v, err := Open(ctx, SqliteVaultType, dsn, "", testKey)
+	require.NoError(t, err)
+	token, err := v.Tokenize(ctx, []byte("secret"), "", 16)
+	require.NoError(t, err)
+	require.NoError(t, v.Close())
+
+	v, err = Open(ctx, SqliteVaultType, dsn, "", []byte("fedcba9876543210fedcba9876543210"))
+	require.NoError(t, err)
+	defer v.Close()
+	_, err = v.Detokenize(ctx, token)
+	require.ErrorContains(t, err, "cannot decrypt value")
+
+	_, err = Open(ctx, SqliteVaultType, dsn, "", []byte("short"))
+	require.Error(t, err)
+	_, err = Open(ctx, "unknown", dsn, "", testKey)
+	require.Error(t, err)
+}
+
+func TestOpenExisting(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "vault.db")
+
+	_, err := OpenExisting(ctx, SqliteVaultType, dsn, "", testKey)
+	require.ErrorContains(t, err, "cannot find vault file")
+	require.NoFileExists(t, dsn)
+
+	v, err := Open(ctx, SqliteVaultType, dsn, "", testKey)
+	require.NoError(t, err)
+	token, err := v.Tokenize(ctx, []byte("secret"), "", 16)
+	require.NoError(t, err)
+	require.NoError(t, v.Close())
+
+	_, err = OpenExisting(ctx, SqliteVaultType, dsn, "unknown", testKey)
+	require.ErrorContains(t, err, `vault table "unknown" does not exist`)
+
+	v, err = OpenExisting(ctx, SqliteVaultType, dsn, "", testKey)
+	require.NoError(t, err)
+	defer v.Close()
+	value, err := v.Detokenize(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(value))
+}
@@ -0,0 +1,76 @@
+package transformers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/eminano/greenmask/pkg/generators"
+)
+
+const dateShiftByteLength = 8
+
+// DateShift - shifts the dates by the number of days derived from the entity key. All the dates of the same entity
+// are shifted by the same offset, so the intervals between them are kept
+type DateShift struct {
+	minDays     int64
+	maxDays     int64
+	excludeZero bool
+	generator   generators.Generator
+}
+
+// NewDateShift - creates the date shift with the offset in the [minDays, maxDays] range. If excludeZero is true, the
+// zero offset is never produced
+func NewDateShift(minDays, maxDays int64, excludeZero bool) (*DateShift, error) {
+	if minDays > maxDays {
+		return nil, ErrWrongLimits
+	}
+	if excludeZero && minDays == 0 && maxDays == 0 {
+		return nil, fmt.Errorf("the range contains only zero offset")
+	}
+	return &DateShift{
+		minDays:     minDays,
+		maxDays:     maxDays,
+		excludeZero: excludeZero,
+	}, nil
+}
+
+// GetOffset - returns the offset in days for the entity key
+func (ds *DateShift) GetOffset(key []byte) (int64, error) {
+	genBytes, err := ds.generator.Generate(key)
+	if err != nil {
+		return 0, fmt.Errorf("error generating offset: %w", err)
+	}
+	span := uint64(ds.maxDays - ds.minDays + 1)
+	zeroInRange := ds.excludeZero && ds.minDays <= 0 && ds.maxDays >= 0
+	if zeroInRange {
+		span--
+	}
+	offset := ds.minDays + int64(binary.LittleEndian.Uint64(genBytes[:dateShiftByteLength])%span)
+	if zeroInRange && offset >= 0 {
+		// Skip zero by moving the non-negative part of the range by one day
+		offset++
+	}
+	return offset, nil
+}
+
+// Shift - shifts the date by the offset of the entity key. The time of the day is kept
+func (ds *DateShift) Shift(key []byte, v time.Time) (time.Time, error) {
+	offset, err := ds.GetOffset(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return v.AddDate(0, 0, int(offset)), nil
+}
+
+func (ds *DateShift) GetRequiredGeneratorByteLength() int {
+	return dateShiftByteLength
+}
+
+func (ds *DateShift) SetGenerator(g generators.Generator) error {
+	if g.Size() < dateShiftByteLength {
+		return fmt.Errorf("requested byte length (%d) higher than generator can produce (%d)", dateShiftByteLength, g.Size())
+	}
+	ds.generator = g
+	return nil
+}
@@ -0,0 +1,48 @@
+package transformers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/generators"
+)
+
+func TestDateShift_Shift(t *testing.T) {
+	ds, err := NewDateShift(-30, 30, true)
+	require.NoError(t, err)
+	g, err := generators.GetHashBytesGen([]byte("salt"), ds.GetRequiredGeneratorByteLength())
+	require.NoError(t, err)
+	require.NoError(t, ds.SetGenerator(g))
+
+	admitted := time.Date(2023, 3, 1, 10, 30, 0, 0, time.UTC)
+	discharged := time.Date(2023, 3, 12, 18, 0, 0, 0, time.UTC)
+
+	shiftedAdmitted, err := ds.Shift([]byte("42"), admitted)
+	require.NoError(t, err)
+	shiftedDischarged, err := ds.Shift([]byte("42"), discharged)
+	require.NoError(t, err)
+	require.NotEqual(t, admitted, shiftedAdmitted)
+	require.Equal(t, discharged.Sub(admitted), shiftedDischarged.Sub(shiftedAdmitted))
+	require.Equal(t, admitted.Hour(), shiftedAdmitted.Hour())
+
+	offsets := make(map[int64]struct{})
+	for i := 0; i < 1000; i++ {
+		offset, err := ds.GetOffset([]byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, offset, int64(-30))
+		require.LessOrEqual(t, offset, int64(30))
+		require.NotZero(t, offset)
+		offsets[offset] = struct{}{}
+	}
+	require.Len(t, offsets, 60)
+}
+
+func TestNewDateShift_wrong_limits(t *testing.T) {
+	_, err := NewDateShift(10, -10, false)
+	require.ErrorIs(t, err, ErrWrongLimits)
+	_, err = NewDateShift(0, 0, true)
+	require.Error(t, err)
+}
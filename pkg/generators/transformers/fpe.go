@@ -0,0 +1,150 @@
+package transformers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const fpeIntRadix = 10
+
+// FpeTransformer - encrypts and decrypts the values keeping their format. The characters of the text values that are
+// not in the alphabet are kept in their positions. The integer values are permuted within the range
+// [-maxValue-1, maxValue] keeping the sign
+type FpeTransformer struct {
+	cipher   FpeCipher
+	alphabet []rune
+	index    map[rune]uint16
+	numerals []uint16
+	// intLength - the length of the numeral string that is used for the integer values
+	intLength int
+	maxValue  uint64
+}
+
+// NewFpeTextTransformer - creates transformer for text values. The alphabet length must be equal to the cipher radix
+func NewFpeTextTransformer(cipher FpeCipher, alphabet []rune) (*FpeTransformer, error) {
+	index := make(map[rune]uint16, len(alphabet))
+	for idx, r := range alphabet {
+		if _, ok := index[r]; ok {
+			return nil, fmt.Errorf("alphabet contains duplicated character '%c'", r)
+		}
+		index[r] = uint16(idx)
+	}
+	return &FpeTransformer{
+		cipher:   cipher,
+		alphabet: alphabet,
+		index:    index,
+	}, nil
+}
+
+// NewFpeIntTransformer - creates transformer for integer values. The cipher radix must be 10
+func NewFpeIntTransformer(cipher FpeCipher, maxValue int64) (*FpeTransformer, error) {
+	if maxValue <= 0 {
+		return nil, fmt.Errorf("max value must be positive")
+	}
+	intLength := len(strconv.FormatInt(maxValue, fpeIntRadix))
+	if intLength < cipher.MinLength() {
+		intLength = cipher.MinLength()
+	}
+	if intLength > cipher.MaxLength() {
+		return nil, ErrFpeValueTooLong
+	}
+	return &FpeTransformer{
+		cipher:    cipher,
+		intLength: intLength,
+		maxValue:  uint64(maxValue),
+		numerals:  make([]uint16, intLength),
+	}, nil
+}
+
+// TransformText - encrypts or decrypts the characters of the value that are in the alphabet
+func (t *FpeTransformer) TransformText(value []byte, tweak []byte, decrypt bool) ([]byte, error) {
+	runes := []rune(string(value))
+	t.numerals = t.numerals[:0]
+	for _, r := range runes {
+		if n, ok := t.index[r]; ok {
+			t.numerals = append(t.numerals, n)
+		}
+	}
+	if len(t.numerals) < t.cipher.MinLength() {
+		return nil, fmt.Errorf(
+			"%w: value contains %d alphabet characters but at least %d are required",
+			ErrFpeValueTooShort, len(t.numerals), t.cipher.MinLength(),
+		)
+	}
+	if len(t.numerals) > t.cipher.MaxLength() {
+		return nil, fmt.Errorf(
+			"%w: value contains %d alphabet characters but at most %d are allowed",
+			ErrFpeValueTooLong, len(t.numerals), t.cipher.MaxLength(),
+		)
+	}
+
+	numerals, err := t.apply(t.numerals, tweak, decrypt)
+	if err != nil {
+		return nil, err
+	}
+	var pos int
+	for idx, r := range runes {
+		if _, ok := t.index[r]; ok {
+			runes[idx] = t.alphabet[numerals[pos]]
+			pos++
+		}
+	}
+	return []byte(string(runes)), nil
+}
+
+// TransformInt64 - encrypts or decrypts the integer keeping its sign. The numeral string is encrypted repeatedly
+// until the result is in the range [0, maxValue] (cycle walking), so the result is a permutation of the range
+func (t *FpeTransformer) TransformInt64(value int64, tweak []byte, decrypt bool) (int64, error) {
+	// The negative values are mapped to the range [0, maxValue] as well, so the whole type range is covered
+	negative := value < 0
+	var abs uint64
+	if negative {
+		abs = uint64(-(value + 1))
+	} else {
+		abs = uint64(value)
+	}
+	if abs > t.maxValue {
+		return 0, fmt.Errorf("value %d is out of the encryption range", value)
+	}
+
+	for i := t.intLength - 1; i >= 0; i-- {
+		t.numerals[i] = uint16(abs % fpeIntRadix)
+		abs /= fpeIntRadix
+	}
+	numerals := t.numerals
+	for {
+		var err error
+		if numerals, err = t.apply(numerals, tweak, decrypt); err != nil {
+			return 0, err
+		}
+		var ok bool
+		if abs, ok = t.numeralsToUint64(numerals); ok {
+			break
+		}
+	}
+
+	if negative {
+		return -int64(abs) - 1, nil
+	}
+	return int64(abs), nil
+}
+
+func (t *FpeTransformer) apply(numerals []uint16, tweak []byte, decrypt bool) ([]uint16, error) {
+	if decrypt {
+		return t.cipher.Decrypt(numerals, tweak)
+	}
+	return t.cipher.Encrypt(numerals, tweak)
+}
+
+// numeralsToUint64 - decodes the numeral string. It returns false if the value is out of the range [0, maxValue]
+func (t *FpeTransformer) numeralsToUint64(numerals []uint16) (uint64, bool) {
+	var res uint64
+	for _, n := range numerals {
+		if res > (math.MaxUint64-uint64(n))/fpeIntRadix {
+			return 0, false
+		}
+		res = res*fpeIntRadix + uint64(n)
+	}
+	return res, res <= t.maxValue
+}
@@ -0,0 +1,360 @@
+package transformers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+)
+
+const (
+	FpeFF1AlgorithmName  = "ff1"
+	FpeFF31AlgorithmName = "ff3-1"
+)
+
+const (
+	fpeMinRadix = 2
+	fpeMaxRadix = 1 << 16
+	// fpeMinDomainSize - the minimal domain size (radix^minLength) required by NIST SP 800-38G Rev. 1
+	fpeMinDomainSize = 1_000_000
+	ff1Rounds        = 10
+	ff3Rounds        = 8
+	ff31TweakLength  = 7
+	ff3TweakLength   = 8
+)
+
+var (
+	ErrFpeValueTooShort = errors.New("value is too short for format-preserving encryption")
+	ErrFpeValueTooLong  = errors.New("value is too long for format-preserving encryption")
+)
+
+// FpeCipher - format-preserving encryption of the numeral strings. Each numeral is in range [0, radix)
+type FpeCipher interface {
+	Encrypt(x []uint16, tweak []byte) ([]uint16, error)
+	Decrypt(x []uint16, tweak []byte) ([]uint16, error)
+	// MinLength - the minimal length of the numeral string
+	MinLength() int
+	// MaxLength - the maximal length of the numeral string
+	MaxLength() int
+	// ValidateTweak - checks that the tweak is suitable for the cipher
+	ValidateTweak(tweak []byte) error
+}
+
+// NewFpeCipher - creates the FF1 or FF3-1 cipher by the algorithm name
+func NewFpeCipher(algorithm string, key []byte, radix int) (FpeCipher, error) {
+	switch algorithm {
+	case FpeFF1AlgorithmName:
+		return NewFF1Cipher(key, radix)
+	case FpeFF31AlgorithmName:
+		return NewFF31Cipher(key, radix)
+	}
+	return nil, fmt.Errorf("unknown format-preserving encryption algorithm \"%s\"", algorithm)
+}
+
+func newFpeBlock(key []byte, radix int) (cipher.Block, int, error) {
+	if radix < fpeMinRadix || radix > fpeMaxRadix {
+		return nil, 0, fmt.Errorf("radix must be in range [%d, %d] got %d", fpeMinRadix, fpeMaxRadix, radix)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot create AES cipher: %w", err)
+	}
+	minLength := 2
+	for domainSize := radix * radix; domainSize < fpeMinDomainSize; domainSize *= radix {
+		minLength++
+	}
+	return block, minLength, nil
+}
+
+// FF1Cipher - NIST SP 800-38G FF1 format-preserving encryption
+type FF1Cipher struct {
+	block     cipher.Block
+	radix     int
+	bigRadix  *big.Int
+	minLength int
+}
+
+func NewFF1Cipher(key []byte, radix int) (*FF1Cipher, error) {
+	block, minLength, err := newFpeBlock(key, radix)
+	if err != nil {
+		return nil, err
+	}
+	return &FF1Cipher{
+		block:     block,
+		radix:     radix,
+		bigRadix:  big.NewInt(int64(radix)),
+		minLength: minLength,
+	}, nil
+}
+
+func (c *FF1Cipher) MinLength() int {
+	return c.minLength
+}
+
+func (c *FF1Cipher) MaxLength() int {
+	return math.MaxInt32
+}
+
+func (c *FF1Cipher) ValidateTweak(tweak []byte) error {
+	if len(tweak) > math.MaxInt32 {
+		return fmt.Errorf("tweak is too long")
+	}
+	return nil
+}
+
+func (c *FF1Cipher) Encrypt(x []uint16, tweak []byte) ([]uint16, error) {
+	return c.cipher(x, tweak, false)
+}
+
+func (c *FF1Cipher) Decrypt(x []uint16, tweak []byte) ([]uint16, error) {
+	return c.cipher(x, tweak, true)
+}
+
+func (c *FF1Cipher) cipher(x []uint16, tweak []byte, decrypt bool) ([]uint16, error) {
+	n := len(x)
+	if n < c.minLength {
+		return nil, ErrFpeValueTooShort
+	}
+	if err := c.ValidateTweak(tweak); err != nil {
+		return nil, err
+	}
+	u := n / 2
+	v := n - u
+	a := slices.Clone(x[:u])
+	b := slices.Clone(x[u:])
+
+	// The byte length of the numeral string B and the length of the pseudorandom output
+	maxB := new(big.Int).Exp(c.bigRadix, big.NewInt(int64(v)), nil)
+	bLen := (maxB.Sub(maxB, big.NewInt(1)).BitLen() + 7) / 8
+	d := 4*((bLen+3)/4) + 4
+
+	p := make([]byte, aes.BlockSize)
+	p[0], p[1], p[2] = 1, 2, 1
+	p[3], p[4], p[5] = byte(c.radix>>16), byte(c.radix>>8), byte(c.radix)
+	p[6] = 10
+	p[7] = byte(u)
+	binary.BigEndian.PutUint32(p[8:12], uint32(n))
+	binary.BigEndian.PutUint32(p[12:16], uint32(len(tweak)))
+
+	qLen := len(tweak) + bLen + 1
+	qLen += (aes.BlockSize - qLen%aes.BlockSize) % aes.BlockSize
+	q := make([]byte, qLen)
+	copy(q, tweak)
+
+	blocks := (d + aes.BlockSize - 1) / aes.BlockSize
+	s := make([]byte, blocks*aes.BlockSize)
+	r := make([]byte, aes.BlockSize)
+	tmp := make([]byte, aes.BlockSize)
+	modU := new(big.Int).Exp(c.bigRadix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(c.bigRadix, big.NewInt(int64(v)), nil)
+	y := new(big.Int)
+	cNum := new(big.Int)
+
+	for round := 0; round < ff1Rounds; round++ {
+		i := round
+		src, dst := b, a
+		if decrypt {
+			i = ff1Rounds - 1 - round
+			src, dst = a, b
+		}
+		// Q = T || [0]^((-t-b-1) mod 16) || [i]^1 || [NUM_radix(B)]^b
+		clear(q[len(tweak):])
+		q[qLen-bLen-1] = byte(i)
+		numRadix(src, c.bigRadix).FillBytes(q[qLen-bLen:])
+
+		// R = PRF(P || Q)
+		c.block.Encrypt(r, p)
+		for off := 0; off < qLen; off += aes.BlockSize {
+			xorBytes(r, r, q[off:off+aes.BlockSize])
+			c.block.Encrypt(r, r)
+		}
+		copy(s, r)
+		for j := 1; j < blocks; j++ {
+			clear(tmp)
+			binary.BigEndian.PutUint64(tmp[8:], uint64(j))
+			xorBytes(tmp, tmp, r)
+			c.block.Encrypt(s[j*aes.BlockSize:], tmp)
+		}
+		y.SetBytes(s[:d])
+
+		m, mod := u, modU
+		if i%2 == 1 {
+			m, mod = v, modV
+		}
+		cNum.Set(numRadix(dst, c.bigRadix))
+		if decrypt {
+			cNum.Sub(cNum, y)
+		} else {
+			cNum.Add(cNum, y)
+		}
+		cNum.Mod(cNum, mod)
+		res := strRadix(cNum, c.bigRadix, m)
+		if decrypt {
+			b, a = a, res
+		} else {
+			a, b = b, res
+		}
+	}
+	return append(a, b...), nil
+}
+
+// FF31Cipher - NIST SP 800-38G Rev. 1 FF3-1 format-preserving encryption. The tweak must be 56 bits
+type FF31Cipher struct {
+	block     cipher.Block
+	radix     int
+	bigRadix  *big.Int
+	minLength int
+	maxLength int
+}
+
+func NewFF31Cipher(key []byte, radix int) (*FF31Cipher, error) {
+	// FF3 uses the reversed key
+	revKey := slices.Clone(key)
+	slices.Reverse(revKey)
+	block, minLength, err := newFpeBlock(revKey, radix)
+	if err != nil {
+		return nil, err
+	}
+	return &FF31Cipher{
+		block:     block,
+		radix:     radix,
+		bigRadix:  big.NewInt(int64(radix)),
+		minLength: minLength,
+		maxLength: 2 * int(math.Floor(96/math.Log2(float64(radix)))),
+	}, nil
+}
+
+func (c *FF31Cipher) MinLength() int {
+	return c.minLength
+}
+
+func (c *FF31Cipher) MaxLength() int {
+	return c.maxLength
+}
+
+func (c *FF31Cipher) ValidateTweak(tweak []byte) error {
+	if len(tweak) != ff31TweakLength {
+		return fmt.Errorf("tweak must be %d bytes length got %d", ff31TweakLength, len(tweak))
+	}
+	return nil
+}
+
+func (c *FF31Cipher) Encrypt(x []uint16, tweak []byte) ([]uint16, error) {
+	if err := c.ValidateTweak(tweak); err != nil {
+		return nil, err
+	}
+	return c.cipher(x, expandFF31Tweak(tweak), false)
+}
+
+func (c *FF31Cipher) Decrypt(x []uint16, tweak []byte) ([]uint16, error) {
+	if err := c.ValidateTweak(tweak); err != nil {
+		return nil, err
+	}
+	return c.cipher(x, expandFF31Tweak(tweak), true)
+}
+
+// cipher - FF3 algorithm with 64 bits tweak
+func (c *FF31Cipher) cipher(x []uint16, tweak []byte, decrypt bool) ([]uint16, error) {
+	n := len(x)
+	if n < c.minLength {
+		return nil, ErrFpeValueTooShort
+	}
+	if n > c.maxLength {
+		return nil, ErrFpeValueTooLong
+	}
+	u := (n + 1) / 2
+	v := n - u
+	a := slices.Clone(x[:u])
+	b := slices.Clone(x[u:])
+	tl, tr := tweak[:4], tweak[4:]
+
+	p := make([]byte, aes.BlockSize)
+	modU := new(big.Int).Exp(c.bigRadix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(c.bigRadix, big.NewInt(int64(v)), nil)
+	y := new(big.Int)
+	cNum := new(big.Int)
+	for round := 0; round < ff3Rounds; round++ {
+		i := round
+		src, dst := b, a
+		if decrypt {
+			i = ff3Rounds - 1 - round
+			src, dst = a, b
+		}
+		m, mod, w := u, modU, tr
+		if i%2 == 1 {
+			m, mod, w = v, modV, tl
+		}
+		// P = W xor [i]^4 || [NUM_radix(REV(B))]^12
+		copy(p, w)
+		p[3] ^= byte(i)
+		numRadix(reversed(src), c.bigRadix).FillBytes(p[4:])
+
+		// S = REVB(CIPH_REVB(K)(REVB(P)))
+		slices.Reverse(p)
+		c.block.Encrypt(p, p)
+		slices.Reverse(p)
+		y.SetBytes(p)
+
+		cNum.Set(numRadix(reversed(dst), c.bigRadix))
+		if decrypt {
+			cNum.Sub(cNum, y)
+		} else {
+			cNum.Add(cNum, y)
+		}
+		cNum.Mod(cNum, mod)
+		res := reversed(strRadix(cNum, c.bigRadix, m))
+		if decrypt {
+			b, a = a, res
+		} else {
+			a, b = b, res
+		}
+	}
+	return append(a, b...), nil
+}
+
+// expandFF31Tweak - converts 56 bits FF3-1 tweak into 64 bits FF3 tweak
+func expandFF31Tweak(tweak []byte) []byte {
+	res := make([]byte, ff3TweakLength)
+	copy(res[:3], tweak[:3])
+	res[3] = tweak[3] & 0xF0
+	copy(res[4:7], tweak[4:7])
+	res[7] = (tweak[3] & 0x0F) << 4
+	return res
+}
+
+func numRadix(x []uint16, radix *big.Int) *big.Int {
+	res := new(big.Int)
+	digit := new(big.Int)
+	for _, v := range x {
+		res.Mul(res, radix)
+		res.Add(res, digit.SetUint64(uint64(v)))
+	}
+	return res
+}
+
+func strRadix(x *big.Int, radix *big.Int, m int) []uint16 {
+	res := make([]uint16, m)
+	v := new(big.Int).Set(x)
+	mod := new(big.Int)
+	for i := m - 1; i >= 0; i-- {
+		v.DivMod(v, radix, mod)
+		res[i] = uint16(mod.Uint64())
+	}
+	return res
+}
+
+func reversed(x []uint16) []uint16 {
+	res := slices.Clone(x)
+	slices.Reverse(res)
+	return res
+}
+
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
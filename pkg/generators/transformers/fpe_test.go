@@ -0,0 +1,187 @@
+package transformers
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const fpeTestAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func fpeTestNumerals(t *testing.T, s string) []uint16 {
+	res := make([]uint16, 0, len(s))
+	for _, c := range s {
+		idx := -1
+		for i, a := range fpeTestAlphabet {
+			if a == c {
+				idx = i
+			}
+		}
+		require.NotEqual(t, -1, idx)
+		res = append(res, uint16(idx))
+	}
+	return res
+}
+
+func fpeTestString(numerals []uint16) string {
+	res := make([]byte, 0, len(numerals))
+	for _, n := range numerals {
+		res = append(res, fpeTestAlphabet[n])
+	}
+	return string(res)
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	res, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return res
+}
+
+func TestFF1Cipher_nist_samples(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		radix     int
+		tweak     string
+		plaintext string
+		expected  string
+	}{
+		{
+			name:      "sample 1",
+			key:       "2B7E151628AED2A6ABF7158809CF4F3C",
+			radix:     10,
+			plaintext: "0123456789",
+			expected:  "2433477484",
+		},
+		{
+			name:      "sample 2",
+			key:       "2B7E151628AED2A6ABF7158809CF4F3C",
+			radix:     10,
+			tweak:     "39383736353433323130",
+			plaintext: "0123456789",
+			expected:  "6124200773",
+		},
+		{
+			name:      "sample 3",
+			key:       "2B7E151628AED2A6ABF7158809CF4F3C",
+			radix:     36,
+			tweak:     "3737373770717273373737",
+			plaintext: "0123456789abcdefghi",
+			expected:  "a9tv40mll9kdu509eum",
+		},
+		{
+			name:      "sample 4",
+			key:       "2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F",
+			radix:     10,
+			plaintext: "0123456789",
+			expected:  "2830668132",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewFF1Cipher(mustDecodeHex(t, tt.key), tt.radix)
+			require.NoError(t, err)
+			tweak := mustDecodeHex(t, tt.tweak)
+			res, err := c.Encrypt(fpeTestNumerals(t, tt.plaintext), tweak)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, fpeTestString(res))
+			res, err = c.Decrypt(res, tweak)
+			require.NoError(t, err)
+			require.Equal(t, tt.plaintext, fpeTestString(res))
+		})
+	}
+}
+
+func TestFF31Cipher_nist_samples(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		tweak     string
+		plaintext string
+		expected  string
+	}{
+		{
+			name:      "FF3 sample 1",
+			key:       "EF4359D8D580AA4F7F036D6F04FC6A94",
+			tweak:     "D8E7920AFA330A73",
+			plaintext: "890121234567890000",
+			expected:  "750918814058654607",
+		},
+		{
+			name:      "FF3 sample 2",
+			key:       "EF4359D8D580AA4F7F036D6F04FC6A94",
+			tweak:     "9A768A92F60E12D8",
+			plaintext: "890121234567890000",
+			expected:  "018989839189395384",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewFF31Cipher(mustDecodeHex(t, tt.key), 10)
+			require.NoError(t, err)
+			tweak := mustDecodeHex(t, tt.tweak)
+			res, err := c.cipher(fpeTestNumerals(t, tt.plaintext), tweak, false)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, fpeTestString(res))
+			res, err = c.cipher(res, tweak, true)
+			require.NoError(t, err)
+			require.Equal(t, tt.plaintext, fpeTestString(res))
+		})
+	}
+}
+
+func TestFF31Cipher_tweak(t *testing.T) {
+	c, err := NewFF31Cipher(mustDecodeHex(t, "EF4359D8D580AA4F7F036D6F04FC6A94"), 10)
+	require.NoError(t, err)
+	_, err = c.Encrypt(fpeTestNumerals(t, "890121234567890000"), mustDecodeHex(t, "D8E7920AFA330A73"))
+	require.ErrorContains(t, err, "tweak must be 7 bytes length")
+
+	tweak := mustDecodeHex(t, "D8E7920AFA330A")
+	res, err := c.Encrypt(fpeTestNumerals(t, "890121234567890000"), tweak)
+	require.NoError(t, err)
+	require.Len(t, res, 18)
+	res, err = c.Decrypt(res, tweak)
+	require.NoError(t, err)
+	require.Equal(t, "890121234567890000", fpeTestString(res))
+}
+
+func TestFpeTransformer_TransformText(t *testing.T) {
+	c, err := NewFF1Cipher(mustDecodeHex(t, "2B7E151628AED2A6ABF7158809CF4F3C"), 10)
+	require.NoError(t, err)
+	tr, err := NewFpeTextTransformer(c, []rune("0123456789"))
+	require.NoError(t, err)
+
+	res, err := tr.TransformText([]byte("4111-1111-1111-1111"), nil, false)
+	require.NoError(t, err)
+	require.Regexp(t, `^\d{4}-\d{4}-\d{4}-\d{4}$`, string(res))
+	require.NotEqual(t, "4111-1111-1111-1111", string(res))
+
+	res, err = tr.TransformText(res, nil, true)
+	require.NoError(t, err)
+	require.Equal(t, "4111-1111-1111-1111", string(res))
+
+	_, err = tr.TransformText([]byte("12-34"), nil, false)
+	require.ErrorIs(t, err, ErrFpeValueTooShort)
+}
+
+func TestFpeTransformer_TransformInt64(t *testing.T) {
+	c, err := NewFF31Cipher(mustDecodeHex(t, "EF4359D8D580AA4F7F036D6F04FC6A94"), 10)
+	require.NoError(t, err)
+	tweak := mustDecodeHex(t, "D8E7920AFA330A")
+	for _, maxValue := range []int64{math.MaxInt16, math.MaxInt32, math.MaxInt64} {
+		tr, err := NewFpeIntTransformer(c, maxValue)
+		require.NoError(t, err)
+		for _, v := range []int64{0, 1, 42, -1, -42, maxValue, -maxValue - 1} {
+			res, err := tr.TransformInt64(v, tweak, false)
+			require.NoError(t, err)
+			require.LessOrEqual(t, res, maxValue)
+			require.GreaterOrEqual(t, res, -maxValue-1)
+			require.Equal(t, v < 0, res < 0)
+			res, err = tr.TransformInt64(res, tweak, true)
+			require.NoError(t, err)
+			require.Equal(t, v, res)
+		}
+	}
+}
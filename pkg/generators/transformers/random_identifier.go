@@ -0,0 +1,371 @@
+package transformers
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/eminano/greenmask/pkg/generators"
+)
+
+const (
+	SsnIdentifierKind  = "ssn"
+	IbanIdentifierKind = "iban"
+	CardIdentifierKind = "card"
+	NinoIdentifierKind = "nino"
+	CpfIdentifierKind  = "cpf"
+)
+
+// identifierSeedLength - the number of generator bytes used as a seed of the identifier
+const identifierSeedLength = 32
+
+var IdentifierKinds = []string{
+	SsnIdentifierKind,
+	IbanIdentifierKind,
+	CardIdentifierKind,
+	NinoIdentifierKind,
+	CpfIdentifierKind,
+}
+
+var (
+	// ibanLengths - the lengths of the IBANs by the country code
+	ibanLengths = map[string]int{
+		"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22, "BH": 22, "BR": 29,
+		"BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22, "DK": 18, "DO": 28, "EE": 20, "EG": 29,
+		"ES": 24, "FI": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28,
+		"HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20,
+		"LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MD": 24, "ME": 22, "MK": 19,
+		"MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24, "PL": 28, "PS": 29, "PT": 25, "QA": 29,
+		"RO": 24, "RS": 22, "SA": 24, "SC": 31, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "ST": 25, "SV": 28,
+		"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20,
+	}
+	// ibanNumericCountries - the countries with the numeric BBAN that are used when the country is not kept
+	ibanNumericCountries = []string{"AT", "BE", "DE", "DK", "ES", "FI", "NO", "PL", "PT", "SE"}
+	// cardNetworkPrefixes - the prefixes and lengths of the card numbers that are used when the prefix is not kept
+	cardNetworkPrefixes = []struct {
+		prefix string
+		length int
+	}{
+		{prefix: "4", length: 16},
+		{prefix: "51", length: 16},
+		{prefix: "52", length: 16},
+		{prefix: "53", length: 16},
+		{prefix: "54", length: 16},
+		{prefix: "55", length: 16},
+		{prefix: "34", length: 15},
+		{prefix: "37", length: 15},
+		{prefix: "6011", length: 16},
+	}
+	// ninoInvalidPrefixes - the NINO prefixes that are not allocated
+	ninoInvalidPrefixes = []string{"BG", "GB", "KN", "NK", "NT", "TN", "ZZ"}
+)
+
+// identifierKind - generates the identifier of the kind. The original value is normalized: it contains the upper-cased
+// letters and digits only
+type identifierKind struct {
+	generate func(r *rand.Rand, original []byte, keepPrefix bool) []byte
+	// layout - the canonical format of the identifier. The 'X' characters are replaced with the identifier characters
+	layout string
+}
+
+var identifierKinds = map[string]*identifierKind{
+	SsnIdentifierKind:  {generate: generateSsn, layout: "XXX-XX-XXXX"},
+	IbanIdentifierKind: {generate: generateIban},
+	CardIdentifierKind: {generate: generateCard},
+	NinoIdentifierKind: {generate: generateNino},
+	CpfIdentifierKind:  {generate: generateCpf, layout: "XXX.XXX.XXX-XX"},
+}
+
+// RandomIdentifierTransformer - generates the national identifiers and financial numbers that pass the format and
+// checksum validation. The result keeps the separators of the original value if it has the letters and digits at the
+// same positions, otherwise the canonical format is used
+type RandomIdentifierTransformer struct {
+	kind       *identifierKind
+	keepPrefix bool
+	generator  generators.Generator
+	buf        []byte
+}
+
+func NewRandomIdentifierTransformer(kind string, keepPrefix bool) (*RandomIdentifierTransformer, error) {
+	k, ok := identifierKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown identifier kind \"%s\"", kind)
+	}
+	return &RandomIdentifierTransformer{
+		kind:       k,
+		keepPrefix: keepPrefix,
+	}, nil
+}
+
+func (t *RandomIdentifierTransformer) GetRequiredGeneratorByteLength() int {
+	return identifierSeedLength
+}
+
+func (t *RandomIdentifierTransformer) SetGenerator(g generators.Generator) error {
+	if g.Size() < identifierSeedLength {
+		return fmt.Errorf("requested byte length (%d) higher than generator can produce (%d)", identifierSeedLength, g.Size())
+	}
+	t.generator = g
+	return nil
+}
+
+// Transform - generates the identifier for the original value. The original value is used as the generator input and
+// as the source of the kept prefix
+func (t *RandomIdentifierTransformer) Transform(original []byte) ([]byte, error) {
+	seed, err := t.generator.Generate(original)
+	if err != nil {
+		return nil, err
+	}
+	r := rand.New(rand.NewChaCha8([identifierSeedLength]byte(seed[:identifierSeedLength])))
+
+	t.buf = t.buf[:0]
+	for _, c := range original {
+		if isIdentifierChar(c) {
+			t.buf = append(t.buf, toUpperIdentifierChar(c))
+		}
+	}
+	id := t.kind.generate(r, t.buf, t.keepPrefix)
+
+	switch {
+	case isSameIdentifierFormat(t.buf, id):
+		return applyIdentifierLayout(original, id), nil
+	case t.kind.layout != "":
+		return applyIdentifierLayout([]byte(t.kind.layout), id), nil
+	}
+	return id, nil
+}
+
+// applyIdentifierLayout - replaces the letters and digits of the layout with the identifier characters in order
+func applyIdentifierLayout(layout, id []byte) []byte {
+	res := make([]byte, len(layout))
+	var pos int
+	for i, c := range layout {
+		if isIdentifierChar(c) && pos < len(id) {
+			res[i] = id[pos]
+			pos++
+			continue
+		}
+		res[i] = c
+	}
+	return res
+}
+
+// isSameIdentifierFormat - checks that the values have the same length and the letters and digits at the same positions
+func isSameIdentifierFormat(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if isIdentifierDigits(a[i:i+1]) != isIdentifierDigits(b[i:i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifierChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func toUpperIdentifierChar(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+func isIdentifierDigits(data []byte) bool {
+	for _, c := range data {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func appendRandomDigits(r *rand.Rand, res []byte, count int) []byte {
+	for i := 0; i < count; i++ {
+		res = append(res, byte('0'+r.IntN(10)))
+	}
+	return res
+}
+
+// generateSsn - generates US Social Security Number. The area number is kept if it is requested and valid
+func generateSsn(r *rand.Rand, original []byte, keepPrefix bool) []byte {
+	res := make([]byte, 0, 9)
+	if keepPrefix && len(original) == 9 && isIdentifierDigits(original) && isValidSsnArea(original[:3]) {
+		res = append(res, original[:3]...)
+	} else {
+		// Areas 001-665 and 667-899
+		area := 1 + r.IntN(898)
+		if area >= 666 {
+			area++
+		}
+		res = fmt.Appendf(res, "%03d", area)
+	}
+	res = fmt.Appendf(res, "%02d", 1+r.IntN(99))
+	return fmt.Appendf(res, "%04d", 1+r.IntN(9999))
+}
+
+func isValidSsnArea(area []byte) bool {
+	s := string(area)
+	return s != "000" && s != "666" && s[0] != '9'
+}
+
+// generateIban - generates IBAN with valid check digits. The country code is kept if it is requested and known. If
+// the original value is an IBAN of the expected length, the BBAN keeps the positions of letters and digits
+func generateIban(r *rand.Rand, original []byte, keepPrefix bool) []byte {
+	var country string
+	var length int
+	if keepPrefix && len(original) >= 2 {
+		country = string(original[:2])
+		length = ibanLengths[country]
+	}
+	if length == 0 {
+		country = ibanNumericCountries[r.IntN(len(ibanNumericCountries))]
+		length = ibanLengths[country]
+	}
+
+	res := make([]byte, 0, length)
+	res = append(res, country...)
+	res = append(res, '0', '0')
+	if len(original) == length && string(original[:2]) == country {
+		for _, c := range original[4:] {
+			if c >= 'A' && c <= 'Z' {
+				res = append(res, byte('A'+r.IntN(26)))
+			} else {
+				res = append(res, byte('0'+r.IntN(10)))
+			}
+		}
+	} else {
+		res = appendRandomDigits(r, res, length-4)
+	}
+	check := 98 - ibanMod97(res)
+	res[2], res[3] = byte('0'+check/10), byte('0'+check%10)
+	return res
+}
+
+// ibanMod97 - calculates the ISO 7064 mod 97-10 of the IBAN
+func ibanMod97(iban []byte) int {
+	var res int
+	add := func(c byte) {
+		switch {
+		case c >= '0' && c <= '9':
+			res = (res*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			res = (res*100 + int(c-'A') + 10) % 97
+		}
+	}
+	for _, c := range iban[4:] {
+		add(c)
+	}
+	for _, c := range iban[:4] {
+		add(c)
+	}
+	return res
+}
+
+// generateCard - generates the card number with valid Luhn checksum. The issuer identification number (the first 6
+// digits) and the length are kept if it is requested and the original value is a card number
+func generateCard(r *rand.Rand, original []byte, keepPrefix bool) []byte {
+	var prefix []byte
+	var length int
+	if keepPrefix && len(original) >= 13 && len(original) <= 19 && isIdentifierDigits(original) {
+		prefix, length = original[:6], len(original)
+	} else {
+		network := cardNetworkPrefixes[r.IntN(len(cardNetworkPrefixes))]
+		prefix, length = []byte(network.prefix), network.length
+	}
+
+	res := make([]byte, 0, length)
+	res = append(res, prefix...)
+	res = appendRandomDigits(r, res, length-len(prefix)-1)
+	res = append(res, '0')
+	if checksum := luhnChecksum(res); checksum != 0 {
+		res[length-1] = byte('0' + 10 - checksum)
+	}
+	return res
+}
+
+// luhnChecksum - returns the Luhn checksum of the digits. The valid number has zero checksum
+func luhnChecksum(digits []byte) int {
+	var sum int
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum % 10
+}
+
+// generateNino - generates UK National Insurance number. The prefix letters are kept if it is requested and valid
+func generateNino(r *rand.Rand, original []byte, keepPrefix bool) []byte {
+	res := make([]byte, 0, 9)
+	if keepPrefix && len(original) >= 2 && isValidNinoPrefix(original[:2]) {
+		res = append(res, original[:2]...)
+	} else {
+		for {
+			prefix := []byte{byte('A' + r.IntN(26)), byte('A' + r.IntN(26))}
+			if isValidNinoPrefix(prefix) {
+				res = append(res, prefix...)
+				break
+			}
+		}
+	}
+	res = appendRandomDigits(r, res, 6)
+	return append(res, byte('A'+r.IntN(4)))
+}
+
+func isValidNinoPrefix(prefix []byte) bool {
+	first, second := prefix[0], prefix[1]
+	if first < 'A' || first > 'Z' || second < 'A' || second > 'Z' {
+		return false
+	}
+	switch first {
+	case 'D', 'F', 'I', 'Q', 'U', 'V':
+		return false
+	}
+	switch second {
+	case 'D', 'F', 'I', 'O', 'Q', 'U', 'V':
+		return false
+	}
+	return !slices.Contains(ninoInvalidPrefixes, string(prefix))
+}
+
+// generateCpf - generates Brazilian CPF with valid check digits. The fiscal region digit (the 9th digit) is kept if
+// it is requested and the original value is a CPF
+func generateCpf(r *rand.Rand, original []byte, keepPrefix bool) []byte {
+	res := make([]byte, 0, 11)
+	for {
+		res = appendRandomDigits(r, res[:0], 9)
+		if keepPrefix && len(original) == 11 && isIdentifierDigits(original) {
+			res[8] = original[8]
+		}
+		// The numbers of the same digits are not valid
+		if slices.ContainsFunc(res, func(c byte) bool { return c != res[0] }) {
+			break
+		}
+	}
+	res = append(res, cpfCheckDigit(res))
+	return append(res, cpfCheckDigit(res))
+}
+
+// cpfCheckDigit - calculates the CPF check digit of the digits using the mod 11 algorithm
+func cpfCheckDigit(digits []byte) byte {
+	var sum int
+	for i, c := range digits {
+		sum += int(c-'0') * (len(digits) + 1 - i)
+	}
+	rem := sum % 11
+	if rem < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rem)
+}
@@ -0,0 +1,158 @@
+package transformers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/generators"
+)
+
+func newTestRandomIdentifierTransformer(t *testing.T, kind string, keepPrefix bool) *RandomIdentifierTransformer {
+	tr, err := NewRandomIdentifierTransformer(kind, keepPrefix)
+	require.NoError(t, err)
+	g, err := generators.GetHashBytesGen([]byte("salt"), tr.GetRequiredGeneratorByteLength())
+	require.NoError(t, err)
+	require.NoError(t, tr.SetGenerator(g))
+	return tr
+}
+
+func isValidTestCpf(cpf []byte) bool {
+	return cpfCheckDigit(cpf[:9]) == cpf[9] && cpfCheckDigit(cpf[:10]) == cpf[10]
+}
+
+func TestRandomIdentifierTransformer_Transform(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		keepPrefix bool
+		original   string
+		pattern    string
+		validate   func(id []byte) bool
+	}{
+		{
+			name:     "ssn",
+			kind:     SsnIdentifierKind,
+			original: "some value",
+			pattern:  `^(?:00[1-9]|0[1-9]\d|[1-5]\d\d|6[0-57-9]\d|66[0-57-9]|[78]\d\d)-(?:0[1-9]|[1-9]\d)-\d{4}$`,
+		},
+		{
+			name:       "ssn keep area without separators",
+			kind:       SsnIdentifierKind,
+			keepPrefix: true,
+			original:   "123456789",
+			pattern:    `^123\d{6}$`,
+		},
+		{
+			name:     "iban",
+			kind:     IbanIdentifierKind,
+			original: "some value",
+			pattern:  `^[A-Z]{2}\d{13,26}$`,
+			validate: func(id []byte) bool { return ibanLengths[string(id[:2])] == len(id) && ibanMod97(id) == 1 },
+		},
+		{
+			name:       "iban keep country and format",
+			kind:       IbanIdentifierKind,
+			keepPrefix: true,
+			original:   "GB82 WEST 1234 5698 7654 32",
+			pattern:    `^GB\d{2} [A-Z]{4} \d{4} \d{4} \d{4} \d{2}$`,
+			validate: func(id []byte) bool {
+				return ibanMod97(bytes.ReplaceAll(id, []byte(" "), nil)) == 1
+			},
+		},
+		{
+			name:     "card",
+			kind:     CardIdentifierKind,
+			original: "some value",
+			pattern:  `^(?:4\d{15}|5[1-5]\d{14}|3[47]\d{13}|6011\d{12})$`,
+			validate: func(id []byte) bool { return luhnChecksum(id) == 0 },
+		},
+		{
+			name:       "card keep issuer",
+			kind:       CardIdentifierKind,
+			keepPrefix: true,
+			original:   "4111-1111-1111-1111",
+			pattern:    `^4111-11\d{2}-\d{4}-\d{4}$`,
+			validate: func(id []byte) bool {
+				return luhnChecksum(bytes.ReplaceAll(id, []byte("-"), nil)) == 0
+			},
+		},
+		{
+			name:     "nino",
+			kind:     NinoIdentifierKind,
+			original: "some value",
+			pattern:  `^[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z]\d{6}[A-D]$`,
+		},
+		{
+			name:       "nino keep prefix",
+			kind:       NinoIdentifierKind,
+			keepPrefix: true,
+			original:   "QQ 12 34 56 C",
+			pattern:    `^[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] \d{2} \d{2} \d{2} [A-D]$`,
+		},
+		{
+			name:       "nino keep valid prefix",
+			kind:       NinoIdentifierKind,
+			keepPrefix: true,
+			original:   "AB123456C",
+			pattern:    `^AB\d{6}[A-D]$`,
+		},
+		{
+			name:     "cpf",
+			kind:     CpfIdentifierKind,
+			original: "some value",
+			pattern:  `^\d{3}\.\d{3}\.\d{3}-\d{2}$`,
+			validate: func(id []byte) bool {
+				return isValidTestCpf([]byte(strings.NewReplacer(".", "", "-", "").Replace(string(id))))
+			},
+		},
+		{
+			name:       "cpf keep region",
+			kind:       CpfIdentifierKind,
+			keepPrefix: true,
+			original:   "52998224725",
+			pattern:    `^\d{8}7\d{2}$`,
+			validate:   isValidTestCpf,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestRandomIdentifierTransformer(t, tt.kind, tt.keepPrefix)
+			res, err := tr.Transform([]byte(tt.original))
+			require.NoError(t, err)
+			require.Regexp(t, tt.pattern, string(res))
+			if tt.validate != nil {
+				require.True(t, tt.validate(res), string(res))
+			}
+			// The hash engine generates the same value for the same input
+			again, err := tr.Transform([]byte(tt.original))
+			require.NoError(t, err)
+			require.Equal(t, string(res), string(again))
+		})
+	}
+}
+
+func TestRandomIdentifierTransformer_checksum(t *testing.T) {
+	for _, kind := range []string{IbanIdentifierKind, CardIdentifierKind, CpfIdentifierKind} {
+		tr := newTestRandomIdentifierTransformer(t, kind, false)
+		for i := 0; i < 1000; i++ {
+			res, err := tr.Transform([]byte{byte(i), byte(i >> 8)})
+			require.NoError(t, err)
+			switch kind {
+			case IbanIdentifierKind:
+				require.Equal(t, 1, ibanMod97(res), string(res))
+			case CardIdentifierKind:
+				require.Equal(t, 0, luhnChecksum(res), string(res))
+			case CpfIdentifierKind:
+				require.True(t, isValidTestCpf([]byte(strings.NewReplacer(".", "", "-", "").Replace(string(res)))), string(res))
+			}
+		}
+	}
+}
+
+func TestNewRandomIdentifierTransformer_unknownKind(t *testing.T) {
+	_, err := NewRandomIdentifierTransformer("passport", false)
+	require.Error(t, err)
+}
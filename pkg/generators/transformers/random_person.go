@@ -3,6 +3,8 @@ package transformers
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
 	"slices"
 
 	"github.com/eminano/greenmask/pkg/generators"
@@ -14,6 +16,12 @@ const (
 	AnyGenderName    = "Any"
 )
 
+const (
+	PersonGenderAttributeName = "Gender"
+	// randomPersonSeedLength - the number of generator bytes used as a seed of the attribute keys
+	randomPersonSeedLength = 8
+)
+
 const (
 	RandomFullNameTransformerFullNameMode = iota
 	RandomFullNameTransformerFirstNameMode
@@ -112,22 +120,53 @@ var DefaultTitlesFemale = []string{
 type Database map[string]map[string][]string
 
 type PersonDatabase struct {
-	Db              Database
-	Genders         []string
-	Attributes      []string
-	AttributesCount int
+	Db         Database
+	Genders    []string
+	Attributes []string
+	// FormatAttributes - the attributes which values are formats. The '#' characters of the format are replaced with
+	// random digits
+	FormatAttributes map[string]struct{}
+	AttributesCount  int
 }
 
+// GetRandomAttribute - returns the attribute value by the random index.
+//
+// Deprecated: use GetAttributeByKey
 func (pd *PersonDatabase) GetRandomAttribute(gender, attr string, randomIdx uint32) string {
 	attrs := pd.Db[gender][attr]
+	if len(attrs) == 0 {
+		return ""
+	}
 	return attrs[randomIdx%uint32(len(attrs))]
 }
 
+// GetAttributeByKey - returns the attribute value by the key. The value is chosen by the jump consistent hash, so
+// after appending the values to the list only the part of keys is moved to the new values
+func (pd *PersonDatabase) GetAttributeByKey(gender, attr string, key uint64) string {
+	attrs := pd.Db[gender][attr]
+	if len(attrs) == 0 {
+		return ""
+	}
+	return attrs[jumpConsistentHash(key, len(attrs))]
+}
+
+// GetRandomGender - returns the gender by the random index.
+//
+// Deprecated: use GetGenderByKey
 func (pd *PersonDatabase) GetRandomGender(randomIdx uint32) string {
 	return pd.Genders[randomIdx%uint32(len(pd.Genders))]
 }
 
+// GetGenderByKey - returns the gender chosen by the jump consistent hash of the key
+func (pd *PersonDatabase) GetGenderByKey(key uint64) string {
+	return pd.Genders[jumpConsistentHash(key, len(pd.Genders))]
+}
+
 func NewPersonalDatabase(data Database) *PersonDatabase {
+	return newPersonDatabase(data, nil)
+}
+
+func newPersonDatabase(data Database, formatAttributes map[string]struct{}) *PersonDatabase {
 	uniqueAttributes := make(map[string]struct{})
 	genders := make([]string, 0, len(data))
 
@@ -142,6 +181,8 @@ func NewPersonalDatabase(data Database) *PersonDatabase {
 			uniqueAttributes[attrName] = struct{}{}
 		}
 	}
+	// The genders are sorted to make the generation deterministic
+	slices.Sort(genders)
 
 	attributes := make([]string, 0, len(uniqueAttributes))
 	for attrName := range uniqueAttributes {
@@ -150,11 +191,16 @@ func NewPersonalDatabase(data Database) *PersonDatabase {
 
 	slices.Sort(attributes)
 
+	if formatAttributes == nil {
+		formatAttributes = make(map[string]struct{})
+	}
+
 	return &PersonDatabase{
-		Db:              data,
-		Attributes:      attributes,
-		Genders:         genders,
-		AttributesCount: attrsCount,
+		Db:               data,
+		Attributes:       attributes,
+		Genders:          genders,
+		FormatAttributes: formatAttributes,
+		AttributesCount:  attrsCount,
 	}
 }
 
@@ -180,31 +226,45 @@ type NameAttrs struct {
 	Title     string
 }
 
+// RandomPersonTransformer - generates the personal attributes from the dataset. Each attribute is chosen by its own
+// key derived from the generated seed and the attribute name, so adding new attributes or appending values to the
+// dataset keeps the most of the generated values for the hash engine
 type RandomPersonTransformer struct {
-	gender     string
-	byteLength int
-	generator  generators.Generator
+	gender    string
+	generator generators.Generator
 	// db - mapping gender to other personal attribute
 	// common structure
 	// gender -> person_attribute -> []possible values
 	db     *PersonDatabase
 	result map[string]string
+	buf    []byte
 }
 
+// NewRandomPersonTransformer - creates the transformer for the person database. If the database is nil the
+// DefaultPersonMap is used.
+//
+// Deprecated: use NewRandomPersonTransformerFromDataset
 func NewRandomPersonTransformer(gender string, personDb Database) *RandomPersonTransformer {
-
 	if personDb == nil {
 		personDb = DefaultPersonMap
 	}
+	return NewRandomPersonTransformerFromDataset(gender, &PersonDataset{Genders: personDb})
+}
+
+// NewRandomPersonTransformerFromDataset - creates the transformer for the dataset. If the dataset is nil the default
+// locale dataset is used
+func NewRandomPersonTransformerFromDataset(gender string, dataset *PersonDataset) *RandomPersonTransformer {
+
+	if dataset == nil {
+		dataset = PersonLocaleDatasets[DefaultPersonLocale]
+	}
 
-	db := NewPersonalDatabase(personDb)
+	db := dataset.Database()
 
 	return &RandomPersonTransformer{
 		gender: gender,
-		// we assume 4 bytes peer attribute + 1 byte for gender
-		db:         db,
-		result:     make(map[string]string, db.AttributesCount),
-		byteLength: db.AttributesCount * 4,
+		db:     db,
+		result: make(map[string]string, len(db.Attributes)+1),
 	}
 }
 
@@ -218,46 +278,88 @@ func (rpt *RandomPersonTransformer) GetFullName(gender string, original []byte)
 	if err != nil {
 		return nil, err
 	}
+	seed := binary.LittleEndian.Uint64(resBytes)
 
-	genderRandIdx := resBytes[0]
-	gender, err = rpt.getGender(gender, genderRandIdx)
+	gender, err = rpt.getGender(gender, getPersonAttributeKey(seed, PersonGenderAttributeName))
 	if err != nil {
 		return nil, err
 	}
 
-	startIdx := 1
+	clear(rpt.result)
+	rpt.result[PersonGenderAttributeName] = gender
 	for _, attr := range rpt.db.Attributes {
-		attrIdx := binary.LittleEndian.Uint32(resBytes[startIdx : startIdx+4])
-		rpt.result[attr] = rpt.db.GetRandomAttribute(gender, attr, attrIdx)
-		startIdx += 4
+		key := getPersonAttributeKey(seed, attr)
+		value := rpt.db.GetAttributeByKey(gender, attr, key)
+		if _, ok := rpt.db.FormatAttributes[attr]; ok {
+			value = rpt.fillFormat(value, key)
+		}
+		rpt.result[attr] = value
 	}
 
 	return rpt.result, nil
 }
 
-func (rpt *RandomPersonTransformer) getGender(gender string, randomGenderIdx byte) (string, error) {
+// fillFormat - replaces the '#' characters of the format with the random digits
+func (rpt *RandomPersonTransformer) fillFormat(format string, key uint64) string {
+	r := rand.New(rand.NewPCG(key, ^key))
+	rpt.buf = rpt.buf[:0]
+	for i := 0; i < len(format); i++ {
+		if format[i] == '#' {
+			rpt.buf = append(rpt.buf, byte('0'+r.IntN(10)))
+			continue
+		}
+		rpt.buf = append(rpt.buf, format[i])
+	}
+	return string(rpt.buf)
+}
+
+func (rpt *RandomPersonTransformer) getGender(gender string, key uint64) (string, error) {
 	if gender == "" {
 		gender = rpt.gender
 	}
 
-	switch gender {
-	case MaleGenderName, FemaleGenderName:
-	case AnyGenderName:
-		gender = rpt.db.GetRandomGender(uint32(randomGenderIdx))
-	default:
+	if gender == AnyGenderName {
+		return rpt.db.GetGenderByKey(key), nil
+	}
+	if !slices.Contains(rpt.db.Genders, gender) {
 		return "", fmt.Errorf("unable to match gender \"%s\"", gender)
 	}
 	return gender, nil
 }
 
 func (rpt *RandomPersonTransformer) GetRequiredGeneratorByteLength() int {
-	return rpt.byteLength
+	return randomPersonSeedLength
 }
 
 func (rpt *RandomPersonTransformer) SetGenerator(g generators.Generator) error {
-	if g.Size() < rpt.byteLength {
-		return fmt.Errorf("requested byte length (%d) higher than generator can produce (%d)", rpt.byteLength, g.Size())
+	if g.Size() < randomPersonSeedLength {
+		return fmt.Errorf("requested byte length (%d) higher than generator can produce (%d)", randomPersonSeedLength, g.Size())
 	}
 	rpt.generator = g
 	return nil
 }
+
+// getPersonAttributeKey - derives the key of the attribute from the seed. The key depends on the attribute name only,
+// so the keys of the existing attributes are not changed when the new attributes are added to the dataset
+func getPersonAttributeKey(seed uint64, attr string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(attr))
+	// splitmix64 finalizer
+	key := seed ^ h.Sum64()
+	key = (key ^ (key >> 30)) * 0xbf58476d1ce4e5b9
+	key = (key ^ (key >> 27)) * 0x94d049bb133111eb
+	return key ^ (key >> 31)
+}
+
+// jumpConsistentHash - maps the key to the bucket in range [0, buckets). When the number of buckets grows from n to
+// m, only (m-n)/m of the keys are moved and only to the new buckets. See "A Fast, Minimal Memory, Consistent Hash
+// Algorithm" by Lamping and Veach
+func jumpConsistentHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
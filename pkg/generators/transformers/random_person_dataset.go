@@ -0,0 +1,131 @@
+package transformers
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnPersonLocale   = "en"
+	DePersonLocale   = "de"
+	JaPersonLocale   = "ja"
+	PtBrPersonLocale = "pt_BR"
+
+	DefaultPersonLocale = EnPersonLocale
+)
+
+// PersonDataset - the source of the personal attributes. The attributes of the Common and Formats sections do not
+// depend on the gender and are available for each gender
+type PersonDataset struct {
+	// Genders - gender -> attribute -> values
+	Genders map[string]map[string][]string `json:"genders" yaml:"genders"`
+	// Common - attribute -> values
+	Common map[string][]string `json:"common" yaml:"common"`
+	// Formats - attribute -> formats. The '#' characters of the format are replaced with random digits
+	Formats map[string][]string `json:"formats" yaml:"formats"`
+}
+
+// PersonLocaleDatasets - the built-in datasets by locale
+var PersonLocaleDatasets = map[string]*PersonDataset{
+	EnPersonLocale:   enPersonDataset,
+	DePersonLocale:   dePersonDataset,
+	JaPersonLocale:   jaPersonDataset,
+	PtBrPersonLocale: ptBrPersonDataset,
+}
+
+// GetPersonLocales - returns the sorted list of the built-in locales
+func GetPersonLocales() []string {
+	return slices.Sorted(maps.Keys(PersonLocaleDatasets))
+}
+
+// LoadPersonDatasetFile - loads the dataset from the file in YAML or JSON format
+func LoadPersonDatasetFile(path string) (*PersonDataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dataset file: %w", err)
+	}
+	ds := &PersonDataset{}
+	if err = yaml.Unmarshal(data, ds); err != nil {
+		return nil, fmt.Errorf("unable to parse dataset file: %w", err)
+	}
+	return ds, nil
+}
+
+// Extend - returns the new dataset that contains the values of both datasets. The values of the other dataset are
+// appended to the end of the lists, so the most of the hash engine results stay the same
+func (ds *PersonDataset) Extend(other *PersonDataset) *PersonDataset {
+	res := &PersonDataset{
+		Genders: make(map[string]map[string][]string, len(ds.Genders)),
+		Common:  extendPersonAttributes(nil, ds.Common),
+		Formats: extendPersonAttributes(nil, ds.Formats),
+	}
+	for gender, attrs := range ds.Genders {
+		res.Genders[gender] = extendPersonAttributes(nil, attrs)
+	}
+	for gender, attrs := range other.Genders {
+		res.Genders[gender] = extendPersonAttributes(res.Genders[gender], attrs)
+	}
+	res.Common = extendPersonAttributes(res.Common, other.Common)
+	res.Formats = extendPersonAttributes(res.Formats, other.Formats)
+	return res
+}
+
+func extendPersonAttributes(dst, src map[string][]string) map[string][]string {
+	if dst == nil {
+		dst = make(map[string][]string, len(src))
+	}
+	for attr, values := range src {
+		dst[attr] = append(slices.Clip(dst[attr]), values...)
+	}
+	return dst
+}
+
+// Validate - checks that the attributes have values. The dataset may have no genders if it is used to extend the
+// locale dataset
+func (ds *PersonDataset) Validate() error {
+	if _, ok := ds.Genders[AnyGenderName]; ok {
+		return fmt.Errorf("gender name \"%s\" is reserved", AnyGenderName)
+	}
+	for gender, attrs := range ds.Genders {
+		for attr, values := range attrs {
+			if len(values) == 0 {
+				return fmt.Errorf("attribute \"%s\" of gender \"%s\" has no values", attr, gender)
+			}
+		}
+	}
+	for _, attrs := range []map[string][]string{ds.Common, ds.Formats} {
+		for attr, values := range attrs {
+			if len(values) == 0 {
+				return fmt.Errorf("attribute \"%s\" has no values", attr)
+			}
+		}
+	}
+	for attr := range ds.Formats {
+		if _, ok := ds.Common[attr]; ok {
+			return fmt.Errorf("attribute \"%s\" is defined in both common and formats sections", attr)
+		}
+	}
+	return nil
+}
+
+// Database - returns the database where the common and format attributes are added to each gender. The gender
+// attributes override the common ones with the same name
+func (ds *PersonDataset) Database() *PersonDatabase {
+	db := make(Database, len(ds.Genders))
+	formatAttributes := make(map[string]struct{}, len(ds.Formats))
+	for attr := range ds.Formats {
+		formatAttributes[attr] = struct{}{}
+	}
+	for gender, attrs := range ds.Genders {
+		genderAttrs := make(map[string][]string, len(attrs)+len(ds.Common)+len(ds.Formats))
+		maps.Copy(genderAttrs, ds.Common)
+		maps.Copy(genderAttrs, ds.Formats)
+		maps.Copy(genderAttrs, attrs)
+		db[gender] = genderAttrs
+	}
+	return newPersonDatabase(db, formatAttributes)
+}
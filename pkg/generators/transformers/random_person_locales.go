@@ -0,0 +1,211 @@
+package transformers
+
+var enPersonDataset = &PersonDataset{
+	Genders: DefaultPersonMap,
+	Common: map[string][]string{
+		"Street": {
+			"Main Street", "Oak Street", "Pine Street", "Maple Avenue", "Cedar Lane", "Elm Street", "Washington Avenue",
+			"Lake Street", "Hill Road", "Park Avenue", "Sunset Boulevard", "River Road", "Church Street", "Spring Street",
+			"Highland Avenue", "Jefferson Street", "Lincoln Avenue", "Madison Avenue", "Forest Drive", "Franklin Street",
+			"Walnut Street", "Willow Lane", "Chestnut Street", "Meadow Lane", "Center Street", "Mill Road",
+			"Broadway", "Cherry Lane", "Adams Street", "Valley Road",
+		},
+		"City": {
+			"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview", "Salem", "Madison",
+			"Georgetown", "Arlington", "Ashland", "Dover", "Oxford", "Jackson", "Burlington", "Manchester", "Milton",
+			"Newport", "Auburn", "Dayton", "Lexington", "Milford", "Winchester", "Hudson", "Kingston", "Mount Vernon",
+			"Centerville", "Cleveland", "Marion",
+		},
+		"State": {
+			"Alabama", "Alaska", "Arizona", "Arkansas", "California", "Colorado", "Connecticut", "Delaware", "Florida",
+			"Georgia", "Hawaii", "Idaho", "Illinois", "Indiana", "Iowa", "Kansas", "Kentucky", "Louisiana", "Maine",
+			"Maryland", "Massachusetts", "Michigan", "Minnesota", "Mississippi", "Missouri", "Montana", "Nebraska",
+			"Nevada", "New Hampshire", "New Jersey", "New Mexico", "New York", "North Carolina", "North Dakota", "Ohio",
+			"Oklahoma", "Oregon", "Pennsylvania", "Rhode Island", "South Carolina", "South Dakota", "Tennessee", "Texas",
+			"Utah", "Vermont", "Virginia", "Washington", "West Virginia", "Wisconsin", "Wyoming",
+		},
+	},
+	Formats: map[string][]string{
+		"BuildingNumber": {"#", "##", "###", "####"},
+		"PostalCode":     {"#####"},
+		"Phone": {
+			"+1 2##-555-01##", "+1 3##-555-01##", "+1 4##-555-01##", "+1 5##-555-01##", "+1 6##-555-01##",
+			"+1 7##-555-01##", "+1 8##-555-01##", "+1 9##-555-01##",
+		},
+	},
+}
+
+var dePersonDataset = &PersonDataset{
+	Genders: map[string]map[string][]string{
+		MaleGenderName: {
+			"Title": {"Herr", "Herr Dr.", "Herr Prof."},
+			"FirstName": {
+				"Alexander", "Andreas", "Benjamin", "Bernd", "Christian", "Daniel", "David", "Dennis", "Dieter",
+				"Dirk", "Elias", "Felix", "Finn", "Florian", "Frank", "Gerhard", "Günter", "Hans", "Heinz", "Helmut",
+				"Jan", "Jens", "Jonas", "Jörg", "Jürgen", "Kai", "Karl", "Klaus", "Lars", "Leon", "Lukas", "Manfred",
+				"Marco", "Markus", "Martin", "Matthias", "Max", "Michael", "Niklas", "Noah", "Oliver", "Patrick", "Paul",
+				"Peter", "Philipp", "Ralf", "Sebastian", "Stefan", "Sven", "Thomas", "Tim", "Tobias", "Uwe", "Werner",
+				"Wolfgang",
+			},
+			"LastName": dePersonLastNames,
+		},
+		FemaleGenderName: {
+			"Title": {"Frau", "Frau Dr.", "Frau Prof."},
+			"FirstName": {
+				"Andrea", "Angelika", "Anja", "Anna", "Birgit", "Brigitte", "Christina", "Claudia", "Emilia", "Emma",
+				"Gabriele", "Hanna", "Heike", "Helga", "Ingrid", "Julia", "Jutta", "Karin", "Katharina", "Kerstin",
+				"Laura", "Lea", "Lena", "Lina", "Marie", "Martina", "Melanie", "Mia", "Monika", "Nadine", "Nicole",
+				"Petra", "Renate", "Sabine", "Sandra", "Sarah", "Silke", "Sofia", "Stefanie", "Susanne", "Tanja",
+				"Ursula", "Ute",
+			},
+			"LastName": dePersonLastNames,
+		},
+	},
+	Common: map[string][]string{
+		"Street": {
+			"Hauptstraße", "Schulstraße", "Gartenstraße", "Bahnhofstraße", "Dorfstraße", "Bergstraße", "Birkenweg",
+			"Lindenstraße", "Kirchstraße", "Waldstraße", "Ringstraße", "Schillerstraße", "Goethestraße",
+			"Jahnstraße", "Wiesenweg", "Buchenweg", "Am Sportplatz", "Mühlenweg", "Rosenstraße", "Friedhofstraße",
+			"Feldstraße", "Parkstraße", "Mozartstraße", "Eichenweg", "Industriestraße",
+		},
+		"City": {
+			"Berlin", "Hamburg", "München", "Köln", "Frankfurt am Main", "Stuttgart", "Düsseldorf", "Leipzig",
+			"Dortmund", "Essen", "Bremen", "Dresden", "Hannover", "Nürnberg", "Duisburg", "Bochum", "Wuppertal",
+			"Bielefeld", "Bonn", "Münster", "Mannheim", "Karlsruhe", "Augsburg", "Wiesbaden", "Freiburg im Breisgau",
+		},
+		"State": {
+			"Baden-Württemberg", "Bayern", "Berlin", "Brandenburg", "Bremen", "Hamburg", "Hessen",
+			"Mecklenburg-Vorpommern", "Niedersachsen", "Nordrhein-Westfalen", "Rheinland-Pfalz", "Saarland",
+			"Sachsen", "Sachsen-Anhalt", "Schleswig-Holstein", "Thüringen",
+		},
+	},
+	Formats: map[string][]string{
+		"BuildingNumber": {"#", "##", "##a", "###"},
+		"PostalCode":     {"#####"},
+		"Phone": {
+			"+49 30 ########", "+49 40 ########", "+49 89 ########", "+49 221 #######", "+49 69 ########",
+			"+49 151 ########", "+49 160 #######", "+49 170 #######", "+49 176 ########",
+		},
+	},
+}
+
+var dePersonLastNames = []string{
+	"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann",
+	"Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann", "Braun",
+	"Krüger", "Hofmann", "Hartmann", "Lange", "Schmitt", "Werner", "Schmitz", "Krause", "Meier", "Lehmann",
+	"Schmid", "Schulze", "Maier", "Köhler", "Herrmann", "König", "Walter", "Mayer", "Huber", "Kaiser", "Fuchs",
+	"Peters", "Lang", "Scholz", "Möller", "Weiß", "Jung", "Hahn", "Schubert", "Vogel", "Friedrich", "Keller",
+	"Günther", "Frank", "Berger", "Winkler", "Roth", "Beck", "Lorenz",
+}
+
+var jaPersonDataset = &PersonDataset{
+	Genders: map[string]map[string][]string{
+		MaleGenderName: {
+			"Title": {"様"},
+			"FirstName": {
+				"翔太", "大輔", "健太", "拓也", "直樹", "誠", "浩", "隆", "学", "剛", "達也", "和也", "大樹", "翔",
+				"蓮", "悠真", "湊", "陽翔", "樹", "大翔", "悠人", "颯太", "陸", "海斗", "健一", "雄一", "正樹", "哲也",
+				"亮", "修", "博", "茂", "清", "勇", "明", "悟", "洋平", "康介", "裕太", "俊介",
+			},
+			"LastName": jaPersonLastNames,
+		},
+		FemaleGenderName: {
+			"Title": {"様"},
+			"FirstName": {
+				"陽菜", "結愛", "葵", "凛", "結衣", "美咲", "さくら", "愛", "彩", "真由美", "恵子", "久美子", "由美",
+				"明美", "智子", "裕子", "直美", "幸子", "京子", "洋子", "優子", "美穂", "麻衣", "舞", "愛美", "千尋",
+				"芽依", "美月", "莉子", "紬", "杏", "澪", "花子", "春香", "七海", "楓", "彩花", "奈々", "香織", "瞳",
+			},
+			"LastName": jaPersonLastNames,
+		},
+	},
+	Common: map[string][]string{
+		"Street": {
+			"本町", "栄町", "緑町", "旭町", "幸町", "寿町", "中央", "桜町", "東町", "西町", "南町", "北町", "大手町",
+			"昭和町", "元町", "宮前", "松原", "若葉", "青葉台", "日の出町",
+		},
+		"City": {
+			"千代田区", "新宿区", "渋谷区", "世田谷区", "横浜市", "川崎市", "大阪市", "名古屋市", "札幌市", "福岡市",
+			"神戸市", "京都市", "さいたま市", "広島市", "仙台市", "千葉市", "北九州市", "堺市", "浜松市", "熊本市",
+		},
+		"State": {
+			"北海道", "青森県", "宮城県", "秋田県", "福島県", "茨城県", "栃木県", "群馬県", "埼玉県", "千葉県",
+			"東京都", "神奈川県", "新潟県", "富山県", "石川県", "長野県", "岐阜県", "静岡県", "愛知県", "三重県",
+			"京都府", "大阪府", "兵庫県", "奈良県", "岡山県", "広島県", "山口県", "香川県", "愛媛県", "福岡県",
+			"長崎県", "熊本県", "大分県", "宮崎県", "鹿児島県", "沖縄県",
+		},
+	},
+	Formats: map[string][]string{
+		"BuildingNumber": {"#-#-#", "#-##-#", "#-#-##", "##-#"},
+		"PostalCode":     {"###-####"},
+		"Phone": {
+			"+81 3-####-####", "+81 6-####-####", "+81 45-###-####", "+81 52-###-####", "+81 90-####-####",
+			"+81 80-####-####", "+81 70-####-####",
+		},
+	},
+}
+
+var jaPersonLastNames = []string{
+	"佐藤", "鈴木", "高橋", "田中", "伊藤", "渡辺", "山本", "中村", "小林", "加藤", "吉田", "山田", "佐々木", "山口",
+	"松本", "井上", "木村", "林", "斎藤", "清水", "山崎", "森", "池田", "橋本", "阿部", "石川", "山下", "中島", "石井",
+	"小川", "前田", "岡田", "長谷川", "藤田", "後藤", "近藤", "村上", "遠藤", "青木", "坂本",
+}
+
+var ptBrPersonDataset = &PersonDataset{
+	Genders: map[string]map[string][]string{
+		MaleGenderName: {
+			"Title": {"Sr.", "Dr.", "Prof."},
+			"FirstName": {
+				"Miguel", "Arthur", "Gael", "Heitor", "Theo", "Davi", "Gabriel", "Bernardo", "Samuel", "João",
+				"Pedro", "Lucas", "Matheus", "Rafael", "Gustavo", "Felipe", "Bruno", "Guilherme", "Leonardo", "Thiago",
+				"Rodrigo", "Marcelo", "Eduardo", "Carlos", "José", "Antônio", "Francisco", "Paulo", "Luiz", "Marcos",
+				"Fernando", "Ricardo", "André", "Vinícius", "Diego", "Caio", "Enzo", "Murilo", "Henrique", "Daniel",
+			},
+			"LastName": ptBrPersonLastNames,
+		},
+		FemaleGenderName: {
+			"Title": {"Sra.", "Srta.", "Dra.", "Profa."},
+			"FirstName": {
+				"Helena", "Alice", "Laura", "Maria", "Valentina", "Heloísa", "Maria Clara", "Maria Cecília", "Júlia",
+				"Sophia", "Manuela", "Isabella", "Lívia", "Luiza", "Beatriz", "Mariana", "Ana", "Gabriela", "Larissa",
+				"Camila", "Amanda", "Letícia", "Fernanda", "Juliana", "Patrícia", "Aline", "Adriana", "Vanessa",
+				"Bruna", "Carolina", "Francisca", "Antônia", "Rafaela", "Bianca", "Lorena", "Yasmin", "Cecília",
+				"Clara", "Giovanna", "Débora",
+			},
+			"LastName": ptBrPersonLastNames,
+		},
+	},
+	Common: map[string][]string{
+		"Street": {
+			"Rua das Flores", "Rua São João", "Avenida Paulista", "Rua Sete de Setembro", "Rua XV de Novembro",
+			"Avenida Brasil", "Rua Quinze de Novembro", "Rua Tiradentes", "Rua Santos Dumont", "Avenida Getúlio Vargas",
+			"Rua Dom Pedro II", "Rua da Paz", "Rua Rio de Janeiro", "Avenida Atlântica", "Rua das Palmeiras",
+			"Rua Bela Vista", "Rua Barão do Rio Branco", "Avenida Presidente Vargas", "Rua Marechal Deodoro",
+			"Rua Castro Alves",
+		},
+		"City": {
+			"São Paulo", "Rio de Janeiro", "Brasília", "Salvador", "Fortaleza", "Belo Horizonte", "Manaus", "Curitiba",
+			"Recife", "Goiânia", "Belém", "Porto Alegre", "Guarulhos", "Campinas", "São Luís", "Maceió", "Natal",
+			"Teresina", "Campo Grande", "João Pessoa", "Florianópolis", "Santos", "Niterói", "Londrina", "Joinville",
+		},
+		"State": {
+			"AC", "AL", "AP", "AM", "BA", "CE", "DF", "ES", "GO", "MA", "MT", "MS", "MG", "PA", "PB", "PR", "PE", "PI",
+			"RJ", "RN", "RS", "RO", "RR", "SC", "SP", "SE", "TO",
+		},
+	},
+	Formats: map[string][]string{
+		"BuildingNumber": {"#", "##", "###", "####"},
+		"PostalCode":     {"#####-###"},
+		"Phone": {
+			"+55 11 9####-####", "+55 21 9####-####", "+55 31 9####-####", "+55 41 9####-####", "+55 51 9####-####",
+			"+55 61 9####-####", "+55 71 9####-####", "+55 81 9####-####", "+55 11 3###-####", "+55 21 2###-####",
+		},
+	},
+}
+
+var ptBrPersonLastNames = []string{
+	"Silva", "Santos", "Oliveira", "Souza", "Rodrigues", "Ferreira", "Alves", "Pereira", "Lima", "Gomes", "Costa",
+	"Ribeiro", "Martins", "Carvalho", "Almeida", "Lopes", "Soares", "Fernandes", "Vieira", "Barbosa", "Rocha", "Dias",
+	"Nascimento", "Andrade", "Moreira", "Nunes", "Marques", "Machado", "Mendes", "Freitas", "Cardoso", "Ramos",
+	"Gonçalves", "Santana", "Teixeira", "Araújo", "Pinto", "Correia", "Cavalcanti", "Monteiro",
+}
@@ -1,7 +1,9 @@
 package transformers
 
 import (
+	"maps"
 	"slices"
+	"strconv"
 	"testing"
 	"time"
 
@@ -20,3 +22,72 @@ func TestRandomNameTransformer_GetFullName(t *testing.T) {
 	require.True(t, slices.Contains(DefaultFirstNamesMale, res["FirstName"]) || slices.Contains(DefaultFirstNamesFemale, res["FirstName"]))
 	require.True(t, slices.Contains(DefaultLastNames, res["LastName"]))
 }
+
+func TestPersonDatabase_GetRandomAttribute(t *testing.T) {
+	db := NewPersonalDatabase(DefaultPersonMap)
+	require.Equal(t, DefaultLastNames[1], db.GetRandomAttribute(MaleGenderName, "LastName", uint32(len(DefaultLastNames)+1)))
+	require.Equal(t, FemaleGenderName, db.GetRandomGender(0))
+	require.Equal(t, MaleGenderName, db.GetRandomGender(1))
+	require.Empty(t, db.GetRandomAttribute(MaleGenderName, "Unknown", 1))
+}
+
+func TestPersonLocaleDatasets(t *testing.T) {
+	for _, locale := range GetPersonLocales() {
+		t.Run(locale, func(t *testing.T) {
+			ds := PersonLocaleDatasets[locale]
+			require.NoError(t, ds.Validate())
+			rnt := NewRandomPersonTransformerFromDataset(AnyGenderName, ds)
+			g, err := generators.GetHashBytesGen([]byte("salt"), rnt.GetRequiredGeneratorByteLength())
+			require.NoError(t, err)
+			require.NoError(t, rnt.SetGenerator(g))
+			res, err := rnt.GetFullName("", []byte("John Doe"))
+			require.NoError(t, err)
+			for _, attr := range []string{"Title", "FirstName", "LastName", "Street", "City", "State"} {
+				require.NotEmpty(t, res[attr], attr)
+			}
+			require.Contains(t, []string{MaleGenderName, FemaleGenderName}, res[PersonGenderAttributeName])
+			require.NotContains(t, res["Phone"], "#")
+			require.NotContains(t, res["PostalCode"], "#")
+		})
+	}
+}
+
+func TestRandomPersonTransformer_GetFullName_extendedDataset(t *testing.T) {
+	generate := func(ds *PersonDataset, original string) map[string]string {
+		rnt := NewRandomPersonTransformerFromDataset(AnyGenderName, ds)
+		g, err := generators.GetHashBytesGen([]byte("salt"), rnt.GetRequiredGeneratorByteLength())
+		require.NoError(t, err)
+		require.NoError(t, rnt.SetGenerator(g))
+		res, err := rnt.GetFullName("", []byte(original))
+		require.NoError(t, err)
+		return maps.Clone(res)
+	}
+
+	base := PersonLocaleDatasets[DePersonLocale]
+	extended := base.Extend(&PersonDataset{
+		Genders: map[string]map[string][]string{
+			MaleGenderName:   {"FirstName": {"Hinnerk", "Wilhelm"}},
+			FemaleGenderName: {"FirstName": {"Frieda", "Greta"}},
+		},
+		Common: map[string][]string{
+			"Country": {"Deutschland"},
+			"City":    {"Lübeck", "Rostock"},
+		},
+	})
+	require.Len(t, base.Common["City"], len(extended.Common["City"])-2)
+
+	var changed int
+	for i := 0; i < 1000; i++ {
+		original := strconv.Itoa(i)
+		before, after := generate(base, original), generate(extended, original)
+		require.Equal(t, "Deutschland", after["Country"])
+		for _, attr := range []string{PersonGenderAttributeName, "LastName", "Street", "State", "Phone"} {
+			require.Equal(t, before[attr], after[attr], attr)
+		}
+		if before["FirstName"] != after["FirstName"] || before["City"] != after["City"] {
+			changed++
+		}
+	}
+	// Only the values mapped to the appended items are changed
+	require.Less(t, changed, 250)
+}
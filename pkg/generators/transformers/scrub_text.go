@@ -0,0 +1,461 @@
+package transformers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/eminano/greenmask/pkg/generators"
+)
+
+const (
+	ScrubRedactStrategyName = "redact"
+	ScrubFakeStrategyName   = "fake"
+	ScrubHashStrategyName   = "hash"
+)
+
+const (
+	ScrubEmailDetectorName = "email"
+	ScrubPhoneDetectorName = "phone"
+	ScrubIbanDetectorName  = "iban"
+	ScrubCardDetectorName  = "card"
+	ScrubIpv4DetectorName  = "ipv4"
+	ScrubIpv6DetectorName  = "ipv6"
+)
+
+const (
+	// scrubSeedLength - the number of generator bytes used as a seed of the fake value
+	scrubSeedLength = 32
+	// scrubHashLength - the number of hash bytes in the replacement of the hash strategy
+	scrubHashLength      = 8
+	scrubFakeEmailDomain = "example.com"
+)
+
+// ScrubBuiltInDetectorNames - the names of the built-in detectors in order of their priority
+var ScrubBuiltInDetectorNames = []string{
+	ScrubEmailDetectorName,
+	ScrubIbanDetectorName,
+	ScrubCardDetectorName,
+	ScrubIpv4DetectorName,
+	ScrubIpv6DetectorName,
+	ScrubPhoneDetectorName,
+}
+
+var scrubDateRegexp = regexp.MustCompile(`^\d{4}[-./]\d{1,2}[-./]\d{1,2}$|^\d{1,2}[-./]\d{1,2}[-./]\d{4}$`)
+
+// ScrubDetector - detects the values of the specific kind in the text. The candidates are found by the regular
+// expression and then are checked by the validation function if it is set
+type ScrubDetector struct {
+	Name     string
+	Strategy string
+	// Replacement - the replacement of the redact strategy
+	Replacement []byte
+	regexp      *regexp.Regexp
+	// validate - returns the length of the valid prefix of the candidate found at the start position of the text or 0
+	// if the candidate is not valid
+	validate func(text []byte, start int, candidate []byte) int
+	// fake - generates the fake value of the same kind
+	fake func(r *rand.Rand, value []byte) []byte
+}
+
+// NewScrubDetector - creates the detector for the custom regular expression. The fake values of the custom detector
+// keep the format of the original value: letters are replaced with letters and digits with digits
+func NewScrubDetector(name, pattern, strategy string, replacement []byte) (*ScrubDetector, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile detector \"%s\" pattern: %w", name, err)
+	}
+	d := &ScrubDetector{
+		Name:   name,
+		regexp: re,
+		fake:   fakeScrubValueByFormat,
+	}
+	return d, d.init(strategy, replacement)
+}
+
+// NewScrubBuiltInDetector - creates the built-in detector by name
+func NewScrubBuiltInDetector(name, strategy string, replacement []byte) (*ScrubDetector, error) {
+	var d *ScrubDetector
+	switch name {
+	case ScrubEmailDetectorName:
+		d = &ScrubDetector{
+			regexp: regexp.MustCompile(`[A-Za-z0-9][A-Za-z0-9._%+\-]*@[A-Za-z0-9](?:[A-Za-z0-9\-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9\-]*[A-Za-z0-9])?)*\.[A-Za-z]{2,}`),
+			fake:   fakeScrubEmail,
+		}
+	case ScrubIbanDetectorName:
+		d = &ScrubDetector{
+			regexp:   regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+			validate: validateScrubIban,
+			fake:     fakeScrubIban,
+		}
+	case ScrubCardDetectorName:
+		d = &ScrubDetector{
+			regexp:   regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+			validate: validateScrubCard,
+			fake:     fakeScrubCard,
+		}
+	case ScrubIpv4DetectorName:
+		d = &ScrubDetector{
+			regexp: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+			fake:   fakeScrubIpv4,
+		}
+	case ScrubIpv6DetectorName:
+		d = &ScrubDetector{
+			regexp:   regexp.MustCompile(`(?:[0-9A-Fa-f]{0,4}:){2,7}(?:\d{1,3}(?:\.\d{1,3}){3}|[0-9A-Fa-f]{0,4})`),
+			validate: validateScrubIpv6,
+			fake:     fakeScrubIpv6,
+		}
+	case ScrubPhoneDetectorName:
+		d = &ScrubDetector{
+			regexp:   regexp.MustCompile(`(?:\+\d{1,3}[ \-.]?)?(?:\(\d{1,4}\)[ \-.]?)?\d{2,4}(?:[ \-.]?\d{2,4}){1,4}\b`),
+			validate: validateScrubPhone,
+			fake:     fakeScrubPhone,
+		}
+	default:
+		return nil, fmt.Errorf("unknown built-in detector \"%s\"", name)
+	}
+	d.Name = name
+	return d, d.init(strategy, replacement)
+}
+
+func (d *ScrubDetector) init(strategy string, replacement []byte) error {
+	switch strategy {
+	case ScrubRedactStrategyName, ScrubFakeStrategyName, ScrubHashStrategyName:
+	default:
+		return fmt.Errorf("unknown strategy \"%s\" of detector \"%s\"", strategy, d.Name)
+	}
+	d.Strategy = strategy
+	d.Replacement = replacement
+	if d.Replacement == nil {
+		d.Replacement = []byte("[" + strings.ToUpper(d.Name) + "]")
+	}
+	return nil
+}
+
+type scrubMatch struct {
+	start    int
+	end      int
+	detector *ScrubDetector
+}
+
+// ScrubTextTransformer - finds the sensitive values in the text by the detectors and replaces them in a single pass
+// according to the detector strategy. If the matches of different detectors overlap, the leftmost one wins, then the
+// longest one, then the one of the detector that is earlier in the list
+type ScrubTextTransformer struct {
+	detectors     []*ScrubDetector
+	generator     generators.Generator
+	hashGenerator generators.Generator
+	matches       []scrubMatch
+	buf           []byte
+}
+
+func NewScrubTextTransformer(detectors []*ScrubDetector) (*ScrubTextTransformer, error) {
+	if len(detectors) == 0 {
+		return nil, fmt.Errorf("at least one detector is required")
+	}
+	return &ScrubTextTransformer{
+		detectors: detectors,
+	}, nil
+}
+
+func (t *ScrubTextTransformer) GetRequiredGeneratorByteLength() int {
+	return scrubSeedLength
+}
+
+// SetGenerator - sets the generator of the fake strategy
+func (t *ScrubTextTransformer) SetGenerator(g generators.Generator) error {
+	if g.Size() < scrubSeedLength {
+		return fmt.Errorf("requested byte length (%d) higher than generator can produce (%d)", scrubSeedLength, g.Size())
+	}
+	t.generator = g
+	return nil
+}
+
+// SetHashGenerator - sets the generator of the hash strategy. It must be deterministic
+func (t *ScrubTextTransformer) SetHashGenerator(g generators.Generator) error {
+	if g.Size() < scrubHashLength {
+		return fmt.Errorf("requested byte length (%d) higher than generator can produce (%d)", scrubHashLength, g.Size())
+	}
+	t.hashGenerator = g
+	return nil
+}
+
+// Transform - returns the scrubbed text. The returned slice is valid until the next call
+func (t *ScrubTextTransformer) Transform(data []byte) ([]byte, error) {
+	t.matches = t.matches[:0]
+	for _, d := range t.detectors {
+		t.findMatches(d, data)
+	}
+	if len(t.matches) == 0 {
+		t.buf = append(t.buf[:0], data...)
+		return t.buf, nil
+	}
+	// The sort is stable, so the detector order is kept for the same matches
+	slices.SortStableFunc(t.matches, func(a, b scrubMatch) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return b.end - a.end
+	})
+
+	t.buf = t.buf[:0]
+	var pos int
+	for _, m := range t.matches {
+		if m.start < pos {
+			continue
+		}
+		t.buf = append(t.buf, data[pos:m.start]...)
+		replacement, err := t.replace(m.detector, data[m.start:m.end])
+		if err != nil {
+			return nil, fmt.Errorf("unable to replace \"%s\" detector value: %w", m.detector.Name, err)
+		}
+		t.buf = append(t.buf, replacement...)
+		pos = m.end
+	}
+	t.buf = append(t.buf, data[pos:]...)
+	return t.buf, nil
+}
+
+// findMatches - finds the valid matches of the detector. The search is continued from the next byte after the invalid
+// candidate, so the candidate that captured the part of the valid value does not hide it. Therefore, the validation
+// functions check that the candidate is not a part of a longer value
+func (t *ScrubTextTransformer) findMatches(d *ScrubDetector, data []byte) {
+	for pos := 0; pos < len(data); {
+		loc := d.regexp.FindIndex(data[pos:])
+		if loc == nil {
+			return
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		if d.validate != nil {
+			length := d.validate(data, start, data[start:end])
+			if length == 0 {
+				pos = start + 1
+				continue
+			}
+			end = start + length
+		}
+		t.matches = append(t.matches, scrubMatch{start: start, end: end, detector: d})
+		pos = max(end, start+1)
+	}
+}
+
+func (t *ScrubTextTransformer) replace(d *ScrubDetector, value []byte) ([]byte, error) {
+	switch d.Strategy {
+	case ScrubFakeStrategyName:
+		if t.generator == nil {
+			return nil, fmt.Errorf("generator is not set")
+		}
+		seed, err := t.generator.Generate(value)
+		if err != nil {
+			return nil, err
+		}
+		return d.fake(rand.New(rand.NewChaCha8([scrubSeedLength]byte(seed[:scrubSeedLength]))), value), nil
+	case ScrubHashStrategyName:
+		if t.hashGenerator == nil {
+			return nil, fmt.Errorf("hash generator is not set")
+		}
+		sum, err := t.hashGenerator.Generate(value)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]byte, 0, len(d.Name)+1+2*scrubHashLength)
+		res = append(res, strings.ToUpper(d.Name)...)
+		res = append(res, '_')
+		return hex.AppendEncode(res, sum[:scrubHashLength]), nil
+	}
+	return d.Replacement, nil
+}
+
+func validateScrubIban(text []byte, start int, candidate []byte) int {
+	if !isScrubValueStart(text, start) {
+		return 0
+	}
+	// The candidate may capture the following words separated by space, so the valid prefix is searched from the
+	// longest to the shortest one
+	for end := len(candidate); end > 0; end-- {
+		if end < len(candidate) && candidate[end] != ' ' {
+			continue
+		}
+		iban := bytes.ReplaceAll(candidate[:end], []byte(" "), nil)
+		if l, ok := ibanLengths[string(iban[:2])]; ok && l == len(iban) && ibanMod97(iban) == 1 {
+			return end
+		}
+	}
+	return 0
+}
+
+func validateScrubCard(text []byte, start int, candidate []byte) int {
+	if !isScrubValueStart(text, start) {
+		return 0
+	}
+	// The groups of digits must be separated by the same separator
+	if sepIdx := slices.IndexFunc(candidate, isNotScrubDigit); sepIdx != -1 {
+		sep := candidate[sepIdx]
+		if end := slices.IndexFunc(candidate, func(c byte) bool { return isNotScrubDigit(c) && c != sep }); end != -1 {
+			candidate = candidate[:end]
+		}
+	}
+	for end := len(candidate); end > 0; end-- {
+		if end < len(candidate) && !isNotScrubDigit(candidate[end]) || isNotScrubDigit(candidate[end-1]) {
+			continue
+		}
+		digits := scrubDigits(candidate[:end])
+		if len(digits) >= 13 && len(digits) <= 19 && luhnChecksum(digits) == 0 {
+			return end
+		}
+	}
+	return 0
+}
+
+// validateScrubIpv6 - checks that the candidate is a standalone IPv6 address. The neighbour characters are checked
+// to skip the parts of the words like "std::string"
+func validateScrubIpv6(text []byte, start int, candidate []byte) int {
+	end := start + len(candidate)
+	if start > 0 && isScrubWordChar(text[start-1]) || end < len(text) && isScrubWordChar(text[end]) {
+		return 0
+	}
+	if !slices.ContainsFunc(candidate, isScrubWordChar) {
+		return 0
+	}
+	addr, err := netip.ParseAddr(string(candidate))
+	if err != nil || !addr.Is6() {
+		return 0
+	}
+	return len(candidate)
+}
+
+func isScrubWordChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':'
+}
+
+func validateScrubPhone(text []byte, start int, candidate []byte) int {
+	if !isScrubValueStart(text, start) || scrubDateRegexp.Match(candidate) {
+		return 0
+	}
+	digits := scrubDigits(candidate)
+	if len(digits) < 7 || len(digits) > 15 {
+		return 0
+	}
+	return len(candidate)
+}
+
+// isScrubValueStart - checks that the value found at the start position is not a part of a word or a number. The
+// number is also continued by the digit group separated by the single separator
+func isScrubValueStart(text []byte, start int) bool {
+	if start == 0 {
+		return true
+	}
+	prev := text[start-1]
+	if isScrubWordChar(prev) && prev != ':' {
+		return false
+	}
+	if prev == ' ' || prev == '-' || prev == '.' {
+		return start < 2 || isNotScrubDigit(text[start-2])
+	}
+	return true
+}
+
+func isNotScrubDigit(c byte) bool {
+	return c < '0' || c > '9'
+}
+
+func scrubDigits(data []byte) []byte {
+	res := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c >= '0' && c <= '9' {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// fakeScrubValueByFormat - replaces the letters with the random letters of the same case and the digits with the
+// random digits. The rest of the characters are kept
+func fakeScrubValueByFormat(r *rand.Rand, value []byte) []byte {
+	res := make([]byte, len(value))
+	for i, c := range value {
+		switch {
+		case c >= '0' && c <= '9':
+			res[i] = byte('0' + r.IntN(10))
+		case c >= 'a' && c <= 'z':
+			res[i] = byte('a' + r.IntN(26))
+		case c >= 'A' && c <= 'Z':
+			res[i] = byte('A' + r.IntN(26))
+		default:
+			res[i] = c
+		}
+	}
+	return res
+}
+
+func fakeScrubEmail(r *rand.Rand, value []byte) []byte {
+	at := bytes.IndexByte(value, '@')
+	res := fakeScrubValueByFormat(r, value[:at])
+	res = append(res, '@')
+	return append(res, scrubFakeEmailDomain...)
+}
+
+// fakeScrubIban - keeps the country code, generates BBAN of the same format and calculates the check digits
+func fakeScrubIban(r *rand.Rand, value []byte) []byte {
+	res := slices.Clone(value[:2])
+	res = append(res, '0', '0')
+	res = append(res, fakeScrubValueByFormat(r, value[4:])...)
+	compact := bytes.ReplaceAll(res, []byte(" "), nil)
+	check := 98 - ibanMod97(compact)
+	res[2], res[3] = byte('0'+check/10), byte('0'+check%10)
+	return res
+}
+
+// fakeScrubCard - keeps the first digit (the card network) and separators and generates the number with valid Luhn
+// checksum
+func fakeScrubCard(r *rand.Rand, value []byte) []byte {
+	res := slices.Clone(value)
+	last := -1
+	for i := 1; i < len(res); i++ {
+		if res[i] >= '0' && res[i] <= '9' {
+			res[i] = byte('0' + r.IntN(10))
+			last = i
+		}
+	}
+	res[last] = '0'
+	if checksum := luhnChecksum(scrubDigits(res)); checksum != 0 {
+		res[last] = byte('0' + 10 - checksum)
+	}
+	return res
+}
+
+// fakeScrubIpv4 - generates the address from the private network 10.0.0.0/8
+func fakeScrubIpv4(r *rand.Rand, value []byte) []byte {
+	return netip.AddrFrom4([4]byte{10, byte(r.IntN(256)), byte(r.IntN(256)), byte(r.IntN(256))}).AppendTo(nil)
+}
+
+// fakeScrubIpv6 - generates the address from the unique local network fd00::/8
+func fakeScrubIpv6(r *rand.Rand, value []byte) []byte {
+	var addr [16]byte
+	binary.BigEndian.PutUint64(addr[:8], r.Uint64())
+	binary.BigEndian.PutUint64(addr[8:], r.Uint64())
+	addr[0] = 0xfd
+	return netip.AddrFrom16(addr).AppendTo(nil)
+}
+
+// fakeScrubPhone - keeps the country code and the formatting and replaces the rest of the digits
+func fakeScrubPhone(r *rand.Rand, value []byte) []byte {
+	var prefixLength int
+	if value[0] == '+' {
+		prefixLength = 1
+		for prefixLength < len(value) && value[prefixLength] >= '0' && value[prefixLength] <= '9' {
+			prefixLength++
+		}
+		if prefixLength == len(value) {
+			prefixLength = 1
+		}
+	}
+	res := slices.Clone(value[:prefixLength])
+	return append(res, fakeScrubValueByFormat(r, value[prefixLength:])...)
+}
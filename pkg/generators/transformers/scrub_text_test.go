@@ -0,0 +1,134 @@
+package transformers
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eminano/greenmask/pkg/generators"
+)
+
+func newTestScrubTextTransformer(t *testing.T, strategy string, custom ...*ScrubDetector) *ScrubTextTransformer {
+	var detectors []*ScrubDetector
+	for _, name := range ScrubBuiltInDetectorNames {
+		d, err := NewScrubBuiltInDetector(name, strategy, nil)
+		require.NoError(t, err)
+		detectors = append(detectors, d)
+	}
+	detectors = append(detectors, custom...)
+	tr, err := NewScrubTextTransformer(detectors)
+	require.NoError(t, err)
+	g, err := generators.GetHashBytesGen([]byte("salt"), tr.GetRequiredGeneratorByteLength())
+	require.NoError(t, err)
+	require.NoError(t, tr.SetGenerator(g))
+	require.NoError(t, tr.SetHashGenerator(g))
+	return tr
+}
+
+func TestScrubTextTransformer_redact(t *testing.T) {
+	employee, err := NewScrubDetector("employee_id", `EMP-\d{6}`, ScrubRedactStrategyName, []byte("<EMPLOYEE>"))
+	require.NoError(t, err)
+	tr := newTestScrubTextTransformer(t, ScrubRedactStrategyName, employee)
+
+	tests := []struct {
+		name     string
+		original string
+		expected string
+	}{
+		{
+			name:     "email",
+			original: "Contact john.doe+test@mail.example.com today",
+			expected: "Contact [EMAIL] today",
+		},
+		{
+			name:     "valid card",
+			original: "Paid with 4111 1111 1111 1111, thanks",
+			expected: "Paid with [CARD], thanks",
+		},
+		{
+			name:     "invalid card",
+			original: "Ref 4111 1111 1111 1112",
+			expected: "Ref 4111 1111 1111 1112",
+		},
+		{
+			name:     "iban with following words",
+			original: "IBAN GB82 WEST 1234 5698 7654 32 AND MORE",
+			expected: "IBAN [IBAN] AND MORE",
+		},
+		{
+			name:     "invalid iban",
+			original: "Code DE00123456780000000000 end",
+			expected: "Code DE00123456780000000000 end",
+		},
+		{
+			name:     "ip addresses",
+			original: "From 192.168.10.1 and 2001:db8::8a2e:370:7334 via std::string at 12:30:45",
+			expected: "From [IPV4] and [IPV6] via std::string at 12:30:45",
+		},
+		{
+			name:     "phone and date",
+			original: "Call +44 20 7946 0958 on 2024-01-15",
+			expected: "Call [PHONE] on 2024-01-15",
+		},
+		{
+			name:     "custom detector",
+			original: "Assigned to EMP-123456.",
+			expected: "Assigned to <EMPLOYEE>.",
+		},
+		{
+			name:     "nothing to scrub",
+			original: "Nothing here",
+			expected: "Nothing here",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tr.Transform([]byte(tt.original))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(res))
+		})
+	}
+}
+
+func TestScrubTextTransformer_fake(t *testing.T) {
+	tr := newTestScrubTextTransformer(t, ScrubFakeStrategyName)
+	original := "john@corp.com, GB82 WEST 1234 5698 7654 32, 4111-1111-1111-1111, 192.168.10.1, fe80::1, +44 20 7946 0958"
+	res, err := tr.Transform([]byte(original))
+	require.NoError(t, err)
+	require.Regexp(t,
+		regexp.MustCompile(`^[a-z]{4}@example\.com, GB\d{2} [A-Z]{4} \d{4} \d{4} \d{4} \d{2}, 4\d{3}-\d{4}-\d{4}-\d{4}, 10\.\d+\.\d+\.\d+, fd[0-9a-f:]+, \+44 \d{2} \d{4} \d{4}$`),
+		string(res),
+	)
+	require.NotEqual(t, original, string(res))
+
+	// The fake values must pass the detector validation
+	redact := newTestScrubTextTransformer(t, ScrubRedactStrategyName)
+	scrubbed, err := redact.Transform(res)
+	require.NoError(t, err)
+	require.Equal(t, "[EMAIL], [IBAN], [CARD], [IPV4], [IPV6], [PHONE]", string(scrubbed))
+
+	// The hash engine generates the same fake values for the same original values
+	again, err := tr.Transform([]byte(original))
+	require.NoError(t, err)
+	require.Equal(t, string(res), string(again))
+}
+
+func TestScrubTextTransformer_hash(t *testing.T) {
+	tr := newTestScrubTextTransformer(t, ScrubHashStrategyName)
+	res, err := tr.Transform([]byte("from john@corp.com to john@corp.com and jane@corp.com"))
+	require.NoError(t, err)
+	require.Regexp(t, `^from (EMAIL_[0-9a-f]{16}) to (EMAIL_[0-9a-f]{16}) and (EMAIL_[0-9a-f]{16})$`, string(res))
+	m := regexp.MustCompile(`EMAIL_[0-9a-f]{16}`).FindAllString(string(res), -1)
+	require.Equal(t, m[0], m[1])
+	require.NotEqual(t, m[0], m[2])
+}
+
+func TestScrubDetector_errors(t *testing.T) {
+	_, err := NewScrubBuiltInDetector("unknown", ScrubRedactStrategyName, nil)
+	require.Error(t, err)
+	_, err = NewScrubBuiltInDetector(ScrubEmailDetectorName, "unknown", nil)
+	require.Error(t, err)
+	_, err = NewScrubDetector("custom", "(", ScrubRedactStrategyName, nil)
+	require.Error(t, err)
+}
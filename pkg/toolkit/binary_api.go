@@ -0,0 +1,207 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolkit
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// binaryHeaderLength - the length of the message rows count
+const binaryHeaderLength = 4
+
+// BinaryApi - the interaction API that transfers the rows in length-prefixed binary messages. A message may contain
+// any number of rows and is encoded as
+//
+//	uint32 message length (excluding this field)
+//	uint32 rows count
+//	for each row: uint32 row length, row encoded as RawRecordBinary
+//
+// All the integers are big endian
+type BinaryApi struct {
+	transferringColumns []*Column
+	affectedColumns     []*Column
+	record              *RawRecordBinary
+	w                   io.Writer
+	r                   io.Reader
+	// out - the outgoing message
+	out     []byte
+	outRows int
+	// in - the received message. The decoded rows reference it until the next message is read
+	in      []byte
+	prefix  [4]byte
+	rows    []*RawRecordBinary
+	inRows  int
+	nextRow int
+}
+
+func NewBinaryApi(transferringColumns []*Column, affectedColumns []*Column) *BinaryApi {
+	return &BinaryApi{
+		transferringColumns: transferringColumns,
+		affectedColumns:     affectedColumns,
+		record:              NewRawRecordBinary(),
+	}
+}
+
+func (b *BinaryApi) SetWriter(w io.Writer) {
+	b.w = w
+	b.out = b.out[:0]
+	b.outRows = 0
+}
+
+func (b *BinaryApi) SetReader(r io.Reader) {
+	b.r = r
+	b.inRows = 0
+	b.nextRow = 0
+}
+
+func (b *BinaryApi) GetRowDriverFromRecord(r *Record) (RowDriver, error) {
+	for _, c := range b.transferringColumns {
+		v, err := r.GetRawColumnValueByIdx(c.Idx)
+		if err != nil {
+			return nil, fmt.Errorf("error getting raw atribute value: %w", err)
+		}
+		if err = b.record.SetColumn(c.Idx, v); err != nil {
+			return nil, fmt.Errorf("unable to set new value: %w", err)
+		}
+	}
+	return b.record, nil
+}
+
+func (b *BinaryApi) SetRowDriverToRecord(rd RowDriver, r *Record) error {
+	for _, c := range b.affectedColumns {
+		v, err := rd.GetColumn(c.Idx)
+		if err != nil {
+			return fmt.Errorf(`error getting column %d value: %w`, c.Idx, err)
+		}
+		if err = r.SetRawColumnValueByIdx(c.Idx, v); err != nil {
+			return fmt.Errorf(`error setting column %d value to record: %w`, c.Idx, err)
+		}
+	}
+	return nil
+}
+
+// Encode - writes the message with the single row
+func (b *BinaryApi) Encode(ctx context.Context, row RowDriver) error {
+	if err := b.AppendRow(row); err != nil {
+		return err
+	}
+	return b.Flush(ctx)
+}
+
+// AppendRow - encodes the row into the outgoing message. The row can be reused right after the call
+func (b *BinaryApi) AppendRow(row RowDriver) error {
+	rr, ok := row.(*RawRecordBinary)
+	if !ok {
+		return fmt.Errorf("binary interaction api expects RawRecordBinary but received %T", row)
+	}
+	if len(b.out) == 0 {
+		b.out = append(b.out, make([]byte, 4+binaryHeaderLength)...)
+	}
+	start := len(b.out)
+	b.out = append(b.out, 0, 0, 0, 0)
+	b.out = rr.appendEncoded(b.out)
+	binary.BigEndian.PutUint32(b.out[start:], uint32(len(b.out)-start-4))
+	b.outRows++
+	return nil
+}
+
+// Flush - writes the outgoing message if it has rows
+func (b *BinaryApi) Flush(ctx context.Context) error {
+	if b.outRows == 0 {
+		return nil
+	}
+	binary.BigEndian.PutUint32(b.out, uint32(len(b.out)-4))
+	binary.BigEndian.PutUint32(b.out[4:], uint32(b.outRows))
+	_, err := b.w.Write(b.out)
+	b.out = b.out[:0]
+	b.outRows = 0
+	if err != nil {
+		return fmt.Errorf("error writing binary message: %w", err)
+	}
+	return nil
+}
+
+// Decode - returns the next row of the received message and reads the next message if all the rows are decoded
+func (b *BinaryApi) Decode(ctx context.Context) (RowDriver, error) {
+	for b.nextRow >= b.inRows {
+		if err := b.readMessage(); err != nil {
+			return nil, err
+		}
+	}
+	row := b.rows[b.nextRow]
+	b.nextRow++
+	return row, nil
+}
+
+// PendingRows - returns the number of the rows of the received message that are not decoded yet
+func (b *BinaryApi) PendingRows() int {
+	return b.inRows - b.nextRow
+}
+
+func (b *BinaryApi) readMessage() error {
+	if _, err := io.ReadFull(b.r, b.prefix[:]); err != nil {
+		if err == io.EOF {
+			return err
+		}
+		return fmt.Errorf("error reading binary message length: %w", err)
+	}
+	length := int(binary.BigEndian.Uint32(b.prefix[:]))
+	if length < binaryHeaderLength {
+		return fmt.Errorf("binary message is too short: %d bytes", length)
+	}
+	if cap(b.in) < length {
+		b.in = make([]byte, length)
+	}
+	b.in = b.in[:length]
+	if _, err := io.ReadFull(b.r, b.in); err != nil {
+		return fmt.Errorf("error reading binary message: %w", err)
+	}
+
+	data := b.in[binaryHeaderLength:]
+	// The count is received from the peer, so it is checked before the rows are allocated. Each row has at least
+	// the length prefix
+	rowsCount := binary.BigEndian.Uint32(b.in)
+	if uint64(rowsCount) > uint64(len(data)/4) {
+		return fmt.Errorf("binary message rows count %d exceeds the message length %d", rowsCount, length)
+	}
+	count := int(rowsCount)
+	for len(b.rows) < count {
+		b.rows = append(b.rows, NewRawRecordBinary())
+	}
+	for i := 0; i < count; i++ {
+		if len(data) < 4 {
+			return fmt.Errorf("binary message is truncated at row %d", i)
+		}
+		rowLength := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint64(rowLength) > uint64(len(data)) {
+			return fmt.Errorf("binary message is truncated at row %d", i)
+		}
+		if err := b.rows[i].Decode(data[:rowLength]); err != nil {
+			return fmt.Errorf("error decoding row %d: %w", i, err)
+		}
+		data = data[rowLength:]
+	}
+	b.inRows = count
+	b.nextRow = 0
+	return nil
+}
+
+func (b *BinaryApi) Clean() {
+	b.record.Clean()
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolkit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawRecordBinary(t *testing.T) {
+	rr := NewRawRecordBinary()
+	require.NoError(t, rr.SetColumn(3, NewRawValue([]byte("test"), false)))
+	require.NoError(t, rr.SetColumn(1, NewRawValue(nil, true)))
+	require.NoError(t, rr.SetColumn(3, NewRawValue([]byte("new"), false)))
+	data, err := rr.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 2, 0, 3, 0, 0, 0, 3, 'n', 'e', 'w', 0, 1, 0xff, 0xff, 0xff, 0xff}, data)
+
+	decoded := NewRawRecordBinary()
+	require.NoError(t, decoded.Decode(data))
+	assert.Equal(t, 2, decoded.Length())
+	v, err := decoded.GetColumn(3)
+	require.NoError(t, err)
+	assert.Equal(t, NewRawValue([]byte("new"), false), v)
+	v, err = decoded.GetColumn(1)
+	require.NoError(t, err)
+	assert.True(t, v.IsNull)
+	_, err = decoded.GetColumn(2)
+	require.Error(t, err)
+
+	require.ErrorContains(t, decoded.Decode(data[:len(data)-1]), "truncated")
+	require.ErrorContains(t, decoded.Decode(append(data, 0)), "trailing")
+}
+
+func TestBinaryApi_batch(t *testing.T) {
+	ctx := context.Background()
+	columns := []*Column{{Name: "id", Idx: 0}, {Name: "title", Idx: 2}}
+	buf := &bytes.Buffer{}
+	api := NewBinaryApi(columns, columns)
+	api.SetWriter(buf)
+
+	values := [][2]string{{"1", "first"}, {"2", ""}, {"3", "third"}}
+	for _, v := range values {
+		row := NewRawRecordBinary()
+		require.NoError(t, row.SetColumn(0, NewRawValue([]byte(v[0]), false)))
+		require.NoError(t, row.SetColumn(2, NewRawValue([]byte(v[1]), v[1] == "")))
+		require.NoError(t, api.AppendRow(row))
+	}
+	require.NoError(t, api.Flush(ctx))
+	require.NoError(t, api.Encode(ctx, NewRawRecordBinary()))
+
+	api.SetReader(buf)
+	for i, v := range values {
+		row, err := api.Decode(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, len(values)-i-1, api.PendingRows())
+		id, err := row.GetColumn(0)
+		require.NoError(t, err)
+		assert.Equal(t, v[0], string(id.Data))
+		title, err := row.GetColumn(2)
+		require.NoError(t, err)
+		assert.Equal(t, v[1] == "", title.IsNull)
+		assert.Equal(t, v[1], string(title.Data))
+	}
+	row, err := api.Decode(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, row.Length())
+	_, err = api.Decode(ctx)
+	require.ErrorIs(t, err, io.EOF)
+
+	require.ErrorContains(t, api.AppendRow(&RawRecord{}), "expects RawRecordBinary")
+}
+
+func TestBinaryApi_Decode_invalid_rows_count(t *testing.T) {
+	api := NewBinaryApi(nil, nil)
+	// The message of 8 bytes declares 0xFFFFFFFF rows but fits only one row length prefix
+	api.SetReader(bytes.NewReader([]byte{0, 0, 0, 8, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}))
+	_, err := api.Decode(context.Background())
+	require.ErrorContains(t, err, "rows count 4294967295 exceeds the message length 8")
+	assert.Empty(t, api.rows)
+}
@@ -45,6 +45,7 @@ type Cmd struct {
 	printDefinition bool
 	validate        bool
 	transform       bool
+	grpcAddress     string
 	params          map[string]Parameterizer
 }
 
@@ -82,7 +83,9 @@ func (c *Cmd) setupDefaultCmd() {
 	c.PersistentFlags().BoolVar(&c.transform, "transform", false, "run transformation")
 	c.PersistentFlags().BoolVar(&c.validate, "validate", false, "validate using provided meta")
 	c.PersistentFlags().BoolVar(&c.printDefinition, "print-definition", false, "print transformer definition")
-	c.MarkFlagsMutuallyExclusive("transform", "validate", "print-definition")
+	c.PersistentFlags().StringVar(&c.grpcAddress, "grpc", "",
+		"serve transformer via gRPC on the address (unix:///path/to.sock or host:port)")
+	c.MarkFlagsMutuallyExclusive("transform", "validate", "print-definition", "grpc")
 	c.PersistentFlags().StringVar(&c.logFormat, "log-format", "text", "logging format [text|json]")
 	c.PersistentFlags().StringVar(&c.logLevel, "log-level", zerolog.LevelInfoValue,
 		fmt.Sprintf(
@@ -107,23 +110,26 @@ func (c *Cmd) run(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	if !c.validate && !c.transform {
-		log.Fatal().Msgf("behaviour parameter was not provided: expected one of validate transform or print-definition")
+	if !c.validate && !c.transform && c.grpcAddress == "" {
+		log.Fatal().Msgf("behaviour parameter was not provided: expected one of validate transform print-definition or grpc")
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// The signal handler is set up before the transformation is started, otherwise the early SIGTERM kills the
+	// process instead of the graceful shutdown
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
 	done := make(chan struct{})
 	eg := &errgroup.Group{}
 	eg.Go(func() error {
 		defer func() {
 			cancel()
 		}()
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 		select {
-		case <-c:
+		case <-sigs:
 			log.Debug().Msg("received sigterm")
 		case <-ctx.Done():
 			return ctx.Err()
@@ -138,6 +144,8 @@ func (c *Cmd) run(cmd *cobra.Command, args []string) {
 			log.Debug().Msg("done")
 		} else if c.transform {
 			err = c.performTransform(ctx)
+		} else {
+			err = NewGrpcServer(c.definition).Serve(ctx, c.grpcAddress)
 		}
 		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Warn().Err(err).Msgf("exited with error")
@@ -220,6 +228,12 @@ func (c *Cmd) performTransform(ctx context.Context) error {
 	api.SetReader(os.Stdin)
 	api.SetWriter(os.Stdout)
 
+	batchApi, isBatchApi := api.(BatchInteractionApi)
+	batchTransformer, isBatchTransformer := transformer.(BatchTransformer)
+	if isBatchApi && isBatchTransformer {
+		return performTransformBatch(ctx, batchTransformer, batchApi, driver)
+	}
+
 	record := NewRecord(driver)
 	for {
 
@@ -254,7 +268,7 @@ func (c *Cmd) performTransform(ctx context.Context) error {
 		}
 
 		go func() {
-			err = api.Encode(ctx, resultRow)
+			err = encodeRow(ctx, api, resultRow)
 			rwChan <- struct{}{}
 		}()
 
@@ -266,6 +280,96 @@ func (c *Cmd) performTransform(ctx context.Context) error {
 	}
 }
 
+// performTransformBatch - transforms all the rows of the received message by a single TransformBatch call and
+// writes them in a single message
+func performTransformBatch(ctx context.Context, transformer BatchTransformer, api BatchInteractionApi, driver *Driver) error {
+	rwChan := make(chan struct{}, 1)
+	var records []*Record
+	for {
+		var count int
+		var err error
+		go func() {
+			defer func() {
+				rwChan <- struct{}{}
+			}()
+			for {
+				var row RowDriver
+				row, err = api.Decode(ctx)
+				if err != nil {
+					return
+				}
+				if count == len(records) {
+					records = append(records, NewRecord(driver))
+				}
+				records[count].SetRow(row)
+				count++
+				if api.PendingRows() == 0 {
+					return
+				}
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-rwChan:
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("error decoding data via api: %w", err)
+		}
+
+		if err = transformer.TransformBatch(ctx, records[:count]); err != nil {
+			return fmt.Errorf("transformation error: %w", err)
+		}
+
+		go func() {
+			defer func() {
+				rwChan <- struct{}{}
+			}()
+			for _, r := range records[:count] {
+				var row RowDriver
+				if row, err = r.Encode(); err != nil {
+					err = fmt.Errorf("error encoding record: %w", err)
+					return
+				}
+				if err = api.AppendRow(row); err != nil {
+					return
+				}
+			}
+			err = api.Flush(ctx)
+		}()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-rwChan:
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// encodeRow - writes the transformed row. If the API supports batches, the rows are written in a single message
+// when the last row of the received message is transformed
+func encodeRow(ctx context.Context, api InteractionApi, row RowDriver) error {
+	batchApi, ok := api.(BatchInteractionApi)
+	if !ok {
+		return api.Encode(ctx, row)
+	}
+	if err := batchApi.AppendRow(row); err != nil {
+		return err
+	}
+	if batchApi.PendingRows() > 0 {
+		return nil
+	}
+	return batchApi.Flush(ctx)
+}
+
 func (c *Cmd) init(ctx context.Context) (Transformer, *Driver, ValidationWarnings, error) {
 	var warnings ValidationWarnings
 
@@ -302,13 +406,28 @@ func (c *Cmd) init(ctx context.Context) (Transformer, *Driver, ValidationWarning
 	if err := json.Unmarshal(data, meta); err != nil {
 		return nil, nil, nil, fmt.Errorf("error umarshalling meta: %w", err)
 	}
-
 	c.meta = meta
+
+	t, driver, params, warnings, err := newTransformerFromMeta(ctx, c.definition, meta)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	c.params = params
+	return t, driver, warnings, nil
+}
+
+// newTransformerFromMeta - validates the received metadata, initializes the driver and the parameters and creates
+// the transformer. The transformer is nil if the parameters have fatal warnings
+func newTransformerFromMeta(ctx context.Context, definition *TransformerDefinition, meta *Meta) (
+	Transformer, *Driver, map[string]Parameterizer, ValidationWarnings, error,
+) {
+	var warnings ValidationWarnings
+
 	if meta.Table == nil {
-		return nil, nil, nil, fmt.Errorf("error umarshalling meta: empty Table")
+		return nil, nil, nil, nil, fmt.Errorf("error umarshalling meta: empty Table")
 	}
 	if err := meta.Table.Validate(); err != nil {
-		return nil, nil, nil, fmt.Errorf("metadata validation error: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("metadata validation error: %w", err)
 	}
 	log.Debug().Msg("validation completed")
 
@@ -317,25 +436,24 @@ func (c *Cmd) init(ctx context.Context) (Transformer, *Driver, ValidationWarning
 
 	driver, driverWarnings, err := NewDriver(meta.Table, meta.Types)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("error initilizing Driver: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("error initilizing Driver: %w", err)
 	}
 	warnings = append(warnings, driverWarnings...)
 
-	params, pw, err := InitParameters(driver, c.definition.Parameters, meta.Parameters.Static, meta.Parameters.Dynamic)
+	params, pw, err := InitParameters(driver, definition.Parameters, meta.Parameters.Static, meta.Parameters.Dynamic)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("error parsing parameters: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("error parsing parameters: %w", err)
 	}
 	if pw.IsFatal() {
-		return nil, nil, pw, nil
+		return nil, nil, nil, pw, nil
 	}
 
-	t, initWarnings, err := c.definition.New(ctx, driver, params)
+	t, initWarnings, err := definition.New(ctx, driver, params)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("error initializing transformer: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("error initializing transformer: %w", err)
 	}
-	c.params = params
 
 	warnings = append(warnings, initWarnings...)
 
-	return t, driver, warnings, nil
+	return t, driver, params, warnings, nil
 }
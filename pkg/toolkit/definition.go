@@ -19,9 +19,10 @@ import (
 )
 
 const (
-	JsonModeName = "json"
-	CsvModeName  = "csv"
-	TextModeName = "text"
+	JsonModeName   = "json"
+	CsvModeName    = "csv"
+	TextModeName   = "text"
+	BinaryModeName = "binary"
 )
 
 var DefaultRowDriverParams = DriverParams{
@@ -40,7 +41,7 @@ type DriverParams struct {
 
 // Validate - validate driver params and set default values if needed
 func (dp *DriverParams) Validate() error {
-	if dp.Name != JsonModeName && dp.Name != CsvModeName && dp.Name != TextModeName {
+	if dp.Name != JsonModeName && dp.Name != CsvModeName && dp.Name != TextModeName && dp.Name != BinaryModeName {
 		return fmt.Errorf(`unexpected driver name "%s"`, dp.Name)
 	}
 
@@ -0,0 +1,311 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	// GrpcServiceName - the full name of the gRPC service implemented by the custom transformers
+	GrpcServiceName = "greenmask.transformer.v1.Transformer"
+	// GrpcCodecName - the content subtype of the messages. The messages are encoded in JSON, so the service can be
+	// implemented in any language without the generated code
+	GrpcCodecName = "json"
+
+	GrpcGetDefinitionMethod = "/" + GrpcServiceName + "/GetDefinition"
+	GrpcValidateMethod      = "/" + GrpcServiceName + "/Validate"
+	GrpcTransformMethod     = "/" + GrpcServiceName + "/Transform"
+)
+
+func init() {
+	encoding.RegisterCodec(grpcJsonCodec{})
+}
+
+type grpcJsonCodec struct{}
+
+func (grpcJsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (grpcJsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (grpcJsonCodec) Name() string {
+	return GrpcCodecName
+}
+
+type GrpcDefinitionRequest struct{}
+
+type GrpcDefinitionResponse struct {
+	// Definition - the transformer definition in the same format as printed by --print-definition
+	Definition json.RawMessage `json:"definition"`
+}
+
+type GrpcValidateRequest struct {
+	Meta json.RawMessage `json:"meta"`
+}
+
+type GrpcValidateResponse struct {
+	Warnings ValidationWarnings `json:"warnings,omitempty"`
+}
+
+// GrpcTransformRequest - the message of the Transform stream. The first message of the stream contains only the
+// metadata, the next ones contain only the rows. Each row is encoded with the transformer driver and ends with the
+// new line as in the Cmd interaction
+type GrpcTransformRequest struct {
+	Meta json.RawMessage `json:"meta,omitempty"`
+	Rows [][]byte        `json:"rows,omitempty"`
+}
+
+// GrpcTransformResponse - the response to the GrpcTransformRequest. The response to the metadata contains the
+// initialization warnings, the response to the rows contains the transformed rows in the same order
+type GrpcTransformResponse struct {
+	Warnings ValidationWarnings `json:"warnings,omitempty"`
+	Rows     [][]byte           `json:"rows,omitempty"`
+}
+
+// ParseGrpcAddress - returns the network and address for the listener. The address is either unix:///path/to.sock
+// or host:port
+func ParseGrpcAddress(address string) (network string, addr string) {
+	if strings.HasPrefix(address, "unix://") {
+		return "unix", strings.TrimPrefix(address, "unix://")
+	}
+	if strings.HasPrefix(address, "unix:") {
+		return "unix", strings.TrimPrefix(address, "unix:")
+	}
+	return "tcp", address
+}
+
+type grpcTransformerServer interface {
+	getDefinition(ctx context.Context, req *GrpcDefinitionRequest) (*GrpcDefinitionResponse, error)
+	validate(ctx context.Context, req *GrpcValidateRequest) (*GrpcValidateResponse, error)
+	transform(stream grpc.ServerStream) error
+}
+
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: GrpcServiceName,
+	HandlerType: (*grpcTransformerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetDefinition",
+			Handler: func(
+				srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor,
+			) (any, error) {
+				req := &GrpcDefinitionRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(grpcTransformerServer).getDefinition(ctx, req.(*GrpcDefinitionRequest))
+				}
+				if interceptor == nil {
+					return handler(ctx, req)
+				}
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: GrpcGetDefinitionMethod}, handler)
+			},
+		},
+		{
+			MethodName: "Validate",
+			Handler: func(
+				srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor,
+			) (any, error) {
+				req := &GrpcValidateRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(grpcTransformerServer).validate(ctx, req.(*GrpcValidateRequest))
+				}
+				if interceptor == nil {
+					return handler(ctx, req)
+				}
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: GrpcValidateMethod}, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Transform",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				return srv.(grpcTransformerServer).transform(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+// GrpcServer - serves the transformer via gRPC. Unlike Cmd a single process serves any number of tables and workers
+// concurrently: each Transform stream gets its own transformer instance
+type GrpcServer struct {
+	definition *TransformerDefinition
+	server     *grpc.Server
+}
+
+func NewGrpcServer(definition *TransformerDefinition, opts ...grpc.ServerOption) *GrpcServer {
+	if definition == nil {
+		panic("definition cannot be nil")
+	}
+	s := &GrpcServer{
+		definition: definition,
+		server:     grpc.NewServer(opts...),
+	}
+	s.server.RegisterService(&grpcServiceDesc, s)
+	return s
+}
+
+// Serve - listens the address and serves the requests until the context is cancelled. The stale unix socket file
+// is removed before listening
+func (s *GrpcServer) Serve(ctx context.Context, address string) error {
+	network, addr := ParseGrpcAddress(address)
+	if network == "unix" {
+		if err := os.Remove(addr); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing unix socket: %w", err)
+		}
+	}
+	lis, err := net.Listen(network, addr)
+	if err != nil {
+		return fmt.Errorf("error listening %s: %w", address, err)
+	}
+	log.Debug().Str("Address", address).Msg("serving transformer via grpc")
+
+	go func() {
+		<-ctx.Done()
+		s.server.GracefulStop()
+	}()
+	if err = s.server.Serve(lis); err != nil {
+		return fmt.Errorf("error serving grpc: %w", err)
+	}
+	return nil
+}
+
+func (s *GrpcServer) getDefinition(_ context.Context, _ *GrpcDefinitionRequest) (*GrpcDefinitionResponse, error) {
+	data, err := json.Marshal(s.definition)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding transformer definition: %w", err)
+	}
+	return &GrpcDefinitionResponse{Definition: data}, nil
+}
+
+func (s *GrpcServer) validate(ctx context.Context, req *GrpcValidateRequest) (*GrpcValidateResponse, error) {
+	transformer, _, _, warnings, err := s.newTransformer(ctx, req.Meta)
+	if err != nil {
+		return nil, err
+	}
+	if warnings.IsFatal() {
+		return &GrpcValidateResponse{Warnings: warnings}, nil
+	}
+	validateWarnings, err := transformer.Validate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error validating transformer: %w", err)
+	}
+	return &GrpcValidateResponse{Warnings: append(warnings, validateWarnings...)}, nil
+}
+
+func (s *GrpcServer) transform(stream grpc.ServerStream) error {
+	ctx := stream.Context()
+	req := &GrpcTransformRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		return fmt.Errorf("error receiving metadata: %w", err)
+	}
+	transformer, driver, params, warnings, err := s.newTransformer(ctx, req.Meta)
+	if err != nil {
+		return err
+	}
+	if warnings.IsFatal() {
+		return stream.SendMsg(&GrpcTransformResponse{Warnings: warnings})
+	}
+
+	affectedColumnsIdx, _, err := GetAffectedAndTransferringColumns(params, driver)
+	if err != nil {
+		return fmt.Errorf("error getting transferring and affected columns: %w", err)
+	}
+	api, err := NewApi(s.definition.Driver, affectedColumnsIdx, affectedColumnsIdx, driver)
+	if err != nil {
+		return fmt.Errorf("error inializing api: %w", err)
+	}
+	if err = stream.SendMsg(&GrpcTransformResponse{Warnings: warnings}); err != nil {
+		return fmt.Errorf("error sending initialization warnings: %w", err)
+	}
+
+	in := &bytes.Buffer{}
+	out := &bytes.Buffer{}
+	api.SetWriter(out)
+	record := NewRecord(driver)
+	for {
+		req = &GrpcTransformRequest{}
+		if err = stream.RecvMsg(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("error receiving rows: %w", err)
+		}
+
+		// The rows of the batch are decoded from the single reader as they were read from stdin
+		in.Reset()
+		for _, row := range req.Rows {
+			in.Write(row)
+		}
+		api.SetReader(in)
+
+		res := &GrpcTransformResponse{Rows: make([][]byte, 0, len(req.Rows))}
+		for range req.Rows {
+			row, err := api.Decode(ctx)
+			if err != nil {
+				return fmt.Errorf("error decoding data via api: %w", err)
+			}
+			record.SetRow(row)
+			if err = transformer.Transform(ctx, record); err != nil {
+				return fmt.Errorf("transformation error: %w", err)
+			}
+			resultRow, err := record.Encode()
+			if err != nil {
+				return fmt.Errorf("error encoding record: %w", err)
+			}
+			out.Reset()
+			if err = api.Encode(ctx, resultRow); err != nil {
+				return fmt.Errorf("error encoding data via api: %w", err)
+			}
+			res.Rows = append(res.Rows, bytes.Clone(out.Bytes()))
+		}
+		if err = stream.SendMsg(res); err != nil {
+			return fmt.Errorf("error sending rows: %w", err)
+		}
+	}
+}
+
+func (s *GrpcServer) newTransformer(ctx context.Context, data json.RawMessage) (
+	Transformer, *Driver, map[string]Parameterizer, ValidationWarnings, error,
+) {
+	meta := &Meta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("error umarshalling meta: %w", err)
+	}
+	return newTransformerFromMeta(ctx, s.definition, meta)
+}
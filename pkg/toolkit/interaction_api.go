@@ -41,6 +41,19 @@ type InteractionApi interface {
 	Clean()
 }
 
+// BatchInteractionApi - the InteractionApi that transfers multiple rows in a single message. AppendRow adds the row to
+// the outgoing message and Flush writes it. Decode returns the rows of the received message one by one, and
+// PendingRows returns the number of rows of the received message that are not decoded yet
+type BatchInteractionApi interface {
+	InteractionApi
+	// AppendRow - encode the row into the outgoing message
+	AppendRow(row RowDriver) error
+	// Flush - write the outgoing message into io.Writer
+	Flush(ctx context.Context) error
+	// PendingRows - count of rows of the received message that are not decoded yet
+	PendingRows() int
+}
+
 func NewApi(rowDriverParams *DriverParams, transferringColumns []*Column, affectedColumns []*Column, driver *Driver) (InteractionApi, error) {
 	var err error
 	var api InteractionApi
@@ -75,6 +88,8 @@ func NewApi(rowDriverParams *DriverParams, transferringColumns []*Column, affect
 		}
 	case CsvModeName:
 		api = NewCsvApi(transferringColumns, affectedColumns, driver, rowDriverParams)
+	case BinaryModeName:
+		api = NewBinaryApi(transferringColumns, affectedColumns)
 	default:
 		return nil, fmt.Errorf("unknown interaction API: %s", rowDriverParams.Name)
 	}
@@ -0,0 +1,119 @@
+// Copyright 2023 Greenmask
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolkit
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// binaryNullLength - the length of the NULL value, it is -1 as int32
+const binaryNullLength uint32 = 0xFFFFFFFF
+
+// RawRecordBinary - the row of the binary interaction format. It contains only the transferred columns and is
+// encoded as
+//
+//	uint16 columns count
+//	for each column: uint16 column index, int32 data length (-1 for NULL), data
+//
+// All the integers are big endian. The decoded values reference the decoded data
+type RawRecordBinary struct {
+	indexes []int
+	values  []RawValue
+}
+
+func NewRawRecordBinary() *RawRecordBinary {
+	return &RawRecordBinary{}
+}
+
+func (rr *RawRecordBinary) GetColumn(idx int) (*RawValue, error) {
+	for i, columnIdx := range rr.indexes {
+		if columnIdx == idx {
+			return &rr.values[i], nil
+		}
+	}
+	return nil, fmt.Errorf("column with idx=%d is not found", idx)
+}
+
+func (rr *RawRecordBinary) SetColumn(idx int, v *RawValue) error {
+	for i, columnIdx := range rr.indexes {
+		if columnIdx == idx {
+			rr.values[i] = *v
+			return nil
+		}
+	}
+	rr.indexes = append(rr.indexes, idx)
+	rr.values = append(rr.values, *v)
+	return nil
+}
+
+func (rr *RawRecordBinary) Encode() ([]byte, error) {
+	return rr.appendEncoded(nil), nil
+}
+
+func (rr *RawRecordBinary) appendEncoded(buf []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(rr.indexes)))
+	for i, idx := range rr.indexes {
+		buf = binary.BigEndian.AppendUint16(buf, uint16(idx))
+		if rr.values[i].IsNull {
+			buf = binary.BigEndian.AppendUint32(buf, binaryNullLength)
+			continue
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(rr.values[i].Data)))
+		buf = append(buf, rr.values[i].Data...)
+	}
+	return buf
+}
+
+func (rr *RawRecordBinary) Decode(data []byte) error {
+	rr.Clean()
+	if len(data) < 2 {
+		return fmt.Errorf("binary row is too short")
+	}
+	count := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	for i := 0; i < count; i++ {
+		if len(data) < 6 {
+			return fmt.Errorf("binary row is truncated at column %d", i)
+		}
+		idx := int(binary.BigEndian.Uint16(data))
+		length := binary.BigEndian.Uint32(data[2:])
+		data = data[6:]
+		if length == binaryNullLength {
+			rr.indexes = append(rr.indexes, idx)
+			rr.values = append(rr.values, RawValue{IsNull: true})
+			continue
+		}
+		if uint64(length) > uint64(len(data)) {
+			return fmt.Errorf("binary row is truncated at column %d", i)
+		}
+		rr.indexes = append(rr.indexes, idx)
+		rr.values = append(rr.values, RawValue{Data: data[:length:length]})
+		data = data[length:]
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("binary row has %d unexpected trailing bytes", len(data))
+	}
+	return nil
+}
+
+func (rr *RawRecordBinary) Length() int {
+	return len(rr.indexes)
+}
+
+func (rr *RawRecordBinary) Clean() {
+	rr.indexes = rr.indexes[:0]
+	rr.values = rr.values[:0]
+}
@@ -26,3 +26,11 @@ type Transformer interface {
 	Validate(ctx context.Context) (ValidationWarnings, error)
 	Transform(ctx context.Context, r *Record) error
 }
+
+// BatchTransformer - the optional interface of the Transformer that transforms all the records of the received
+// message at once, for instance to perform a single lookup for them. TransformBatch is called instead of Transform
+// when the driver transfers the rows in batches
+type BatchTransformer interface {
+	Transformer
+	TransformBatch(ctx context.Context, records []*Record) error
+}